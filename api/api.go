@@ -10,6 +10,7 @@ import (
 	"go.sia.tech/hostd/host/alerts"
 	"go.sia.tech/hostd/host/contracts"
 	"go.sia.tech/hostd/host/metrics"
+	"go.sia.tech/hostd/host/selftest"
 	"go.sia.tech/hostd/host/settings"
 	"go.sia.tech/hostd/host/storage"
 	"go.sia.tech/hostd/logging"
@@ -34,11 +35,33 @@ type (
 	// Settings updates and retrieves the host's settings
 	Settings interface {
 		Announce() error
+		// LastAnnouncement returns the host's last broadcast announcement
+		LastAnnouncement() (settings.Announcement, error)
 
 		UpdateSettings(s settings.Settings) error
-		Settings() settings.Settings
+		// BaseSettings returns the host's configured settings without the
+		// active settings profile applied
+		BaseSettings() settings.Settings
+		// History returns the recorded revisions of the host's settings,
+		// newest first.
+		History(limit, offset int) ([]settings.SettingsRevision, error)
+		// Rollback restores the host's settings to a previous revision.
+		Rollback(revision uint64) (settings.Settings, error)
+
+		// Profiles returns the host's settings profiles and the name of the
+		// active profile
+		Profiles() ([]settings.SettingsProfile, string)
+		// UpdateProfile adds or replaces a settings profile
+		UpdateProfile(settings.SettingsProfile) error
+		// RemoveProfile removes a settings profile
+		RemoveProfile(name string) error
 
 		UpdateDDNS(force bool) error
+		// DDNSStatus returns the health of the host's dynamic DNS
+		DDNSStatus() (settings.DDNSStatus, error)
+		// DDNSHistory returns the recorded dynamic DNS update attempts,
+		// newest first
+		DDNSHistory(limit, offset int) ([]settings.DDNSAttempt, error)
 	}
 
 	// Metrics retrieves metrics related to the host
@@ -47,6 +70,9 @@ type (
 		PeriodMetrics(start time.Time, periods int, interval metrics.Interval) (period []metrics.Metrics, err error)
 		// Metrics returns aggregated metrics for the host as of the timestamp.
 		Metrics(time.Time) (m metrics.Metrics, err error)
+		// PriceHistory returns the price changes made by the pricing
+		// engine between start and end.
+		PriceHistory(start, end time.Time) ([]metrics.PriceChange, error)
 	}
 
 	// A VolumeManager manages the host's storage volumes
@@ -54,23 +80,83 @@ type (
 		Usage() (usedSectors uint64, totalSectors uint64, err error)
 		Volumes() ([]storage.VolumeMeta, error)
 		Volume(id int) (storage.VolumeMeta, error)
-		AddVolume(localPath string, maxSectors uint64, result chan<- error) (storage.Volume, error)
+		AddVolume(localPath string, maxSectors uint64, backend storage.VolumeBackend, result chan<- error) (storage.Volume, error)
 		RemoveVolume(id int, force bool, result chan<- error) error
 		ResizeVolume(id int, maxSectors uint64, result chan<- error) error
 		SetReadOnly(id int, readOnly bool) error
 		RemoveSector(root types.Hash256) error
 		ResizeCache(size uint32)
+		// SetVolumeTier sets the storage tier of a volume.
+		SetVolumeTier(id int, tier string) error
+		// SetTierSettings updates the thresholds and bandwidth limit of the
+		// tier migrator.
+		SetTierSettings(storage.TierSettings)
+		// SetScrubRate sets the maximum read rate of the scrubber
+		SetScrubRate(bytesPerSecond uint64)
+
+		// ScrubVolume starts or resumes an integrity scrub of a volume
+		ScrubVolume(id int) error
+		// CancelScrub stops a running scrub, persisting its progress
+		CancelScrub(id int) error
+		// ScrubStatus returns the progress of a volume's scrub
+		ScrubStatus(id int) (storage.ScrubStatus, error)
+		// PlanRebalance returns the sector moves needed to evenly utilize
+		// all writable volumes
+		PlanRebalance() (storage.RebalancePlan, error)
+		// Rebalance starts moving sectors between volumes in the background
+		Rebalance(maxBandwidth uint64, result chan<- error) (storage.RebalancePlan, error)
+		// RebalanceStatus returns the progress of the running rebalance
+		RebalanceStatus() storage.RebalanceStatus
+		// CancelRebalance stops the running rebalance
+		CancelRebalance() error
+		// VolumeOperations returns the unfinished add, resize and remove
+		// operations
+		VolumeOperations() ([]storage.VolumeOperation, error)
+		// CancelVolumeOperation stops the running operation on a volume
+		CancelVolumeOperation(id int) error
+		// SetParitySettings updates the scheme and group size of the parity
+		// encoder
+		SetParitySettings(storage.ParitySettings) error
+		// RebuildVolume repopulates a replacement volume with the sectors of
+		// a failed volume, reconstructing unreadable sectors from parity
+		RebuildVolume(from, to int, result chan<- error) error
 	}
 
 	// A ContractManager manages the host's contracts
 	ContractManager interface {
 		Contracts(filter contracts.ContractFilter) ([]contracts.Contract, int, error)
 		Contract(id types.FileContractID) (contracts.Contract, error)
+		// ExportContracts calls fn for each contract matching the filter
+		// with its financial records
+		ExportContracts(filter contracts.ContractFilter, fn func(contracts.ExportedContract) error) error
 
 		// CheckIntegrity checks the integrity of a contract's sector roots on
 		// disk. The result of each sector checked is sent on the returned
 		// channel. Read errors are logged.
 		CheckIntegrity(ctx context.Context, contractID types.FileContractID) (<-chan contracts.IntegrityResult, uint64, error)
+		// SetProofFeePolicy sets the fee policy used when submitting storage
+		// proofs
+		SetProofFeePolicy(contracts.ProofFeePolicy)
+		// SubscribeEvents returns a channel that receives contract lifecycle
+		// events and a function to unsubscribe
+		SubscribeEvents() (<-chan contracts.Event, func())
+		// SetWebhooks sets the URLs that contract lifecycle events are posted
+		// to
+		SetWebhooks([]string) error
+
+		// RenterPolicy returns the policy of a renter
+		RenterPolicy(types.PublicKey) (contracts.RenterPolicy, error)
+		// RenterPolicies returns all renter policies
+		RenterPolicies() ([]contracts.RenterPolicy, error)
+		// SetRenterPolicy sets the policy of a renter
+		SetRenterPolicy(contracts.RenterPolicy) error
+		// RemoveRenterPolicy removes the policy of a renter
+		RemoveRenterPolicy(types.PublicKey) error
+		// Renters returns aggregate statistics for renters ordered by the
+		// time they were last seen and the total number of renters
+		Renters(limit, offset int) ([]contracts.RenterStats, int, error)
+		// RenterStats returns aggregate statistics for a renter
+		RenterStats(types.PublicKey) (contracts.RenterStats, error)
 	}
 
 	// Alerts retrieves and dismisses notifications
@@ -105,6 +191,12 @@ type (
 		AcceptTransactionSet(txns []types.Transaction) error
 	}
 
+	// A SelfTester checks that the host's RHP endpoints are reachable at
+	// the host's net address
+	SelfTester interface {
+		Run(context.Context) selftest.Report
+	}
+
 	// An api provides an HTTP API for the host
 	api struct {
 		hostKey types.PublicKey
@@ -122,13 +214,14 @@ type (
 		logs      LogStore
 		metrics   Metrics
 		settings  Settings
+		selftest  SelfTester
 
 		checks integrityCheckJobs
 	}
 )
 
 // NewServer initializes the API
-func NewServer(name string, hostKey types.PublicKey, a Alerts, g Syncer, chain ChainManager, tp TPool, cm ContractManager, vm VolumeManager, m Metrics, ls LogStore, s Settings, w Wallet, log *zap.Logger) http.Handler {
+func NewServer(name string, hostKey types.PublicKey, a Alerts, g Syncer, chain ChainManager, tp TPool, cm ContractManager, vm VolumeManager, m Metrics, ls LogStore, s Settings, w Wallet, st SelfTester, log *zap.Logger) http.Handler {
 	api := &api{
 		hostKey: hostKey,
 		name:    name,
@@ -148,6 +241,7 @@ func NewServer(name string, hostKey types.PublicKey, a Alerts, g Syncer, chain C
 		metrics:   m,
 		settings:  s,
 		wallet:    w,
+		selftest:  st,
 		log:       log,
 	}
 	return jape.Mux(map[string]jape.Handler{
@@ -163,28 +257,61 @@ func NewServer(name string, hostKey types.PublicKey, a Alerts, g Syncer, chain C
 		"GET /alerts":          api.handleGETAlerts,
 		"POST /alerts/dismiss": api.handlePOSTAlertsDismiss,
 		// settings endpoints
-		"GET /settings":             api.handleGETSettings,
-		"PATCH /settings":           api.handlePATCHSettings,
-		"POST /settings/announce":   api.handlePOSTAnnounce,
-		"PUT /settings/ddns/update": api.handlePUTDDNSUpdate,
+		"GET /settings":                     api.handleGETSettings,
+		"PATCH /settings":                   api.handlePATCHSettings,
+		"POST /settings/announce":           api.handlePOSTAnnounce,
+		"PUT /settings/ddns/update":         api.handlePUTDDNSUpdate,
+		"GET /settings/ddns/status":         api.handleGETDDNSStatus,
+		"GET /settings/ddns/history":        api.handleGETDDNSHistory,
+		"GET /settings/history":             api.handleGETSettingsHistory,
+		"POST /settings/rollback/:revision": api.handlePOSTSettingsRollback,
+		"GET /settings/profiles":            api.handleGETSettingsProfiles,
+		"PUT /settings/profiles/:name":      api.handlePUTSettingsProfile,
+		"DELETE /settings/profiles/:name":   api.handleDELETESettingsProfile,
 		// metrics endpoints
 		"GET /metrics":         api.handleGETMetrics,
 		"GET /metrics/:period": api.handleGETPeriodMetrics,
+		// price endpoints. The history is kept with the other metrics, but
+		// GET /metrics/prices cannot coexist with GET /metrics/:period in
+		// httprouter v1.3.0.
+		"GET /prices/history": api.handleGETPriceHistory,
 		// contract endpoints
 		"POST /contracts":                 api.handlePostContracts,
 		"GET /contracts/:id":              api.handleGETContract,
 		"GET /contracts/:id/integrity":    api.handleGETContractCheck,
 		"PUT /contracts/:id/integrity":    api.handlePUTContractCheck,
 		"DELETE /contracts/:id/integrity": api.handleDeleteContractCheck,
+		// contract export endpoints. GET /contracts/export would share a path
+		// segment with GET /contracts/:id, which httprouter v1.3.0 rejects.
+		"GET /export/contracts": api.handleGETContractsExport,
+		// contract event endpoints. The stream lives under /events because
+		// httprouter v1.3.0 panics when /contracts/events is registered next
+		// to the /contracts/:id wildcard.
+		"GET /events/contracts": api.handleGETContractEvents,
+		// renter endpoints
+		"GET /renters":                api.handleGETRenters,
+		"GET /renters/:key":           api.handleGETRenter,
+		"GET /renters/:key/policy":    api.handleGETRenterPolicy,
+		"PUT /renters/:key/policy":    api.handlePUTRenterPolicy,
+		"DELETE /renters/:key/policy": api.handleDELETERenterPolicy,
+		// the policy list cannot be GET /renters/policies since httprouter
+		// v1.3.0 does not allow it next to GET /renters/:key.
+		"GET /policies/renters": api.handleGETRenterPolicies,
 		// sector endpoints
 		"DELETE /sectors/:root": api.handleDeleteSector,
 		// volume endpoints
-		"GET /volumes":            api.handleGETVolumes,
-		"POST /volumes":           api.handlePOSTVolume,
-		"GET /volumes/:id":        api.handleGETVolume,
-		"PUT /volumes/:id":        api.handlePUTVolume,
-		"DELETE /volumes/:id":     api.handleDeleteVolume,
-		"PUT /volumes/:id/resize": api.handlePUTVolumeResize,
+		"GET /volumes":              api.handleGETVolumes,
+		"POST /volumes":             api.handlePOSTVolume,
+		"GET /volumes/:id":          api.handleGETVolume,
+		"PUT /volumes/:id":          api.handlePUTVolume,
+		"DELETE /volumes/:id":       api.handleDeleteVolume,
+		"PUT /volumes/:id/resize":   api.handlePUTVolumeResize,
+		"GET /volumes/:id/scrub":    api.handleGETVolumeScrub,
+		"PUT /volumes/:id/scrub":    api.handlePUTVolumeScrub,
+		"DELETE /volumes/:id/scrub": api.handleDELETEVolumeScrub,
+
+		"DELETE /volumes/:id/operation": api.handleDELETEVolumeOperation,
+		"PUT /volumes/:id/rebuild":      api.handlePUTVolumeRebuild,
 		// tpool endpoints
 		"GET /tpool/fee": api.handleGETTPoolFee,
 		// wallet endpoints
@@ -195,6 +322,18 @@ func NewServer(name string, hostKey types.PublicKey, a Alerts, g Syncer, chain C
 		// system endpoints
 		"GET /system/dir": api.handleGETSystemDir,
 		"PUT /system/dir": api.handlePUTSystemDir,
+
+		"POST /system/selftest": api.handlePOSTSystemSelfTest,
+		// rebalance endpoints. httprouter v1.3.0 cannot register
+		// /volumes/rebalance next to the /volumes/:id wildcard, so the
+		// rebalance job is served from /system/rebalance instead.
+		"GET /system/rebalance":    api.handleGETVolumesRebalance,
+		"PUT /system/rebalance":    api.handlePUTVolumesRebalance,
+		"DELETE /system/rebalance": api.handleDELETEVolumesRebalance,
+		// volume operation endpoints. GET /volumes/operations would
+		// conflict with GET /volumes/:id, operations are cancelled through
+		// DELETE /volumes/:id/operation.
+		"GET /system/operations": api.handleGETVolumesOperations,
 		// log endpoints
 		"POST /log/entries":   api.handlePOSTLogEntries,
 		"DELETE /log/entries": api.handleDELETELogEntries,
@@ -1,14 +1,22 @@
 package api
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"go.sia.tech/core/types"
 	"go.sia.tech/hostd/host/contracts"
 	"go.sia.tech/hostd/host/metrics"
+	"go.sia.tech/hostd/host/selftest"
 	"go.sia.tech/hostd/host/settings"
 	"go.sia.tech/hostd/host/storage"
 	"go.sia.tech/hostd/logging"
@@ -82,6 +90,48 @@ func (c *Client) TestDDNS() error {
 	return c.c.PUT("/settings/ddns/update", nil)
 }
 
+// DDNSStatus returns the health of the host's dynamic DNS.
+func (c *Client) DDNSStatus() (status settings.DDNSStatus, err error) {
+	err = c.c.GET("/settings/ddns/status", &status)
+	return
+}
+
+// DDNSHistory returns the recorded dynamic DNS update attempts, newest first.
+func (c *Client) DDNSHistory(limit, offset int) (history []settings.DDNSAttempt, err error) {
+	err = c.c.GET(fmt.Sprintf("/settings/ddns/history?limit=%d&offset=%d", limit, offset), &history)
+	return
+}
+
+// SettingsHistory returns the recorded revisions of the host's settings,
+// newest first.
+func (c *Client) SettingsHistory(limit, offset int) (history []settings.SettingsRevision, err error) {
+	err = c.c.GET(fmt.Sprintf("/settings/history?limit=%d&offset=%d", limit, offset), &history)
+	return
+}
+
+// RollbackSettings restores the host's settings to a previous revision.
+func (c *Client) RollbackSettings(revision uint64) (config settings.Settings, err error) {
+	err = c.c.POST(fmt.Sprintf("/settings/rollback/%d", revision), nil, &config)
+	return
+}
+
+// SettingsProfiles returns the host's settings profiles and the name of the
+// active profile.
+func (c *Client) SettingsProfiles() (resp SettingsProfilesResponse, err error) {
+	err = c.c.GET("/settings/profiles", &resp)
+	return
+}
+
+// UpdateSettingsProfile adds or replaces a settings profile.
+func (c *Client) UpdateSettingsProfile(profile settings.SettingsProfile) error {
+	return c.c.PUT("/settings/profiles/"+profile.Name, profile)
+}
+
+// RemoveSettingsProfile removes a settings profile.
+func (c *Client) RemoveSettingsProfile(name string) error {
+	return c.c.DELETE("/settings/profiles/" + name)
+}
+
 // Metrics returns the metrics of the host at the specified time.
 func (c *Client) Metrics(at time.Time) (metrics metrics.Metrics, err error) {
 	v := url.Values{
@@ -91,6 +141,18 @@ func (c *Client) Metrics(at time.Time) (metrics metrics.Metrics, err error) {
 	return
 }
 
+// PriceHistory returns the price changes made by the pricing engine between
+// start and end. It requests /prices/history since a /metrics/prices route
+// would clash with the /metrics/:period parameter.
+func (c *Client) PriceHistory(start, end time.Time) (history []metrics.PriceChange, err error) {
+	v := url.Values{
+		"start": []string{start.Format(time.RFC3339)},
+		"end":   []string{end.Format(time.RFC3339)},
+	}
+	err = c.c.GET("/prices/history?"+v.Encode(), &history)
+	return
+}
+
 // PeriodMetrics returns the metrics of the host for n periods starting at start.
 func (c *Client) PeriodMetrics(start time.Time, n int, interval metrics.Interval) (periods []metrics.Metrics, err error) {
 	v := url.Values{
@@ -114,6 +176,107 @@ func (c *Client) Contract(id types.FileContractID) (contract contracts.Contract,
 	return
 }
 
+// ContractEvents streams contract lifecycle events to fn until ctx is
+// cancelled or fn returns an error. The stream is served from /events/contracts
+// because /contracts/events would collide with the /contracts/:id parameter in
+// the router.
+func (c *Client) ContractEvents(ctx context.Context, fn func(contracts.Event) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%v/events/contracts", c.c.BaseURL), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth("", c.c.Password)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.New(strings.TrimSpace(string(body)))
+	}
+
+	s := bufio.NewScanner(resp.Body)
+	for s.Scan() {
+		line := s.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var event contracts.Event
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+			return fmt.Errorf("failed to decode event: %w", err)
+		} else if err := fn(event); err != nil {
+			return err
+		}
+	}
+	if err := s.Err(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// ExportContracts writes the contracts matching the filter, with their
+// financial records, to w in the specified format. Exports are requested from
+// /export/contracts; the router cannot register /contracts/export alongside
+// the contract ID parameter.
+func (c *Client) ExportContracts(w io.Writer, format string, filter contracts.ContractFilter) error {
+	query := encodeContractFilter(filter)
+	query.Set("format", format)
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%v/export/contracts?%v", c.c.BaseURL, query.Encode()), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth("", c.c.Password)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.New(strings.TrimSpace(string(body)))
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Renters returns aggregate statistics for renters ordered by the time they
+// were last seen.
+func (c *Client) Renters(limit, offset int) (resp RentersResponse, err error) {
+	err = c.c.GET(fmt.Sprintf("/renters?limit=%d&offset=%d", limit, offset), &resp)
+	return
+}
+
+// Renter returns the statistics and policy of a renter.
+func (c *Client) Renter(renterKey types.PublicKey) (resp RenterResponse, err error) {
+	err = c.c.GET("/renters/"+renterKey.String(), &resp)
+	return
+}
+
+// RenterPolicies returns all renter policies. The list is served from
+// /policies/renters because /renters/policies conflicts with the /renters/:key
+// route.
+func (c *Client) RenterPolicies() (policies []contracts.RenterPolicy, err error) {
+	err = c.c.GET("/policies/renters", &policies)
+	return
+}
+
+// RenterPolicy returns the policy of a renter.
+func (c *Client) RenterPolicy(renterKey types.PublicKey) (policy contracts.RenterPolicy, err error) {
+	err = c.c.GET(fmt.Sprintf("/renters/%v/policy", renterKey), &policy)
+	return
+}
+
+// SetRenterPolicy sets the policy of a renter.
+func (c *Client) SetRenterPolicy(policy contracts.RenterPolicy) error {
+	return c.c.PUT(fmt.Sprintf("/renters/%v/policy", policy.RenterKey), policy)
+}
+
+// RemoveRenterPolicy removes the policy of a renter.
+func (c *Client) RemoveRenterPolicy(renterKey types.PublicKey) error {
+	return c.c.DELETE(fmt.Sprintf("/renters/%v/policy", renterKey))
+}
+
 // StartIntegrityCheck scans the volume with the specified ID for consistency errors.
 func (c *Client) StartIntegrityCheck(id types.FileContractID) error {
 	return c.c.PUT(fmt.Sprintf("/contracts/%v/integrity", id), nil)
@@ -150,11 +313,13 @@ func (c *Client) Volume(id int) (volume VolumeMeta, err error) {
 	return
 }
 
-// AddVolume adds a new volume to the host
-func (c *Client) AddVolume(localPath string, sectors uint64) (vol storage.Volume, err error) {
+// AddVolume adds a new volume to the host. An empty backend stores the
+// volume's data in a flat file at localPath.
+func (c *Client) AddVolume(localPath string, sectors uint64, backend storage.VolumeBackend) (vol storage.Volume, err error) {
 	req := AddVolumeRequest{
 		LocalPath:  localPath,
 		MaxSectors: sectors,
+		Backend:    backend,
 	}
 	err = c.c.POST("/volumes", req, &vol)
 	return
@@ -178,6 +343,69 @@ func (c *Client) ResizeVolume(id int, sectors uint64) error {
 	return c.c.PUT(fmt.Sprintf("/volumes/%v/resize", id), req)
 }
 
+// Rebalance starts moving sectors between volumes until every writable volume
+// has a similar utilization. maxBandwidth limits the number of bytes per second
+// moved, 0 is unlimited. The planned moves can be retrieved with
+// RebalanceStatus.
+//
+// The rebalance endpoints are under /system/rebalance rather than
+// /volumes/rebalance because the router does not allow a static path segment
+// to share a position with the /volumes/:id parameter.
+func (c *Client) Rebalance(maxBandwidth uint64) error {
+	return c.c.PUT("/system/rebalance", RebalanceRequest{MaxBandwidth: maxBandwidth})
+}
+
+// RebalanceStatus returns the progress of the running rebalance. If no
+// rebalance is running, the status contains the moves that would be made
+// without moving any sectors.
+func (c *Client) RebalanceStatus() (status storage.RebalanceStatus, err error) {
+	err = c.c.GET("/system/rebalance", &status)
+	return
+}
+
+// CancelRebalance stops the running rebalance.
+func (c *Client) CancelRebalance() error {
+	return c.c.DELETE("/system/rebalance")
+}
+
+// ScrubVolume starts an integrity scrub of the volume with the specified ID.
+// The scrubber's read rate is set by the host's ScrubRate setting.
+func (c *Client) ScrubVolume(id int) error {
+	return c.c.PUT(fmt.Sprintf("/volumes/%v/scrub", id), nil)
+}
+
+// ScrubStatus returns the progress of the volume's integrity scrub.
+func (c *Client) ScrubStatus(id int) (status storage.ScrubStatus, err error) {
+	err = c.c.GET(fmt.Sprintf("/volumes/%v/scrub", id), &status)
+	return
+}
+
+// CancelScrub stops the volume's integrity scrub. The scrub will be resumed
+// from its current position the next time it is started.
+func (c *Client) CancelScrub(id int) error {
+	return c.c.DELETE(fmt.Sprintf("/volumes/%v/scrub", id))
+}
+
+// VolumeOperations returns the unfinished add, resize and remove operations.
+// They are listed by /system/operations since the router cannot serve
+// /volumes/operations alongside /volumes/:id.
+func (c *Client) VolumeOperations() (ops []storage.VolumeOperation, err error) {
+	err = c.c.GET("/system/operations", &ops)
+	return
+}
+
+// CancelVolumeOperation stops the running operation on a volume. The volume
+// is left at its current size.
+func (c *Client) CancelVolumeOperation(id int) error {
+	return c.c.DELETE(fmt.Sprintf("/volumes/%v/operation", id))
+}
+
+// RebuildVolume moves the sectors of a failed volume to a replacement volume.
+// Sectors that cannot be read are reconstructed from parity.
+func (c *Client) RebuildVolume(id, replacementID int) error {
+	return c.c.PUT(fmt.Sprintf("/volumes/%v/rebuild", id), RebuildVolumeRequest{ReplacementID: replacementID})
+}
+
 // Wallet returns the state of the host's wallet.
 func (c *Client) Wallet() (resp WalletResponse, err error) {
 	err = c.c.GET("/wallet", &resp)
@@ -225,6 +453,13 @@ func (c *Client) MkDir(path string) error {
 	return c.c.PUT("/system/dir", req)
 }
 
+// SelfTest dials the host's RHP2, RHP3 TCP, and RHP3 WebSocket endpoints at
+// the host's net address and reports the latency and errors of each.
+func (c *Client) SelfTest() (resp selftest.Report, err error) {
+	err = c.c.POST("/system/selftest", nil, &resp)
+	return
+}
+
 // LogEntries returns log entries matching the filter.
 func (c *Client) LogEntries(filter logging.Filter) ([]logging.Entry, int, error) {
 	var resp LogResponse
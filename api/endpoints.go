@@ -10,6 +10,7 @@ import (
 	"runtime"
 	"time"
 
+	rhpv2 "go.sia.tech/core/rhp/v2"
 	"go.sia.tech/core/types"
 	"go.sia.tech/hostd/build"
 	"go.sia.tech/hostd/host/contracts"
@@ -38,11 +39,17 @@ func (a *api) checkServerError(c jape.Context, context string, err error) bool {
 }
 
 func (a *api) handleGETHostState(c jape.Context) {
+	announcement, err := a.settings.LastAnnouncement()
+	if !a.checkServerError(c, "failed to get last announcement", err) {
+		return
+	}
+
 	c.Encode(HostState{
-		Name:          a.name,
-		PublicKey:     a.hostKey,
-		WalletAddress: a.wallet.Address(),
-		StartTime:     startTime,
+		Name:             a.name,
+		PublicKey:        a.hostKey,
+		WalletAddress:    a.wallet.Address(),
+		StartTime:        startTime,
+		LastAnnouncement: announcement,
 		BuildState: BuildState{
 			Network:   build.NetworkName(),
 			Version:   build.Version(),
@@ -115,11 +122,11 @@ func (a *api) handlePOSTAnnounce(c jape.Context) {
 }
 
 func (a *api) handleGETSettings(c jape.Context) {
-	c.Encode(a.settings.Settings())
+	c.Encode(a.settings.BaseSettings())
 }
 
 func (a *api) handlePATCHSettings(c jape.Context) {
-	buf, err := json.Marshal(a.settings.Settings())
+	buf, err := json.Marshal(a.settings.BaseSettings())
 	if !a.checkServerError(c, "failed to marshal existing settings", err) {
 		return
 	}
@@ -150,15 +157,122 @@ func (a *api) handlePATCHSettings(c jape.Context) {
 		return
 	}
 
+	parity := storage.ParitySettings{
+		Scheme:       settings.ParityScheme,
+		DataShards:   settings.ParityDataShards,
+		ParityShards: settings.ParityShards,
+	}
+	if err := parity.Validate(); err != nil {
+		c.Error(err, http.StatusBadRequest)
+		return
+	}
+	if err := settings.PricingEngine.Validate(); err != nil {
+		c.Error(err, http.StatusBadRequest)
+		return
+	}
+	for _, webhook := range settings.ContractWebhooks {
+		if err := contracts.ValidateWebhook(webhook); err != nil {
+			c.Error(err, http.StatusBadRequest)
+			return
+		}
+	}
+
 	err = a.settings.UpdateSettings(settings)
 	if !a.checkServerError(c, "failed to update settings", err) {
 		return
+	} else if !a.applySettings(c, settings) {
+		return
+	}
+	c.Encode(a.settings.BaseSettings())
+}
+
+func (a *api) handleGETSettingsHistory(c jape.Context) {
+	limit, offset := parseLimitParams(c, 100, 500)
+	history, err := a.settings.History(limit, offset)
+	if !a.checkServerError(c, "failed to get settings history", err) {
+		return
+	}
+	c.Encode(history)
+}
+
+func (a *api) handlePOSTSettingsRollback(c jape.Context) {
+	var revision int
+	if err := c.DecodeParam("revision", &revision); err != nil {
+		return
+	} else if revision < 0 {
+		c.Error(errors.New("revision must be non-negative"), http.StatusBadRequest)
+		return
+	}
+
+	restored, err := a.settings.Rollback(uint64(revision))
+	if errors.Is(err, settings.ErrRevisionNotFound) {
+		c.Error(err, http.StatusNotFound)
+		return
+	} else if !a.checkServerError(c, "failed to roll back settings", err) {
+		return
+	} else if !a.applySettings(c, restored) {
+		return
+	}
+	c.Encode(a.settings.BaseSettings())
+}
+
+func (a *api) handleGETSettingsProfiles(c jape.Context) {
+	profiles, active := a.settings.Profiles()
+	c.Encode(SettingsProfilesResponse{
+		Active:   active,
+		Profiles: profiles,
+	})
+}
+
+func (a *api) handlePUTSettingsProfile(c jape.Context) {
+	var profile settings.SettingsProfile
+	if err := c.Decode(&profile); err != nil {
+		return
+	}
+	profile.Name = c.PathParams.ByName("name")
+	if err := profile.Validate(); err != nil {
+		c.Error(err, http.StatusBadRequest)
+		return
+	}
+	a.checkServerError(c, "failed to update settings profile", a.settings.UpdateProfile(profile))
+}
+
+func (a *api) handleDELETESettingsProfile(c jape.Context) {
+	err := a.settings.RemoveProfile(c.PathParams.ByName("name"))
+	if errors.Is(err, settings.ErrProfileNotFound) {
+		c.Error(err, http.StatusNotFound)
+		return
 	}
+	a.checkServerError(c, "failed to remove settings profile", err)
+}
 
+// applySettings updates the other managers after the host's settings have
+// changed. If an error occurs, it is written to the response and false is
+// returned.
+func (a *api) applySettings(c jape.Context, settings settings.Settings) bool {
 	// Resize the cache based on the updated settings
 	a.volumes.ResizeCache(settings.SectorCacheSize)
-
-	c.Encode(a.settings.Settings())
+	a.volumes.SetScrubRate(settings.ScrubRate)
+	a.volumes.SetTierSettings(storage.TierSettings{
+		PromoteWindow: settings.TierPromoteWindow,
+		DemoteAfter:   settings.TierDemoteAfter,
+		MaxBandwidth:  settings.TierMigrationLimit,
+	})
+	err := a.volumes.SetParitySettings(storage.ParitySettings{
+		Scheme:       settings.ParityScheme,
+		DataShards:   settings.ParityDataShards,
+		ParityShards: settings.ParityShards,
+	})
+	if !a.checkServerError(c, "failed to update parity settings", err) {
+		return false
+	}
+	a.contracts.SetProofFeePolicy(contracts.ProofFeePolicy{
+		ResubmitInterval: settings.ProofResubmitInterval,
+		FeeIncrease:      settings.ProofFeeIncrease,
+		MaxFee:           settings.ProofMaxFee,
+	})
+	err = a.contracts.SetWebhooks(settings.ContractWebhooks)
+	return a.checkServerError(c, "failed to update contract webhooks", err)
 }
 
 func (a *api) handlePUTDDNSUpdate(c jape.Context) {
@@ -166,6 +280,23 @@ func (a *api) handlePUTDDNSUpdate(c jape.Context) {
 	a.checkServerError(c, "failed to update dynamic DNS", err)
 }
 
+func (a *api) handleGETDDNSStatus(c jape.Context) {
+	status, err := a.settings.DDNSStatus()
+	if !a.checkServerError(c, "failed to get dynamic DNS status", err) {
+		return
+	}
+	c.Encode(status)
+}
+
+func (a *api) handleGETDDNSHistory(c jape.Context) {
+	limit, offset := parseLimitParams(c, 100, 500)
+	history, err := a.settings.DDNSHistory(limit, offset)
+	if !a.checkServerError(c, "failed to get dynamic DNS history", err) {
+		return
+	}
+	c.Encode(history)
+}
+
 func (a *api) handleGETMetrics(c jape.Context) {
 	var timestamp time.Time
 	if err := c.DecodeForm("timestamp", &timestamp); err != nil {
@@ -181,6 +312,30 @@ func (a *api) handleGETMetrics(c jape.Context) {
 	c.Encode(metrics)
 }
 
+func (a *api) handleGETPriceHistory(c jape.Context) {
+	var start, end time.Time
+	if err := c.DecodeForm("start", &start); err != nil {
+		return
+	} else if err := c.DecodeForm("end", &end); err != nil {
+		return
+	}
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if start.IsZero() {
+		start = end.Add(-30 * 24 * time.Hour)
+	} else if start.After(end) {
+		c.Error(errors.New("start time cannot be after end time"), http.StatusBadRequest)
+		return
+	}
+
+	history, err := a.metrics.PriceHistory(start, end)
+	if !a.checkServerError(c, "failed to get price history", err) {
+		return
+	}
+	c.Encode(history)
+}
+
 func (a *api) handleGETPeriodMetrics(c jape.Context) {
 	var interval metrics.Interval
 	if err := c.DecodeParam("period", &interval); err != nil {
@@ -274,6 +429,125 @@ func (a *api) handleGETContract(c jape.Context) {
 	c.Encode(contract)
 }
 
+// handleGETContractEvents streams contract lifecycle events as Server-Sent
+// Events until the client disconnects.
+func (a *api) handleGETContractEvents(c jape.Context) {
+	flusher, ok := c.ResponseWriter.(http.Flusher)
+	if !ok {
+		c.Error(errors.New("streaming not supported"), http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe := a.contracts.SubscribeEvents()
+	defer unsubscribe()
+
+	h := c.ResponseWriter.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	c.ResponseWriter.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			buf, err := json.Marshal(event)
+			if err != nil {
+				a.log.Error("failed to marshal contract event", zap.Error(err))
+				return
+			} else if _, err := fmt.Fprintf(c.ResponseWriter, "event: %s\ndata: %s\n\n", event.Type, buf); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (a *api) handleGETRenters(c jape.Context) {
+	limit, offset := parseLimitParams(c, 100, 500)
+	renters, count, err := a.contracts.Renters(limit, offset)
+	if !a.checkServerError(c, "failed to get renters", err) {
+		return
+	}
+	c.Encode(RentersResponse{
+		Count:   count,
+		Renters: renters,
+	})
+}
+
+func (a *api) handleGETRenter(c jape.Context) {
+	var renterKey types.PublicKey
+	if err := c.DecodeParam("key", &renterKey); err != nil {
+		return
+	}
+	stats, err := a.contracts.RenterStats(renterKey)
+	if errors.Is(err, contracts.ErrRenterNotFound) {
+		c.Error(err, http.StatusNotFound)
+		return
+	} else if !a.checkServerError(c, "failed to get renter stats", err) {
+		return
+	}
+	policy, err := a.contracts.RenterPolicy(renterKey)
+	if !a.checkServerError(c, "failed to get renter policy", err) {
+		return
+	}
+	c.Encode(RenterResponse{
+		RenterStats: stats,
+		StoredData:  stats.Sectors * rhpv2.SectorSize,
+		Policy:      policy,
+	})
+}
+
+func (a *api) handleGETRenterPolicies(c jape.Context) {
+	policies, err := a.contracts.RenterPolicies()
+	if !a.checkServerError(c, "failed to get renter policies", err) {
+		return
+	}
+	c.Encode(policies)
+}
+
+func (a *api) handleGETRenterPolicy(c jape.Context) {
+	var renterKey types.PublicKey
+	if err := c.DecodeParam("key", &renterKey); err != nil {
+		return
+	}
+	policy, err := a.contracts.RenterPolicy(renterKey)
+	if !a.checkServerError(c, "failed to get renter policy", err) {
+		return
+	}
+	c.Encode(policy)
+}
+
+func (a *api) handlePUTRenterPolicy(c jape.Context) {
+	var renterKey types.PublicKey
+	if err := c.DecodeParam("key", &renterKey); err != nil {
+		return
+	}
+	var policy contracts.RenterPolicy
+	if err := c.Decode(&policy); err != nil {
+		return
+	}
+	policy.RenterKey = renterKey
+	if err := policy.Validate(); err != nil {
+		c.Error(err, http.StatusBadRequest)
+		return
+	}
+	a.checkServerError(c, "failed to set renter policy", a.contracts.SetRenterPolicy(policy))
+}
+
+func (a *api) handleDELETERenterPolicy(c jape.Context) {
+	var renterKey types.PublicKey
+	if err := c.DecodeParam("key", &renterKey); err != nil {
+		return
+	}
+	a.checkServerError(c, "failed to remove renter policy", a.contracts.RemoveRenterPolicy(renterKey))
+}
+
 func (a *api) handleGETVolume(c jape.Context) {
 	var id int
 	if err := c.DecodeParam("id", &id); err != nil {
@@ -307,6 +581,19 @@ func (a *api) handlePUTVolume(c jape.Context) {
 		return
 	}
 
+	if req.Tier != nil {
+		err := a.volumes.SetVolumeTier(id, *req.Tier)
+		if errors.Is(err, storage.ErrVolumeNotFound) {
+			c.Error(err, http.StatusNotFound)
+			return
+		} else if errors.Is(err, storage.ErrInvalidTier) {
+			c.Error(err, http.StatusBadRequest)
+			return
+		} else if !a.checkServerError(c, "failed to update volume tier", err) {
+			return
+		}
+	}
+
 	err := a.volumes.SetReadOnly(id, req.ReadOnly)
 	if errors.Is(err, storage.ErrVolumeNotFound) {
 		c.Error(err, http.StatusNotFound)
@@ -346,12 +633,31 @@ func (a *api) handlePOSTVolume(c jape.Context) {
 	} else if req.MaxSectors == 0 {
 		c.Error(errors.New("max sectors is required"), http.StatusBadRequest)
 		return
+	} else if err := req.Backend.Validate(); err != nil {
+		c.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	switch req.Tier {
+	case storage.VolumeTierNone, storage.VolumeTierHot, storage.VolumeTierCold:
+	default:
+		c.Error(fmt.Errorf("%w: %q", storage.ErrInvalidTier, req.Tier), http.StatusBadRequest)
+		return
 	}
 
-	volume, err := a.volumes.AddVolume(req.LocalPath, req.MaxSectors, nil)
+	volume, err := a.volumes.AddVolume(req.LocalPath, req.MaxSectors, req.Backend, nil)
 	if !a.checkServerError(c, "failed to add volume", err) {
 		return
 	}
+	if req.Tier != storage.VolumeTierNone {
+		err := a.volumes.SetVolumeTier(volume.ID, req.Tier)
+		if !a.checkServerError(c, "failed to set volume tier", err) {
+			return
+		}
+		volume.Tier = req.Tier
+	}
+	// never return the backend's credentials
+	volume.Backend = volume.Backend.Redacted()
 	c.Encode(volume)
 }
 
@@ -388,6 +694,147 @@ func (a *api) handlePUTVolumeResize(c jape.Context) {
 	a.checkServerError(c, "failed to resize volume", err)
 }
 
+func (a *api) handleGETVolumesRebalance(c jape.Context) {
+	status := a.volumes.RebalanceStatus()
+	if !status.Running {
+		// report the planned moves if a rebalance is not running
+		plan, err := a.volumes.PlanRebalance()
+		if !a.checkServerError(c, "failed to plan rebalance", err) {
+			return
+		}
+		status.Plan = plan
+	}
+	c.Encode(status)
+}
+
+func (a *api) handlePUTVolumesRebalance(c jape.Context) {
+	var req RebalanceRequest
+	if err := c.Decode(&req); err != nil {
+		return
+	}
+
+	if req.DryRun {
+		plan, err := a.volumes.PlanRebalance()
+		if !a.checkServerError(c, "failed to plan rebalance", err) {
+			return
+		}
+		c.Encode(plan)
+		return
+	}
+
+	plan, err := a.volumes.Rebalance(req.MaxBandwidth, nil)
+	if errors.Is(err, storage.ErrRebalanceRunning) {
+		c.Error(err, http.StatusConflict)
+		return
+	} else if !a.checkServerError(c, "failed to start rebalance", err) {
+		return
+	}
+	c.Encode(plan)
+}
+
+func (a *api) handleDELETEVolumesRebalance(c jape.Context) {
+	err := a.volumes.CancelRebalance()
+	a.checkServerError(c, "failed to cancel rebalance", err)
+}
+
+func (a *api) handleGETVolumesOperations(c jape.Context) {
+	ops, err := a.volumes.VolumeOperations()
+	if !a.checkServerError(c, "failed to get volume operations", err) {
+		return
+	}
+	c.Encode(ops)
+}
+
+func (a *api) handleDELETEVolumeOperation(c jape.Context) {
+	var id int
+	if err := c.DecodeParam("id", &id); err != nil {
+		return
+	} else if id < 0 {
+		c.Error(errors.New("invalid volume id"), http.StatusBadRequest)
+		return
+	}
+
+	err := a.volumes.CancelVolumeOperation(id)
+	if errors.Is(err, storage.ErrOperationNotFound) {
+		c.Error(err, http.StatusNotFound)
+		return
+	}
+	a.checkServerError(c, "failed to cancel volume operation", err)
+}
+
+func (a *api) handlePUTVolumeRebuild(c jape.Context) {
+	var id int
+	if err := c.DecodeParam("id", &id); err != nil {
+		return
+	} else if id < 0 {
+		c.Error(errors.New("invalid volume id"), http.StatusBadRequest)
+		return
+	}
+
+	var req RebuildVolumeRequest
+	if err := c.Decode(&req); err != nil {
+		return
+	} else if req.ReplacementID < 0 || req.ReplacementID == id {
+		c.Error(errors.New("invalid replacement volume id"), http.StatusBadRequest)
+		return
+	}
+
+	err := a.volumes.RebuildVolume(id, req.ReplacementID, nil)
+	a.checkServerError(c, "failed to start rebuild", err)
+}
+
+func (a *api) handleGETVolumeScrub(c jape.Context) {
+	var id int
+	if err := c.DecodeParam("id", &id); err != nil {
+		return
+	} else if id < 0 {
+		c.Error(errors.New("invalid volume id"), http.StatusBadRequest)
+		return
+	}
+
+	status, err := a.volumes.ScrubStatus(id)
+	if errors.Is(err, storage.ErrVolumeNotFound) {
+		c.Error(err, http.StatusNotFound)
+		return
+	} else if !a.checkServerError(c, "failed to get scrub status", err) {
+		return
+	}
+	c.Encode(status)
+}
+
+func (a *api) handlePUTVolumeScrub(c jape.Context) {
+	var id int
+	if err := c.DecodeParam("id", &id); err != nil {
+		return
+	} else if id < 0 {
+		c.Error(errors.New("invalid volume id"), http.StatusBadRequest)
+		return
+	}
+
+	err := a.volumes.ScrubVolume(id)
+	if errors.Is(err, storage.ErrVolumeNotFound) {
+		c.Error(err, http.StatusNotFound)
+		return
+	} else if errors.Is(err, storage.ErrScrubRunning) {
+		c.Error(err, http.StatusConflict)
+		return
+	}
+	a.checkServerError(c, "failed to start scrub", err)
+}
+
+func (a *api) handleDELETEVolumeScrub(c jape.Context) {
+	var id int
+	if err := c.DecodeParam("id", &id); err != nil {
+		return
+	} else if id < 0 {
+		c.Error(errors.New("invalid volume id"), http.StatusBadRequest)
+		return
+	}
+
+	err := a.volumes.CancelScrub(id)
+	a.checkServerError(c, "failed to cancel scrub", err)
+}
+
 func (a *api) handleGETWallet(c jape.Context) {
 	spendable, confirmed, unconfirmed, err := a.wallet.Balance()
 	if !a.checkServerError(c, "failed to get wallet", err) {
@@ -555,6 +1002,10 @@ func (a *api) handlePUTSystemDir(c jape.Context) {
 	a.checkServerError(c, "failed to create dir", os.MkdirAll(req.Path, 0775))
 }
 
+func (a *api) handlePOSTSystemSelfTest(c jape.Context) {
+	c.Encode(a.selftest.Run(c.Request.Context()))
+}
+
 func (a *api) handleGETTPoolFee(c jape.Context) {
 	c.Encode(a.tpool.RecommendedFee())
 }
@@ -610,6 +1061,8 @@ func parseLimitParams(c jape.Context, defaultLimit, maxLimit int) (limit, offset
 }
 
 func toJSONVolume(vol storage.VolumeMeta) VolumeMeta {
+	// never return the backend's credentials
+	vol.Backend = vol.Backend.Redacted()
 	jvm := VolumeMeta{
 		VolumeMeta: vol,
 	}
@@ -0,0 +1,63 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/api"
+	"go.sia.tech/hostd/host/storage"
+	"go.sia.tech/hostd/host/storage/s3"
+	"go.uber.org/zap/zaptest"
+)
+
+// stubVolumes is a volume manager that only implements AddVolume.
+type stubVolumes struct {
+	api.VolumeManager
+}
+
+func (sv *stubVolumes) AddVolume(localPath string, maxSectors uint64, backend storage.VolumeBackend, result chan<- error) (storage.Volume, error) {
+	return storage.Volume{
+		ID:           1,
+		LocalPath:    localPath,
+		TotalSectors: maxSectors,
+		Backend:      backend,
+	}, nil
+}
+
+func TestAddVolumeRedacted(t *testing.T) {
+	server := httptest.NewServer(api.NewServer("", types.PublicKey{}, nil, nil, nil, nil, nil, &stubVolumes{}, nil, nil, nil, nil, nil, zaptest.NewLogger(t)))
+	defer server.Close()
+	client := api.NewClient(server.URL, "")
+
+	opts := s3.Options{
+		Endpoint:        "https://s3.example.com",
+		Bucket:          "hostd",
+		AccessKeyID:     "access",
+		SecretAccessKey: "supersecret",
+		PathStyle:       true,
+	}
+	buf, err := json.Marshal(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	volume, err := client.AddVolume("volume", 100, storage.VolumeBackend{Type: storage.VolumeBackendS3, Options: buf})
+	if err != nil {
+		t.Fatal(err)
+	} else if strings.Contains(string(volume.Backend.Options), opts.SecretAccessKey) {
+		t.Fatalf("expected secret to be redacted, got %s", volume.Backend.Options)
+	}
+
+	var got s3.Options
+	if err := json.Unmarshal(volume.Backend.Options, &got); err != nil {
+		t.Fatal(err)
+	}
+	expected := opts
+	expected.SecretAccessKey = ""
+	if got != expected {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+}
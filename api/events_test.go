@@ -0,0 +1,66 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/api"
+	"go.sia.tech/hostd/host/contracts"
+	"go.uber.org/zap/zaptest"
+	"lukechampine.com/frand"
+)
+
+// stubEvents is a contract manager that only implements SubscribeEvents.
+type stubEvents struct {
+	api.ContractManager
+
+	events chan contracts.Event
+}
+
+func (se *stubEvents) SubscribeEvents() (<-chan contracts.Event, func()) {
+	return se.events, func() {}
+}
+
+func TestContractEvents(t *testing.T) {
+	se := &stubEvents{events: make(chan contracts.Event, 3)}
+	server := httptest.NewServer(api.NewServer("", types.PublicKey{}, nil, nil, nil, nil, se, nil, nil, nil, nil, nil, nil, zaptest.NewLogger(t)))
+	defer server.Close()
+	client := api.NewClient(server.URL, "")
+
+	expected := []contracts.Event{
+		{Type: contracts.EventFormed, ContractID: frand.Entropy256(), Height: 1},
+		{Type: contracts.EventProofSubmitted, ContractID: frand.Entropy256(), Height: 2, Data: map[string]any{"attempt": float64(1)}},
+		{Type: contracts.EventSuccessful, ContractID: frand.Entropy256(), Height: 3},
+	}
+	for _, event := range expected {
+		se.events <- event
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	errDone := errors.New("done")
+	var received []contracts.Event
+	err := client.ContractEvents(ctx, func(event contracts.Event) error {
+		received = append(received, event)
+		if len(received) == len(expected) {
+			return errDone
+		}
+		return nil
+	})
+	if !errors.Is(err, errDone) {
+		t.Fatalf("expected stream to end after %v events, got %v (%v events)", len(expected), err, len(received))
+	}
+	for i := range expected {
+		if received[i].Type != expected[i].Type || received[i].ContractID != expected[i].ContractID || received[i].Height != expected[i].Height {
+			t.Fatalf("event %v: expected %+v, got %+v", i, expected[i], received[i])
+		}
+	}
+	if received[1].Data["attempt"] != float64(1) {
+		t.Fatalf("expected event data to be preserved, got %v", received[1].Data)
+	}
+}
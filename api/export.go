@@ -0,0 +1,310 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/contracts"
+	"go.sia.tech/jape"
+	"go.uber.org/zap"
+)
+
+// supported contract export formats
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatJSON = "json"
+)
+
+// exportCSVHeader is the header row of a CSV contract export. Each contract
+// row is followed by a row for each of its financial records.
+var exportCSVHeader = []string{
+	"record",
+	"contract_id",
+	"renter_key",
+	"status",
+	"negotiation_height",
+	"window_start",
+	"window_end",
+	"resolution_height",
+	"renewed_from",
+	"renewed_to",
+	"locked_collateral",
+	"risked_collateral",
+	"rpc_revenue",
+	"storage_revenue",
+	"ingress_revenue",
+	"egress_revenue",
+	"account_funding",
+	"timestamp",
+}
+
+// formatSiacoins returns the exact decimal representation of a currency in
+// Siacoins.
+func formatSiacoins(c types.Currency) string {
+	s := new(big.Rat).SetFrac(c.Big(), types.Siacoins(1).Big()).FloatString(24)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+func contractCSVRow(contract contracts.Contract) []string {
+	var renewedFrom, renewedTo string
+	if contract.RenewedFrom != (types.FileContractID{}) {
+		renewedFrom = contract.RenewedFrom.String()
+	}
+	if contract.RenewedTo != (types.FileContractID{}) {
+		renewedTo = contract.RenewedTo.String()
+	}
+	return []string{
+		"contract",
+		contract.Revision.ParentID.String(),
+		contract.RenterKey().String(),
+		contract.Status.String(),
+		strconv.FormatUint(contract.NegotiationHeight, 10),
+		strconv.FormatUint(contract.Revision.WindowStart, 10),
+		strconv.FormatUint(contract.Revision.WindowEnd, 10),
+		strconv.FormatUint(contract.ResolutionHeight, 10),
+		renewedFrom,
+		renewedTo,
+		formatSiacoins(contract.LockedCollateral),
+		formatSiacoins(contract.Usage.RiskedCollateral),
+		formatSiacoins(contract.Usage.RPCRevenue),
+		formatSiacoins(contract.Usage.StorageRevenue),
+		formatSiacoins(contract.Usage.IngressRevenue),
+		formatSiacoins(contract.Usage.EgressRevenue),
+		formatSiacoins(contract.Usage.AccountFunding),
+		"",
+	}
+}
+
+func financialRecordCSVRow(id types.FileContractID, record contracts.FinancialRecord) []string {
+	return []string{
+		"financial",
+		id.String(),
+		"", "", "", "", "", "", "", "", "", "",
+		formatSiacoins(record.RPCRevenue),
+		formatSiacoins(record.StorageRevenue),
+		formatSiacoins(record.IngressRevenue),
+		formatSiacoins(record.EgressRevenue),
+		"",
+		record.Timestamp.UTC().Format(time.RFC3339),
+	}
+}
+
+// encodeContractFilter encodes a contract filter as URL query parameters.
+func encodeContractFilter(filter contracts.ContractFilter) url.Values {
+	v := make(url.Values)
+	for _, status := range filter.Statuses {
+		v.Add("status", status.String())
+	}
+	for _, id := range filter.ContractIDs {
+		v.Add("contractID", id.String())
+	}
+	for _, id := range filter.RenewedFrom {
+		v.Add("renewedFrom", id.String())
+	}
+	for _, id := range filter.RenewedTo {
+		v.Add("renewedTo", id.String())
+	}
+	for _, key := range filter.RenterKey {
+		v.Add("renterKey", key.String())
+	}
+	setUint := func(key string, n uint64) {
+		if n > 0 {
+			v.Set(key, strconv.FormatUint(n, 10))
+		}
+	}
+	setUint("minNegotiationHeight", filter.MinNegotiationHeight)
+	setUint("maxNegotiationHeight", filter.MaxNegotiationHeight)
+	setUint("minExpirationHeight", filter.MinExpirationHeight)
+	setUint("maxExpirationHeight", filter.MaxExpirationHeight)
+	if filter.Limit > 0 {
+		v.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	if filter.Offset > 0 {
+		v.Set("offset", strconv.Itoa(filter.Offset))
+	}
+	if filter.SortField != "" {
+		v.Set("sortField", filter.SortField)
+	}
+	if filter.SortDesc {
+		v.Set("sortDesc", "true")
+	}
+	return v
+}
+
+// decodeContractFilter decodes a contract filter from URL query parameters.
+func decodeContractFilter(v url.Values) (filter contracts.ContractFilter, err error) {
+	for _, s := range v["status"] {
+		var status contracts.ContractStatus
+		if err := status.UnmarshalJSON([]byte(s)); err != nil {
+			return contracts.ContractFilter{}, err
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+	decodeIDs := func(key string) (ids []types.FileContractID, err error) {
+		for _, s := range v[key] {
+			var id types.FileContractID
+			if err := id.UnmarshalText([]byte(s)); err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %w", key, s, err)
+			}
+			ids = append(ids, id)
+		}
+		return
+	}
+	if filter.ContractIDs, err = decodeIDs("contractID"); err != nil {
+		return contracts.ContractFilter{}, err
+	} else if filter.RenewedFrom, err = decodeIDs("renewedFrom"); err != nil {
+		return contracts.ContractFilter{}, err
+	} else if filter.RenewedTo, err = decodeIDs("renewedTo"); err != nil {
+		return contracts.ContractFilter{}, err
+	}
+	for _, s := range v["renterKey"] {
+		var key types.PublicKey
+		if err := key.UnmarshalText([]byte(s)); err != nil {
+			return contracts.ContractFilter{}, fmt.Errorf("invalid renterKey %q: %w", s, err)
+		}
+		filter.RenterKey = append(filter.RenterKey, key)
+	}
+
+	decodeUint := func(key string, n *uint64) error {
+		if s := v.Get(key); s != "" {
+			var err error
+			if *n, err = strconv.ParseUint(s, 10, 64); err != nil {
+				return fmt.Errorf("invalid %s %q: %w", key, s, err)
+			}
+		}
+		return nil
+	}
+	decodeInt := func(key string, n *int) error {
+		if s := v.Get(key); s != "" {
+			var err error
+			if *n, err = strconv.Atoi(s); err != nil {
+				return fmt.Errorf("invalid %s %q: %w", key, s, err)
+			} else if *n < 0 {
+				return fmt.Errorf("invalid %s %q: must be non-negative", key, s)
+			}
+		}
+		return nil
+	}
+	if err := decodeUint("minNegotiationHeight", &filter.MinNegotiationHeight); err != nil {
+		return contracts.ContractFilter{}, err
+	} else if err := decodeUint("maxNegotiationHeight", &filter.MaxNegotiationHeight); err != nil {
+		return contracts.ContractFilter{}, err
+	} else if err := decodeUint("minExpirationHeight", &filter.MinExpirationHeight); err != nil {
+		return contracts.ContractFilter{}, err
+	} else if err := decodeUint("maxExpirationHeight", &filter.MaxExpirationHeight); err != nil {
+		return contracts.ContractFilter{}, err
+	} else if err := decodeInt("limit", &filter.Limit); err != nil {
+		return contracts.ContractFilter{}, err
+	} else if err := decodeInt("offset", &filter.Offset); err != nil {
+		return contracts.ContractFilter{}, err
+	}
+
+	filter.SortField = v.Get("sortField")
+	if s := v.Get("sortDesc"); s != "" {
+		if filter.SortDesc, err = strconv.ParseBool(s); err != nil {
+			return contracts.ContractFilter{}, fmt.Errorf("invalid sortDesc %q: %w", s, err)
+		}
+	}
+	return filter, nil
+}
+
+// handleGETContractsExport streams every contract matching the filter in the
+// query string, along with its financial records, as CSV or JSON.
+func (a *api) handleGETContractsExport(c jape.Context) {
+	format := ExportFormatCSV
+	if err := c.DecodeForm("format", &format); err != nil {
+		return
+	} else if format != ExportFormatCSV && format != ExportFormatJSON {
+		c.Error(fmt.Errorf("unsupported export format %q", format), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := decodeContractFilter(c.Request.URL.Query())
+	if err != nil {
+		c.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	// the response is not started until the first contract is exported so
+	// that filter errors can still be returned to the client
+	var started bool
+	start := func() {
+		h := c.ResponseWriter.Header()
+		if format == ExportFormatJSON {
+			h.Set("Content-Type", "application/json")
+		} else {
+			h.Set("Content-Type", "text/csv")
+		}
+		h.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="contracts.%s"`, format))
+		c.ResponseWriter.WriteHeader(http.StatusOK)
+		started = true
+	}
+
+	switch format {
+	case ExportFormatJSON:
+		enc := json.NewEncoder(c.ResponseWriter)
+		err = a.contracts.ExportContracts(filter, func(contract contracts.ExportedContract) error {
+			sep := ","
+			if !started {
+				start()
+				sep = "["
+			}
+			if _, err := c.ResponseWriter.Write([]byte(sep)); err != nil {
+				return err
+			}
+			return enc.Encode(contract)
+		})
+		if err == nil {
+			if !started {
+				start()
+				_, err = c.ResponseWriter.Write([]byte("[]\n"))
+			} else {
+				_, err = c.ResponseWriter.Write([]byte("]\n"))
+			}
+		}
+	default:
+		w := csv.NewWriter(c.ResponseWriter)
+		err = a.contracts.ExportContracts(filter, func(contract contracts.ExportedContract) error {
+			if !started {
+				start()
+				if err := w.Write(exportCSVHeader); err != nil {
+					return err
+				}
+			}
+			if err := w.Write(contractCSVRow(contract.Contract)); err != nil {
+				return err
+			}
+			for _, record := range contract.FinancialRecords {
+				if err := w.Write(financialRecordCSVRow(contract.Revision.ParentID, record)); err != nil {
+					return err
+				}
+			}
+			w.Flush()
+			return w.Error()
+		})
+		if err == nil && !started {
+			start()
+			err = w.Write(exportCSVHeader)
+		}
+		if err == nil {
+			w.Flush()
+			err = w.Error()
+		}
+	}
+
+	if err != nil && !started {
+		a.checkServerError(c, "failed to export contracts", err)
+	} else if err != nil {
+		// the response has already started, the error can only be logged
+		a.log.Error("failed to export contracts", zap.Error(err))
+	}
+}
@@ -0,0 +1,271 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/api"
+	"go.sia.tech/hostd/host/contracts"
+	"go.uber.org/zap/zaptest"
+	"lukechampine.com/frand"
+)
+
+var exportHeader = []string{
+	"record", "contract_id", "renter_key", "status", "negotiation_height",
+	"window_start", "window_end", "resolution_height", "renewed_from",
+	"renewed_to", "locked_collateral", "risked_collateral", "rpc_revenue",
+	"storage_revenue", "ingress_revenue", "egress_revenue", "account_funding",
+	"timestamp",
+}
+
+// stubContracts is a contract manager that only implements ExportContracts.
+type stubContracts struct {
+	api.ContractManager
+
+	exported []contracts.ExportedContract
+	filter   contracts.ContractFilter
+}
+
+func (sc *stubContracts) ExportContracts(filter contracts.ContractFilter, fn func(contracts.ExportedContract) error) error {
+	sc.filter = filter
+	for _, contract := range sc.exported {
+		if err := fn(contract); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportedContract(records int) contracts.ExportedContract {
+	renterKey := types.NewPrivateKeyFromSeed(frand.Bytes(32)).PublicKey()
+	contract := contracts.ExportedContract{
+		Contract: contracts.Contract{
+			SignedRevision: contracts.SignedRevision{
+				Revision: types.FileContractRevision{
+					ParentID: frand.Entropy256(),
+					UnlockConditions: types.UnlockConditions{
+						PublicKeys: []types.UnlockKey{renterKey.UnlockKey()},
+					},
+					FileContract: types.FileContract{
+						WindowStart: 200 + frand.Uint64n(100),
+						WindowEnd:   400 + frand.Uint64n(100),
+					},
+				},
+			},
+			Status:            contracts.ContractStatusActive,
+			LockedCollateral:  types.Siacoins(100),
+			NegotiationHeight: frand.Uint64n(100),
+			RenewedFrom:       frand.Entropy256(),
+			Usage: contracts.Usage{
+				RPCRevenue:       types.Siacoins(1).Div64(3),
+				StorageRevenue:   types.Siacoins(10),
+				IngressRevenue:   types.NewCurrency64(1), // 1 H
+				RiskedCollateral: types.Siacoins(50),
+			},
+		},
+		FinancialRecords: []contracts.FinancialRecord{},
+	}
+	for i := 0; i < records; i++ {
+		contract.FinancialRecords = append(contract.FinancialRecords, contracts.FinancialRecord{
+			RPCRevenue:     types.Siacoins(uint32(i + 1)),
+			StorageRevenue: types.Siacoins(1).Div64(2),
+			EgressRevenue:  types.NewCurrency64(1),
+			Timestamp:      time.Now().Add(time.Duration(i) * time.Hour).Truncate(time.Second),
+		})
+	}
+	return contract
+}
+
+func TestExportContracts(t *testing.T) {
+	sc := &stubContracts{
+		exported: []contracts.ExportedContract{exportedContract(2), exportedContract(0)},
+	}
+	server := httptest.NewServer(api.NewServer("", types.PublicKey{}, nil, nil, nil, nil, sc, nil, nil, nil, nil, nil, nil, zaptest.NewLogger(t)))
+	defer server.Close()
+	client := api.NewClient(server.URL, "")
+
+	t.Run("headers", func(t *testing.T) {
+		for _, tt := range []struct {
+			format, contentType string
+		}{
+			{"csv", "text/csv"},
+			{"json", "application/json"},
+		} {
+			resp, err := http.Get(server.URL + "/export/contracts?format=" + tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("%v: expected status 200, got %v", tt.format, resp.StatusCode)
+			} else if ct := resp.Header.Get("Content-Type"); ct != tt.contentType {
+				t.Fatalf("%v: expected content type %q, got %q", tt.format, tt.contentType, ct)
+			} else if cd := resp.Header.Get("Content-Disposition"); cd != `attachment; filename="contracts.`+tt.format+`"` {
+				t.Fatalf("%v: unexpected content disposition %q", tt.format, cd)
+			}
+		}
+
+		// the format defaults to CSV
+		resp, err := http.Get(server.URL + "/export/contracts")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if ct := resp.Header.Get("Content-Type"); ct != "text/csv" {
+			t.Fatalf("expected default content type text/csv, got %q", ct)
+		}
+
+		resp, err = http.Get(server.URL + "/export/contracts?format=xml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected status 400 for an unsupported format, got %v", resp.StatusCode)
+		}
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		filter := contracts.ContractFilter{
+			Statuses:            []contracts.ContractStatus{contracts.ContractStatusActive},
+			ContractIDs:         []types.FileContractID{sc.exported[0].Revision.ParentID},
+			RenterKey:           []types.PublicKey{sc.exported[0].RenterKey()},
+			MinExpirationHeight: 100,
+			Limit:               10,
+			Offset:              5,
+			SortField:           "expirationHeight",
+			SortDesc:            true,
+		}
+		if err := client.ExportContracts(new(bytes.Buffer), api.ExportFormatCSV, filter); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(sc.filter, filter) {
+			t.Fatalf("expected filter %+v, got %+v", filter, sc.filter)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := client.ExportContracts(&buf, api.ExportFormatCSV, contracts.ContractFilter{}); err != nil {
+			t.Fatal(err)
+		}
+		rows, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		} else if len(rows) != 5 { // header + 2 contracts + 2 financial records
+			t.Fatalf("expected 5 rows, got %v", len(rows))
+		} else if !reflect.DeepEqual(rows[0], exportHeader) {
+			t.Fatalf("unexpected header %v", rows[0])
+		}
+
+		c := sc.exported[0]
+		row := rows[1]
+		expected := []string{
+			"contract",
+			c.Revision.ParentID.String(),
+			c.RenterKey().String(),
+			"active",
+			strconv.FormatUint(c.NegotiationHeight, 10),
+			strconv.FormatUint(c.Revision.WindowStart, 10),
+			strconv.FormatUint(c.Revision.WindowEnd, 10),
+			"0",
+			c.RenewedFrom.String(),
+			"",
+			"100",
+			"50",
+			"0.333333333333333333333333",
+			"10",
+			"0.000000000000000000000001",
+			"0",
+			"0",
+			"",
+		}
+		if !reflect.DeepEqual(row, expected) {
+			t.Fatalf("unexpected contract row\nexpected %q\ngot      %q", expected, row)
+		}
+
+		for i, record := range c.FinancialRecords {
+			row := rows[2+i]
+			if len(row) != len(exportHeader) {
+				t.Fatalf("expected %v columns, got %v", len(exportHeader), len(row))
+			} else if row[0] != "financial" || row[1] != c.Revision.ParentID.String() {
+				t.Fatalf("unexpected financial record %q", row)
+			} else if row[12] != strconv.Itoa(i+1) || row[13] != "0.5" || row[14] != "0" || row[15] != "0.000000000000000000000001" {
+				t.Fatalf("unexpected revenue in financial record %q", row)
+			}
+			ts, err := time.Parse(time.RFC3339, row[17])
+			if err != nil {
+				t.Fatal(err)
+			} else if !ts.Equal(record.Timestamp) {
+				t.Fatalf("expected timestamp %v, got %v", record.Timestamp, ts)
+			}
+		}
+
+		if row := rows[4]; row[0] != "contract" || row[1] != sc.exported[1].Revision.ParentID.String() {
+			t.Fatalf("unexpected contract row %q", row)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := client.ExportContracts(&buf, api.ExportFormatJSON, contracts.ContractFilter{}); err != nil {
+			t.Fatal(err)
+		}
+		var exported []contracts.ExportedContract
+		if err := json.Unmarshal(buf.Bytes(), &exported); err != nil {
+			t.Fatal(err)
+		} else if len(exported) != len(sc.exported) {
+			t.Fatalf("expected %v contracts, got %v", len(sc.exported), len(exported))
+		}
+		for i := range exported {
+			got, expected := exported[i], sc.exported[i]
+			if got.Revision.ParentID != expected.Revision.ParentID {
+				t.Fatalf("expected contract %v, got %v", expected.Revision.ParentID, got.Revision.ParentID)
+			} else if !got.Usage.RPCRevenue.Equals(expected.Usage.RPCRevenue) {
+				t.Fatalf("expected rpc revenue %v, got %v", expected.Usage.RPCRevenue, got.Usage.RPCRevenue)
+			} else if len(got.FinancialRecords) != len(expected.FinancialRecords) {
+				t.Fatalf("expected %v financial records, got %v", len(expected.FinancialRecords), len(got.FinancialRecords))
+			}
+			for j := range got.FinancialRecords {
+				if !got.FinancialRecords[j].Timestamp.Equal(expected.FinancialRecords[j].Timestamp) {
+					t.Fatalf("expected timestamp %v, got %v", expected.FinancialRecords[j].Timestamp, got.FinancialRecords[j].Timestamp)
+				} else if !got.FinancialRecords[j].RPCRevenue.Equals(expected.FinancialRecords[j].RPCRevenue) {
+					t.Fatalf("expected rpc revenue %v, got %v", expected.FinancialRecords[j].RPCRevenue, got.FinancialRecords[j].RPCRevenue)
+				}
+			}
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		sc.exported = nil
+
+		var buf bytes.Buffer
+		if err := client.ExportContracts(&buf, api.ExportFormatCSV, contracts.ContractFilter{}); err != nil {
+			t.Fatal(err)
+		}
+		rows, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		} else if len(rows) != 1 || !reflect.DeepEqual(rows[0], exportHeader) {
+			t.Fatalf("expected only the header, got %q", rows)
+		}
+
+		buf.Reset()
+		if err := client.ExportContracts(&buf, api.ExportFormatJSON, contracts.ContractFilter{}); err != nil {
+			t.Fatal(err)
+		}
+		var exported []contracts.ExportedContract
+		if err := json.Unmarshal(buf.Bytes(), &exported); err != nil {
+			t.Fatal(err)
+		} else if exported == nil || len(exported) != 0 {
+			t.Fatalf("expected an empty array, got %q", buf.String())
+		}
+	})
+}
@@ -9,6 +9,7 @@ import (
 
 	"go.sia.tech/core/types"
 	"go.sia.tech/hostd/host/contracts"
+	"go.sia.tech/hostd/host/settings"
 	"go.sia.tech/hostd/host/storage"
 	"go.sia.tech/hostd/logging"
 )
@@ -55,6 +56,9 @@ type (
 		PublicKey     types.PublicKey `json:"publicKey"`
 		WalletAddress types.Address   `json:"walletAddress"`
 		StartTime     time.Time       `json:"startTime"`
+		// LastAnnouncement is the host's last broadcast announcement. It is
+		// empty if the host has not announced.
+		LastAnnouncement settings.Announcement `json:"lastAnnouncement"`
 		BuildState
 	}
 
@@ -74,6 +78,10 @@ type (
 	AddVolumeRequest struct {
 		LocalPath  string `json:"localPath"`
 		MaxSectors uint64 `json:"maxSectors"`
+		// Backend selects where the volume's sector data is stored. If
+		// omitted, the data is stored in a flat file at LocalPath.
+		Backend storage.VolumeBackend `json:"backend"`
+		Tier    string                `json:"tier"`
 	}
 
 	// JSONErrors is a slice of errors that can be marshaled to and unmarshaled
@@ -90,6 +98,9 @@ type (
 	// UpdateVolumeRequest is the request body for the [PUT] /volume/:id endpoint.
 	UpdateVolumeRequest struct {
 		ReadOnly bool `json:"readOnly"`
+		// Tier changes the storage tier of the volume. If nil, the tier is
+		// not changed.
+		Tier *string `json:"tier,omitempty"`
 	}
 
 	// ResizeVolumeRequest is the request body for the [PUT] /volume/:id/resize endpoint.
@@ -97,12 +108,55 @@ type (
 		MaxSectors uint64 `json:"maxSectors"`
 	}
 
+	// RebalanceRequest is the request body for the [PUT] /system/rebalance
+	// endpoint.
+	RebalanceRequest struct {
+		// DryRun returns the planned moves without moving any sectors.
+		DryRun bool `json:"dryRun"`
+		// MaxBandwidth is the maximum number of bytes per second moved
+		// between volumes. 0 is unlimited.
+		MaxBandwidth uint64 `json:"maxBandwidth"`
+	}
+
+	// RebuildVolumeRequest is the request body for the [PUT]
+	// /volumes/:id/rebuild endpoint.
+	RebuildVolumeRequest struct {
+		// ReplacementID is the ID of the volume the failed volume's sectors
+		// are moved to
+		ReplacementID int `json:"replacementID"`
+	}
+
+	// SettingsProfilesResponse is the response body for the [GET]
+	// /settings/profiles endpoint.
+	SettingsProfilesResponse struct {
+		// Active is the name of the active profile. It is empty if no
+		// profile is active.
+		Active   string                     `json:"active"`
+		Profiles []settings.SettingsProfile `json:"profiles"`
+	}
+
 	// ContractsResponse is the response body for the [POST] /contracts endpoint.
 	ContractsResponse struct {
 		Count     int                  `json:"count"`
 		Contracts []contracts.Contract `json:"contracts"`
 	}
 
+	// RentersResponse is the response body for the [GET] /renters endpoint.
+	RentersResponse struct {
+		Count   int                     `json:"count"`
+		Renters []contracts.RenterStats `json:"renters"`
+	}
+
+	// RenterResponse is the response body for the [GET] /renters/:key
+	// endpoint.
+	RenterResponse struct {
+		contracts.RenterStats
+		// StoredData is the number of bytes stored in the renter's active
+		// contracts.
+		StoredData uint64                 `json:"storedData"`
+		Policy     contracts.RenterPolicy `json:"policy"`
+	}
+
 	// WalletResponse is the response body for the [GET] /wallet endpoint.
 	WalletResponse struct {
 		ScanHeight  uint64         `json:"scanHeight"`
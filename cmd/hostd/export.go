@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"go.sia.tech/hostd/api"
+	"go.sia.tech/hostd/host/contracts"
+)
+
+// runExport handles the export subcommand. Exports are requested from the
+// API of a running hostd node at the address of the -http flag.
+func runExport(args []string) {
+	if len(args) == 0 || args[0] != "contracts" {
+		log.Fatal("usage: hostd export contracts [-format csv|json] [-status active,successful] [-o contracts.csv]")
+	}
+
+	fs := flag.NewFlagSet("export contracts", flag.ExitOnError)
+	format := fs.String("format", api.ExportFormatCSV, "export format (csv, json)")
+	statuses := fs.String("status", "", "comma-separated list of contract statuses to export (default all)")
+	output := fs.String("o", "", `file to write the export to, "-" for stdout (default "contracts.<format>")`)
+	fs.Parse(args[1:])
+
+	var filter contracts.ContractFilter
+	if len(*statuses) != 0 {
+		for _, s := range strings.Split(*statuses, ",") {
+			var status contracts.ContractStatus
+			check("invalid contract status", status.UnmarshalJSON([]byte(strings.TrimSpace(s))))
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	switch *output {
+	case "-":
+	case "":
+		*output = "contracts." + *format
+		fallthrough
+	default:
+		f, err := os.Create(*output)
+		check("failed to create export file", err)
+		defer f.Close()
+		w = f
+	}
+
+	addr := apiAddr
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+	client := api.NewClient(fmt.Sprintf("http://%s/api", addr), getAPIPassword())
+	check("failed to export contracts", client.ExportContracts(w, *format, filter))
+	if w != os.Stdout {
+		log.Println("Exported contracts to", *output)
+	}
+}
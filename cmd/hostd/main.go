@@ -18,6 +18,7 @@ import (
 	"go.sia.tech/core/wallet"
 	"go.sia.tech/hostd/api"
 	"go.sia.tech/hostd/build"
+	"go.sia.tech/hostd/host/selftest"
 	"go.sia.tech/jape"
 	"go.sia.tech/web/hostd"
 	"go.uber.org/zap"
@@ -116,6 +117,9 @@ func main() {
 		log.Println("Recovery Phrase:", phrase)
 		log.Println("Address", key.PublicKey().StandardAddress())
 		return
+	case "export":
+		runExport(flag.Args()[1:])
+		return
 	}
 
 	if err := os.MkdirAll(dir, 0700); err != nil {
@@ -172,10 +176,15 @@ func main() {
 	}
 	defer node.Close()
 
+	selfTester, err := selftest.New(hostKey.PublicKey(), rhpv3WSListener.Addr().String(), node.settings)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	auth := jape.BasicAuth(apiPassword)
 	web := http.Server{
 		Handler: webRouter{
-			api: auth(api.NewServer(name, hostKey.PublicKey(), node.a, node.g, node.cm, node.tp, node.contracts, node.storage, node.metrics, node.store, node.settings, node.w, logger.Named("api"))),
+			api: auth(api.NewServer(name, hostKey.PublicKey(), node.a, node.g, node.cm, node.tp, node.contracts, node.storage, node.metrics, node.store, node.settings, node.w, selfTester, logger.Named("api"))),
 			ui:  hostd.Handler(),
 		},
 		ReadTimeout: 30 * time.Second,
@@ -119,6 +119,7 @@ type node struct {
 
 	metrics   *metrics.MetricManager
 	settings  *settings.ConfigManager
+	pricing   *settings.PricingEngine
 	accounts  *accounts.AccountManager
 	contracts *contracts.ContractManager
 	registry  *registry.Manager
@@ -135,6 +136,8 @@ func (n *node) Close() error {
 	n.rhp2.Close()
 	n.rhp2Monitor.Close()
 	n.rhp3Monitor.Close()
+	n.pricing.Close()
+	n.settings.Close()
 	n.storage.Close()
 	n.contracts.Close()
 	n.w.Close()
@@ -241,22 +244,45 @@ func newNode(gatewayAddr, rhp2Addr, rhp3Addr, dir string, bootstrap bool, wallet
 	discoveredAddr := net.JoinHostPort(g.Address().Host(), rhp2Port)
 	logger.Debug("discovered address", zap.String("addr", discoveredAddr))
 
-	sr, err := settings.NewConfigManager(dir, hostKey, discoveredAddr, db, cm, tp, w, logger.Named("settings"))
+	am := alerts.NewManager()
+	sr, err := settings.NewConfigManager(dir, hostKey, discoveredAddr, db, am, cm, tp, w, g, logger.Named("settings"))
 	if err != nil {
 		return nil, types.PrivateKey{}, fmt.Errorf("failed to create settings manager: %w", err)
 	}
 
 	accountManager := accounts.NewManager(db, sr)
-	am := alerts.NewManager()
 	sm, err := storage.NewVolumeManager(db, am, cm, logger.Named("volumes"), sr.Settings().SectorCacheSize)
 	if err != nil {
 		return nil, types.PrivateKey{}, fmt.Errorf("failed to create storage manager: %w", err)
 	}
+	sm.SetScrubRate(sr.Settings().ScrubRate)
+	sm.SetTierSettings(storage.TierSettings{
+		PromoteWindow: sr.Settings().TierPromoteWindow,
+		DemoteAfter:   sr.Settings().TierDemoteAfter,
+		MaxBandwidth:  sr.Settings().TierMigrationLimit,
+	})
+	if err := sm.SetParitySettings(storage.ParitySettings{
+		Scheme:       sr.Settings().ParityScheme,
+		DataShards:   sr.Settings().ParityDataShards,
+		ParityShards: sr.Settings().ParityShards,
+	}); err != nil {
+		return nil, types.PrivateKey{}, fmt.Errorf("failed to set parity settings: %w", err)
+	}
+
+	pricingEngine := settings.NewPricingEngine(sr, sm, db, logger.Named("pricing"))
 
 	contractManager, err := contracts.NewManager(db, am, sm, cm, tp, w, logger.Named("contracts"))
 	if err != nil {
 		return nil, types.PrivateKey{}, fmt.Errorf("failed to create contract manager: %w", err)
 	}
+	contractManager.SetProofFeePolicy(contracts.ProofFeePolicy{
+		ResubmitInterval: sr.Settings().ProofResubmitInterval,
+		FeeIncrease:      sr.Settings().ProofFeeIncrease,
+		MaxFee:           sr.Settings().ProofMaxFee,
+	})
+	if err := contractManager.SetWebhooks(sr.Settings().ContractWebhooks); err != nil {
+		return nil, types.PrivateKey{}, fmt.Errorf("failed to set contract webhooks: %w", err)
+	}
 	registryManager := registry.NewManager(hostKey, db, logger.Named("registry"))
 
 	rhp2Monitor := rhp.NewDataRecorder(&rhp2MonitorStore{db}, logger.Named("rhp2Monitor"))
@@ -281,6 +307,7 @@ func newNode(gatewayAddr, rhp2Addr, rhp3Addr, dir string, bootstrap bool, wallet
 
 		metrics:   metrics.NewManager(db),
 		settings:  sr,
+		pricing:   pricingEngine,
 		accounts:  accountManager,
 		contracts: contractManager,
 		storage:   sm,
@@ -6,7 +6,10 @@ require (
 	github.com/aws/aws-sdk-go v1.44.156
 	github.com/cloudflare/cloudflare-go v0.66.0
 	github.com/hashicorp/golang-lru/v2 v2.0.3
+	github.com/julienschmidt/httprouter v1.3.0
+	github.com/klauspost/reedsolomon v1.11.7
 	github.com/mattn/go-sqlite3 v1.14.16
+	github.com/miekg/dns v1.1.50
 	gitlab.com/NebulousLabs/encoding v0.0.0-20200604091946-456c3dc907fe
 	go.sia.tech/core v0.1.12-0.20230529164041-6347a98003be
 	go.sia.tech/jape v0.9.1-0.20230525021720-ecf031ecbffb
@@ -14,6 +17,7 @@ require (
 	go.sia.tech/siad v1.5.10-0.20230228235644-3059c0b930ca
 	go.sia.tech/web/hostd v0.16.0
 	go.uber.org/zap v1.24.0
+	golang.org/x/crypto v0.5.0
 	golang.org/x/sys v0.7.0
 	golang.org/x/term v0.7.0
 	golang.org/x/time v0.3.0
@@ -37,10 +41,8 @@ require (
 	github.com/hashicorp/go-retryablehttp v0.7.2 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
 	github.com/json-iterator/go v1.1.12 // indirect
-	github.com/julienschmidt/httprouter v1.3.0 // indirect
 	github.com/klauspost/compress v1.10.3 // indirect
 	github.com/klauspost/cpuid/v2 v2.2.3 // indirect
-	github.com/klauspost/reedsolomon v1.11.7 // indirect
 	github.com/mattn/go-isatty v0.0.17 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/pkg/errors v0.9.1 // indirect
@@ -70,7 +72,7 @@ require (
 	go.sia.tech/web v0.0.0-20230616170703-7ed0b639fb22 // indirect
 	go.uber.org/atomic v1.10.0 // indirect
 	go.uber.org/multierr v1.8.0 // indirect
-	golang.org/x/crypto v0.5.0 // indirect
+	golang.org/x/mod v0.8.0 // indirect
 	golang.org/x/net v0.9.0 // indirect
 	golang.org/x/text v0.9.0 // indirect
 	golang.org/x/tools v0.6.0 // indirect
@@ -19,6 +19,7 @@ const (
 	ActionBroadcastFinalRevision = "revision"
 	ActionBroadcastResolution    = "resolve"
 	ActionExpire                 = "expire"
+	ActionRehearseProof          = "rehearse"
 )
 
 func (cm *ContractManager) buildStorageProof(id types.FileContractID, filesize uint64, index uint64) (types.StorageProof, error) {
@@ -28,13 +29,22 @@ func (cm *ContractManager) buildStorageProof(id types.FileContractID, filesize u
 		}, nil
 	}
 
-	sectorIndex := index / rhpv2.LeavesPerSector
-	segmentIndex := index % rhpv2.LeavesPerSector
-
 	roots, err := cm.SectorRoots(id, 0, 0)
 	if err != nil {
 		return types.StorageProof{}, err
 	}
+	return cm.buildSectorStorageProof(id, roots, index)
+}
+
+// buildSectorStorageProof builds a storage proof for the leaf at index using
+// the contract's sector roots.
+func (cm *ContractManager) buildSectorStorageProof(id types.FileContractID, roots []types.Hash256, index uint64) (types.StorageProof, error) {
+	sectorIndex := index / rhpv2.LeavesPerSector
+	segmentIndex := index % rhpv2.LeavesPerSector
+	if sectorIndex >= uint64(len(roots)) {
+		return types.StorageProof{}, fmt.Errorf("leaf index %v out of range", index)
+	}
+
 	root := roots[sectorIndex]
 	sector, err := cm.storage.Read(root)
 	if err != nil {
@@ -149,17 +159,70 @@ func (cm *ContractManager) handleContractAction(id types.FileContractID, height
 		}
 		log.Info("broadcast final revision", zap.Uint64("revisionNumber", contract.Revision.RevisionNumber), zap.String("transactionID", revisionTxn.ID().String()))
 	case ActionBroadcastResolution:
-		if (height-contract.Revision.WindowStart)%3 != 0 {
-			// debounce resolution broadcasts to prevent spamming
-			log.Debug("skipping resolution", zap.Uint64("windowStart", contract.Revision.WindowStart))
-			return
-		}
 		validPayout, missedPayout := contract.Revision.ValidHostPayout(), contract.Revision.MissedHostPayout()
 		if missedPayout.Cmp(validPayout) >= 0 {
 			log.Info("skipping storage proof, no benefit to host", zap.String("validPayout", validPayout.ExactString()), zap.String("missedPayout", missedPayout.ExactString()))
 			if err := cm.store.SetContractStatus(id, ContractStatusSuccessful); err != nil {
 				log.Error("failed to set contract status", zap.Error(err))
+				return
 			}
+			cm.emitEvent(EventSuccessful, id, height, nil)
+			return
+		}
+
+		if contract.Revision.WindowEnd-height <= ProofAlertBuffer {
+			cm.alerts.Register(alerts.Alert{
+				ID:       types.Hash256(id),
+				Severity: alerts.SeverityCritical,
+				Message:  "Storage proof not confirmed",
+				Data: map[string]any{
+					"contractID":  id,
+					"blockHeight": height,
+					"windowEnd":   contract.Revision.WindowEnd,
+					"submission":  contract.ProofSubmission,
+				},
+				Timestamp: time.Now(),
+			})
+		}
+
+		policy := cm.getProofFeePolicy()
+		prev := contract.ProofSubmission
+		if prev != nil && prev.Height <= height && height-prev.Height < policy.ResubmitInterval {
+			// wait for the previous attempt to be confirmed before
+			// resubmitting with a higher fee
+			log.Debug("skipping resolution", zap.Uint64("lastSubmission", prev.Height), zap.Uint64("resubmitInterval", policy.ResubmitInterval))
+			return
+		}
+
+		// increase the fee of each resubmission, paying more than the host
+		// gains from the proof is never worth it
+		var prevFee types.Currency
+		var attempts int
+		if prev != nil {
+			prevFee, attempts = prev.Fee, prev.Attempts
+		}
+		fee := policy.NextFee(cm.tpool.RecommendedFee().Mul64(1000), prevFee)
+		if benefit := validPayout.Sub(missedPayout); fee.Cmp(benefit) > 0 {
+			fee = benefit
+		}
+		submission := ProofSubmission{
+			Fee:       fee,
+			Attempts:  attempts + 1,
+			Height:    height,
+			Timestamp: time.Now(),
+		}
+
+		// siad does not replace pooled transactions, so a new proof conflicts
+		// with any proof for the contract that is still in the pool. Record
+		// the attempt so the next broadcast after the pooled proof is
+		// confirmed or evicted uses the increased fee.
+		if pooledID, ok := cm.pooledStorageProof(id); ok {
+			submission.TransactionID = pooledID
+			submission.Error = "a storage proof for the contract is still in the transaction pool"
+			if err := cm.store.SetProofSubmission(id, submission); err != nil {
+				log.Error("failed to store proof submission", zap.Error(err))
+			}
+			log.Warn("skipping resolution, previous storage proof has not been confirmed", zap.String("transactionID", pooledID.String()), zap.String("nextFee", fee.ExactString()), zap.Int("attempt", submission.Attempts))
 			return
 		}
 
@@ -178,8 +241,6 @@ func (cm *ContractManager) handleContractAction(id types.FileContractID, height
 			return
 		}
 
-		// TODO: consider cost of broadcasting the proof
-		fee := cm.tpool.RecommendedFee().Mul64(1000)
 		resolutionTxnSet := []types.Transaction{
 			{
 				// intermediate funding transaction is required by siad because
@@ -213,25 +274,63 @@ func (cm *ContractManager) handleContractAction(id types.FileContractID, height
 		} else if err := cm.wallet.SignTransaction(cs, &resolutionTxnSet[1], proofToSign, types.CoveredFields{WholeTransaction: true}); err != nil { // sign the proof transaction
 			log.Error("failed to sign resolution transaction", zap.Error(err))
 			return
-		} else if err := cm.tpool.AcceptTransactionSet(resolutionTxnSet); err != nil { // broadcast the transaction set
+		}
+
+		// record the attempt even if the set is rejected so the fee still
+		// increases and the next attempt waits for the resubmit interval
+		submission.TransactionID = resolutionTxnSet[1].ID()
+		broadcastErr := cm.tpool.AcceptTransactionSet(resolutionTxnSet) // broadcast the transaction set
+		if broadcastErr != nil {
+			submission.Error = broadcastErr.Error()
+		}
+		if err := cm.store.SetProofSubmission(id, submission); err != nil {
+			log.Error("failed to store proof submission", zap.Error(err))
+		}
+		if broadcastErr != nil {
 			buf, _ := json.Marshal(resolutionTxnSet)
-			log.Error("failed to broadcast resolution transaction set", zap.Error(err), zap.ByteString("transactionSet", buf))
+			log.Error("failed to broadcast resolution transaction set", zap.Error(broadcastErr), zap.Int("attempt", submission.Attempts), zap.ByteString("transactionSet", buf))
+			return
+		}
+		log.Info("broadcast storage proof", zap.String("transactionID", resolutionTxnSet[1].ID().String()), zap.String("fee", fee.ExactString()), zap.Int("attempt", submission.Attempts), zap.Duration("elapsed", time.Since(start)))
+		cm.emitEvent(EventProofSubmitted, id, height, map[string]any{
+			"transactionID": submission.TransactionID,
+			"fee":           submission.Fee,
+			"attempt":       submission.Attempts,
+		})
+	case ActionRehearseProof:
+		if r := contract.ProofRehearsal; r != nil && r.RevisionNumber == contract.Revision.RevisionNumber && height-r.Height < 3 {
+			// debounce rehearsals to prevent repeatedly reading sectors
+			log.Debug("skipping proof rehearsal", zap.Uint64("lastRehearsal", r.Height))
 			return
 		}
-		log.Info("broadcast storage proof", zap.String("transactionID", resolutionTxnSet[1].ID().String()), zap.Duration("elapsed", time.Since(start)))
+		cm.rehearseProof(contract, height, log)
 	case ActionReject:
 		if err := cm.store.SetContractStatus(id, ContractStatusRejected); err != nil {
 			log.Error("failed to set contract status", zap.Error(err))
+			return
 		}
 		log.Info("contract rejected", zap.Uint64("negotiationHeight", contract.NegotiationHeight))
+		cm.emitEvent(EventRejected, id, height, nil)
 	case ActionExpire:
+		if contract.Status != ContractStatusActive {
+			// the contract was resolved after the action was queued, its
+			// event has already been emitted
+			log.Debug("skipping expiration", zap.Stringer("status", contract.Status))
+			return
+		}
+
 		validPayout, missedPayout := contract.Revision.ValidHostPayout(), contract.Revision.MissedHostPayout()
 		if validPayout.Cmp(missedPayout) > 0 {
 			// if the host valid payout is greater than the missed payout, the
 			// host lost potential revenue.
 			if err := cm.store.SetContractStatus(id, ContractStatusFailed); err != nil {
 				log.Error("failed to set contract status", zap.Error(err))
+				return
 			}
+			cm.emitEvent(EventFailed, id, height, map[string]any{
+				"validPayout":  validPayout,
+				"missedPayout": missedPayout,
+			})
 			cm.alerts.Register(alerts.Alert{
 				ID:       frand.Entropy256(),
 				Severity: alerts.SeverityWarning,
@@ -245,11 +344,12 @@ func (cm *ContractManager) handleContractAction(id types.FileContractID, height
 			log.Error("contract failed, revenue lost", zap.Uint64("windowStart", contract.Revision.WindowStart), zap.Uint64("windowEnd", contract.Revision.WindowEnd), zap.String("validPayout", validPayout.ExactString()), zap.String("missedPayout", missedPayout.ExactString()))
 			return
 		}
-		// note: this should always be a no-op, but it's good to be explicit
 		if err := cm.store.SetContractStatus(id, ContractStatusSuccessful); err != nil {
 			log.Error("failed to set contract status", zap.Error(err))
+			return
 		}
 		log.Info("contract expired")
+		cm.emitEvent(EventSuccessful, id, height, nil)
 	default:
 		log.Panic("unrecognized contract action", zap.Stack("stack"))
 	}
@@ -19,6 +19,9 @@ const (
 	// RevisionSubmissionBuffer number of blocks before the proof window to
 	// submit a revision and prevent modification of the contract.
 	RevisionSubmissionBuffer = 36 // 6 hours
+	// ProofRehearsalBuffer is the number of blocks before the proof window to
+	// start rehearsing the contract's storage proof.
+	ProofRehearsalBuffer = 12 // 2 hours
 )
 
 // A SectorAction denotes the type of action to be performed on a
@@ -56,6 +59,7 @@ const (
 	ContractSortStatus            = "status"
 	ContractSortNegotiationHeight = "negotiationHeight"
 	ContractSortExpirationHeight  = "expirationHeight"
+	ContractSortRenterKey         = "renterKey"
 )
 
 type (
@@ -111,6 +115,13 @@ type (
 		// RenewedFrom is the ID of the contract that this contract renewed. If
 		// this contract is not a renewal, the field is the zero value.
 		RenewedFrom types.FileContractID `json:"renewedFrom"`
+		// ProofRehearsal is the result of the most recent storage proof
+		// rehearsal. If the proof has not been rehearsed, the field is nil.
+		ProofRehearsal *ProofRehearsal `json:"proofRehearsal,omitempty"`
+		// ProofSubmission is the most recent broadcast of the contract's
+		// storage proof. If the proof has not been broadcast, the field is
+		// nil.
+		ProofSubmission *ProofSubmission `json:"proofSubmission,omitempty"`
 	}
 
 	// ContractFilter defines the filter criteria for a contract query.
@@ -149,6 +160,10 @@ type (
 	ContractUpdater struct {
 		store ContractStore
 		log   *zap.Logger
+		emit  func(typ string, id types.FileContractID, height uint64, data map[string]any)
+		// checkStorage returns an error if the renter is not allowed to
+		// store additional sectors.
+		checkStorage func(renterKey types.PublicKey, addedSectors uint64) error
 
 		once sync.Once
 		done func() // done is called when the updater is closed.
@@ -332,12 +347,20 @@ func (cu *ContractUpdater) Commit(revision SignedRevision, usage Usage) error {
 		panic("contract updater used with wrong contract")
 	}
 
+	if n := uint64(len(cu.sectorRoots)); n > cu.sectors {
+		if err := cu.checkStorage(revision.RenterKey(), n-cu.sectors); err != nil {
+			return err
+		}
+	}
+
 	start := time.Now()
 	err := cu.store.ReviseContract(revision, usage, cu.sectors, cu.sectorActions)
-	if err == nil {
-		// clear the committed sector actions
-		cu.sectorActions = cu.sectorActions[:0]
+	if err != nil {
+		return err
 	}
+	// clear the committed sector actions
+	cu.sectorActions = cu.sectorActions[:0]
 	cu.log.Debug("contract update committed", zap.String("contractID", revision.Revision.ParentID.String()), zap.Uint64("revision", revision.Revision.RevisionNumber), zap.Duration("elapsed", time.Since(start)))
-	return err
+	cu.emit(EventRevised, revision.Revision.ParentID, 0, map[string]any{"revisionNumber": revision.Revision.RevisionNumber, "filesize": revision.Revision.Filesize})
+	return nil
 }
@@ -0,0 +1,232 @@
+package contracts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.uber.org/zap"
+)
+
+// defines the contract lifecycle event types
+const (
+	EventFormed         = "formed"
+	EventConfirmed      = "confirmed"
+	EventRevised        = "revised"
+	EventRenewed        = "renewed"
+	EventProofSubmitted = "proofSubmitted"
+	EventSuccessful     = "successful"
+	EventFailed         = "failed"
+	EventRejected       = "rejected"
+)
+
+const (
+	// eventBufferSize is the number of events buffered for each subscriber.
+	// Events are dropped if a subscriber falls behind.
+	eventBufferSize = 64
+	// webhookQueueSize is the number of events queued for each webhook.
+	// Events are dropped if a webhook falls behind.
+	webhookQueueSize = 256
+
+	webhookTimeout     = 30 * time.Second
+	webhookMaxAttempts = 5
+	webhookBackoff     = time.Second
+)
+
+type (
+	// An Event is a transition in a contract's lifecycle.
+	Event struct {
+		Type       string               `json:"type"`
+		ContractID types.FileContractID `json:"contractID"`
+		Height     uint64               `json:"height"`
+		Data       map[string]any       `json:"data,omitempty"`
+		Timestamp  time.Time            `json:"timestamp"`
+	}
+
+	// A webhookQueue buffers events for a single webhook. Events are
+	// delivered in order by one worker.
+	webhookQueue struct {
+		events chan Event
+		cancel context.CancelFunc // stops the worker when the webhook is removed
+	}
+)
+
+// ValidateWebhook returns an error if a webhook URL is not an absolute HTTP or
+// HTTPS URL.
+func ValidateWebhook(webhook string) error {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return fmt.Errorf("invalid webhook %q: %w", webhook, err)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid webhook %q: scheme must be http or https", webhook)
+	} else if u.Host == "" {
+		return fmt.Errorf("invalid webhook %q: missing host", webhook)
+	}
+	return nil
+}
+
+// SubscribeEvents returns a channel that receives contract lifecycle events.
+// The returned function must be called to unsubscribe and close the channel.
+func (cm *ContractManager) SubscribeEvents() (<-chan Event, func()) {
+	c := make(chan Event, eventBufferSize)
+
+	cm.mu.Lock()
+	id := cm.nextSubscriberID
+	cm.nextSubscriberID++
+	cm.subscribers[id] = c
+	cm.mu.Unlock()
+
+	var once bool
+	return c, func() {
+		cm.mu.Lock()
+		defer cm.mu.Unlock()
+		if once {
+			return
+		}
+		once = true
+		delete(cm.subscribers, id)
+		close(c)
+	}
+}
+
+// SetWebhooks sets the URLs that contract lifecycle events are posted to.
+// Workers are started for new webhooks and stopped for removed webhooks.
+func (cm *ContractManager) SetWebhooks(webhooks []string) error {
+	for _, webhook := range webhooks {
+		if err := ValidateWebhook(webhook); err != nil {
+			return err
+		}
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	keep := make(map[string]bool)
+	for _, webhook := range webhooks {
+		keep[webhook] = true
+		if _, ok := cm.webhooks[webhook]; ok {
+			continue
+		}
+
+		done, err := cm.tg.Add()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := cm.tg.WithContext(context.Background())
+		q := &webhookQueue{
+			events: make(chan Event, webhookQueueSize),
+			cancel: cancel,
+		}
+		cm.webhooks[webhook] = q
+		go func(webhook string) {
+			defer done()
+			cm.deliverWebhooks(ctx, webhook, q.events)
+		}(webhook)
+	}
+	for webhook, q := range cm.webhooks {
+		if !keep[webhook] {
+			q.cancel()
+			delete(cm.webhooks, webhook)
+		}
+	}
+	return nil
+}
+
+// emitEvent sends an event to all subscribers and webhooks. If the event's
+// height is not set, the current height is used.
+func (cm *ContractManager) emitEvent(typ string, id types.FileContractID, height uint64, data map[string]any) {
+	if height == 0 {
+		height = atomic.LoadUint64(&cm.blockHeight)
+	}
+	event := Event{
+		Type:       typ,
+		ContractID: id,
+		Height:     height,
+		Data:       data,
+		Timestamp:  time.Now(),
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	for _, c := range cm.subscribers {
+		select {
+		case c <- event:
+		default:
+			cm.log.Warn("dropping contract event, subscriber is full", zap.String("type", typ), zap.Stringer("contractID", id))
+		}
+	}
+	for webhook, q := range cm.webhooks {
+		select {
+		case q.events <- event:
+		default:
+			cm.log.Warn("dropping contract event, webhook queue is full", zap.String("webhook", webhook), zap.String("type", typ), zap.Stringer("contractID", id))
+		}
+	}
+}
+
+// deliverWebhooks posts queued events to a webhook until the context is
+// cancelled.
+func (cm *ContractManager) deliverWebhooks(ctx context.Context, webhook string, events <-chan Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-events:
+			cm.deliverWebhook(ctx, webhook, event)
+		}
+	}
+}
+
+// deliverWebhook posts an event to a webhook. Failed deliveries are retried
+// with exponential backoff.
+func (cm *ContractManager) deliverWebhook(ctx context.Context, webhook string, event Event) {
+	buf, err := json.Marshal(event)
+	if err != nil {
+		panic(err) // should never happen
+	}
+
+	log := cm.log.Named("webhooks").With(zap.String("webhook", webhook), zap.String("type", event.Type), zap.Stringer("contractID", event.ContractID))
+	backoff := webhookBackoff
+	for attempt := 1; ; attempt++ {
+		err := postWebhook(ctx, webhook, buf)
+		if err == nil {
+			return
+		} else if attempt >= webhookMaxAttempts {
+			log.Error("failed to deliver webhook", zap.Int("attempts", attempt), zap.Error(err))
+			return
+		}
+		log.Debug("failed to deliver webhook, retrying", zap.Int("attempt", attempt), zap.Duration("backoff", backoff), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func postWebhook(ctx context.Context, webhook string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package contracts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/internal/threadgroup"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestWebhookQueue(t *testing.T) {
+	var mu sync.Mutex
+	var heights []uint64
+	received := make(chan struct{}, 1)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Error(err)
+			return
+		}
+		mu.Lock()
+		heights = append(heights, event.Height)
+		mu.Unlock()
+		select {
+		case received <- struct{}{}:
+		default:
+		}
+		<-release
+	}))
+	defer srv.Close()
+
+	cm := &ContractManager{
+		tg:          threadgroup.New(),
+		log:         zaptest.NewLogger(t),
+		subscribers: make(map[int]chan Event),
+		webhooks:    make(map[string]*webhookQueue),
+	}
+	if err := cm.SetWebhooks([]string{srv.URL}); err != nil {
+		t.Fatal(err)
+	}
+
+	// block the worker on the first delivery
+	cm.emitEvent(EventFormed, types.FileContractID{}, 1, nil)
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for webhook")
+	}
+
+	// fill the queue, events that do not fit are dropped
+	const dropped = 5
+	for i := 0; i < webhookQueueSize+dropped; i++ {
+		cm.emitEvent(EventRevised, types.FileContractID{}, uint64(i+2), nil)
+	}
+	close(release)
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		mu.Lock()
+		n := len(heights)
+		mu.Unlock()
+		if n == webhookQueueSize+1 {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("expected %v deliveries, got %v", webhookQueueSize+1, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// events are delivered in order
+	mu.Lock()
+	for i, height := range heights {
+		if height != uint64(i+1) {
+			t.Fatalf("expected event %v to have height %v, got %v", i, i+1, height)
+		}
+	}
+	mu.Unlock()
+
+	// removing the webhook stops delivery
+	if err := cm.SetWebhooks(nil); err != nil {
+		t.Fatal(err)
+	}
+	cm.emitEvent(EventFailed, types.FileContractID{}, 1000, nil)
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	if len(heights) != webhookQueueSize+1 {
+		t.Fatalf("expected no deliveries after removing the webhook, got %v", len(heights)-webhookQueueSize-1)
+	}
+	mu.Unlock()
+
+	// stopping the manager waits for the workers to exit
+	if err := cm.SetWebhooks([]string{srv.URL}); err != nil {
+		t.Fatal(err)
+	}
+	stopped := make(chan struct{})
+	go func() {
+		cm.tg.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for webhook workers to stop")
+	}
+	if err := cm.SetWebhooks([]string{"http://localhost/other"}); err == nil {
+		t.Fatal("expected error adding a webhook after shutdown")
+	}
+}
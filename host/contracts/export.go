@@ -0,0 +1,82 @@
+package contracts
+
+import (
+	"fmt"
+	"time"
+
+	"go.sia.tech/core/types"
+)
+
+// exportBatchSize is the number of contracts loaded from the store at a time
+// during an export.
+const exportBatchSize = 100
+
+type (
+	// A FinancialRecord is a snapshot of the revenue earned by a contract.
+	FinancialRecord struct {
+		RPCRevenue     types.Currency `json:"rpc"`
+		StorageRevenue types.Currency `json:"storage"`
+		IngressRevenue types.Currency `json:"ingress"`
+		EgressRevenue  types.Currency `json:"egress"`
+		Timestamp      time.Time      `json:"timestamp"`
+	}
+
+	// An ExportedContract pairs a contract with its financial records.
+	ExportedContract struct {
+		Contract
+		FinancialRecords []FinancialRecord `json:"financialRecords"`
+	}
+)
+
+// ExportContracts calls fn for each contract matching the filter. Unlike
+// Contracts, the filter's limit is not capped; a limit of 0 exports every
+// matching contract.
+func (cm *ContractManager) ExportContracts(filter ContractFilter, fn func(ExportedContract) error) error {
+	remaining := filter.Limit
+	for {
+		filter.Limit = exportBatchSize
+		if remaining > 0 && remaining < exportBatchSize {
+			filter.Limit = remaining
+		}
+
+		batch, _, err := cm.store.Contracts(filter)
+		if err != nil {
+			return fmt.Errorf("failed to get contracts: %w", err)
+		} else if len(batch) == 0 {
+			return nil
+		}
+
+		ids := make([]types.FileContractID, 0, len(batch))
+		for _, contract := range batch {
+			ids = append(ids, contract.Revision.ParentID)
+		}
+		records, err := cm.store.ContractFinancialRecords(ids)
+		if err != nil {
+			return fmt.Errorf("failed to get financial records: %w", err)
+		}
+
+		for _, contract := range batch {
+			exported := ExportedContract{
+				Contract:         contract,
+				FinancialRecords: records[contract.Revision.ParentID],
+			}
+			if exported.FinancialRecords == nil {
+				exported.FinancialRecords = []FinancialRecord{}
+			}
+			if err := fn(exported); err != nil {
+				return err
+			}
+		}
+
+		if remaining > 0 {
+			remaining -= len(batch)
+			if remaining <= 0 {
+				return nil
+			}
+		}
+		if len(batch) < filter.Limit {
+			return nil
+		}
+		filter.Offset += len(batch)
+	}
+}
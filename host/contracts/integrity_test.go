@@ -87,7 +87,7 @@ func TestCheckIntegrity(t *testing.T) {
 	defer s.Close()
 
 	result := make(chan error, 1)
-	if _, err := s.AddVolume(filepath.Join(dir, "data.dat"), 10, result); err != nil {
+	if _, err := s.AddVolume(filepath.Join(dir, "data.dat"), 10, storage.VolumeBackend{}, result); err != nil {
 		t.Fatal(err)
 	} else if err := <-result; err != nil {
 		t.Fatal(err)
@@ -47,6 +47,7 @@ type (
 	TransactionPool interface {
 		AcceptTransactionSet([]types.Transaction) error
 		RecommendedFee() types.Currency
+		Transactions() []types.Transaction
 	}
 
 	// A StorageManager stores and retrieves sectors.
@@ -82,8 +83,13 @@ type (
 
 		processQueue chan uint64 // signals that the contract manager should process actions for a given block height
 
-		mu    sync.Mutex                       // guards the following fields
-		locks map[types.FileContractID]*locker // contracts must be locked while they are being modified
+		mu             sync.Mutex                       // guards the following fields
+		locks          map[types.FileContractID]*locker // contracts must be locked while they are being modified
+		proofFeePolicy ProofFeePolicy
+
+		webhooks         map[string]*webhookQueue
+		subscribers      map[int]chan Event
+		nextSubscriberID int
 	}
 )
 
@@ -172,6 +178,7 @@ func (cm *ContractManager) AddContract(revision SignedRevision, formationSet []t
 		return err
 	}
 	cm.log.Debug("contract formed", zap.Stringer("contractID", revision.Revision.ParentID))
+	cm.emitEvent(EventFormed, revision.Revision.ParentID, 0, nil)
 	return nil
 }
 
@@ -197,6 +204,7 @@ func (cm *ContractManager) RenewContract(renewal SignedRevision, existing Signed
 		return err
 	}
 	cm.log.Debug("contract renewed", zap.Stringer("renewalID", renewal.Revision.ParentID), zap.Stringer("existingID", existing.Revision.ParentID))
+	cm.emitEvent(EventRenewed, renewal.Revision.ParentID, 0, map[string]any{"renewedFrom": existing.Revision.ParentID})
 	return nil
 }
 
@@ -276,6 +284,8 @@ func (cm *ContractManager) ProcessConsensusChange(cc modules.ConsensusChange) {
 		blockHeight++
 	}
 
+	// events are emitted after the state is committed
+	var events []Event
 	err = cm.store.UpdateContractState(cc.ID, uint64(cc.BlockHeight), func(tx UpdateStateTransaction) error {
 		for _, reverted := range revertedFormations {
 			if relevant, err := tx.ContractRelevant(reverted.id); err != nil {
@@ -359,6 +369,7 @@ func (cm *ContractManager) ProcessConsensusChange(cc modules.ConsensusChange) {
 			}
 
 			log.Debug("contract formation confirmed", zap.Stringer("contractID", applied.id), zap.Stringer("block", applied.index))
+			events = append(events, Event{Type: EventConfirmed, ContractID: applied.id, Height: applied.index.Height})
 			cm.alerts.Dismiss(types.Hash256(applied.id)) // dismiss any lifecycle alerts for this contract
 		}
 
@@ -387,7 +398,8 @@ func (cm *ContractManager) ProcessConsensusChange(cc modules.ConsensusChange) {
 			}
 
 			log.Debug("contract resolution confirmed", zap.Stringer("contractID", applied.id), zap.Stringer("block", applied.index))
-			cm.alerts.Dismiss(types.Hash256(applied.id)) // dismiss any lifecycle alerts for this contract
+			events = append(events, Event{Type: EventSuccessful, ContractID: applied.id, Height: applied.index.Height})
+			cm.alerts.Dismiss(types.Hash256(applied.id), proofRehearsalAlertID(applied.id)) // dismiss any lifecycle alerts for this contract
 		}
 		return nil
 	})
@@ -395,6 +407,9 @@ func (cm *ContractManager) ProcessConsensusChange(cc modules.ConsensusChange) {
 		log.Error("failed to process consensus change", zap.Error(err))
 		return
 	}
+	for _, event := range events {
+		cm.emitEvent(event.Type, event.ContractID, event.Height, event.Data)
+	}
 
 	scanHeight := uint64(cc.BlockHeight)
 	atomic.StoreUint64(&cm.blockHeight, scanHeight)
@@ -419,6 +434,9 @@ func (cm *ContractManager) ReviseContract(contractID types.FileContractID) (*Con
 	return &ContractUpdater{
 		store: cm.store,
 		log:   cm.log.Named("contractUpdater"),
+		emit:  cm.emitEvent,
+
+		checkStorage: cm.checkRenterStorage,
 
 		contractID:  contractID,
 		sectors:     uint64(len(roots)),
@@ -469,8 +487,11 @@ func NewManager(store ContractStore, alerts Alerts, storage StorageManager, c Ch
 		tpool:   tpool,
 		wallet:  wallet,
 
-		processQueue: make(chan uint64, 100),
-		locks:        make(map[types.FileContractID]*locker),
+		processQueue:   make(chan uint64, 100),
+		locks:          make(map[types.FileContractID]*locker),
+		proofFeePolicy: DefaultProofFeePolicy,
+		subscribers:    make(map[int]chan Event),
+		webhooks:       make(map[string]*webhookQueue),
 	}
 
 	changeID, err := store.LastContractChange()
@@ -2,8 +2,11 @@ package contracts_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
 	"sync"
 	"testing"
@@ -191,7 +194,7 @@ func TestContractLifecycle(t *testing.T) {
 		defer s.Close()
 
 		result := make(chan error, 1)
-		if _, err := s.AddVolume(filepath.Join(dir, "data.dat"), 10, result); err != nil {
+		if _, err := s.AddVolume(filepath.Join(dir, "data.dat"), 10, storage.VolumeBackend{}, result); err != nil {
 			t.Fatal(err)
 		} else if err := <-result; err != nil {
 			t.Fatal(err)
@@ -203,6 +206,33 @@ func TestContractLifecycle(t *testing.T) {
 		}
 		defer c.Close()
 
+		events, unsubscribe := c.SubscribeEvents()
+		defer unsubscribe()
+
+		// fail the first webhook delivery to test retries
+		var webhookMu sync.Mutex
+		var webhookCalls int
+		webhookEvents := make(map[string]bool)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			webhookMu.Lock()
+			defer webhookMu.Unlock()
+			webhookCalls++
+			if webhookCalls == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			var event contracts.Event
+			if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+				t.Error(err)
+				return
+			}
+			webhookEvents[event.Type] = true
+		}))
+		defer srv.Close()
+		if err := c.SetWebhooks([]string{srv.URL}); err != nil {
+			t.Fatal(err)
+		}
+
 		if err := node.MineBlocks(node.Address(), int(stypes.MaturityDelay+2)); err != nil {
 			t.Fatal(err)
 		}
@@ -321,6 +351,8 @@ func TestContractLifecycle(t *testing.T) {
 			t.Fatal("expected contract to be successful")
 		} else if contract.ResolutionHeight != proofHeight {
 			t.Fatalf("expected resolution height %v, got %v", proofHeight, contract.ResolutionHeight)
+		} else if contract.ProofSubmission == nil || contract.ProofSubmission.Attempts != 1 {
+			t.Fatalf("expected 1 proof submission, got %+v", contract.ProofSubmission)
 		} else if m, err := node.Store().Metrics(time.Now()); err != nil {
 			t.Fatal(err)
 		} else if m.Contracts.Active != 0 {
@@ -328,6 +360,40 @@ func TestContractLifecycle(t *testing.T) {
 		} else if m.Contracts.Successful != 1 {
 			t.Fatal("expected 1 successful contract")
 		}
+
+		expectedEvents := []string{contracts.EventFormed, contracts.EventConfirmed, contracts.EventRevised, contracts.EventProofSubmitted, contracts.EventSuccessful}
+		for _, expected := range expectedEvents {
+			select {
+			case event := <-events:
+				if event.Type != expected {
+					t.Fatalf("expected event %q, got %q", expected, event.Type)
+				} else if event.ContractID != rev.Revision.ParentID {
+					t.Fatalf("expected contract %v, got %v", rev.Revision.ParentID, event.ContractID)
+				}
+			default:
+				t.Fatalf("expected event %q", expected)
+			}
+		}
+
+		// expiring the resolved contract should not emit another event
+		remainingBlocks = rev.Revision.WindowEnd - node.TipState().Index.Height + 2
+		if err := node.MineBlocks(types.VoidAddress, int(remainingBlocks)); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Second) // sync time
+		select {
+		case event := <-events:
+			t.Fatalf("unexpected event %q", event.Type)
+		default:
+		}
+
+		webhookMu.Lock()
+		defer webhookMu.Unlock()
+		for _, expected := range expectedEvents {
+			if !webhookEvents[expected] {
+				t.Fatalf("expected webhook for event %q", expected)
+			}
+		}
 	})
 
 	t.Run("0 filesize contract", func(t *testing.T) {
@@ -349,7 +415,7 @@ func TestContractLifecycle(t *testing.T) {
 		defer s.Close()
 
 		result := make(chan error, 1)
-		if _, err := s.AddVolume(filepath.Join(dir, "data.dat"), 10, result); err != nil {
+		if _, err := s.AddVolume(filepath.Join(dir, "data.dat"), 10, storage.VolumeBackend{}, result); err != nil {
 			t.Fatal(err)
 		} else if err := <-result; err != nil {
 			t.Fatal(err)
@@ -460,6 +526,8 @@ func TestContractLifecycle(t *testing.T) {
 			t.Fatal("expected contract to be successful")
 		} else if contract.ResolutionHeight != proofHeight {
 			t.Fatalf("expected resolution height %v, got %v", proofHeight, contract.ResolutionHeight)
+		} else if contract.ProofSubmission == nil || contract.ProofSubmission.Attempts != 1 {
+			t.Fatalf("expected 1 proof submission, got %+v", contract.ProofSubmission)
 		} else if m, err := node.Store().Metrics(time.Now()); err != nil {
 			t.Fatal(err)
 		} else if m.Contracts.Active != 0 {
@@ -469,3 +537,262 @@ func TestContractLifecycle(t *testing.T) {
 		}
 	})
 }
+
+func TestProofRehearsal(t *testing.T) {
+	hostKey, renterKey := types.NewPrivateKeyFromSeed(frand.Bytes(32)), types.NewPrivateKeyFromSeed(frand.Bytes(32))
+
+	dir := t.TempDir()
+	log := zaptest.NewLogger(t)
+	node, err := test.NewWallet(hostKey, dir, log.Named("wallet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+
+	am := alerts.NewManager()
+	// disable the cache so removed sectors are not read from memory
+	s, err := storage.NewVolumeManager(node.Store(), am, node.ChainManager(), log.Named("storage"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	result := make(chan error, 1)
+	if _, err := s.AddVolume(filepath.Join(dir, "data.dat"), 10, storage.VolumeBackend{}, result); err != nil {
+		t.Fatal(err)
+	} else if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := contracts.NewManager(node.Store(), am, s, node.ChainManager(), node.TPool(), node, log.Named("contracts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := node.MineBlocks(node.Address(), int(stypes.MaturityDelay+2)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond) // sync time
+
+	rev, err := formContract(renterKey, hostKey, 50, 60, c, node, node.ChainManager(), node.TPool())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// confirm the formation
+	if err := node.MineBlocks(types.VoidAddress, 1); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond) // sync time
+
+	var sector [rhpv2.SectorSize]byte
+	frand.Read(sector[:256])
+	root := rhpv2.SectorRoot(&sector)
+	release, err := s.Write(root, &sector)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := release(); err != nil {
+		t.Fatal(err)
+	}
+
+	rev.Revision.RevisionNumber++
+	rev.Revision.Filesize = rhpv2.SectorSize
+	rev.Revision.FileMerkleRoot = rhpv2.MetaRoot([]types.Hash256{root})
+	sigHash := hashRevision(rev.Revision)
+	rev.HostSignature = hostKey.SignHash(sigHash)
+	rev.RenterSignature = renterKey.SignHash(sigHash)
+
+	updater, err := c.ReviseContract(rev.Revision.ParentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer updater.Close()
+
+	updater.AppendSector(root)
+	if err := updater.Commit(rev, contracts.Usage{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// remove the sector so the proof cannot be built
+	if err := s.RemoveSector(root); err != nil {
+		t.Fatal(err)
+	}
+
+	contract, err := c.Contract(rev.Revision.ParentID)
+	if err != nil {
+		t.Fatal(err)
+	} else if contract.ProofRehearsal != nil {
+		t.Fatal("expected no proof rehearsal")
+	}
+
+	// mine until the proof is rehearsed
+	remainingBlocks := rev.Revision.WindowStart - node.TipState().Index.Height - contracts.ProofRehearsalBuffer
+	if err := node.MineBlocks(types.VoidAddress, int(remainingBlocks)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Second) // sync time
+
+	contract, err = c.Contract(rev.Revision.ParentID)
+	if err != nil {
+		t.Fatal(err)
+	} else if contract.ProofRehearsal == nil {
+		t.Fatal("expected proof rehearsal")
+	} else if contract.ProofRehearsal.Passed {
+		t.Fatal("expected proof rehearsal to fail")
+	} else if contract.ProofRehearsal.RevisionNumber != rev.Revision.RevisionNumber {
+		t.Fatalf("expected revision number %v, got %v", rev.Revision.RevisionNumber, contract.ProofRehearsal.RevisionNumber)
+	} else if len(contract.ProofRehearsal.FailedRoots) != 1 || contract.ProofRehearsal.FailedRoots[0] != root {
+		t.Fatalf("expected failed root %v, got %v", root, contract.ProofRehearsal.FailedRoots)
+	}
+
+	hasAlert := func() bool {
+		for _, a := range am.Active() {
+			if a.Severity == alerts.SeverityCritical && a.Data["contractID"] == rev.Revision.ParentID {
+				return true
+			}
+		}
+		return false
+	}
+	if !hasAlert() {
+		t.Fatal("expected critical alert")
+	}
+
+	// store the sector and mine until the proof is rehearsed again
+	release, err = s.Write(root, &sector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	if err := node.MineBlocks(types.VoidAddress, 3); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Second) // sync time
+
+	contract, err = c.Contract(rev.Revision.ParentID)
+	if err != nil {
+		t.Fatal(err)
+	} else if contract.ProofRehearsal == nil || !contract.ProofRehearsal.Passed {
+		t.Fatalf("expected proof rehearsal to pass, got %+v", contract.ProofRehearsal)
+	} else if len(contract.ProofRehearsal.FailedRoots) != 0 {
+		t.Fatal("expected no failed roots")
+	} else if hasAlert() {
+		t.Fatal("expected alert to be dismissed")
+	}
+}
+
+func TestProofFeePolicy(t *testing.T) {
+	policy := contracts.ProofFeePolicy{
+		ResubmitInterval: 3,
+		FeeIncrease:      50,
+		MaxFee:           types.NewCurrency64(300),
+	}
+
+	tests := []struct {
+		recommended, previous, expected types.Currency
+	}{
+		{types.NewCurrency64(100), types.ZeroCurrency, types.NewCurrency64(100)},       // first submission
+		{types.NewCurrency64(100), types.NewCurrency64(100), types.NewCurrency64(150)}, // bumped
+		{types.NewCurrency64(200), types.NewCurrency64(100), types.NewCurrency64(200)}, // recommended fee increased
+		{types.NewCurrency64(100), types.NewCurrency64(250), types.NewCurrency64(300)}, // capped
+		{types.NewCurrency64(500), types.ZeroCurrency, types.NewCurrency64(300)},       // recommended fee capped
+	}
+	for _, test := range tests {
+		if fee := policy.NextFee(test.recommended, test.previous); !fee.Equals(test.expected) {
+			t.Fatalf("expected fee %v for recommended %v and previous %v, got %v", test.expected, test.recommended, test.previous, fee)
+		}
+	}
+
+	policy.MaxFee = types.ZeroCurrency
+	if fee := policy.NextFee(types.NewCurrency64(100), types.NewCurrency64(1000)); !fee.Equals(types.NewCurrency64(1500)) {
+		t.Fatalf("expected uncapped fee 1500, got %v", fee)
+	}
+}
+
+func TestExportContracts(t *testing.T) {
+	hostKey := types.NewPrivateKeyFromSeed(frand.Bytes(32))
+
+	dir := t.TempDir()
+	log := zaptest.NewLogger(t)
+	node, err := test.NewWallet(hostKey, dir, log.Named("wallet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+
+	am := alerts.NewManager()
+	s, err := storage.NewVolumeManager(node.Store(), am, node.ChainManager(), log.Named("storage"), sectorCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	c, err := contracts.NewManager(node.Store(), am, s, node.ChainManager(), node.TPool(), node, log.Named("contracts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// add more contracts than a single page of the store
+	const n = 250
+	for i := 0; i < n; i++ {
+		renterKey := types.NewPrivateKeyFromSeed(frand.Bytes(32))
+		uc := types.UnlockConditions{
+			PublicKeys: []types.UnlockKey{
+				renterKey.PublicKey().UnlockKey(),
+				hostKey.PublicKey().UnlockKey(),
+			},
+			SignaturesRequired: 2,
+		}
+		rev := contracts.SignedRevision{
+			Revision: types.FileContractRevision{
+				ParentID:         frand.Entropy256(),
+				UnlockConditions: uc,
+				FileContract: types.FileContract{
+					UnlockHash:  types.Hash256(uc.UnlockHash()),
+					WindowStart: 100 + uint64(i),
+					WindowEnd:   200 + uint64(i),
+				},
+			},
+		}
+		if err := node.Store().AddContract(rev, nil, types.Siacoins(1), contracts.Usage{RPCRevenue: types.Siacoins(uint32(i))}, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		limit, offset int
+		expected      int
+	}{
+		{0, 0, n},
+		{150, 0, 150},
+		{0, 200, n - 200},
+		{120, 180, n - 180},
+	}
+	for _, tt := range tests {
+		filter := contracts.ContractFilter{
+			Limit:     tt.limit,
+			Offset:    tt.offset,
+			SortField: contracts.ContractSortExpirationHeight,
+		}
+		seen := make(map[types.FileContractID]bool)
+		var lastExpiration uint64
+		err := c.ExportContracts(filter, func(contract contracts.ExportedContract) error {
+			if seen[contract.Revision.ParentID] {
+				return fmt.Errorf("contract %v exported twice", contract.Revision.ParentID)
+			} else if contract.Revision.WindowStart < lastExpiration {
+				return fmt.Errorf("contract %v exported out of order", contract.Revision.ParentID)
+			} else if contract.FinancialRecords == nil {
+				return errors.New("expected financial records to be non-nil")
+			}
+			seen[contract.Revision.ParentID] = true
+			lastExpiration = contract.Revision.WindowStart
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		} else if len(seen) != tt.expected {
+			t.Fatalf("limit %v offset %v: expected %v contracts, got %v", tt.limit, tt.offset, tt.expected, len(seen))
+		}
+	}
+}
@@ -47,10 +47,37 @@ type (
 		Contracts(ContractFilter) ([]Contract, int, error)
 		// Contract returns the contract with the given ID.
 		Contract(types.FileContractID) (Contract, error)
+		// RenterPolicy returns the policy of a renter. If the renter does
+		// not have a policy, ErrNotFound is returned.
+		RenterPolicy(types.PublicKey) (RenterPolicy, error)
+		// RenterPolicies returns all renter policies.
+		RenterPolicies() ([]RenterPolicy, error)
+		// SetRenterPolicy adds or updates the policy of a renter.
+		SetRenterPolicy(RenterPolicy) error
+		// RemoveRenterPolicy removes the policy of a renter.
+		RemoveRenterPolicy(types.PublicKey) error
+		// RenterUsage returns the number of active contracts and sectors
+		// stored by a renter.
+		RenterUsage(types.PublicKey) (RenterUsage, error)
+		// Renters returns aggregate statistics for renters ordered by the
+		// time they were last seen and the total number of renters.
+		Renters(limit, offset int) ([]RenterStats, int, error)
+		// RenterStats returns aggregate statistics for a renter. If the
+		// renter is unknown, ErrRenterNotFound is returned.
+		RenterStats(types.PublicKey) (RenterStats, error)
+		// ContractFinancialRecords returns the financial records of the
+		// contracts with the given IDs.
+		ContractFinancialRecords([]types.FileContractID) (map[types.FileContractID][]FinancialRecord, error)
 		// ContractFormationSet returns the formation transaction set for the
 		// contract with the given ID.
 		ContractFormationSet(types.FileContractID) ([]types.Transaction, error)
 		SetContractStatus(types.FileContractID, ContractStatus) error
+		// SetProofRehearsal stores the result of the contract's most recent
+		// storage proof rehearsal.
+		SetProofRehearsal(types.FileContractID, ProofRehearsal) error
+		// SetProofSubmission stores the most recent broadcast of the
+		// contract's storage proof.
+		SetProofSubmission(types.FileContractID, ProofSubmission) error
 		// Add stores the provided contract, should error if the contract
 		// already exists in the store.
 		AddContract(revision SignedRevision, formationSet []types.Transaction, lockedCollateral types.Currency, initialUsage Usage, negotationHeight uint64) error
@@ -0,0 +1,86 @@
+package contracts
+
+import (
+	"time"
+
+	"go.sia.tech/core/types"
+)
+
+// ProofAlertBuffer is the number of blocks before the end of the proof window
+// to alert if a storage proof has not been confirmed.
+const ProofAlertBuffer = 6 // 1 hour
+
+type (
+	// A ProofFeePolicy determines the miner fee of storage proof transactions.
+	// Unconfirmed storage proofs are resubmitted every ResubmitInterval blocks
+	// with the fee increased by FeeIncrease percent.
+	ProofFeePolicy struct {
+		ResubmitInterval uint64 `json:"resubmitInterval"`
+		FeeIncrease      uint64 `json:"feeIncrease"`
+		// MaxFee is the maximum miner fee of a storage proof transaction. A
+		// zero value only limits the fee to the host's benefit from
+		// submitting the proof.
+		MaxFee types.Currency `json:"maxFee"`
+	}
+
+	// A ProofSubmission tracks the most recent broadcast of a contract's
+	// storage proof.
+	ProofSubmission struct {
+		TransactionID types.TransactionID `json:"transactionID"`
+		Fee           types.Currency      `json:"fee"`
+		// Attempts is the number of times the proof has been broadcast,
+		// including attempts that were rejected by the transaction pool.
+		Attempts int    `json:"attempts"`
+		Height   uint64 `json:"height"`
+		// Error is the reason the most recent attempt was not accepted by
+		// the transaction pool. It is empty if the attempt was accepted.
+		Error     string    `json:"error,omitempty"`
+		Timestamp time.Time `json:"timestamp"`
+	}
+)
+
+// DefaultProofFeePolicy is the fee policy used if none is set.
+var DefaultProofFeePolicy = ProofFeePolicy{
+	ResubmitInterval: 3,
+	FeeIncrease:      50,
+}
+
+// NextFee returns the miner fee of the next storage proof submission. The fee
+// is never lower than the recommended fee. If the proof was previously
+// submitted, the previous fee is increased by FeeIncrease percent.
+func (p ProofFeePolicy) NextFee(recommended, previous types.Currency) types.Currency {
+	fee := recommended
+	if bumped := previous.Mul64(100 + p.FeeIncrease).Div64(100); bumped.Cmp(fee) > 0 {
+		fee = bumped
+	}
+	if !p.MaxFee.IsZero() && fee.Cmp(p.MaxFee) > 0 {
+		fee = p.MaxFee
+	}
+	return fee
+}
+
+// pooledStorageProof returns the ID of a transaction in the transaction pool
+// that contains a storage proof for the contract.
+func (cm *ContractManager) pooledStorageProof(id types.FileContractID) (types.TransactionID, bool) {
+	for _, txn := range cm.tpool.Transactions() {
+		for _, sp := range txn.StorageProofs {
+			if sp.ParentID == id {
+				return txn.ID(), true
+			}
+		}
+	}
+	return types.TransactionID{}, false
+}
+
+// SetProofFeePolicy sets the fee policy used when submitting storage proofs.
+func (cm *ContractManager) SetProofFeePolicy(p ProofFeePolicy) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.proofFeePolicy = p
+}
+
+func (cm *ContractManager) getProofFeePolicy() ProofFeePolicy {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return cm.proofFeePolicy
+}
@@ -0,0 +1,184 @@
+package contracts
+
+import (
+	"errors"
+	"testing"
+
+	"go.sia.tech/core/consensus"
+	"go.sia.tech/core/types"
+	"go.uber.org/zap/zaptest"
+	"lukechampine.com/frand"
+)
+
+// stubProofStore is a contract store that holds a single contract.
+type stubProofStore struct {
+	ContractStore
+	contract Contract
+}
+
+func (s *stubProofStore) Contract(types.FileContractID) (Contract, error) {
+	return s.contract, nil
+}
+
+func (s *stubProofStore) SetProofSubmission(_ types.FileContractID, submission ProofSubmission) error {
+	s.contract.ProofSubmission = &submission
+	return nil
+}
+
+// stubChain is a chain manager that returns an empty consensus state.
+type stubChain struct {
+	ChainManager
+}
+
+func (stubChain) TipState() consensus.State { return consensus.State{} }
+
+func (stubChain) IndexAtHeight(height uint64) (types.ChainIndex, error) {
+	return types.ChainIndex{Height: height}, nil
+}
+
+// stubWallet funds transactions with a random input and does not sign them.
+type stubWallet struct{}
+
+func (stubWallet) Address() types.Address                   { return types.VoidAddress }
+func (stubWallet) UnlockConditions() types.UnlockConditions { return types.UnlockConditions{} }
+
+func (stubWallet) FundTransaction(txn *types.Transaction, _ types.Currency) ([]types.Hash256, func(), error) {
+	txn.SiacoinInputs = append(txn.SiacoinInputs, types.SiacoinInput{ParentID: frand.Entropy256()})
+	return []types.Hash256{types.Hash256(txn.SiacoinInputs[0].ParentID)}, func() {}, nil
+}
+
+func (stubWallet) SignTransaction(consensus.State, *types.Transaction, []types.Hash256, types.CoveredFields) error {
+	return nil
+}
+
+// stubTPool is a transaction pool that rejects the next set if reject is set.
+type stubTPool struct {
+	reject error
+	pool   []types.Transaction
+}
+
+func (tp *stubTPool) RecommendedFee() types.Currency    { return types.NewCurrency64(1) }
+func (tp *stubTPool) Transactions() []types.Transaction { return tp.pool }
+
+func (tp *stubTPool) AcceptTransactionSet(txns []types.Transaction) error {
+	if err := tp.reject; err != nil {
+		tp.reject = nil
+		return err
+	}
+	tp.pool = append(tp.pool, txns...)
+	return nil
+}
+
+func TestNextFee(t *testing.T) {
+	recommended := types.NewCurrency64(1000)
+	tests := []struct {
+		policy   ProofFeePolicy
+		previous types.Currency
+		expected types.Currency
+	}{
+		{ProofFeePolicy{FeeIncrease: 50}, types.ZeroCurrency, recommended},                                                          // first submission
+		{ProofFeePolicy{FeeIncrease: 50}, types.NewCurrency64(500), recommended},                                                    // never below the recommended fee
+		{ProofFeePolicy{FeeIncrease: 50}, types.NewCurrency64(1000), types.NewCurrency64(1500)},                                     // increased
+		{ProofFeePolicy{FeeIncrease: 0}, types.NewCurrency64(2000), types.NewCurrency64(2000)},                                      // no increase
+		{ProofFeePolicy{FeeIncrease: 100, MaxFee: types.NewCurrency64(1800)}, types.NewCurrency64(1000), types.NewCurrency64(1800)}, // capped
+		{ProofFeePolicy{FeeIncrease: 50, MaxFee: types.NewCurrency64(1800)}, types.NewCurrency64(1800), types.NewCurrency64(1800)},  // stays at the cap
+		{ProofFeePolicy{MaxFee: types.NewCurrency64(500)}, types.ZeroCurrency, types.NewCurrency64(500)},                            // cap below the recommended fee
+	}
+	for _, tt := range tests {
+		if fee := tt.policy.NextFee(recommended, tt.previous); !fee.Equals(tt.expected) {
+			t.Fatalf("policy %+v, previous fee %v: expected %v, got %v", tt.policy, tt.previous, tt.expected, fee)
+		}
+	}
+}
+
+func TestBroadcastResolutionRejected(t *testing.T) {
+	contract := Contract{
+		SignedRevision: SignedRevision{
+			Revision: types.FileContractRevision{
+				ParentID: frand.Entropy256(),
+				FileContract: types.FileContract{
+					WindowStart: 90,
+					WindowEnd:   200,
+					ValidProofOutputs: []types.SiacoinOutput{
+						{}, {Value: types.Siacoins(10)},
+					},
+					MissedProofOutputs: []types.SiacoinOutput{
+						{}, {Value: types.Siacoins(1)},
+					},
+				},
+			},
+		},
+		Status: ContractStatusActive,
+	}
+	id := contract.Revision.ParentID
+
+	store := &stubProofStore{contract: contract}
+	tpool := &stubTPool{reject: errors.New("conflicting transaction set")}
+	cm := &ContractManager{
+		store:          store,
+		chain:          stubChain{},
+		tpool:          tpool,
+		wallet:         stubWallet{},
+		log:            zaptest.NewLogger(t),
+		proofFeePolicy: ProofFeePolicy{ResubmitInterval: 3, FeeIncrease: 50},
+	}
+
+	assertSubmission := func(attempts int, fee uint64, rejected bool) *ProofSubmission {
+		t.Helper()
+		sub := store.contract.ProofSubmission
+		if sub == nil {
+			t.Fatal("expected proof submission")
+		} else if sub.Attempts != attempts {
+			t.Fatalf("expected %v attempts, got %v", attempts, sub.Attempts)
+		} else if !sub.Fee.Equals(types.NewCurrency64(fee)) {
+			t.Fatalf("expected fee %v, got %v", fee, sub.Fee)
+		} else if rejected && sub.Error == "" {
+			t.Fatal("expected submission error")
+		} else if !rejected && sub.Error != "" {
+			t.Fatalf("expected no submission error, got %q", sub.Error)
+		}
+		return sub
+	}
+
+	// the first broadcast is rejected, but the attempt is still recorded
+	cm.handleContractAction(id, 100, ActionBroadcastResolution)
+	assertSubmission(1, 1000, true)
+	if len(tpool.pool) != 0 {
+		t.Fatalf("expected empty pool, got %v transactions", len(tpool.pool))
+	}
+
+	// the next attempt waits for the resubmit interval
+	cm.handleContractAction(id, 101, ActionBroadcastResolution)
+	assertSubmission(1, 1000, true)
+
+	// the resubmission increases the fee
+	cm.handleContractAction(id, 103, ActionBroadcastResolution)
+	sub := assertSubmission(2, 1500, false)
+	if len(tpool.pool) != 2 {
+		t.Fatalf("expected 2 pooled transactions, got %v", len(tpool.pool))
+	} else if sub.TransactionID != tpool.pool[1].ID() {
+		t.Fatalf("expected transaction %v, got %v", tpool.pool[1].ID(), sub.TransactionID)
+	} else if !tpool.pool[1].MinerFees[0].Equals(types.NewCurrency64(1500)) {
+		t.Fatalf("expected miner fee 1500, got %v", tpool.pool[1].MinerFees[0])
+	}
+
+	// a pooled proof cannot be replaced, the attempt is recorded without
+	// broadcasting a conflicting set
+	cm.handleContractAction(id, 106, ActionBroadcastResolution)
+	sub = assertSubmission(3, 2250, true)
+	if len(tpool.pool) != 2 {
+		t.Fatalf("expected 2 pooled transactions, got %v", len(tpool.pool))
+	} else if sub.TransactionID != tpool.pool[1].ID() {
+		t.Fatalf("expected pooled transaction %v, got %v", tpool.pool[1].ID(), sub.TransactionID)
+	}
+
+	// once the pooled proof is evicted, the increased fee is broadcast
+	tpool.pool = nil
+	cm.handleContractAction(id, 109, ActionBroadcastResolution)
+	assertSubmission(4, 3375, false)
+	if len(tpool.pool) != 2 {
+		t.Fatalf("expected 2 pooled transactions, got %v", len(tpool.pool))
+	} else if !tpool.pool[1].MinerFees[0].Equals(types.NewCurrency64(3375)) {
+		t.Fatalf("expected miner fee 3375, got %v", tpool.pool[1].MinerFees[0])
+	}
+}
@@ -0,0 +1,165 @@
+package contracts
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"time"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/alerts"
+	"go.uber.org/zap"
+	"lukechampine.com/frand"
+)
+
+// proofRehearsalSamples is the number of random leaves proven during each
+// proof rehearsal.
+const proofRehearsalSamples = 3
+
+// A ProofRehearsal is the result of building and verifying a storage proof for
+// a contract before its proof window opens.
+type ProofRehearsal struct {
+	// Height is the block height the rehearsal was performed at.
+	Height uint64 `json:"height"`
+	// RevisionNumber is the revision number of the contract that was
+	// rehearsed.
+	RevisionNumber uint64 `json:"revisionNumber"`
+	Passed         bool   `json:"passed"`
+	// FailedRoots are the sector roots that could not be proven.
+	FailedRoots []types.Hash256 `json:"failedRoots,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	Timestamp   time.Time       `json:"timestamp"`
+}
+
+// proofRehearsalAlertID returns the ID of the alert registered when a
+// contract's proof rehearsal fails.
+func proofRehearsalAlertID(id types.FileContractID) types.Hash256 {
+	return types.HashBytes(append([]byte("proofRehearsal"), id[:]...))
+}
+
+// storageProofRoot calculates the Merkle root of a storage proof. The leaf is
+// truncated if it is the last leaf of a partial segment.
+func storageProofRoot(sp types.StorageProof, leafIndex, filesize uint64) types.Hash256 {
+	const leafSize = uint64(len(types.StorageProof{}.Leaf))
+	lastLeafIndex := filesize / leafSize
+	if filesize%leafSize == 0 {
+		lastLeafIndex--
+	}
+	leaf := sp.Leaf[:]
+	if leafIndex == lastLeafIndex && filesize%leafSize != 0 {
+		leaf = leaf[:filesize%leafSize]
+	}
+
+	buf := make([]byte, 1+len(leaf))
+	buf[0] = 0 // leaf hash prefix
+	copy(buf[1:], leaf)
+	root := types.HashBytes(buf)
+	subtreeHeight := bits.Len64(leafIndex ^ lastLeafIndex)
+	for i, h := range sp.Proof {
+		if leafIndex&(1<<i) != 0 || i >= subtreeHeight {
+			root = hashNodes(h, root)
+		} else {
+			root = hashNodes(root, h)
+		}
+	}
+	return root
+}
+
+// hashNodes returns the Merkle parent of two nodes.
+func hashNodes(left, right types.Hash256) types.Hash256 {
+	buf := make([]byte, 1+2*len(left))
+	buf[0] = 1 // node hash prefix
+	copy(buf[1:], left[:])
+	copy(buf[1+len(left):], right[:])
+	return types.HashBytes(buf)
+}
+
+// rehearseStorageProof builds and verifies storage proofs for random leaves of
+// the contract. The leaf that must be proven is not known until the block
+// before the proof window, so the rehearsal samples random leaves to detect
+// missing or corrupt sectors early.
+func (cm *ContractManager) rehearseStorageProof(contract Contract) (failed []types.Hash256, err error) {
+	filesize := contract.Revision.Filesize
+	if filesize == 0 {
+		// empty contracts do not require a proof
+		return nil, nil
+	}
+
+	roots, err := cm.store.SectorRoots(contract.Revision.ParentID, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sector roots: %w", err)
+	} else if calculated := rhpv2.MetaRoot(roots); calculated != contract.Revision.FileMerkleRoot {
+		return nil, fmt.Errorf("expected Merkle root %v, got %v", contract.Revision.FileMerkleRoot, calculated)
+	}
+
+	leaves := filesize / rhpv2.LeafSize
+	if filesize%rhpv2.LeafSize != 0 {
+		leaves++
+	}
+	checked := make(map[types.Hash256]bool)
+	for i := 0; i < proofRehearsalSamples; i++ {
+		leafIndex := frand.Uint64n(leaves)
+		root := roots[leafIndex/rhpv2.LeavesPerSector]
+		if _, ok := checked[root]; ok {
+			continue
+		}
+
+		sp, err := cm.buildSectorStorageProof(contract.Revision.ParentID, roots, leafIndex)
+		if err != nil {
+			cm.log.Debug("failed to build rehearsal proof", zap.Stringer("contractID", contract.Revision.ParentID), zap.Stringer("root", root), zap.Error(err))
+			checked[root] = false
+		} else {
+			checked[root] = storageProofRoot(sp, leafIndex, filesize) == contract.Revision.FileMerkleRoot
+		}
+		if !checked[root] {
+			failed = append(failed, root)
+		}
+	}
+	if len(failed) > 0 {
+		return failed, errors.New("storage proof could not be verified")
+	}
+	return nil, nil
+}
+
+// rehearseProof rehearses the contract's storage proof, stores the result, and
+// registers a critical alert if the proof could not be verified.
+func (cm *ContractManager) rehearseProof(contract Contract, height uint64, log *zap.Logger) {
+	id := contract.Revision.ParentID
+	rehearsal := ProofRehearsal{
+		Height:         height,
+		RevisionNumber: contract.Revision.RevisionNumber,
+		Passed:         true,
+		Timestamp:      time.Now(),
+	}
+	failed, err := cm.rehearseStorageProof(contract)
+	if err != nil {
+		rehearsal.Passed = false
+		rehearsal.FailedRoots = failed
+		rehearsal.Error = err.Error()
+	}
+	if err := cm.store.SetProofRehearsal(id, rehearsal); err != nil {
+		log.Error("failed to store proof rehearsal", zap.Error(err))
+	}
+
+	if rehearsal.Passed {
+		cm.alerts.Dismiss(proofRehearsalAlertID(id))
+		log.Debug("proof rehearsal passed", zap.Uint64("windowStart", contract.Revision.WindowStart))
+		return
+	}
+
+	log.Error("proof rehearsal failed", zap.Uint64("windowStart", contract.Revision.WindowStart), zap.Stringers("failedRoots", failed), zap.Error(err))
+	cm.alerts.Register(alerts.Alert{
+		ID:       proofRehearsalAlertID(id),
+		Severity: alerts.SeverityCritical,
+		Message:  "Storage proof rehearsal failed",
+		Data: map[string]any{
+			"contractID":  id,
+			"blockHeight": height,
+			"windowStart": contract.Revision.WindowStart,
+			"failedRoots": failed,
+			"error":       err.Error(),
+		},
+		Timestamp: time.Now(),
+	})
+}
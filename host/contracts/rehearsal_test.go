@@ -0,0 +1,170 @@
+package contracts
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	"go.sia.tech/core/types"
+	"go.sia.tech/siad/crypto"
+	"go.uber.org/zap/zaptest"
+	"lukechampine.com/frand"
+)
+
+// stubRootStore is a contract store that only returns a fixed set of sector
+// roots.
+type stubRootStore struct {
+	ContractStore
+	roots []types.Hash256
+}
+
+func (s *stubRootStore) SectorRoots(types.FileContractID, uint64, uint64) ([]types.Hash256, error) {
+	return s.roots, nil
+}
+
+// stubSectors is an in-memory sector store.
+type stubSectors map[types.Hash256]*[rhpv2.SectorSize]byte
+
+func (s stubSectors) Read(root types.Hash256) (*[rhpv2.SectorSize]byte, error) {
+	sector, ok := s[root]
+	if !ok {
+		return nil, errors.New("sector not found")
+	}
+	return sector, nil
+}
+
+func randomSectors(n int) (stubSectors, []types.Hash256) {
+	sectors := make(stubSectors)
+	roots := make([]types.Hash256, 0, n)
+	for i := 0; i < n; i++ {
+		var sector [rhpv2.SectorSize]byte
+		frand.Read(sector[:])
+		root := rhpv2.SectorRoot(&sector)
+		sectors[root] = &sector
+		roots = append(roots, root)
+	}
+	return sectors, roots
+}
+
+func TestStorageProofRoot(t *testing.T) {
+	const sectors = 5
+	storage, roots := randomSectors(sectors)
+	cm := &ContractManager{storage: storage}
+
+	filesize := uint64(sectors * rhpv2.SectorSize)
+	leaves := filesize / rhpv2.LeafSize
+	metaRoot := rhpv2.MetaRoot(roots)
+	indices := []uint64{0, 1, rhpv2.LeavesPerSector - 1, rhpv2.LeavesPerSector, leaves - 1}
+	for i := 0; i < 10; i++ {
+		indices = append(indices, frand.Uint64n(leaves))
+	}
+
+	for _, index := range indices {
+		sp, err := cm.buildSectorStorageProof(types.FileContractID{}, roots, index)
+		if err != nil {
+			t.Fatal(err)
+		} else if root := storageProofRoot(sp, index, filesize); root != metaRoot {
+			t.Fatalf("leaf %v: expected root %v, got %v", index, metaRoot, root)
+		}
+
+		// the proof should also be accepted by consensus
+		hashes := make([]crypto.Hash, len(sp.Proof))
+		for i := range sp.Proof {
+			hashes[i] = crypto.Hash(sp.Proof[i])
+		}
+		if !crypto.VerifySegment(sp.Leaf[:], hashes, leaves, index, crypto.Hash(metaRoot)) {
+			t.Fatalf("leaf %v: proof rejected by consensus", index)
+		}
+
+		// a modified leaf should not match
+		sp.Leaf[frand.Intn(len(sp.Leaf))] ^= 1
+		if root := storageProofRoot(sp, index, filesize); root == metaRoot {
+			t.Fatalf("leaf %v: expected modified leaf to change the root", index)
+		}
+	}
+
+	// files that do not end on a leaf boundary truncate the last leaf
+	data := frand.Bytes(1000)
+	dataRoot := types.Hash256(crypto.MerkleRoot(data))
+	numLeaves := uint64(len(data)+rhpv2.LeafSize-1) / rhpv2.LeafSize
+	for index := uint64(0); index < numLeaves; index++ {
+		base, hashSet := crypto.MerkleProof(data, index)
+		sp := types.StorageProof{
+			Proof: make([]types.Hash256, len(hashSet)),
+		}
+		copy(sp.Leaf[:], base)
+		for i := range hashSet {
+			sp.Proof[i] = types.Hash256(hashSet[i])
+		}
+		if root := storageProofRoot(sp, index, uint64(len(data))); root != dataRoot {
+			t.Fatalf("leaf %v: expected root %v, got %v", index, dataRoot, root)
+		}
+	}
+}
+
+func TestRehearseStorageProof(t *testing.T) {
+	storage, roots := randomSectors(1)
+	root := roots[0]
+	cm := &ContractManager{
+		store:   &stubRootStore{roots: roots},
+		storage: storage,
+		log:     zaptest.NewLogger(t),
+	}
+
+	contract := Contract{
+		SignedRevision: SignedRevision{
+			Revision: types.FileContractRevision{
+				ParentID: frand.Entropy256(),
+				FileContract: types.FileContract{
+					Filesize:       rhpv2.SectorSize,
+					FileMerkleRoot: rhpv2.MetaRoot(roots),
+				},
+			},
+		},
+	}
+
+	// every sampled leaf is in the only sector, so any problem with the
+	// sector should be detected
+	if failed, err := cm.rehearseStorageProof(contract); err != nil {
+		t.Fatal(err)
+	} else if len(failed) != 0 {
+		t.Fatalf("expected no failed roots, got %v", failed)
+	}
+
+	// corrupt the sector
+	good := storage[root]
+	corrupt := *good
+	corrupt[frand.Intn(len(corrupt))] ^= 1
+	storage[root] = &corrupt
+	if failed, err := cm.rehearseStorageProof(contract); err == nil {
+		t.Fatal("expected corrupt sector to fail the rehearsal")
+	} else if !reflect.DeepEqual(failed, []types.Hash256{root}) {
+		t.Fatalf("expected failed root %v, got %v", root, failed)
+	}
+
+	// remove the sector
+	delete(storage, root)
+	if failed, err := cm.rehearseStorageProof(contract); err == nil {
+		t.Fatal("expected missing sector to fail the rehearsal")
+	} else if !reflect.DeepEqual(failed, []types.Hash256{root}) {
+		t.Fatalf("expected failed root %v, got %v", root, failed)
+	}
+	storage[root] = good
+
+	// the stored roots must match the contract's Merkle root
+	mismatch := contract
+	mismatch.Revision.FileMerkleRoot = frand.Entropy256()
+	if failed, err := cm.rehearseStorageProof(mismatch); err == nil {
+		t.Fatal("expected Merkle root mismatch to fail the rehearsal")
+	} else if len(failed) != 0 {
+		t.Fatalf("expected no failed roots, got %v", failed)
+	}
+
+	// empty contracts do not need a proof
+	empty := contract
+	empty.Revision.Filesize = 0
+	if failed, err := cm.rehearseStorageProof(empty); err != nil || len(failed) != 0 {
+		t.Fatalf("expected empty contract to pass, got %v %v", failed, err)
+	}
+}
@@ -0,0 +1,244 @@
+package contracts
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/settings"
+)
+
+const (
+	// minPriceMultiplier and maxPriceMultiplier bound a renter's price
+	// multiplier.
+	minPriceMultiplier = 0.01
+	maxPriceMultiplier = 100
+)
+
+var (
+	// ErrRenterNotFound is returned when a renter has never formed a contract
+	// with the host and does not have a policy.
+	ErrRenterNotFound = errors.New("renter not found")
+	// ErrRenterBlocked is returned when a blocked renter attempts to form or
+	// renew a contract or to store additional data.
+	ErrRenterBlocked = errors.New("renter is blocked by the host")
+	// ErrRenterContractLimit is returned when a renter attempts to form a
+	// contract after reaching its contract limit.
+	ErrRenterContractLimit = errors.New("renter has reached the maximum number of contracts")
+	// ErrRenterStorageLimit is returned when a revision would increase a
+	// renter's stored data beyond its storage limit.
+	ErrRenterStorageLimit = errors.New("renter has reached the maximum amount of stored data")
+)
+
+type (
+	// A RenterPolicy restricts the contracts a renter can form with the host
+	// and adjusts the prices the renter is charged.
+	RenterPolicy struct {
+		RenterKey types.PublicKey `json:"renterKey"`
+		// Blocked prevents the renter from forming or renewing contracts and
+		// from storing additional data. Existing data can still be
+		// downloaded.
+		Blocked bool `json:"blocked"`
+		// MaxContracts is the maximum number of active contracts the renter
+		// can have. Renewals do not count towards the limit. If zero, the
+		// number of contracts is not limited.
+		MaxContracts uint64 `json:"maxContracts"`
+		// MaxStorage is the maximum number of bytes the renter can store
+		// across all active contracts. If zero, the renter's stored data is
+		// not limited.
+		MaxStorage uint64 `json:"maxStorage"`
+		// PriceMultiplier is multiplied with the host's prices for the
+		// renter. It must be between 0.01 and 100. Collateral is not
+		// affected. If zero, the host's prices are used.
+		PriceMultiplier float64 `json:"priceMultiplier"`
+		Note            string  `json:"note,omitempty"`
+
+		LastUpdated time.Time `json:"lastUpdated"`
+	}
+
+	// RenterStats are aggregate statistics of a renter's contracts.
+	RenterStats struct {
+		RenterKey types.PublicKey `json:"renterKey"`
+		// ActiveContracts is the number of pending or active contracts that
+		// have not been renewed.
+		ActiveContracts uint64 `json:"activeContracts"`
+		TotalContracts  uint64 `json:"totalContracts"`
+		// Sectors is the number of sectors stored in the renter's active
+		// contracts.
+		Sectors uint64 `json:"sectors"`
+
+		// Revenue is the lifetime usage of the renter's contracts, excluding
+		// rejected contracts.
+		Revenue Usage `json:"revenue"`
+		// LockedCollateral and RiskedCollateral are the collateral of the
+		// renter's active contracts.
+		LockedCollateral types.Currency `json:"lockedCollateral"`
+		RiskedCollateral types.Currency `json:"riskedCollateral"`
+		// AccountBalance is the balance of the ephemeral accounts funded by
+		// the renter's contracts.
+		AccountBalance types.Currency `json:"accountBalance"`
+
+		// FirstSeen and LastSeen are the times the renter first formed a
+		// contract and last formed or revised a contract. They are the zero
+		// value if the renter has not been seen since they were tracked.
+		FirstSeen time.Time `json:"firstSeen"`
+		LastSeen  time.Time `json:"lastSeen"`
+	}
+
+	// RenterUsage is the number of active contracts and the amount of data a
+	// renter is storing on the host.
+	RenterUsage struct {
+		Contracts uint64 `json:"contracts"`
+		Sectors   uint64 `json:"sectors"`
+	}
+)
+
+// Validate returns an error if the policy is invalid.
+func (p RenterPolicy) Validate() error {
+	if p.PriceMultiplier == 0 {
+		return nil
+	} else if math.IsNaN(p.PriceMultiplier) || p.PriceMultiplier < minPriceMultiplier || p.PriceMultiplier > maxPriceMultiplier {
+		return fmt.Errorf("price multiplier must be 0 or between %v and %v, got %v", minPriceMultiplier, maxPriceMultiplier, p.PriceMultiplier)
+	}
+	return nil
+}
+
+// ApplyPrice multiplies a price by the policy's price multiplier. Prices that
+// would overflow are capped at the maximum currency value.
+func (p RenterPolicy) ApplyPrice(c types.Currency) types.Currency {
+	if p.PriceMultiplier == 0 || p.PriceMultiplier == 1 {
+		return c
+	}
+	r := new(big.Rat).SetFloat64(p.PriceMultiplier)
+	if r == nil || r.Sign() < 0 {
+		return c
+	}
+	r.Mul(r, new(big.Rat).SetInt(c.Big()))
+	n := new(big.Int).Quo(r.Num(), r.Denom())
+	if n.BitLen() > 128 {
+		return types.MaxCurrency
+	}
+	return types.NewCurrency(n.Uint64(), new(big.Int).Rsh(n, 64).Uint64())
+}
+
+// ApplyPricing returns the host's settings with the policy's price multiplier
+// applied. The collateral multiplier is adjusted so that the collateral
+// offered to the renter is unchanged.
+func (p RenterPolicy) ApplyPricing(s settings.Settings) settings.Settings {
+	if p.PriceMultiplier == 0 || p.PriceMultiplier == 1 {
+		return s
+	}
+	s.ContractPrice = p.ApplyPrice(s.ContractPrice)
+	s.BaseRPCPrice = p.ApplyPrice(s.BaseRPCPrice)
+	s.SectorAccessPrice = p.ApplyPrice(s.SectorAccessPrice)
+	s.StoragePrice = p.ApplyPrice(s.StoragePrice)
+	s.IngressPrice = p.ApplyPrice(s.IngressPrice)
+	s.EgressPrice = p.ApplyPrice(s.EgressPrice)
+	s.CollateralMultiplier /= p.PriceMultiplier
+	return s
+}
+
+// StoredData returns the number of bytes the renter is storing.
+func (u RenterUsage) StoredData() uint64 {
+	return u.Sectors * rhpv2.SectorSize
+}
+
+// RenterPolicy returns the policy of a renter. If the renter does not have a
+// policy, the default policy is returned.
+func (cm *ContractManager) RenterPolicy(renterKey types.PublicKey) (RenterPolicy, error) {
+	policy, err := cm.store.RenterPolicy(renterKey)
+	if errors.Is(err, ErrNotFound) {
+		return RenterPolicy{RenterKey: renterKey}, nil
+	} else if err != nil {
+		return RenterPolicy{}, fmt.Errorf("failed to get renter policy: %w", err)
+	}
+	return policy, nil
+}
+
+// RenterPolicies returns all renter policies.
+func (cm *ContractManager) RenterPolicies() ([]RenterPolicy, error) {
+	return cm.store.RenterPolicies()
+}
+
+// SetRenterPolicy sets the policy of a renter.
+func (cm *ContractManager) SetRenterPolicy(policy RenterPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	policy.LastUpdated = time.Now()
+	return cm.store.SetRenterPolicy(policy)
+}
+
+// RemoveRenterPolicy removes the policy of a renter. The renter is treated
+// the same as any other renter.
+func (cm *ContractManager) RemoveRenterPolicy(renterKey types.PublicKey) error {
+	return cm.store.RemoveRenterPolicy(renterKey)
+}
+
+// CheckRenterPolicy returns the renter's policy or an error if the renter is
+// not allowed to add contracts. Renewals should pass zero additional
+// contracts.
+func (cm *ContractManager) CheckRenterPolicy(renterKey types.PublicKey, additionalContracts uint64) (RenterPolicy, error) {
+	policy, err := cm.RenterPolicy(renterKey)
+	if err != nil {
+		return RenterPolicy{}, err
+	} else if policy.Blocked {
+		return RenterPolicy{}, ErrRenterBlocked
+	} else if policy.MaxContracts == 0 || additionalContracts == 0 {
+		return policy, nil
+	}
+
+	usage, err := cm.store.RenterUsage(renterKey)
+	if err != nil {
+		return RenterPolicy{}, fmt.Errorf("failed to get renter usage: %w", err)
+	} else if usage.Contracts+additionalContracts > policy.MaxContracts {
+		return RenterPolicy{}, fmt.Errorf("%w: limit %v", ErrRenterContractLimit, policy.MaxContracts)
+	}
+	return policy, nil
+}
+
+// checkRenterStorage returns an error if adding sectors would exceed the
+// renter's storage limit.
+func (cm *ContractManager) checkRenterStorage(renterKey types.PublicKey, addedSectors uint64) error {
+	if addedSectors == 0 {
+		return nil
+	}
+
+	policy, err := cm.RenterPolicy(renterKey)
+	if err != nil {
+		return err
+	} else if policy.Blocked {
+		return ErrRenterBlocked
+	} else if policy.MaxStorage == 0 {
+		return nil
+	}
+
+	usage, err := cm.store.RenterUsage(renterKey)
+	if err != nil {
+		return fmt.Errorf("failed to get renter usage: %w", err)
+	} else if (usage.Sectors+addedSectors)*rhpv2.SectorSize > policy.MaxStorage {
+		return fmt.Errorf("%w: limit %v bytes", ErrRenterStorageLimit, policy.MaxStorage)
+	}
+	return nil
+}
+
+// RenterUsage returns the number of active contracts and sectors stored by a
+// renter.
+func (cm *ContractManager) RenterUsage(renterKey types.PublicKey) (RenterUsage, error) {
+	return cm.store.RenterUsage(renterKey)
+}
+
+// Renters returns aggregate statistics for renters ordered by the time they
+// were last seen and the total number of renters.
+func (cm *ContractManager) Renters(limit, offset int) ([]RenterStats, int, error) {
+	return cm.store.Renters(limit, offset)
+}
+
+// RenterStats returns aggregate statistics for a renter.
+func (cm *ContractManager) RenterStats(renterKey types.PublicKey) (RenterStats, error) {
+	return cm.store.RenterStats(renterKey)
+}
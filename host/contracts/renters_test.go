@@ -0,0 +1,58 @@
+package contracts_test
+
+import (
+	"math"
+	"testing"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/contracts"
+)
+
+func TestApplyPrice(t *testing.T) {
+	tests := []struct {
+		multiplier float64
+		price      types.Currency
+		expected   types.Currency
+	}{
+		{0, types.Siacoins(1), types.Siacoins(1)},
+		{1, types.Siacoins(1), types.Siacoins(1)},
+		{2, types.Siacoins(3), types.Siacoins(6)},
+		{0.5, types.Siacoins(1), types.Siacoins(1).Div64(2)},
+		{0.01, types.NewCurrency64(100), types.NewCurrency64(1)},
+		{0.0009765625, types.NewCurrency64(1024000), types.NewCurrency64(1000)}, // not rounded to zero
+		{1.0625, types.NewCurrency64(10000), types.NewCurrency64(10625)},        // not truncated to three decimals
+		{1.5, types.NewCurrency64(3), types.NewCurrency64(4)},                   // rounded down
+		{2, types.MaxCurrency, types.MaxCurrency},
+	}
+	for _, tt := range tests {
+		p := contracts.RenterPolicy{PriceMultiplier: tt.multiplier}
+		if price := p.ApplyPrice(tt.price); !price.Equals(tt.expected) {
+			t.Fatalf("multiplier %v: expected %v, got %v", tt.multiplier, tt.expected, price)
+		}
+	}
+}
+
+func TestRenterPolicyValidate(t *testing.T) {
+	tests := []struct {
+		multiplier float64
+		valid      bool
+	}{
+		{0, true},
+		{0.01, true},
+		{1, true},
+		{100, true},
+		{0.001, false},
+		{100.5, false},
+		{-1, false},
+		{math.NaN(), false},
+		{math.Inf(1), false},
+	}
+	for _, tt := range tests {
+		err := contracts.RenterPolicy{PriceMultiplier: tt.multiplier}.Validate()
+		if tt.valid && err != nil {
+			t.Fatalf("multiplier %v: expected valid, got %v", tt.multiplier, err)
+		} else if !tt.valid && err == nil {
+			t.Fatalf("multiplier %v: expected error", tt.multiplier)
+		}
+	}
+}
@@ -12,6 +12,8 @@ type (
 		PeriodMetrics(start time.Time, n int, interval Interval) (period []Metrics, err error)
 		// Metrics returns aggregated metrics for the host as of the timestamp.
 		Metrics(time.Time) (m Metrics, err error)
+		// PriceHistory returns the price changes between start and end.
+		PriceHistory(start, end time.Time) ([]PriceChange, error)
 	}
 
 	// A MetricManager retrieves metrics from a store
@@ -34,6 +36,12 @@ func (mm *MetricManager) Metrics(timestamp time.Time) (m Metrics, err error) {
 	return mm.store.Metrics(timestamp)
 }
 
+// PriceHistory returns the price changes made by the pricing engine between
+// start and end.
+func (mm *MetricManager) PriceHistory(start, end time.Time) ([]PriceChange, error) {
+	return mm.store.PriceHistory(start, end)
+}
+
 // Normalize returns the normalized timestamp for the given interval.
 func Normalize(timestamp time.Time, interval Interval) (time.Time, error) {
 	switch interval {
@@ -56,6 +56,16 @@ type (
 		CollateralMultiplier float64        `json:"collateralMultiplier"`
 	}
 
+	// A PriceChange is an adjustment of the host's prices by the pricing
+	// engine along with the inputs that caused it.
+	PriceChange struct {
+		Pricing      Pricing   `json:"pricing"`
+		Utilization  float64   `json:"utilization"`
+		ExchangeRate float64   `json:"exchangeRate"`
+		Demand       uint64    `json:"demand"`
+		Timestamp    time.Time `json:"timestamp"`
+	}
+
 	// Registry is a collection of metrics related to the host's registry.
 	Registry struct {
 		Entries    uint64 `json:"entries"`
@@ -77,6 +87,18 @@ type (
 
 		SectorCacheHits   uint64 `json:"sectorCacheHits"`
 		SectorCacheMisses uint64 `json:"sectorCacheMisses"`
+
+		Hot  TierStorage `json:"hot"`
+		Cold TierStorage `json:"cold"`
+
+		PromotedSectors uint64 `json:"promotedSectors"`
+		DemotedSectors  uint64 `json:"demotedSectors"`
+	}
+
+	// TierStorage is a collection of metrics related to a storage tier.
+	TierStorage struct {
+		TotalSectors    uint64 `json:"totalSectors"`
+		PhysicalSectors uint64 `json:"physicalSectors"`
 	}
 
 	// RevenueMetrics is a collection of metrics related to revenue.
@@ -33,7 +33,7 @@ func testRegistry(t *testing.T, privKey types.PrivateKey, limit uint64) *registr
 		db.Close()
 	})
 
-	if err := db.UpdateSettings(settings.Settings{MaxRegistryEntries: limit}); err != nil {
+	if err := db.UpdateSettings(settings.Settings{MaxRegistryEntries: limit}, settings.SourceOperator); err != nil {
 		t.Fatal(err)
 	}
 	return registry.NewManager(privKey, db, log.Named("registry"))
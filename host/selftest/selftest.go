@@ -0,0 +1,249 @@
+// Package selftest checks that the host's RHP endpoints are reachable at the
+// announced address.
+package selftest
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	rhp2 "go.sia.tech/core/rhp/v2"
+	rhp3 "go.sia.tech/core/rhp/v3"
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/settings"
+	"nhooyr.io/websocket"
+)
+
+// endpointTimeout is the maximum time to complete the handshake with a single
+// endpoint.
+const endpointTimeout = 30 * time.Second
+
+type (
+	// A SettingsReporter reports the host's settings and TLS configuration.
+	SettingsReporter interface {
+		Settings() settings.Settings
+		DiscoveredRHP2Address() string
+		RHP3TLSConfig() *tls.Config
+	}
+
+	// An EndpointResult is the result of testing a single endpoint.
+	EndpointResult struct {
+		Address string        `json:"address"`
+		Latency time.Duration `json:"latency"`
+		Error   string        `json:"error,omitempty"`
+	}
+
+	// A Report is the result of a self-test.
+	Report struct {
+		NetAddress string         `json:"netAddress"`
+		RHP2       EndpointResult `json:"rhp2"`
+		RHP3TCP    EndpointResult `json:"rhp3TCP"`
+		RHP3WS     EndpointResult `json:"rhp3WS"`
+		Timestamp  time.Time      `json:"timestamp"`
+	}
+
+	// A Tester dials the host's announced address to check that the RHP
+	// endpoints are reachable.
+	Tester struct {
+		hostKey types.PublicKey
+		wsPort  string
+
+		settings SettingsReporter
+	}
+)
+
+// OK returns true if all endpoints are reachable.
+func (r Report) OK() bool {
+	return r.RHP2.Error == "" && r.RHP3TCP.Error == "" && r.RHP3WS.Error == ""
+}
+
+// test runs fn and records its latency and error.
+func test(ctx context.Context, addr string, fn func(context.Context) error) EndpointResult {
+	ctx, cancel := context.WithTimeout(ctx, endpointTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := fn(ctx)
+	res := EndpointResult{
+		Address: addr,
+		Latency: time.Since(start),
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// dialRHP2 completes the RHP2 handshake and returns the host's settings.
+func (t *Tester) dialRHP2(ctx context.Context, addr string) (hs rhp2.HostSettings, err error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return rhp2.HostSettings{}, fmt.Errorf("failed to dial host: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	transport, err := rhp2.NewRenterTransport(conn, t.hostKey)
+	if err != nil {
+		return rhp2.HostSettings{}, fmt.Errorf("failed to create transport: %w", err)
+	}
+	defer transport.Close()
+
+	var resp rhp2.RPCSettingsResponse
+	if err := transport.Call(rhp2.RPCSettingsID, nil, &resp); err != nil {
+		return rhp2.HostSettings{}, fmt.Errorf("failed to get settings: %w", err)
+	} else if err := json.Unmarshal(resp.Settings, &hs); err != nil {
+		return rhp2.HostSettings{}, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	return hs, nil
+}
+
+// scanRHP3PriceTable completes the RHP3 handshake over conn and requests the
+// host's price table.
+func (t *Tester) scanRHP3PriceTable(ctx context.Context, conn net.Conn) (rhp3.HostPriceTable, error) {
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	transport, err := rhp3.NewRenterTransport(conn, t.hostKey)
+	if err != nil {
+		return rhp3.HostPriceTable{}, fmt.Errorf("failed to create transport: %w", err)
+	}
+	defer transport.Close()
+
+	stream := transport.DialStream()
+	defer stream.Close()
+	if err := stream.WriteRequest(rhp3.RPCUpdatePriceTableID, nil); err != nil {
+		return rhp3.HostPriceTable{}, fmt.Errorf("failed to write request: %w", err)
+	}
+	var resp rhp3.RPCUpdatePriceTableResponse
+	if err := stream.ReadResponse(&resp, 4096); err != nil {
+		return rhp3.HostPriceTable{}, fmt.Errorf("failed to read response: %w", err)
+	}
+	var pt rhp3.HostPriceTable
+	if err := json.Unmarshal(resp.PriceTableJSON, &pt); err != nil {
+		return rhp3.HostPriceTable{}, fmt.Errorf("failed to unmarshal price table: %w", err)
+	}
+	return pt, nil
+}
+
+// dialRHP3 requests the host's price table over the RHP3 TCP endpoint.
+func (t *Tester) dialRHP3(ctx context.Context, addr string) (rhp3.HostPriceTable, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return rhp3.HostPriceTable{}, fmt.Errorf("failed to dial host: %w", err)
+	}
+	return t.scanRHP3PriceTable(ctx, conn)
+}
+
+// dialRHP3WebSocket requests the host's price table over the RHP3 WebSocket
+// endpoint. The url should use the wss scheme.
+func (t *Tester) dialRHP3WebSocket(ctx context.Context, url string, tlsConfig *tls.Config) (rhp3.HostPriceTable, error) {
+	wsConn, _, err := websocket.Dial(ctx, url, &websocket.DialOptions{
+		HTTPClient: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	})
+	if err != nil {
+		return rhp3.HostPriceTable{}, fmt.Errorf("failed to dial host: %w", err)
+	}
+	conn := websocket.NetConn(context.Background(), wsConn, websocket.MessageBinary)
+	return t.scanRHP3PriceTable(ctx, conn)
+}
+
+// Run tests the host's RHP2, RHP3 TCP and RHP3 WebSocket endpoints at the
+// host's net address.
+func (t *Tester) Run(ctx context.Context) Report {
+	addr := t.settings.Settings().NetAddress
+	if len(addr) == 0 {
+		addr = t.settings.DiscoveredRHP2Address()
+	}
+	report := Report{
+		NetAddress: addr,
+		Timestamp:  time.Now(),
+	}
+
+	hostname, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		err = fmt.Errorf("failed to parse net address: %w", err)
+		report.RHP2.Error = err.Error()
+		report.RHP3TCP.Error = err.Error()
+		report.RHP3WS.Error = err.Error()
+		return report
+	}
+
+	// the RHP3 port is only known after the RHP2 settings have been retrieved
+	var siamuxPort string
+	report.RHP2 = test(ctx, addr, func(ctx context.Context) error {
+		hs, err := t.dialRHP2(ctx, addr)
+		if err != nil {
+			return err
+		}
+		siamuxPort = hs.SiaMuxPort
+		return nil
+	})
+
+	rhp3Addr := net.JoinHostPort(hostname, siamuxPort)
+	report.RHP3TCP = test(ctx, rhp3Addr, func(ctx context.Context) error {
+		if len(siamuxPort) == 0 {
+			return errors.New("RHP3 port unknown: RHP2 settings unavailable")
+		}
+		if _, err := t.dialRHP3(ctx, rhp3Addr); err != nil {
+			return fmt.Errorf("failed to get price table: %w", err)
+		}
+		return nil
+	})
+
+	wsAddr := net.JoinHostPort(hostname, t.wsPort)
+	report.RHP3WS = test(ctx, wsAddr, func(ctx context.Context) error {
+		cert, err := t.settings.RHP3TLSConfig().GetCertificate(&tls.ClientHelloInfo{ServerName: hostname})
+		if err != nil {
+			return fmt.Errorf("failed to get configured TLS certificate: %w", err)
+		} else if cert == nil {
+			return errors.New("no TLS certificate configured")
+		}
+		expected := cert.Certificate[0]
+		tlsConfig := &tls.Config{
+			// the certificate may be self-signed; it is checked against the
+			// configured certificate instead
+			InsecureSkipVerify: true,
+			VerifyConnection: func(cs tls.ConnectionState) error {
+				if len(cs.PeerCertificates) == 0 || !bytes.Equal(cs.PeerCertificates[0].Raw, expected) {
+					return errors.New("TLS certificate does not match the configured certificate")
+				}
+				return nil
+			},
+		}
+		if _, err := t.dialRHP3WebSocket(ctx, "wss://"+wsAddr, tlsConfig); err != nil {
+			return fmt.Errorf("failed to get price table: %w", err)
+		}
+		return nil
+	})
+	return report
+}
+
+// New initializes a new Tester. rhp3WSAddr is the address of the RHP3
+// WebSocket listener; only its port is used.
+func New(hostKey types.PublicKey, rhp3WSAddr string, sr SettingsReporter) (*Tester, error) {
+	_, port, err := net.SplitHostPort(rhp3WSAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rhp3 WebSocket address: %w", err)
+	} else if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return nil, fmt.Errorf("invalid rhp3 WebSocket port %q", port)
+	}
+	return &Tester{
+		hostKey:  hostKey,
+		wsPort:   port,
+		settings: sr,
+	}, nil
+}
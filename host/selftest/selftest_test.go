@@ -0,0 +1,58 @@
+package selftest_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/selftest"
+	"go.sia.tech/hostd/internal/test"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestSelfTest(t *testing.T) {
+	dir := t.TempDir()
+	log := zaptest.NewLogger(t)
+	node, err := test.NewNode(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+
+	host, err := test.NewHost(types.GeneratePrivateKey(), dir, node, log.Named("host"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer host.Close()
+
+	tester, err := selftest.New(host.PublicKey(), host.RHPv3WSAddr(), host.Settings())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := tester.Run(context.Background())
+	if !report.OK() {
+		t.Fatalf("expected all endpoints to be reachable: %+v", report)
+	} else if report.RHP2.Address != host.RHPv2Addr() {
+		t.Fatalf("expected rhp2 address %v, got %v", host.RHPv2Addr(), report.RHP2.Address)
+	} else if report.RHP3TCP.Address != host.RHPv3Addr() {
+		t.Fatalf("expected rhp3 address %v, got %v", host.RHPv3Addr(), report.RHP3TCP.Address)
+	} else if report.RHP3WS.Address != host.RHPv3WSAddr() {
+		t.Fatalf("expected rhp3 websocket address %v, got %v", host.RHPv3WSAddr(), report.RHP3WS.Address)
+	}
+
+	// a tester with the wrong host key should fail the handshakes
+	tester, err = selftest.New(types.GeneratePrivateKey().PublicKey(), host.RHPv3WSAddr(), host.Settings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	report = tester.Run(context.Background())
+	if report.RHP2.Error == "" {
+		t.Fatal("expected rhp2 handshake to fail")
+	} else if !strings.Contains(report.RHP3TCP.Error, "RHP3 port unknown") {
+		t.Fatalf("expected rhp3 port to be unknown, got %q", report.RHP3TCP.Error)
+	} else if report.RHP3WS.Error == "" {
+		t.Fatal("expected rhp3 websocket handshake to fail")
+	}
+}
@@ -0,0 +1,416 @@
+package settings
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.sia.tech/hostd/internal/ddns"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/acme"
+)
+
+// defines ACME challenge types
+const (
+	ACMEChallengeHTTP01    = "http-01"
+	ACMEChallengeTLSALPN01 = "tls-alpn-01"
+	ACMEChallengeDNS01     = "dns-01"
+)
+
+const (
+	// acmeRenewBefore is the time before the ACME certificate expires that
+	// it is renewed.
+	acmeRenewBefore = 30 * 24 * time.Hour
+	// acmeTimeout is the maximum time to obtain a certificate.
+	acmeTimeout = 5 * time.Minute
+	// txtPropagationInterval is the interval at which the DNS-01 challenge
+	// record is checked for propagation.
+	txtPropagationInterval = 10 * time.Second
+)
+
+// ACMESettings contains the settings for obtaining the RHP3 WebSocket
+// certificate from an ACME certificate authority, such as Let's Encrypt. The
+// certificate is issued for the hostname of the host's net address.
+type ACMESettings struct {
+	Enabled bool   `json:"enabled"`
+	Email   string `json:"email"`
+	// DirectoryURL is the URL of the ACME directory. If empty, Let's
+	// Encrypt is used.
+	DirectoryURL string `json:"directoryURL"`
+	// Challenge is the type of challenge used to prove control of the
+	// hostname. HTTP-01 challenges are served on HTTPAddress. TLS-ALPN-01
+	// challenges are served by the RHP3 WebSocket listener, which must be
+	// reachable on port 443. DNS-01 challenges create a TXT record using the
+	// host's dynamic DNS provider and do not require any open ports.
+	Challenge   string `json:"challenge"`
+	HTTPAddress string `json:"httpAddress"`
+}
+
+// validateACMESettings validates the ACME settings and sets the defaults.
+func validateACMESettings(s *ACMESettings, dns DNSSettings) error {
+	if !s.Enabled {
+		return nil
+	}
+	if len(s.DirectoryURL) == 0 {
+		s.DirectoryURL = acme.LetsEncryptURL
+	}
+
+	switch s.Challenge {
+	case ACMEChallengeHTTP01:
+		if len(s.HTTPAddress) == 0 {
+			s.HTTPAddress = ":80"
+		} else if _, _, err := net.SplitHostPort(s.HTTPAddress); err != nil {
+			return fmt.Errorf("invalid http address: %w", err)
+		}
+	case ACMEChallengeTLSALPN01:
+	case ACMEChallengeDNS01:
+		if len(dns.Provider) == 0 {
+			return errors.New("dns-01 challenges require a dynamic DNS provider")
+		}
+		provider, err := newDNSProvider(dns, "")
+		if err != nil {
+			return err
+		} else if _, ok := provider.(ddns.TXTProvider); !ok {
+			return fmt.Errorf("dns provider %q does not support TXT records", dns.Provider)
+		}
+	default:
+		return fmt.Errorf("unknown challenge type: %q", s.Challenge)
+	}
+	return nil
+}
+
+// loadOrCreateKey loads an ECDSA private key from path. If the key does not
+// exist, a new key is generated and saved.
+func loadOrCreateKey(path string) (*ecdsa.PrivateKey, error) {
+	buf, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate key: %w", err)
+		} else if err := writeKey(path, key); err != nil {
+			return nil, err
+		}
+		return key, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+
+	block, _ := pem.Decode(buf)
+	if block == nil {
+		return nil, errors.New("failed to decode key")
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
+// encodeKey PEM encodes an ECDSA private key.
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+// writeKey writes an ECDSA private key to path.
+func writeKey(path string, key *ecdsa.PrivateKey) error {
+	buf, err := encodeKey(key)
+	if err != nil {
+		return err
+	} else if err := writeFilesAtomic(atomicFile{path, buf}); err != nil {
+		return fmt.Errorf("failed to write key: %w", err)
+	}
+	return nil
+}
+
+// An atomicFile is a file written by writeFilesAtomic.
+type atomicFile struct {
+	path string
+	data []byte
+}
+
+// writeFilesAtomic writes each file to a temporary file in the same directory
+// and syncs it. The temporary files are only renamed into place after all of
+// them have been written, so a failed write never replaces an existing file
+// and readers never see a partially written file.
+func writeFilesAtomic(files ...atomicFile) error {
+	tmpPaths := make([]string, 0, len(files))
+	defer func() {
+		// remove any temporary files that were not renamed
+		for _, p := range tmpPaths {
+			if p != "" {
+				os.Remove(p)
+			}
+		}
+	}()
+
+	for _, file := range files {
+		f, err := os.CreateTemp(filepath.Dir(file.path), filepath.Base(file.path)+".*.tmp")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary file: %w", err)
+		}
+		tmpPaths = append(tmpPaths, f.Name())
+		if _, err := f.Write(file.data); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write %q: %w", file.path, err)
+		} else if err := f.Sync(); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to sync %q: %w", file.path, err)
+		} else if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to close %q: %w", file.path, err)
+		}
+	}
+
+	for i, file := range files {
+		if err := os.Rename(tmpPaths[i], file.path); err != nil {
+			return fmt.Errorf("failed to rename %q: %w", file.path, err)
+		}
+		tmpPaths[i] = ""
+	}
+	return nil
+}
+
+// acmeHostname returns the hostname the ACME certificate is issued for.
+func acmeHostname(netaddress string) (string, error) {
+	hostname, _, err := net.SplitHostPort(netaddress)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse net address: %w", err)
+	} else if net.ParseIP(hostname) != nil {
+		return "", fmt.Errorf("net address %q must be a hostname", netaddress)
+	}
+	return hostname, nil
+}
+
+// waitForTXT waits until the TXT record for name resolves to value.
+func waitForTXT(ctx context.Context, name, value string) error {
+	t := time.NewTicker(txtPropagationInterval)
+	defer t.Stop()
+	for {
+		records, _ := net.DefaultResolver.LookupTXT(ctx, name)
+		for _, r := range records {
+			if r == value {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("challenge record %q was not propagated: %w", name, ctx.Err())
+		case <-t.C:
+		}
+	}
+}
+
+// renewACMECertificate obtains a new certificate if ACME is enabled and the
+// current certificate is missing, expires soon, or was issued for a different
+// hostname.
+func (m *ConfigManager) renewACMECertificate(ctx context.Context) error {
+	m.mu.Lock()
+	settings := m.settings
+	m.mu.Unlock()
+	if !settings.ACME.Enabled {
+		return nil
+	}
+
+	hostname, err := acmeHostname(settings.NetAddress)
+	if err != nil {
+		return err
+	}
+
+	certPath := filepath.Join(m.dir, "certs")
+	if cert, err := tls.LoadX509KeyPair(filepath.Join(certPath, "acme.crt"), filepath.Join(certPath, "acme.key")); err == nil {
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err == nil && leaf.VerifyHostname(hostname) == nil && time.Until(leaf.NotAfter) > acmeRenewBefore {
+			return nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, acmeTimeout)
+	defer cancel()
+	if err := m.obtainACMECertificate(ctx, settings, hostname); err != nil {
+		return err
+	}
+	m.log.Info("obtained ACME certificate", zap.String("hostname", hostname), zap.String("directory", settings.ACME.DirectoryURL))
+	return nil
+}
+
+// completeChallenge presents the challenge response and waits for the ACME
+// server to validate it.
+func (m *ConfigManager) completeChallenge(ctx context.Context, client *acme.Client, settings Settings, hostname string, authzURL string) error {
+	s := settings.ACME
+	authz, err := client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return fmt.Errorf("failed to get authorization: %w", err)
+	} else if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var chal *acme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == s.Challenge {
+			chal = c
+			break
+		}
+	}
+	if chal == nil {
+		return fmt.Errorf("challenge type %q not offered", s.Challenge)
+	}
+
+	switch s.Challenge {
+	case ACMEChallengeHTTP01:
+		resp, err := client.HTTP01ChallengeResponse(chal.Token)
+		if err != nil {
+			return fmt.Errorf("failed to create challenge response: %w", err)
+		}
+		l, err := net.Listen("tcp", s.HTTPAddress)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %q: %w", s.HTTPAddress, err)
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc(client.HTTP01ChallengePath(chal.Token), func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(resp))
+		})
+		srv := &http.Server{Handler: mux, ReadTimeout: 10 * time.Second}
+		go srv.Serve(l)
+		defer srv.Close()
+	case ACMEChallengeTLSALPN01:
+		cert, err := client.TLSALPN01ChallengeCert(chal.Token, hostname)
+		if err != nil {
+			return fmt.Errorf("failed to create challenge certificate: %w", err)
+		}
+		m.mu.Lock()
+		m.acmeChallengeCert = &cert
+		m.mu.Unlock()
+		defer func() {
+			m.mu.Lock()
+			m.acmeChallengeCert = nil
+			m.mu.Unlock()
+		}()
+	case ACMEChallengeDNS01:
+		value, err := client.DNS01ChallengeRecord(chal.Token)
+		if err != nil {
+			return fmt.Errorf("failed to create challenge record: %w", err)
+		}
+		provider, err := newDNSProvider(settings.DDNS, hostname)
+		if err != nil {
+			return fmt.Errorf("failed to create dns provider: %w", err)
+		}
+		txt, ok := provider.(ddns.TXTProvider)
+		if !ok {
+			return fmt.Errorf("dns provider %q does not support TXT records", settings.DDNS.Provider)
+		}
+		name := "_acme-challenge." + hostname
+		if err := txt.SetTXT(ctx, name, value); err != nil {
+			return fmt.Errorf("failed to set challenge record: %w", err)
+		}
+		defer func() {
+			// use a new context so the record is removed even if the
+			// challenge timed out
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			if err := txt.DeleteTXT(ctx, name, value); err != nil {
+				m.log.Warn("failed to remove challenge record", zap.String("name", name), zap.Error(err))
+			}
+		}()
+		if err := waitForTXT(ctx, name, value); err != nil {
+			return err
+		}
+	}
+
+	if _, err := client.Accept(ctx, chal); err != nil {
+		return fmt.Errorf("failed to accept challenge: %w", err)
+	} else if _, err := client.WaitAuthorization(ctx, authzURL); err != nil {
+		return fmt.Errorf("failed to validate challenge: %w", err)
+	}
+	return nil
+}
+
+// obtainACMECertificate obtains a certificate for hostname from the ACME
+// server and saves it.
+func (m *ConfigManager) obtainACMECertificate(ctx context.Context, settings Settings, hostname string) error {
+	s := settings.ACME
+	certPath := filepath.Join(m.dir, "certs")
+	if err := os.MkdirAll(certPath, 0700); err != nil {
+		return fmt.Errorf("failed to create certs directory: %w", err)
+	}
+
+	accountKey, err := loadOrCreateKey(filepath.Join(certPath, "acme_account.key"))
+	if err != nil {
+		return fmt.Errorf("failed to load ACME account key: %w", err)
+	}
+	client := &acme.Client{
+		Key:          accountKey,
+		DirectoryURL: s.DirectoryURL,
+		UserAgent:    "hostd",
+	}
+
+	var contact []string
+	if len(s.Email) != 0 {
+		contact = append(contact, "mailto:"+s.Email)
+	}
+	if _, err := client.Register(ctx, &acme.Account{Contact: contact}, acme.AcceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return fmt.Errorf("failed to register ACME account: %w", err)
+	}
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(hostname))
+	if err != nil {
+		return fmt.Errorf("failed to create order: %w", err)
+	}
+	for _, authzURL := range order.AuthzURLs {
+		if err := m.completeChallenge(ctx, client, settings, hostname, authzURL); err != nil {
+			return err
+		}
+	}
+	order, err = client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return fmt.Errorf("failed to wait for order: %w", err)
+	}
+
+	certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate certificate key: %w", err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: hostname},
+		DNSNames: []string{hostname},
+	}, crypto.Signer(certKey))
+	if err != nil {
+		return fmt.Errorf("failed to create certificate request: %w", err)
+	}
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return fmt.Errorf("failed to finalize order: %w", err)
+	}
+
+	var certBuf []byte
+	for _, der := range chain {
+		certBuf = append(certBuf, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	keyBuf, err := encodeKey(certKey)
+	if err != nil {
+		return err
+	}
+	// the key is renamed into place first. A certificate reloaded between
+	// the two renames does not match its key and is not loaded, the current
+	// certificate is kept until the next reload.
+	err = writeFilesAtomic(
+		atomicFile{filepath.Join(certPath, "acme.key"), keyBuf},
+		atomicFile{filepath.Join(certPath, "acme.crt"), certBuf},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write certificate: %w", err)
+	}
+	return nil
+}
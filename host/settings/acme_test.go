@@ -0,0 +1,581 @@
+package settings_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/alerts"
+	"go.sia.tech/hostd/host/settings"
+	"go.sia.tech/hostd/internal/test"
+	"go.sia.tech/hostd/persist/sqlite"
+	"go.uber.org/zap/zaptest"
+	"lukechampine.com/frand"
+)
+
+type (
+	// acmeOrder is an order placed with the test ACME server. Each order has
+	// a single authorization with a single HTTP-01 challenge.
+	acmeOrder struct {
+		domain     string
+		token      string
+		thumbprint string
+		authzValid bool
+		cert       []byte
+	}
+
+	// acmeServer is a minimal in-process ACME server implementing the parts
+	// of RFC 8555 used by the config manager. Request signatures are not
+	// verified. HTTP-01 challenges are validated by requesting the key
+	// authorization from the address the domain is resolved to.
+	acmeServer struct {
+		srv  *httptest.Server
+		key  *ecdsa.PrivateKey
+		root *x509.Certificate
+
+		mu       sync.Mutex
+		validity time.Duration
+		resolve  map[string]string
+		accounts map[string]string // kid -> thumbprint
+		orders   []*acmeOrder
+		issued   int
+	}
+)
+
+func newACMEServer(t *testing.T, validity time.Duration) *acmeServer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), frand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "hostd test ACME root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(frand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	as := &acmeServer{
+		key:      key,
+		root:     root,
+		validity: validity,
+		resolve:  make(map[string]string),
+		accounts: make(map[string]string),
+	}
+	as.srv = httptest.NewServer(http.HandlerFunc(as.handle))
+	t.Cleanup(as.srv.Close)
+	return as
+}
+
+// DirectoryURL returns the URL of the server's ACME directory.
+func (as *acmeServer) DirectoryURL() string {
+	return as.srv.URL + "/directory"
+}
+
+// Resolve sets the address HTTP-01 challenges for domain are validated
+// against.
+func (as *acmeServer) Resolve(domain, addr string) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.resolve[domain] = addr
+}
+
+// SetValidity sets the validity of newly issued certificates.
+func (as *acmeServer) SetValidity(d time.Duration) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.validity = d
+}
+
+// Issued returns the number of certificates issued by the server.
+func (as *acmeServer) Issued() int {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	return as.issued
+}
+
+// Roots returns a pool containing the server's root certificate.
+func (as *acmeServer) Roots() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(as.root)
+	return pool
+}
+
+func (as *acmeServer) url(format string, args ...any) string {
+	return as.srv.URL + fmt.Sprintf(format, args...)
+}
+
+func (as *acmeServer) problem(w http.ResponseWriter, code int, format string, args ...any) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]any{
+		"type":   "urn:ietf:params:acme:error:malformed",
+		"detail": fmt.Sprintf(format, args...),
+	})
+}
+
+// jwkThumbprint returns the RFC 7638 thumbprint of an EC JSON web key.
+func jwkThumbprint(jwk json.RawMessage) (string, error) {
+	var key struct {
+		Crv string `json:"crv"`
+		Kty string `json:"kty"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}
+	if err := json.Unmarshal(jwk, &key); err != nil {
+		return "", err
+	} else if key.Kty != "EC" {
+		return "", fmt.Errorf("unsupported key type %q", key.Kty)
+	}
+	h := sha256.Sum256([]byte(fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q,"y":%q}`, key.Crv, key.Kty, key.X, key.Y)))
+	return base64.RawURLEncoding.EncodeToString(h[:]), nil
+}
+
+// decodeJWS decodes the protected header and payload of a flattened JWS
+func decodeJWS(r io.Reader) (kid string, jwk json.RawMessage, payload []byte, err error) {
+	var req struct {
+		Protected string `json:"protected"`
+		Payload   string `json:"payload"`
+	}
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return "", nil, nil, fmt.Errorf("failed to decode jws: %w", err)
+	}
+	buf, err := base64.RawURLEncoding.DecodeString(req.Protected)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to decode protected header: %w", err)
+	}
+	var header struct {
+		KID string          `json:"kid"`
+		JWK json.RawMessage `json:"jwk"`
+	}
+	if err := json.Unmarshal(buf, &header); err != nil {
+		return "", nil, nil, fmt.Errorf("failed to decode protected header: %w", err)
+	}
+	payload, err = base64.RawURLEncoding.DecodeString(req.Payload)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to decode payload: %w", err)
+	}
+	return header.KID, header.JWK, payload, nil
+}
+
+func (as *acmeServer) orderJSON(i int, o *acmeOrder) map[string]any {
+	status := "pending"
+	if o.cert != nil {
+		status = "valid"
+	} else if o.authzValid {
+		status = "ready"
+	}
+	v := map[string]any{
+		"status":         status,
+		"identifiers":    []map[string]string{{"type": "dns", "value": o.domain}},
+		"authorizations": []string{as.url("/authz/%d", i)},
+		"finalize":       as.url("/finalize/%d", i),
+	}
+	if o.cert != nil {
+		v["certificate"] = as.url("/cert/%d", i)
+	}
+	return v
+}
+
+func (as *acmeServer) authzJSON(i int, o *acmeOrder) map[string]any {
+	status := "pending"
+	if o.authzValid {
+		status = "valid"
+	}
+	return map[string]any{
+		"status":     status,
+		"identifier": map[string]string{"type": "dns", "value": o.domain},
+		"challenges": []map[string]string{{
+			"type":   settings.ACMEChallengeHTTP01,
+			"url":    as.url("/challenge/%d", i),
+			"token":  o.token,
+			"status": status,
+		}},
+	}
+}
+
+// validateChallenge requests the key authorization of an order's challenge
+// from the domain's resolved address.
+func (as *acmeServer) validateChallenge(addr string, o *acmeOrder) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://%s/.well-known/acme-challenge/%s", addr, o.token))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	} else if expected := o.token + "." + o.thumbprint; strings.TrimSpace(string(buf)) != expected {
+		return fmt.Errorf("expected key authorization %q, got %q", expected, buf)
+	}
+	return nil
+}
+
+// issue signs a certificate for the CSR of an order.
+func (as *acmeServer) issue(o *acmeOrder, csrDER []byte) error {
+	csr, err := x509.ParseCertificateRequest(csrDER)
+	if err != nil {
+		return fmt.Errorf("failed to parse csr: %w", err)
+	} else if len(csr.DNSNames) != 1 || csr.DNSNames[0] != o.domain {
+		return fmt.Errorf("csr names %v do not match order domain %q", csr.DNSNames, o.domain)
+	}
+
+	as.issued++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(int64(as.issued + 1)),
+		Subject:      pkix.Name{CommonName: o.domain},
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(as.validity),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(frand.Reader, template, as.root, csr.PublicKey, as.key)
+	if err != nil {
+		return fmt.Errorf("failed to create certificate: %w", err)
+	}
+	o.cert = append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: as.root.Raw})...)
+	return nil
+}
+
+func (as *acmeServer) handle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Replay-Nonce", hex(frand.Bytes(16)))
+	w.Header().Set("Cache-Control", "no-store")
+
+	if r.URL.Path == "/directory" {
+		json.NewEncoder(w).Encode(map[string]string{
+			"newNonce":   as.url("/new-nonce"),
+			"newAccount": as.url("/new-account"),
+			"newOrder":   as.url("/new-order"),
+			"revokeCert": as.url("/revoke-cert"),
+			"keyChange":  as.url("/key-change"),
+		})
+		return
+	} else if r.URL.Path == "/new-nonce" {
+		w.WriteHeader(http.StatusOK)
+		return
+	} else if r.Method != http.MethodPost {
+		as.problem(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	kid, jwk, payload, err := decodeJWS(r.Body)
+	if err != nil {
+		as.problem(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if r.URL.Path == "/new-account" {
+		thumbprint, err := jwkThumbprint(jwk)
+		if err != nil {
+			as.problem(w, http.StatusBadRequest, "%v", err)
+			return
+		}
+		status := http.StatusCreated
+		kid := as.url("/account/%s", thumbprint)
+		if _, ok := as.accounts[kid]; ok {
+			status = http.StatusOK
+		}
+		as.accounts[kid] = thumbprint
+		w.Header().Set("Location", kid)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]any{"status": "valid"})
+		return
+	}
+
+	thumbprint, ok := as.accounts[kid]
+	if !ok {
+		as.problem(w, http.StatusUnauthorized, "unknown account %q", kid)
+		return
+	}
+
+	if r.URL.Path == "/new-order" {
+		var req struct {
+			Identifiers []struct {
+				Type  string `json:"type"`
+				Value string `json:"value"`
+			} `json:"identifiers"`
+		}
+		if err := json.Unmarshal(payload, &req); err != nil {
+			as.problem(w, http.StatusBadRequest, "failed to decode order: %v", err)
+			return
+		} else if len(req.Identifiers) != 1 || req.Identifiers[0].Type != "dns" {
+			as.problem(w, http.StatusBadRequest, "expected a single dns identifier")
+			return
+		}
+		o := &acmeOrder{
+			domain:     req.Identifiers[0].Value,
+			token:      hex(frand.Bytes(16)),
+			thumbprint: thumbprint,
+		}
+		as.orders = append(as.orders, o)
+		i := len(as.orders) - 1
+		w.Header().Set("Location", as.url("/order/%d", i))
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(as.orderJSON(i, o))
+		return
+	}
+
+	// the remaining resources belong to an order
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 2 {
+		as.problem(w, http.StatusNotFound, "not found")
+		return
+	}
+	i, err := strconv.Atoi(parts[1])
+	if err != nil || i < 0 || i >= len(as.orders) {
+		as.problem(w, http.StatusNotFound, "order not found")
+		return
+	}
+	o := as.orders[i]
+	if o.thumbprint != thumbprint {
+		as.problem(w, http.StatusUnauthorized, "order belongs to a different account")
+		return
+	}
+
+	switch parts[0] {
+	case "order":
+		w.Header().Set("Location", as.url("/order/%d", i))
+		json.NewEncoder(w).Encode(as.orderJSON(i, o))
+	case "authz":
+		json.NewEncoder(w).Encode(as.authzJSON(i, o))
+	case "challenge":
+		addr, ok := as.resolve[o.domain]
+		if !ok {
+			as.problem(w, http.StatusBadRequest, "domain %q cannot be resolved", o.domain)
+			return
+		} else if err := as.validateChallenge(addr, o); err != nil {
+			as.problem(w, http.StatusForbidden, "challenge failed: %v", err)
+			return
+		}
+		o.authzValid = true
+		json.NewEncoder(w).Encode(as.authzJSON(i, o)["challenges"].([]map[string]string)[0])
+	case "finalize":
+		var req struct {
+			CSR string `json:"csr"`
+		}
+		if !o.authzValid {
+			as.problem(w, http.StatusForbidden, "order is not ready")
+			return
+		} else if err := json.Unmarshal(payload, &req); err != nil {
+			as.problem(w, http.StatusBadRequest, "failed to decode finalize request: %v", err)
+			return
+		}
+		csr, err := base64.RawURLEncoding.DecodeString(req.CSR)
+		if err != nil {
+			as.problem(w, http.StatusBadRequest, "failed to decode csr: %v", err)
+			return
+		} else if err := as.issue(o, csr); err != nil {
+			as.problem(w, http.StatusBadRequest, "%v", err)
+			return
+		}
+		w.Header().Set("Location", as.url("/order/%d", i))
+		json.NewEncoder(w).Encode(as.orderJSON(i, o))
+	case "cert":
+		if o.cert == nil {
+			as.problem(w, http.StatusNotFound, "certificate not issued")
+			return
+		}
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		w.Write(o.cert)
+	default:
+		as.problem(w, http.StatusNotFound, "not found")
+	}
+}
+
+func hex(b []byte) string {
+	return fmt.Sprintf("%x", b)
+}
+
+// freeAddr returns a local address that is not in use
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestACMECertificate(t *testing.T) {
+	hostKey := types.NewPrivateKeyFromSeed(frand.Bytes(32))
+	dir := t.TempDir()
+	log := zaptest.NewLogger(t)
+	node, err := test.NewWallet(hostKey, dir, log.Named("wallet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+
+	db, err := sqlite.OpenDatabase(filepath.Join(dir, "hostd.db"), log.Named("sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	am := alerts.NewManager()
+	manager, err := settings.NewConfigManager(dir, hostKey, "localhost:9882", db, am, node.ChainManager(), node.TPool(), node, node.Gateway(), log.Named("settings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	// serve the RHP3 WebSocket TLS config for the whole test to check that
+	// certificates are swapped without restarting the listener
+	l, err := tls.Listen("tcp", "127.0.0.1:0", manager.RHP3TLSConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+
+	// certificates issued by the test server expire within the renewal
+	// window
+	ca := newACMEServer(t, 10*24*time.Hour)
+	httpAddr := freeAddr(t)
+	ca.Resolve("localhost", httpAddr)
+
+	// peerCertificate returns the certificate presented by the listener.
+	// Only certificates issued by the test server are trusted.
+	peerCertificate := func() (*x509.Certificate, error) {
+		conn, err := tls.Dial("tcp", l.Addr().String(), &tls.Config{
+			ServerName: "localhost",
+			RootCAs:    ca.Roots(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0], nil
+	}
+	waitForCertificate := func(prev *x509.Certificate) *x509.Certificate {
+		t.Helper()
+		var lastErr error
+		for i := 0; i < 100; i++ {
+			cert, err := peerCertificate()
+			if err == nil && (prev == nil || cert.SerialNumber.Cmp(prev.SerialNumber) != 0) {
+				return cert
+			} else if err != nil {
+				lastErr = err
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		t.Fatalf("timed out waiting for ACME certificate: %v", lastErr)
+		return nil
+	}
+
+	// the temporary certificate is not trusted
+	if _, err := peerCertificate(); err == nil {
+		t.Fatal("expected temporary certificate to be untrusted")
+	}
+
+	// enable ACME
+	updated := manager.Settings()
+	updated.NetAddress = "localhost:9882"
+	updated.ACME = settings.ACMESettings{
+		Enabled:      true,
+		Email:        "host@example.com",
+		DirectoryURL: ca.DirectoryURL(),
+		Challenge:    settings.ACMEChallengeHTTP01,
+		HTTPAddress:  httpAddr,
+	}
+	if err := manager.UpdateSettings(updated); err != nil {
+		t.Fatal(err)
+	}
+	issued := waitForCertificate(nil)
+	if err := issued.VerifyHostname("localhost"); err != nil {
+		t.Fatal(err)
+	} else if n := ca.Issued(); n != 1 {
+		t.Fatalf("expected 1 issued certificate, got %v", n)
+	}
+
+	// the key and certificate should be written without leaving temporary
+	// files behind
+	certPath := filepath.Join(dir, "certs")
+	if _, err := tls.LoadX509KeyPair(filepath.Join(certPath, "acme.crt"), filepath.Join(certPath, "acme.key")); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			t.Fatalf("unexpected temporary file %q", entry.Name())
+		}
+	}
+
+	// the certificate expires within the renewal window. Any change to the
+	// ACME settings triggers a check, which should renew the certificate
+	// and swap it on the running listener.
+	ca.SetValidity(90 * 24 * time.Hour)
+	updated.ACME.Email = "renew@example.com"
+	if err := manager.UpdateSettings(updated); err != nil {
+		t.Fatal(err)
+	}
+	renewed := waitForCertificate(issued)
+	if !renewed.NotAfter.After(issued.NotAfter) {
+		t.Fatalf("expected renewed certificate to expire after %v, got %v", issued.NotAfter, renewed.NotAfter)
+	} else if n := ca.Issued(); n != 2 {
+		t.Fatalf("expected 2 issued certificates, got %v", n)
+	}
+
+	// the renewed certificate is not due for renewal
+	updated.ACME.Email = "host@example.com"
+	if err := manager.UpdateSettings(updated); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if n := ca.Issued(); n != 2 {
+		t.Fatalf("expected certificate not to be renewed, got %v issued", n)
+	} else if cert, err := peerCertificate(); err != nil {
+		t.Fatal(err)
+	} else if cert.SerialNumber.Cmp(renewed.SerialNumber) != 0 {
+		t.Fatal("expected renewed certificate to be kept")
+	}
+}
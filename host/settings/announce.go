@@ -0,0 +1,242 @@
+package settings
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/alerts"
+	"go.sia.tech/siad/modules"
+	stypes "go.sia.tech/siad/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// announceCheckInterval is the interval at which the host checks if it
+	// needs to re-announce.
+	announceCheckInterval = 10 * time.Minute
+	// announceCooldown is the minimum number of blocks between automatic
+	// announcements.
+	announceCooldown = 6
+)
+
+// An Announcement is a host announcement broadcast by the host.
+type Announcement struct {
+	TransactionID types.TransactionID `json:"transactionID"`
+	Address       string              `json:"address"`
+	// Height is the height of the chain when the announcement was
+	// broadcast.
+	Height uint64 `json:"height"`
+	// ConfirmedHeight is the height of the block containing the
+	// announcement. It is zero if the announcement has not been confirmed.
+	ConfirmedHeight uint64    `json:"confirmedHeight"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// LastAnnouncement returns the host's last broadcast announcement. If the host
+// has not announced, an empty announcement is returned.
+func (m *ConfigManager) LastAnnouncement() (Announcement, error) {
+	return m.store.LastAnnouncement()
+}
+
+// requestAnnounce marks the host as needing to re-announce and triggers an
+// automatic announcement. The caller must not hold the mutex.
+func (m *ConfigManager) requestAnnounce(reason string) {
+	m.mu.Lock()
+	m.announceReason = reason
+	m.mu.Unlock()
+	go m.autoAnnounce()
+}
+
+// autoAnnounce re-announces the host if its address changed, a re-announcement
+// was requested, or the last announcement is older than the announce interval.
+// Hosts that have never announced are not announced automatically.
+func (m *ConfigManager) autoAnnounce() {
+	done, err := m.tg.Add()
+	if err != nil {
+		return
+	}
+	defer done()
+
+	// only one automatic announcement can be in progress at a time
+	m.announceMu.Lock()
+	defer m.announceMu.Unlock()
+
+	m.mu.Lock()
+	settings := m.effectiveSettings()
+	reason := m.announceReason
+	lastAttempt := m.lastAnnounceAttempt
+	m.mu.Unlock()
+
+	if settings.AnnounceInterval == 0 {
+		return
+	}
+
+	last, err := m.store.LastAnnouncement()
+	if err != nil {
+		m.log.Error("failed to get last announcement", zap.Error(err))
+		return
+	} else if last.TransactionID == (types.TransactionID{}) {
+		return
+	}
+
+	address := settings.NetAddress
+	if len(address) == 0 {
+		address = m.discoveredRHPAddr
+	}
+	height := m.cm.TipState().Index.Height
+	reason = announceReason(reason, last, address, settings.AnnounceInterval, height)
+	if len(reason) == 0 {
+		return
+	} else if inAnnounceCooldown(height, last.Height, lastAttempt) {
+		m.log.Debug("skipping announcement during cooldown", zap.String("reason", reason), zap.Uint64("height", height), zap.Uint64("lastAnnouncement", last.Height))
+		return
+	}
+
+	m.mu.Lock()
+	m.lastAnnounceAttempt = height
+	m.mu.Unlock()
+	if err := m.Announce(); err != nil {
+		m.log.Error("failed to re-announce host", zap.String("reason", reason), zap.Error(err))
+		return
+	}
+	m.mu.Lock()
+	m.announceReason = ""
+	m.mu.Unlock()
+	m.log.Info("re-announced host", zap.String("reason", reason), zap.String("address", address))
+}
+
+// announceReason returns the reason the host should automatically
+// re-announce. An empty string is returned if no announcement is needed.
+func announceReason(requested string, last Announcement, address string, interval, height uint64) string {
+	switch {
+	case len(requested) != 0:
+		return requested
+	case last.Address != address:
+		return "address changed"
+	case height >= last.Height+interval:
+		return "announcement expired"
+	default:
+		return ""
+	}
+}
+
+// inAnnounceCooldown returns true if an automatic announcement at height would
+// be too close to the last announcement or the last attempt. Failed attempts
+// count towards the cooldown to limit the frequency of retries.
+func inAnnounceCooldown(height, lastAnnouncement, lastAttempt uint64) bool {
+	return height < lastAnnouncement+announceCooldown || (lastAttempt != 0 && height < lastAttempt+announceCooldown)
+}
+
+func (m *ConfigManager) checkAnnouncement() {
+	m.autoAnnounce()
+	m.announceTimer.Reset(announceCheckInterval)
+}
+
+// recordAnnouncement persists a broadcast announcement.
+func (m *ConfigManager) recordAnnouncement(txn types.Transaction, address string) error {
+	ann := Announcement{
+		TransactionID: txn.ID(),
+		Address:       address,
+		Height:        m.cm.TipState().Index.Height,
+		Timestamp:     time.Now(),
+	}
+	if err := m.store.AddAnnouncement(ann); err != nil {
+		return fmt.Errorf("failed to record announcement: %w", err)
+	}
+	return nil
+}
+
+// ProcessConsensusChange records the confirmation of the host's announcements
+// and registers an alert if the last announcement has not been confirmed in
+// time.
+func (m *ConfigManager) ProcessConsensusChange(cc modules.ConsensusChange) {
+	done, err := m.tg.Add()
+	if err != nil {
+		return
+	}
+	defer done()
+
+	// parses the announcements in the transaction signed by the host's key
+	hostKey := m.hostKey.PublicKey()
+	ownAnnouncements := func(txn stypes.Transaction) (addresses []string) {
+		for _, arb := range txn.ArbitraryData {
+			addr, pk, err := modules.DecodeAnnouncement(arb)
+			if err != nil || !bytes.Equal(pk.Key, hostKey[:]) {
+				continue
+			}
+			addresses = append(addresses, string(addr))
+		}
+		return
+	}
+
+	var reverted []types.TransactionID
+	for _, block := range cc.RevertedBlocks {
+		for _, txn := range block.Transactions {
+			if len(ownAnnouncements(txn)) != 0 {
+				reverted = append(reverted, types.TransactionID(txn.ID()))
+			}
+		}
+	}
+
+	var confirmed []Announcement
+	// calculate the block height of the first applied block
+	blockHeight := uint64(cc.BlockHeight) - uint64(len(cc.AppliedBlocks)) + 1
+	for _, block := range cc.AppliedBlocks {
+		for _, txn := range block.Transactions {
+			for _, addr := range ownAnnouncements(txn) {
+				confirmed = append(confirmed, Announcement{
+					TransactionID:   types.TransactionID(txn.ID()),
+					Address:         addr,
+					Height:          blockHeight,
+					ConfirmedHeight: blockHeight,
+					Timestamp:       time.Unix(int64(block.Timestamp), 0),
+				})
+			}
+		}
+		blockHeight++
+	}
+
+	if err := m.store.UpdateAnnouncements(cc.ID, reverted, confirmed); err != nil {
+		m.log.Error("failed to update announcements", zap.Error(err))
+		return
+	}
+	for _, txnID := range reverted {
+		m.log.Warn("announcement reverted", zap.Stringer("transactionID", txnID))
+	}
+	for _, ann := range confirmed {
+		m.a.Dismiss(types.Hash256(ann.TransactionID))
+		m.log.Debug("announcement confirmed", zap.Stringer("transactionID", ann.TransactionID), zap.String("address", ann.Address), zap.Uint64("height", ann.ConfirmedHeight))
+	}
+
+	if !cc.Synced {
+		return
+	}
+	m.mu.Lock()
+	window := m.settings.AnnounceConfirmationBlocks
+	m.mu.Unlock()
+	last, err := m.store.LastAnnouncement()
+	if err != nil {
+		m.log.Error("failed to get last announcement", zap.Error(err))
+		return
+	} else if window == 0 || last.TransactionID == (types.TransactionID{}) || last.ConfirmedHeight != 0 {
+		return
+	}
+	height := uint64(cc.BlockHeight)
+	if height < last.Height+window {
+		return
+	}
+	m.a.Register(alerts.Alert{
+		ID:       types.Hash256(last.TransactionID),
+		Severity: alerts.SeverityWarning,
+		Message:  "Host announcement has not been confirmed",
+		Data: map[string]any{
+			"transactionID": last.TransactionID,
+			"address":       last.Address,
+			"height":        last.Height,
+			"blocks":        height - last.Height,
+		},
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,46 @@
+package settings
+
+import "testing"
+
+func TestAnnounceReason(t *testing.T) {
+	last := Announcement{Address: "host.example:9982", Height: 100}
+	tests := []struct {
+		requested string
+		address   string
+		height    uint64
+		expected  string
+	}{
+		{"", last.Address, 150, ""},
+		{"", "other.example:9982", 101, "address changed"},
+		{"", last.Address, 200, "announcement expired"},
+		{"settings changed", last.Address, 101, "settings changed"},
+	}
+	for _, tt := range tests {
+		if reason := announceReason(tt.requested, last, tt.address, 100, tt.height); reason != tt.expected {
+			t.Fatalf("requested %q, address %q, height %v: expected reason %q, got %q", tt.requested, tt.address, tt.height, tt.expected, reason)
+		}
+	}
+}
+
+func TestAnnounceCooldown(t *testing.T) {
+	tests := []struct {
+		height, lastAnnouncement, lastAttempt uint64
+		cooldown                              bool
+	}{
+		{100, 100, 0, true},                                       // just announced
+		{100 + announceCooldown - 1, 100, 0, true},                // last block of the cooldown
+		{100 + announceCooldown, 100, 0, false},                   // cooldown over
+		{200, 100, 0, false},                                      // no previous attempt
+		{200, 100, 198, true},                                     // recent failed attempt
+		{200, 100, 200 - announceCooldown, false},                 // failed attempt outside the cooldown
+		{announceCooldown - 1, 0, 0, true},                        // announcement confirmed near genesis
+		{100 + announceCooldown, 100, 100 + 1, true},              // attempt after the announcement
+		{100 + 2*announceCooldown, 100, 100 + 1, false},           // both outside the cooldown
+		{100 + announceCooldown, 100 + announceCooldown, 0, true}, // announced at the current height
+	}
+	for _, tt := range tests {
+		if cooldown := inAnnounceCooldown(tt.height, tt.lastAnnouncement, tt.lastAttempt); cooldown != tt.cooldown {
+			t.Fatalf("height %v, last announcement %v, last attempt %v: expected cooldown %v, got %v", tt.height, tt.lastAnnouncement, tt.lastAttempt, tt.cooldown, cooldown)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package settings_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/alerts"
+	"go.sia.tech/hostd/host/settings"
+	"go.sia.tech/hostd/internal/test"
+	"go.sia.tech/hostd/persist/sqlite"
+	stypes "go.sia.tech/siad/types"
+	"go.uber.org/zap/zaptest"
+	"lukechampine.com/frand"
+)
+
+func TestAutoAnnounce(t *testing.T) {
+	hostKey := types.NewPrivateKeyFromSeed(frand.Bytes(32))
+	dir := t.TempDir()
+	log := zaptest.NewLogger(t)
+	node, err := test.NewWallet(hostKey, dir, log.Named("wallet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+
+	db, err := sqlite.OpenDatabase(filepath.Join(dir, "hostd.db"), log.Named("sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	manager, err := settings.NewConfigManager(dir, hostKey, "localhost:9882", db, alerts.NewManager(), node.ChainManager(), node.TPool(), node, node.Gateway(), log.Named("settings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	if err := node.MineBlocks(node.Address(), int(stypes.MaturityDelay+2)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond) // sync time
+
+	// waits for the last announcement to have the expected address
+	waitForAnnouncement := func(address string) settings.Announcement {
+		t.Helper()
+		for i := 0; i < 100; i++ {
+			ann, err := manager.LastAnnouncement()
+			if err != nil {
+				t.Fatal(err)
+			} else if ann.Address == address {
+				return ann
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		t.Fatalf("expected announcement for %q", address)
+		return settings.Announcement{}
+	}
+
+	// hosts that have never announced should not be announced automatically
+	updated := manager.Settings()
+	updated.NetAddress = "localhost:10082"
+	if err := manager.UpdateSettings(updated); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if ann, err := manager.LastAnnouncement(); err != nil {
+		t.Fatal(err)
+	} else if ann != (settings.Announcement{}) {
+		t.Fatalf("expected no announcement, got %v", ann)
+	}
+
+	if err := manager.Announce(); err != nil {
+		t.Fatal(err)
+	}
+	ann := waitForAnnouncement(updated.NetAddress)
+	if ann.Height != node.TipState().Index.Height {
+		t.Fatalf("expected announcement height %v, got %v", node.TipState().Index.Height, ann.Height)
+	}
+
+	// changing the address during the cooldown should not re-announce
+	updated.NetAddress = "localhost:10083"
+	if err := manager.UpdateSettings(updated); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if last, err := manager.LastAnnouncement(); err != nil {
+		t.Fatal(err)
+	} else if last != ann {
+		t.Fatalf("expected no new announcement, got %v", last)
+	}
+
+	// after the cooldown, changing the address should re-announce
+	if err := node.MineBlocks(node.Address(), 6); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond) // sync time
+	updated.NetAddress = "localhost:10084"
+	if err := manager.UpdateSettings(updated); err != nil {
+		t.Fatal(err)
+	}
+	if last := waitForAnnouncement(updated.NetAddress); last.TransactionID == ann.TransactionID {
+		t.Fatal("expected a new announcement")
+	}
+}
+
+func TestAnnouncementConfirmation(t *testing.T) {
+	hostKey := types.NewPrivateKeyFromSeed(frand.Bytes(32))
+	dir := t.TempDir()
+	log := zaptest.NewLogger(t)
+	node, err := test.NewWallet(hostKey, dir, log.Named("wallet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+
+	db, err := sqlite.OpenDatabase(filepath.Join(dir, "hostd.db"), log.Named("sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	am := alerts.NewManager()
+	manager, err := settings.NewConfigManager(dir, hostKey, "localhost:9882", db, am, node.ChainManager(), node.TPool(), node, node.Gateway(), log.Named("settings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	if err := node.MineBlocks(node.Address(), int(stypes.MaturityDelay+2)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond) // sync time
+
+	if err := manager.Announce(); err != nil {
+		t.Fatal(err)
+	} else if err := node.MineBlocks(node.Address(), 1); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond) // sync time
+
+	ann, err := manager.LastAnnouncement()
+	if err != nil {
+		t.Fatal(err)
+	} else if ann.ConfirmedHeight != node.TipState().Index.Height {
+		t.Fatalf("expected announcement to be confirmed at %v, got %v", node.TipState().Index.Height, ann.ConfirmedHeight)
+	} else if ann.Address != "localhost:9882" {
+		t.Fatalf("expected address localhost:9882, got %q", ann.Address)
+	}
+
+	// record an announcement that will never be confirmed
+	unconfirmed := settings.Announcement{
+		TransactionID: frand.Entropy256(),
+		Address:       "localhost:9882",
+		Height:        node.TipState().Index.Height,
+		Timestamp:     time.Now(),
+	}
+	if err := db.AddAnnouncement(unconfirmed); err != nil {
+		t.Fatal(err)
+	}
+
+	window := manager.Settings().AnnounceConfirmationBlocks
+	if err := node.MineBlocks(node.Address(), int(window)-1); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond) // sync time
+	if len(am.Active()) != 0 {
+		t.Fatal("expected no alerts before the confirmation window")
+	}
+
+	if err := node.MineBlocks(node.Address(), 1); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond) // sync time
+	active := am.Active()
+	if len(active) != 1 {
+		t.Fatalf("expected 1 alert, got %v", len(active))
+	} else if active[0].ID != types.Hash256(unconfirmed.TransactionID) {
+		t.Fatalf("expected alert for %v, got %v", unconfirmed.TransactionID, active[0].ID)
+	}
+}
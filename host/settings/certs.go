@@ -1,6 +1,8 @@
 package settings
 
 import (
+	"bytes"
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
@@ -13,19 +15,67 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/alerts"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/acme"
+)
+
+const (
+	// certCheckInterval is the interval at which the RHP3 WebSocket
+	// certificate is reloaded and checked for expiration.
+	certCheckInterval = 12 * time.Hour
+	// certExpiryAlertWindow is the time before a certificate expires that an
+	// alert is registered.
+	certExpiryAlertWindow = 14 * 24 * time.Hour
 )
 
+// certExpiryAlertID is the ID of the alert registered when the RHP3 WebSocket
+// certificate is about to expire.
+var certExpiryAlertID = types.HashBytes([]byte("rhp3CertificateExpiry"))
+
+// reloadCertificates loads the RHP3 WebSocket certificate. An ACME
+// certificate is preferred if ACME is enabled, followed by a certificate
+// provided by the host operator. If neither exist, a temporary self-signed
+// certificate is generated.
 func (m *ConfigManager) reloadCertificates() error {
 	certPath := filepath.Join(m.dir, "certs")
 
-	var certificate tls.Certificate
-	if _, err := os.Stat(filepath.Join(certPath, "rhp3.crt")); err == nil {
-		certificate, err = tls.LoadX509KeyPair(filepath.Join(certPath, "rhp3.crt"), filepath.Join(certPath, "rhp3.key"))
+	m.mu.Lock()
+	settings := m.settings
+	current, isTemp := m.rhp3Cert, m.rhp3CertTemp
+	m.mu.Unlock()
+
+	var paths [][2]string
+	if settings.ACME.Enabled {
+		paths = append(paths, [2]string{filepath.Join(certPath, "acme.crt"), filepath.Join(certPath, "acme.key")})
+	}
+	paths = append(paths, [2]string{filepath.Join(certPath, "rhp3.crt"), filepath.Join(certPath, "rhp3.key")})
+
+	var certificate *tls.Certificate
+	for _, p := range paths {
+		if _, err := os.Stat(p[0]); errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to check for certificate: %w", err)
+		}
+		cert, err := tls.LoadX509KeyPair(p[0], p[1])
 		if err != nil {
 			return fmt.Errorf("failed to load certificate: %w", err)
 		}
-	} else if errors.Is(err, os.ErrNotExist) {
-		addr := m.settings.NetAddress
+		certificate = &cert
+		break
+	}
+
+	temp := certificate == nil
+	if temp {
+		if current != nil && isTemp {
+			// keep the existing temporary certificate
+			return nil
+		}
+
+		addr := settings.NetAddress
 		if len(addr) == 0 {
 			addr = m.discoveredRHPAddr
 		}
@@ -34,19 +84,92 @@ func (m *ConfigManager) reloadCertificates() error {
 			return fmt.Errorf("failed to parse netaddress: %w", err)
 		}
 
-		certificate, err = tempCertificate(addr)
+		cert, err := tempCertificate(addr)
 		if err != nil {
 			return fmt.Errorf("failed to create temporary certificate: %w", err)
 		}
-	} else if err != nil {
-		return fmt.Errorf("failed to check for certificate: %w", err)
+		certificate = &cert
+	}
+
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse certificate: %w", err)
 	}
+	certificate.Leaf = leaf
 
-	m.rhp3WSTLS.Certificates = []tls.Certificate{certificate}
+	m.mu.Lock()
+	m.rhp3Cert, m.rhp3CertTemp = certificate, temp
+	m.mu.Unlock()
+
+	m.checkCertificateExpiry(leaf)
+	// re-announce if the certificate changed after it was first loaded
+	if current != nil && !bytes.Equal(current.Certificate[0], certificate.Certificate[0]) {
+		m.log.Info("rhp3 certificate changed", zap.Strings("names", leaf.DNSNames), zap.Time("expiration", leaf.NotAfter))
+		m.requestAnnounce("certificate changed")
+	}
 	return nil
 }
 
-// RHP3TLSConfig returns the TLS config for the rhp3 WebSocket listener
+// checkCertificateExpiry registers an alert if the certificate expires soon
+// and dismisses it otherwise.
+func (m *ConfigManager) checkCertificateExpiry(leaf *x509.Certificate) {
+	remaining := time.Until(leaf.NotAfter)
+	if remaining > certExpiryAlertWindow {
+		m.a.Dismiss(certExpiryAlertID)
+		return
+	}
+
+	severity, message := alerts.SeverityWarning, "RHP3 WebSocket certificate expires soon"
+	if remaining <= 0 {
+		severity, message = alerts.SeverityError, "RHP3 WebSocket certificate expired"
+	}
+	m.a.Register(alerts.Alert{
+		ID:       certExpiryAlertID,
+		Severity: severity,
+		Message:  message,
+		Data: map[string]any{
+			"names":      leaf.DNSNames,
+			"expiration": leaf.NotAfter,
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+// checkCertificates renews the ACME certificate if necessary and reloads the
+// RHP3 WebSocket certificate.
+func (m *ConfigManager) checkCertificates() {
+	ctx, cancel, err := m.tg.AddContext(context.Background())
+	if err != nil {
+		return
+	}
+	defer cancel()
+	defer m.certTimer.Reset(certCheckInterval)
+
+	if err := m.renewACMECertificate(ctx); err != nil {
+		m.log.Error("failed to renew ACME certificate", zap.Error(err))
+	}
+	if err := m.reloadCertificates(); err != nil {
+		m.log.Error("failed to reload rhp3 certificate", zap.Error(err))
+	}
+}
+
+// getCertificate returns the current RHP3 WebSocket certificate. If the
+// client is an ACME server validating a TLS-ALPN-01 challenge, the challenge
+// certificate is returned instead.
+func (m *ConfigManager) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(hello.SupportedProtos) == 1 && hello.SupportedProtos[0] == acme.ALPNProto {
+		if m.acmeChallengeCert == nil {
+			return nil, errors.New("no pending ACME challenge")
+		}
+		return m.acmeChallengeCert, nil
+	}
+	return m.rhp3Cert, nil
+}
+
+// RHP3TLSConfig returns the TLS config for the rhp3 WebSocket listener. The
+// certificate is swapped without restarting the listener when it changes.
 func (m *ConfigManager) RHP3TLSConfig() *tls.Config {
 	return m.rhp3WSTLS
 }
@@ -0,0 +1,137 @@
+package settings_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/alerts"
+	"go.sia.tech/hostd/host/settings"
+	"go.sia.tech/hostd/internal/test"
+	"go.sia.tech/hostd/persist/sqlite"
+	"go.uber.org/zap/zaptest"
+	"lukechampine.com/frand"
+)
+
+// writeCertificate writes a self-signed certificate for name that expires at
+// notAfter to dir.
+func writeCertificate(t *testing.T, dir, name string, notAfter time.Time) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), frand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(frand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	} else if err := os.WriteFile(filepath.Join(dir, "rhp3.crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	} else if err := os.WriteFile(filepath.Join(dir, "rhp3.key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestCertificates(t *testing.T) {
+	hostKey := types.NewPrivateKeyFromSeed(frand.Bytes(32))
+	dir := t.TempDir()
+	log := zaptest.NewLogger(t)
+	node, err := test.NewWallet(hostKey, dir, log.Named("wallet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+
+	db, err := sqlite.OpenDatabase(filepath.Join(dir, "hostd.db"), log.Named("sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// write a certificate that expires within the alert window
+	der := writeCertificate(t, filepath.Join(dir, "certs"), "localhost", time.Now().AddDate(0, 0, 7))
+
+	am := alerts.NewManager()
+	manager, err := settings.NewConfigManager(dir, hostKey, "localhost:9882", db, am, node.ChainManager(), node.TPool(), node, node.Gateway(), log.Named("settings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	cert, err := manager.RHP3TLSConfig().GetCertificate(&tls.ClientHelloInfo{ServerName: "localhost"})
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(cert.Certificate[0], der) {
+		t.Fatal("expected the provided certificate to be used")
+	}
+
+	active := am.Active()
+	if len(active) != 1 {
+		t.Fatalf("expected 1 alert, got %v", len(active))
+	} else if active[0].Severity != alerts.SeverityWarning {
+		t.Fatalf("expected warning severity, got %v", active[0].Severity)
+	}
+
+	// invalid ACME settings should be rejected
+	updated := manager.Settings()
+	updated.ACME = settings.ACMESettings{Enabled: true, Challenge: "dns-42"}
+	if err := manager.UpdateSettings(updated); err == nil {
+		t.Fatal("expected invalid challenge error")
+	}
+	updated.ACME = settings.ACMESettings{Enabled: true, Challenge: settings.ACMEChallengeHTTP01, HTTPAddress: "localhost"}
+	if err := manager.UpdateSettings(updated); err == nil {
+		t.Fatal("expected invalid http address error")
+	}
+	updated.ACME = settings.ACMESettings{Enabled: true, Challenge: settings.ACMEChallengeDNS01}
+	if err := manager.UpdateSettings(updated); err == nil {
+		t.Fatal("expected missing dns provider error")
+	}
+	// No-IP cannot manage TXT records
+	updated.DDNS = settings.DNSSettings{
+		Provider: settings.DNSProviderNoIP,
+		IPv4:     true,
+		Options:  []byte(`{"email":"host@example.com","password":"password"}`),
+	}
+	if err := manager.UpdateSettings(updated); err == nil {
+		t.Fatal("expected unsupported dns provider error")
+	}
+	updated.DDNS = settings.DNSSettings{}
+
+	// the ACME settings should be persisted
+	updated.ACME = settings.ACMESettings{Enabled: false, Email: "host@example.com", Challenge: settings.ACMEChallengeHTTP01}
+	if err := manager.UpdateSettings(updated); err != nil {
+		t.Fatal(err)
+	} else if stored, err := db.Settings(); err != nil {
+		t.Fatal(err)
+	} else if stored.ACME != updated.ACME {
+		t.Fatalf("expected ACME settings %v, got %v", updated.ACME, stored.ACME)
+	}
+}
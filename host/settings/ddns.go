@@ -1,28 +1,51 @@
 package settings
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
+	"net/url"
 	"time"
 
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/alerts"
 	"go.sia.tech/hostd/internal/ddns"
 	"go.sia.tech/hostd/internal/ddns/providers/cloudflare"
+	"go.sia.tech/hostd/internal/ddns/providers/digitalocean"
 	"go.sia.tech/hostd/internal/ddns/providers/duckdns"
+	"go.sia.tech/hostd/internal/ddns/providers/gandi"
+	"go.sia.tech/hostd/internal/ddns/providers/generic"
 	"go.sia.tech/hostd/internal/ddns/providers/noip"
+	"go.sia.tech/hostd/internal/ddns/providers/rfc2136"
 	"go.sia.tech/hostd/internal/ddns/providers/route53"
 	"go.uber.org/zap"
 )
 
 // defines DNS providers
 const (
-	DNSProviderCloudflare = "cloudflare"
-	DNSProviderDuckDNS    = "duckdns"
-	DNSProviderNoIP       = "noip"
-	DNSProviderRoute53    = "route53"
+	DNSProviderCloudflare   = "cloudflare"
+	DNSProviderDigitalOcean = "digitalocean"
+	DNSProviderDuckDNS      = "duckdns"
+	DNSProviderGandi        = "gandi"
+	DNSProviderGeneric      = "generic"
+	DNSProviderNoIP         = "noip"
+	DNSProviderRFC2136      = "rfc2136"
+	DNSProviderRoute53      = "route53"
 )
 
+// defines IP discovery modes
+const (
+	IPDiscoveryEcho   = "echo"
+	IPDiscoveryPeers  = "peers"
+	IPDiscoveryStatic = "static"
+)
+
+// ipDiscoveryTimeout is the maximum time to discover the host's public IP
+// addresses.
+const ipDiscoveryTimeout = 30 * time.Second
+
 type (
 	// Route53Settings contains the settings for the Route53 DNS provider.
 	Route53Settings struct {
@@ -48,15 +71,115 @@ type (
 		ZoneID string `json:"zoneID"`
 	}
 
+	// DigitalOceanSettings contains the settings for the DigitalOcean DNS
+	// provider.
+	DigitalOceanSettings struct {
+		Token  string `json:"token"`
+		Domain string `json:"domain"`
+		// BaseURL overrides the URL of the DigitalOcean API, e.g. to use a
+		// proxy. Defaults to digitalocean.DefaultBaseURL.
+		BaseURL string `json:"baseURL,omitempty"`
+	}
+
+	// GandiSettings contains the settings for the Gandi LiveDNS provider.
+	GandiSettings struct {
+		Token  string `json:"token"`
+		Domain string `json:"domain"`
+		// BaseURL overrides the URL of the Gandi API, e.g. to use a
+		// proxy. Defaults to gandi.DefaultBaseURL.
+		BaseURL string `json:"baseURL,omitempty"`
+	}
+
+	// RFC2136Settings contains the settings for sending RFC 2136 dynamic
+	// updates to an authoritative DNS server. Updates are signed with the
+	// TSIG key if KeyName is set.
+	RFC2136Settings struct {
+		Server       string `json:"server"`
+		Zone         string `json:"zone"`
+		KeyName      string `json:"keyName"`
+		KeyAlgorithm string `json:"keyAlgorithm"`
+		KeySecret    string `json:"keySecret"`
+	}
+
+	// GenericDNSSettings contains the settings for a DNS provider with an
+	// HTTP update API, such as a dyndns2 compatible service. URL is a
+	// template executed with the hostname and IP addresses.
+	GenericDNSSettings struct {
+		URL      string `json:"url"`
+		Method   string `json:"method"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+
+	// IPDiscoverySettings contains the settings for discovering the host's
+	// public IP addresses. By default, the addresses are discovered using
+	// ddns.DefaultEchoURLs.
+	IPDiscoverySettings struct {
+		Mode string `json:"mode"`
+		// EchoURLs are services that respond with the caller's IP address.
+		// At least Quorum of the services must agree on the address.
+		EchoURLs []string `json:"echoURLs,omitempty"`
+		Quorum   int      `json:"quorum,omitempty"`
+		// MinPeers is the minimum number of gateway peers that must respond.
+		// A majority of the responding peers must agree on the address.
+		MinPeers int `json:"minPeers,omitempty"`
+		// StaticIPv4 and StaticIPv6 are the addresses specified by the host
+		// operator.
+		StaticIPv4 string `json:"staticIPv4,omitempty"`
+		StaticIPv6 string `json:"staticIPv6,omitempty"`
+	}
+
 	// DNSSettings contains the settings for the host's dynamic DNS.
 	DNSSettings struct {
-		Provider string          `json:"provider"`
-		IPv4     bool            `json:"ipv4"`
-		IPv6     bool            `json:"ipv6"`
-		Options  json.RawMessage `json:"options"`
+		Provider  string              `json:"provider"`
+		IPv4      bool                `json:"ipv4"`
+		IPv6      bool                `json:"ipv6"`
+		Options   json.RawMessage     `json:"options"`
+		Discovery IPDiscoverySettings `json:"discovery"`
+
+		// AlertFailures is the number of consecutive failed checks before
+		// an alert is registered. If zero, no alert is registered.
+		AlertFailures int `json:"alertFailures"`
+		// HistoryRetention is the duration recorded checks are kept. The
+		// last successful check is always kept. If zero, checks are never
+		// removed.
+		HistoryRetention time.Duration `json:"historyRetention"`
+	}
+
+	// A DDNSAttempt is a check of the host's dynamic DNS records. Updated is
+	// true if the host's IP addresses changed and the provider was updated.
+	DDNSAttempt struct {
+		Provider string `json:"provider"`
+		Hostname string `json:"hostname"`
+		// IPv4 and IPv6 are the detected public IP addresses of the host.
+		IPv4      net.IP    `json:"ipv4,omitempty"`
+		IPv6      net.IP    `json:"ipv6,omitempty"`
+		Success   bool      `json:"success"`
+		Updated   bool      `json:"updated"`
+		Error     string    `json:"error,omitempty"`
+		Timestamp time.Time `json:"timestamp"`
+	}
+
+	// DDNSStatus is the health of the host's dynamic DNS.
+	DDNSStatus struct {
+		Provider string `json:"provider"`
+		// IPv4 and IPv6 are the public IP addresses of the host detected by
+		// the last check.
+		IPv4      net.IP    `json:"ipv4,omitempty"`
+		IPv6      net.IP    `json:"ipv6,omitempty"`
+		LastCheck time.Time `json:"lastCheck"`
+		// ConsecutiveFailures is the number of failed checks since the last
+		// successful check.
+		ConsecutiveFailures int          `json:"consecutiveFailures"`
+		LastSuccess         *DDNSAttempt `json:"lastSuccess"`
+		LastFailure         *DDNSAttempt `json:"lastFailure"`
 	}
 )
 
+// ddnsAlertID is the ID of the alert registered when the dynamic DNS
+// records cannot be updated.
+var ddnsAlertID = types.HashBytes([]byte("ddnsFailure"))
+
 func (m *ConfigManager) triggerDNSUpdate() {
 	if err := m.UpdateDDNS(false); err != nil {
 		m.log.Named("ddns").Error("failed to update ddns", zap.Error(err))
@@ -84,82 +207,164 @@ func (m *ConfigManager) resetDDNS() {
 	m.ddnsUpdateTimer.Reset(dnsUpdateFrequency)
 }
 
-// UpdateDDNS triggers an update of the host's dynamic DNS records.
-func (m *ConfigManager) UpdateDDNS(force bool) error {
-	m.mu.Lock()
-	hostname, _, err := net.SplitHostPort(m.settings.NetAddress)
-	if err != nil {
-		m.mu.Unlock()
-		return fmt.Errorf("failed to split netaddress host and port: %w", err)
-	}
-	settings := m.settings.DDNS
-	lastIPv4, lastIPv6 := m.lastIPv4, m.lastIPv6
-	m.mu.Unlock()
-
-	if force {
-		lastIPv4, lastIPv6 = nil, nil
-	}
-
-	var ipv4 net.IP
-	if settings.IPv4 {
-		// get the IPv4 address
-		ipv4, err = ddns.GetIPv4()
-		if err != nil {
-			return fmt.Errorf("failed to get ipv4 address: %w", err)
-		} else if ipv4.Equal(lastIPv4) {
-			ipv4 = nil
+// newIPDiscoverer initializes the discoverer for the host's public IP
+// addresses.
+func (m *ConfigManager) newIPDiscoverer(s IPDiscoverySettings) (ddns.IPDiscoverer, error) {
+	switch s.Mode {
+	case "", IPDiscoveryEcho:
+		urls, quorum := s.EchoURLs, s.Quorum
+		if len(urls) == 0 {
+			urls = ddns.DefaultEchoURLs
 		}
-	}
-	var ipv6 net.IP
-	if settings.IPv6 {
-		// get the IPv6 address
-		ipv6, err = ddns.GetIPv6()
-		if err != nil {
-			return fmt.Errorf("failed to get ipv6 address: %w", err)
-		} else if ipv6.Equal(lastIPv6) {
-			ipv6 = nil
+		if quorum == 0 {
+			quorum = len(urls)/2 + 1
 		}
+		return ddns.NewEchoDiscoverer(urls, quorum)
+	case IPDiscoveryPeers:
+		if m.syncer == nil {
+			return nil, errors.New("no syncer")
+		}
+		return ddns.NewPeerDiscoverer(m.syncer, s.MinPeers)
+	case IPDiscoveryStatic:
+		return ddns.NewStaticDiscoverer(net.ParseIP(s.StaticIPv4), net.ParseIP(s.StaticIPv6))
+	default:
+		return nil, fmt.Errorf("unknown ip discovery mode: %q", s.Mode)
 	}
+}
 
-	if ipv4 == nil && ipv6 == nil {
-		return nil
+// validateIPDiscoverySettings validates the IP discovery settings and sets the
+// defaults.
+func validateIPDiscoverySettings(s *DNSSettings) error {
+	d := &s.Discovery
+	switch d.Mode {
+	case "", IPDiscoveryEcho:
+		d.Mode = IPDiscoveryEcho
+		d.MinPeers, d.StaticIPv4, d.StaticIPv6 = 0, "", ""
+		for _, u := range d.EchoURLs {
+			if parsed, err := url.Parse(u); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || len(parsed.Host) == 0 {
+				return fmt.Errorf("invalid echo url %q", u)
+			}
+		}
+		n := len(d.EchoURLs)
+		if n == 0 {
+			n = len(ddns.DefaultEchoURLs)
+		}
+		if d.Quorum < 0 || d.Quorum > n {
+			return fmt.Errorf("quorum must be between 1 and %d", n)
+		}
+	case IPDiscoveryPeers:
+		d.EchoURLs, d.Quorum, d.StaticIPv4, d.StaticIPv6 = nil, 0, "", ""
+		if d.MinPeers == 0 {
+			d.MinPeers = 3
+		} else if d.MinPeers < 0 {
+			return errors.New("min peers must be positive")
+		}
+	case IPDiscoveryStatic:
+		d.EchoURLs, d.Quorum, d.MinPeers = nil, 0, 0
+		if s.IPv4 {
+			if ip := net.ParseIP(d.StaticIPv4); ip == nil || ip.To4() == nil {
+				return fmt.Errorf("invalid static ipv4 address %q", d.StaticIPv4)
+			}
+		} else {
+			d.StaticIPv4 = ""
+		}
+		if s.IPv6 {
+			if ip := net.ParseIP(d.StaticIPv6); ip == nil || ip.To4() != nil {
+				return fmt.Errorf("invalid static ipv6 address %q", d.StaticIPv6)
+			}
+		} else {
+			d.StaticIPv6 = ""
+		}
+	default:
+		return fmt.Errorf("unknown ip discovery mode: %q", d.Mode)
 	}
+	return nil
+}
 
+// newDNSProvider initializes the DNS provider for hostname.
+func newDNSProvider(settings DNSSettings, hostname string) (ddns.Provider, error) {
 	var provider ddns.Provider
 	switch settings.Provider {
 	case DNSProviderCloudflare:
 		var options CloudflareSettings
 		if err := json.Unmarshal(settings.Options, &options); err != nil {
-			return fmt.Errorf("failed to parse cloudflare options: %w", err)
+			return nil, fmt.Errorf("failed to parse cloudflare options: %w", err)
 		}
 		provider = cloudflare.New(cloudflare.Options{
 			Token:    options.Token,
 			ZoneID:   options.ZoneID,
 			Hostname: hostname,
 		})
+	case DNSProviderDigitalOcean:
+		var options DigitalOceanSettings
+		if err := json.Unmarshal(settings.Options, &options); err != nil {
+			return nil, fmt.Errorf("failed to parse digitalocean options: %w", err)
+		}
+		provider = digitalocean.New(digitalocean.Options{
+			Token:    options.Token,
+			Domain:   options.Domain,
+			Hostname: hostname,
+			BaseURL:  options.BaseURL,
+		})
 	case DNSProviderDuckDNS:
 		var options DuckDNSSettings
 		if err := json.Unmarshal(settings.Options, &options); err != nil {
-			return fmt.Errorf("failed to parse duckdns options: %w", err)
+			return nil, fmt.Errorf("failed to parse duckdns options: %w", err)
 		}
 		provider = duckdns.New(duckdns.Options{
 			Token:    options.Token,
 			Hostname: hostname,
 		})
+	case DNSProviderGandi:
+		var options GandiSettings
+		if err := json.Unmarshal(settings.Options, &options); err != nil {
+			return nil, fmt.Errorf("failed to parse gandi options: %w", err)
+		}
+		provider = gandi.New(gandi.Options{
+			Token:    options.Token,
+			Domain:   options.Domain,
+			Hostname: hostname,
+			BaseURL:  options.BaseURL,
+		})
+	case DNSProviderGeneric:
+		var options GenericDNSSettings
+		if err := json.Unmarshal(settings.Options, &options); err != nil {
+			return nil, fmt.Errorf("failed to parse generic options: %w", err)
+		}
+		provider = generic.New(generic.Options{
+			URL:      options.URL,
+			Method:   options.Method,
+			Username: options.Username,
+			Password: options.Password,
+			Hostname: hostname,
+		})
 	case DNSProviderNoIP:
 		var options NoIPSettings
 		if err := json.Unmarshal(settings.Options, &options); err != nil {
-			return fmt.Errorf("failed to parse noip options: %w", err)
+			return nil, fmt.Errorf("failed to parse noip options: %w", err)
 		}
 		provider = noip.New(noip.Options{
 			Email:    options.Email,
 			Password: options.Password,
 			Hostname: hostname,
 		})
+	case DNSProviderRFC2136:
+		var options RFC2136Settings
+		if err := json.Unmarshal(settings.Options, &options); err != nil {
+			return nil, fmt.Errorf("failed to parse rfc2136 options: %w", err)
+		}
+		provider = rfc2136.New(rfc2136.Options{
+			Server:       options.Server,
+			Zone:         options.Zone,
+			KeyName:      options.KeyName,
+			KeyAlgorithm: options.KeyAlgorithm,
+			KeySecret:    options.KeySecret,
+			Hostname:     hostname,
+		})
 	case DNSProviderRoute53:
 		var options Route53Settings
 		if err := json.Unmarshal(settings.Options, &options); err != nil {
-			return fmt.Errorf("failed to parse route53 options: %w", err)
+			return nil, fmt.Errorf("failed to parse route53 options: %w", err)
 		}
 		provider = route53.New(route53.Options{
 			ID:       options.ID,
@@ -168,12 +373,166 @@ func (m *ConfigManager) UpdateDDNS(force bool) error {
 			Hostname: hostname,
 		})
 	default:
-		return fmt.Errorf("unknown dns provider: %q", settings.Provider)
+		return nil, fmt.Errorf("unknown dns provider: %q", settings.Provider)
+	}
+	return provider, nil
+}
+
+// UpdateDDNS triggers an update of the host's dynamic DNS records.
+func (m *ConfigManager) UpdateDDNS(force bool) error {
+	attempt, updated, err := m.updateDDNS(force)
+	m.recordDDNSAttempt(attempt, updated, err)
+	return err
+}
+
+// DDNSStatus returns the health of the host's dynamic DNS.
+func (m *ConfigManager) DDNSStatus() (DDNSStatus, error) {
+	m.mu.Lock()
+	status := DDNSStatus{
+		Provider:            m.settings.DDNS.Provider,
+		IPv4:                m.publicIPv4,
+		IPv6:                m.publicIPv6,
+		LastCheck:           m.lastDDNSCheck,
+		ConsecutiveFailures: m.ddnsFailures,
+	}
+	m.mu.Unlock()
+
+	if success, err := m.store.LastDDNSAttempt(true); err != nil {
+		return DDNSStatus{}, fmt.Errorf("failed to get last successful attempt: %w", err)
+	} else if !success.Timestamp.IsZero() {
+		status.LastSuccess = &success
+	}
+	if failure, err := m.store.LastDDNSAttempt(false); err != nil {
+		return DDNSStatus{}, fmt.Errorf("failed to get last failed attempt: %w", err)
+	} else if !failure.Timestamp.IsZero() {
+		status.LastFailure = &failure
+	}
+	return status, nil
+}
+
+// DDNSHistory returns the recorded dynamic DNS update attempts, newest first.
+func (m *ConfigManager) DDNSHistory(limit, offset int) ([]DDNSAttempt, error) {
+	return m.store.DDNSAttempts(limit, offset)
+}
+
+// recordDDNSAttempt persists the attempt, removes attempts older than the
+// retention period, and registers an alert after too many consecutive
+// failures.
+func (m *ConfigManager) recordDDNSAttempt(attempt DDNSAttempt, updated bool, err error) {
+	attempt.Success = err == nil
+	attempt.Updated = updated
+	attempt.Timestamp = time.Now()
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+
+	m.mu.Lock()
+	m.lastDDNSCheck = attempt.Timestamp
+	if attempt.IPv4 != nil || attempt.IPv6 != nil {
+		m.publicIPv4, m.publicIPv6 = attempt.IPv4, attempt.IPv6
+	}
+	if attempt.Success {
+		m.ddnsFailures = 0
+	} else {
+		m.ddnsFailures++
+	}
+	failures := m.ddnsFailures
+	alertFailures, retention := m.settings.DDNS.AlertFailures, m.settings.DDNS.HistoryRetention
+	m.mu.Unlock()
+
+	log := m.log.Named("ddns")
+	if err := m.store.AddDDNSAttempt(attempt); err != nil {
+		log.Error("failed to record attempt", zap.Error(err))
+	}
+	if retention > 0 {
+		if err := m.store.PruneDDNSAttempts(attempt.Timestamp.Add(-retention)); err != nil {
+			log.Error("failed to prune attempts", zap.Error(err))
+		}
+	}
+
+	switch {
+	case attempt.Success:
+		m.a.Dismiss(ddnsAlertID)
+	case alertFailures > 0 && failures >= alertFailures:
+		m.a.Register(alerts.Alert{
+			ID:       ddnsAlertID,
+			Severity: alerts.SeverityWarning,
+			Message:  "Dynamic DNS update failed",
+			Data: map[string]any{
+				"provider": attempt.Provider,
+				"hostname": attempt.Hostname,
+				"failures": failures,
+				"error":    attempt.Error,
+			},
+			Timestamp: attempt.Timestamp,
+		})
+	}
+}
+
+// updateDDNS updates the host's dynamic DNS records if its IP addresses
+// changed. The attempt contains the detected IP addresses. updated is true if
+// the provider was updated.
+func (m *ConfigManager) updateDDNS(force bool) (attempt DDNSAttempt, updated bool, err error) {
+	m.mu.Lock()
+	settings := m.settings.DDNS
+	attempt.Provider = settings.Provider
+	hostname, _, err := net.SplitHostPort(m.settings.NetAddress)
+	if err != nil {
+		m.mu.Unlock()
+		return attempt, false, fmt.Errorf("failed to split netaddress host and port: %w", err)
+	}
+	attempt.Hostname = hostname
+	lastIPv4, lastIPv6 := m.lastIPv4, m.lastIPv6
+	m.mu.Unlock()
+
+	if force {
+		lastIPv4, lastIPv6 = nil, nil
+	}
+
+	discoverer, err := m.newIPDiscoverer(settings.Discovery)
+	if err != nil {
+		return attempt, false, fmt.Errorf("failed to initialize ip discovery: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ipDiscoveryTimeout)
+	defer cancel()
+
+	var ipv4 net.IP
+	if settings.IPv4 {
+		// get the IPv4 address
+		ipv4, err = discoverer.DiscoverIP(ctx, ddns.NetworkModeTCP4)
+		if err != nil {
+			return attempt, false, fmt.Errorf("failed to get ipv4 address: %w", err)
+		}
+		attempt.IPv4 = ipv4
+		if ipv4.Equal(lastIPv4) {
+			ipv4 = nil
+		}
+	}
+	var ipv6 net.IP
+	if settings.IPv6 {
+		// get the IPv6 address
+		ipv6, err = discoverer.DiscoverIP(ctx, ddns.NetworkModeTCP6)
+		if err != nil {
+			return attempt, false, fmt.Errorf("failed to get ipv6 address: %w", err)
+		}
+		attempt.IPv6 = ipv6
+		if ipv6.Equal(lastIPv6) {
+			ipv6 = nil
+		}
+	}
+
+	if ipv4 == nil && ipv6 == nil {
+		return attempt, false, nil
+	}
+
+	provider, err := newDNSProvider(settings, hostname)
+	if err != nil {
+		return attempt, false, err
 	}
 
 	// update the DNS provider
 	if err := provider.Update(ipv4, ipv6); err != nil {
-		return fmt.Errorf("failed to update dns: %w", err)
+		return attempt, false, fmt.Errorf("failed to update dns: %w", err)
 	}
 	m.mu.Lock()
 	m.lastIPv4, m.lastIPv6 = ipv4, ipv6
@@ -181,21 +540,35 @@ func (m *ConfigManager) UpdateDDNS(force bool) error {
 	if !force {
 		m.log.Named("ddns").Info("provider updated", zap.String("hostname", hostname), zap.String("ipv4", ipv4.String()), zap.String("ipv6", ipv6.String()), zap.String("provider", settings.Provider))
 	}
-	return nil
+	// the hostname now resolves to a different IP, re-announce so renters
+	// refresh the host's address
+	if (lastIPv4 != nil && ipv4 != nil) || (lastIPv6 != nil && ipv6 != nil) {
+		m.requestAnnounce("ip address changed")
+	}
+	return attempt, true, nil
 }
 
 // validateDNSSettings validates the DNS settings and returns an error if they
 // are invalid. The Options field will be rewritten to ensure the JSON object
 // matches the expected schema.
 func validateDNSSettings(s *DNSSettings) error {
+	if s.AlertFailures < 0 {
+		return errors.New("alert failures must be non-negative")
+	} else if s.HistoryRetention < 0 {
+		return errors.New("history retention must be non-negative")
+	}
+
 	if len(s.Provider) == 0 {
 		// clear DNS settings if provider is empty
 		s.IPv4 = false
 		s.IPv6 = false
 		s.Options = nil
+		s.Discovery = IPDiscoverySettings{}
 		return nil
 	} else if !s.IPv4 && !s.IPv6 {
 		return errors.New("at least one of IPv4 or IPv6 must be enabled")
+	} else if err := validateIPDiscoverySettings(s); err != nil {
+		return fmt.Errorf("invalid ip discovery settings: %w", err)
 	}
 
 	switch s.Provider {
@@ -211,6 +584,21 @@ func validateDNSSettings(s *DNSSettings) error {
 			return errors.New("zone id must be set")
 		}
 		s.Options, _ = json.Marshal(opts) // re-encode the options to enforce the correct schema
+	case DNSProviderDigitalOcean:
+		var opts DigitalOceanSettings
+		if err := json.Unmarshal(s.Options, &opts); err != nil {
+			return fmt.Errorf("failed to unmarshal digitalocean settings: %w", err)
+		}
+		switch {
+		case len(opts.Token) == 0:
+			return errors.New("token must be set")
+		case len(opts.Domain) == 0:
+			return errors.New("domain must be set")
+		}
+		if err := ddns.ValidateBaseURL(opts.BaseURL); err != nil {
+			return err
+		}
+		s.Options, _ = json.Marshal(opts) // re-encode the options to enforce the correct schema
 	case DNSProviderDuckDNS:
 		var opts DuckDNSSettings
 		if err := json.Unmarshal(s.Options, &opts); err != nil {
@@ -219,6 +607,31 @@ func validateDNSSettings(s *DNSSettings) error {
 			return errors.New("token must be set")
 		}
 		s.Options, _ = json.Marshal(opts) // re-encode the options to enforce the correct schema
+	case DNSProviderGandi:
+		var opts GandiSettings
+		if err := json.Unmarshal(s.Options, &opts); err != nil {
+			return fmt.Errorf("failed to unmarshal gandi settings: %w", err)
+		}
+		switch {
+		case len(opts.Token) == 0:
+			return errors.New("token must be set")
+		case len(opts.Domain) == 0:
+			return errors.New("domain must be set")
+		}
+		if err := ddns.ValidateBaseURL(opts.BaseURL); err != nil {
+			return err
+		}
+		s.Options, _ = json.Marshal(opts) // re-encode the options to enforce the correct schema
+	case DNSProviderGeneric:
+		var opts GenericDNSSettings
+		if err := json.Unmarshal(s.Options, &opts); err != nil {
+			return fmt.Errorf("failed to unmarshal generic settings: %w", err)
+		} else if len(opts.URL) == 0 {
+			return errors.New("url must be set")
+		} else if err := generic.ValidateTemplate(opts.URL, opts.Method); err != nil {
+			return err
+		}
+		s.Options, _ = json.Marshal(opts) // re-encode the options to enforce the correct schema
 	case DNSProviderNoIP:
 		var opts NoIPSettings
 		if err := json.Unmarshal(s.Options, &opts); err != nil {
@@ -231,6 +644,25 @@ func validateDNSSettings(s *DNSSettings) error {
 			return errors.New("password must be set")
 		}
 		s.Options, _ = json.Marshal(opts) // re-encode the options to enforce the correct schema
+	case DNSProviderRFC2136:
+		var opts RFC2136Settings
+		if err := json.Unmarshal(s.Options, &opts); err != nil {
+			return fmt.Errorf("failed to unmarshal rfc2136 settings: %w", err)
+		}
+		switch {
+		case len(opts.Server) == 0:
+			return errors.New("server must be set")
+		case len(opts.Zone) == 0:
+			return errors.New("zone must be set")
+		}
+		if _, _, err := net.SplitHostPort(opts.Server); err != nil {
+			return fmt.Errorf("invalid server address: %w", err)
+		} else if len(opts.KeyName) != 0 {
+			if err := rfc2136.ValidateKey(opts.KeyAlgorithm, opts.KeySecret); err != nil {
+				return err
+			}
+		}
+		s.Options, _ = json.Marshal(opts) // re-encode the options to enforce the correct schema
 	case DNSProviderRoute53:
 		var opts Route53Settings
 		if err := json.Unmarshal(s.Options, &opts); err != nil {
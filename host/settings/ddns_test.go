@@ -0,0 +1,217 @@
+package settings_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/alerts"
+	"go.sia.tech/hostd/host/settings"
+	"go.sia.tech/hostd/internal/test"
+	"go.sia.tech/hostd/persist/sqlite"
+	"go.uber.org/zap"
+	"lukechampine.com/frand"
+)
+
+func TestDNSSettingsValidation(t *testing.T) {
+	hostKey := types.NewPrivateKeyFromSeed(frand.Bytes(32))
+	dir := t.TempDir()
+	// the DNS update triggered by valid settings may outlive the test
+	log := zap.NewNop()
+	node, err := test.NewWallet(hostKey, dir, log.Named("wallet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+
+	db, err := sqlite.OpenDatabase(filepath.Join(dir, "hostd.db"), log.Named("sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	manager, err := settings.NewConfigManager(dir, hostKey, "localhost:9882", db, alerts.NewManager(), node.ChainManager(), node.TPool(), node, node.Gateway(), log.Named("settings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	tests := []struct {
+		provider string
+		options  string
+		valid    bool
+	}{
+		{settings.DNSProviderDigitalOcean, `{"token":"token","domain":"example.com"}`, true},
+		{settings.DNSProviderDigitalOcean, `{"token":"token"}`, false},
+		{settings.DNSProviderGandi, `{"token":"token","domain":"example.com"}`, true},
+		{settings.DNSProviderGandi, `{"domain":"example.com"}`, false},
+		{settings.DNSProviderRFC2136, `{"server":"127.0.0.1:53","zone":"example.com"}`, true},
+		{settings.DNSProviderRFC2136, `{"server":"127.0.0.1:53","zone":"example.com","keyName":"hostd","keySecret":"c2VjcmV0"}`, true},
+		{settings.DNSProviderRFC2136, `{"server":"127.0.0.1:53","zone":"example.com","keyName":"hostd","keyAlgorithm":"hmac-md5","keySecret":"c2VjcmV0"}`, false},
+		{settings.DNSProviderRFC2136, `{"server":"127.0.0.1:53","zone":"example.com","keyName":"hostd","keySecret":"not base64"}`, false},
+		{settings.DNSProviderRFC2136, `{"server":"127.0.0.1","zone":"example.com"}`, false},
+		{settings.DNSProviderGeneric, `{"url":"https://example.com/nic/update?hostname={{.Hostname}}&myip={{.IP}}"}`, true},
+		{settings.DNSProviderGeneric, `{"url":"https://example.com/update/{{.IPv4}}","method":"PUT","username":"user","password":"pass"}`, true},
+		{settings.DNSProviderGeneric, `{"url":"https://example.com/nic/update?myip={{.Address}}"}`, false},
+		{settings.DNSProviderGeneric, `{"url":"ftp://example.com/{{.IP}}"}`, false},
+		{settings.DNSProviderGeneric, `{"url":"https://example.com/{{.IP}}","method":"DELETE"}`, false},
+	}
+	for _, tt := range tests {
+		s := manager.Settings()
+		s.DDNS = settings.DNSSettings{
+			Provider: tt.provider,
+			IPv4:     true,
+			Options:  []byte(tt.options),
+		}
+		if err := manager.UpdateSettings(s); tt.valid && err != nil {
+			t.Fatalf("expected %s options %s to be valid: %v", tt.provider, tt.options, err)
+		} else if !tt.valid && err == nil {
+			t.Fatalf("expected %s options %s to be invalid", tt.provider, tt.options)
+		}
+	}
+}
+
+func TestDDNSStatus(t *testing.T) {
+	hostKey := types.NewPrivateKeyFromSeed(frand.Bytes(32))
+	dir := t.TempDir()
+	log := zap.NewNop()
+	node, err := test.NewWallet(hostKey, dir, log.Named("wallet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+
+	db, err := sqlite.OpenDatabase(filepath.Join(dir, "hostd.db"), log.Named("sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	am := alerts.NewManager()
+	manager, err := settings.NewConfigManager(dir, hostKey, "localhost:9882", db, am, node.ChainManager(), node.TPool(), node, node.Gateway(), log.Named("settings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	// stand-in dyndns2 update service
+	var response atomic.Value
+	response.Store("good")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", response.Load(), r.URL.Query().Get("myip"))
+	}))
+	defer srv.Close()
+
+	options, err := json.Marshal(map[string]string{"url": srv.URL + "/nic/update?hostname={{.Hostname}}&myip={{.IP}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ip := net.ParseIP("192.0.2.1")
+	s := manager.Settings()
+	s.NetAddress = "localhost:9982"
+	s.DDNS = settings.DNSSettings{
+		Provider: settings.DNSProviderGeneric,
+		IPv4:     true,
+		Options:  options,
+		Discovery: settings.IPDiscoverySettings{
+			Mode:       settings.IPDiscoveryStatic,
+			StaticIPv4: ip.String(),
+		},
+		AlertFailures:    3,
+		HistoryRetention: time.Hour,
+	}
+	if err := manager.UpdateSettings(s); err != nil {
+		t.Fatal(err)
+	}
+
+	// wait for the update triggered by the settings change so it does not
+	// race the forced updates below
+	var status settings.DDNSStatus
+	for i := 0; i < 100; i++ {
+		status, err = manager.DDNSStatus()
+		if err != nil {
+			t.Fatal(err)
+		} else if !status.LastCheck.IsZero() {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if status.Provider != settings.DNSProviderGeneric {
+		t.Fatalf("expected provider %q, got %q", settings.DNSProviderGeneric, status.Provider)
+	} else if !status.IPv4.Equal(ip) {
+		t.Fatalf("expected ipv4 %v, got %v", ip, status.IPv4)
+	} else if status.ConsecutiveFailures != 0 {
+		t.Fatalf("expected no failures, got %v", status.ConsecutiveFailures)
+	} else if status.LastSuccess == nil || !status.LastSuccess.IPv4.Equal(ip) {
+		t.Fatalf("expected successful attempt for %v, got %v", ip, status.LastSuccess)
+	} else if status.LastFailure != nil {
+		t.Fatalf("expected no failed attempt, got %v", status.LastFailure)
+	}
+
+	// a check that finds the address unchanged should still be recorded
+	if err := manager.UpdateDDNS(false); err != nil {
+		t.Fatal(err)
+	} else if history, err := manager.DDNSHistory(1, 0); err != nil {
+		t.Fatal(err)
+	} else if len(history) != 1 || !history[0].Success || history[0].Updated {
+		t.Fatalf("expected unchanged check to be recorded, got %v", history)
+	}
+
+	hasAlert := func() bool {
+		for _, a := range am.Active() {
+			if a.Message == "Dynamic DNS update failed" {
+				return true
+			}
+		}
+		return false
+	}
+
+	// the service starts rejecting updates
+	response.Store("badauth")
+	for i := 0; i < 3; i++ {
+		if hasAlert() {
+			t.Fatalf("expected no alert after %v failures", i)
+		} else if err := manager.UpdateDDNS(true); err == nil {
+			t.Fatal("expected update to fail")
+		}
+	}
+
+	status, err = manager.DDNSStatus()
+	if err != nil {
+		t.Fatal(err)
+	} else if status.ConsecutiveFailures != 3 {
+		t.Fatalf("expected 3 failures, got %v", status.ConsecutiveFailures)
+	} else if status.LastFailure == nil || len(status.LastFailure.Error) == 0 {
+		t.Fatalf("expected failed attempt, got %v", status.LastFailure)
+	} else if status.LastSuccess == nil {
+		t.Fatal("expected successful attempt to be kept")
+	}
+
+	if !hasAlert() {
+		t.Fatal("expected dynamic dns alert")
+	}
+
+	// a successful update dismisses the alert
+	response.Store("good")
+	if err := manager.UpdateDDNS(true); err != nil {
+		t.Fatal(err)
+	} else if hasAlert() {
+		t.Fatal("expected dynamic dns alert to be dismissed")
+	}
+
+	history, err := manager.DDNSHistory(100, 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(history) < 6 {
+		t.Fatalf("expected at least 6 attempts, got %v", len(history))
+	} else if !history[0].Success || !history[0].Updated || time.Since(history[0].Timestamp) > time.Minute {
+		t.Fatalf("expected latest attempt to succeed, got %v", history[0])
+	}
+}
@@ -0,0 +1,205 @@
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/alerts"
+	"lukechampine.com/frand"
+)
+
+// ErrRevisionNotFound is returned by the store if a settings revision does not
+// exist.
+var ErrRevisionNotFound = errors.New("settings revision not found")
+
+// defines the sources of settings revisions. Settings profiles are applied on
+// top of the host's settings and do not create revisions.
+const (
+	// SourceDefault is the revision created when the host is initialized
+	// with the default settings.
+	SourceDefault = "default"
+	// SourceOperator is a revision created by the host operator.
+	SourceOperator = "operator"
+	// SourcePricingEngine is a revision created by the pricing engine
+	// adjusting the host's prices.
+	SourcePricingEngine = "pricingEngine"
+	// SourceRollback is a revision created by restoring a previous revision.
+	SourceRollback = "rollback"
+)
+
+type (
+	// A FieldChange is a change to a single setting. Old and New are the
+	// JSON encoded values of the setting.
+	FieldChange struct {
+		Field string          `json:"field"`
+		Old   json.RawMessage `json:"old"`
+		New   json.RawMessage `json:"new"`
+	}
+
+	// A SettingsRevision is a recorded revision of the host's settings and the
+	// changes from the previous revision.
+	SettingsRevision struct {
+		Revision uint64 `json:"revision"`
+		// Source is what created the revision, e.g. SourceOperator. It is
+		// empty for revisions recorded before sources were tracked.
+		Source    string        `json:"source"`
+		Settings  Settings      `json:"settings"`
+		Changes   []FieldChange `json:"changes"`
+		Timestamp time.Time     `json:"timestamp"`
+	}
+)
+
+// flattenJSON adds the leaf values of a JSON object to fields. Nested object
+// fields are named by their dotted path.
+func flattenJSON(prefix string, buf json.RawMessage, fields map[string]json.RawMessage) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &obj); err != nil || obj == nil {
+		// not an object, or null
+		fields[prefix] = buf
+		return
+	}
+	for key, value := range obj {
+		if len(prefix) != 0 {
+			key = prefix + "." + key
+		}
+		flattenJSON(key, value, fields)
+	}
+}
+
+// Diff returns the settings that changed between old and new, sorted by
+// field. Nested settings are named by their dotted path, e.g.
+// "pricingEngine.maxChange". Fields that were removed have an empty new
+// value. The revision is ignored.
+func Diff(old, new Settings) ([]FieldChange, error) {
+	old.Revision, new.Revision = 0, 0
+	encode := func(s Settings) (map[string]json.RawMessage, error) {
+		buf, err := json.Marshal(s)
+		if err != nil {
+			return nil, err
+		}
+		fields := make(map[string]json.RawMessage)
+		flattenJSON("", buf, fields)
+		return fields, nil
+	}
+	oldFields, err := encode(old)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode old settings: %w", err)
+	}
+	newFields, err := encode(new)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode new settings: %w", err)
+	}
+
+	// fields are compared in both directions so that removed fields are
+	// also reported
+	changes := []FieldChange{}
+	for field, value := range newFields {
+		if prev := oldFields[field]; !bytes.Equal(prev, value) {
+			changes = append(changes, FieldChange{Field: field, Old: prev, New: value})
+		}
+	}
+	for field, prev := range oldFields {
+		if _, ok := newFields[field]; !ok {
+			changes = append(changes, FieldChange{Field: field, Old: prev})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+// History returns the recorded revisions of the host's settings, newest first.
+func (m *ConfigManager) History(limit, offset int) ([]SettingsRevision, error) {
+	return m.store.SettingsHistory(limit, offset)
+}
+
+// Rollback restores the host's settings to a previous revision. The restored
+// settings are recorded as a new revision.
+func (m *ConfigManager) Rollback(revision uint64) (Settings, error) {
+	rev, err := m.store.SettingsRevision(revision)
+	if err != nil {
+		return Settings{}, fmt.Errorf("failed to get settings revision: %w", err)
+	}
+
+	s := rev.Settings
+	s.Revision = m.BaseSettings().Revision
+	if err := m.updateSettings(s, SourceRollback); err != nil {
+		return Settings{}, fmt.Errorf("failed to restore settings: %w", err)
+	}
+	return s, nil
+}
+
+// percent returns n as a percentage of d. Big integers are used to avoid
+// overflowing near the maximum currency value.
+func percent(n, d types.Currency) uint64 {
+	p := new(big.Int).Mul(n.Big(), big.NewInt(100))
+	return p.Div(p, d.Big()).Uint64()
+}
+
+// checkPriceChanges registers an alert if a settings change raised a price or
+// lowered collateral by more than the alert threshold.
+func (m *ConfigManager) checkPriceChanges(old, new Settings) {
+	threshold := new.PriceAlertThreshold
+	if threshold == 0 {
+		return
+	}
+
+	// returns the percentage b increased over a, or zero if it did not
+	// increase
+	increase := func(a, b types.Currency) uint64 {
+		if a.IsZero() || b.Cmp(a) <= 0 {
+			return 0
+		}
+		return percent(b.Sub(a), a)
+	}
+	// returns the percentage b decreased from a, or zero if it did not
+	// decrease
+	decrease := func(a, b types.Currency) uint64 {
+		if a.IsZero() || b.Cmp(a) >= 0 {
+			return 0
+		}
+		return percent(a.Sub(b), a)
+	}
+
+	changes := make(map[string]any)
+	prices := []struct {
+		name     string
+		old, new types.Currency
+	}{
+		{"contractPrice", old.ContractPrice, new.ContractPrice},
+		{"baseRPCPrice", old.BaseRPCPrice, new.BaseRPCPrice},
+		{"sectorAccessPrice", old.SectorAccessPrice, new.SectorAccessPrice},
+		{"storagePrice", old.StoragePrice, new.StoragePrice},
+		{"egressPrice", old.EgressPrice, new.EgressPrice},
+		{"ingressPrice", old.IngressPrice, new.IngressPrice},
+	}
+	for _, p := range prices {
+		if pct := increase(p.old, p.new); pct > threshold {
+			changes[p.name] = map[string]any{"old": p.old, "new": p.new, "percent": pct}
+		}
+	}
+	if pct := decrease(old.MaxCollateral, new.MaxCollateral); pct > threshold {
+		changes["maxCollateral"] = map[string]any{"old": old.MaxCollateral, "new": new.MaxCollateral, "percent": pct}
+	}
+	if old.CollateralMultiplier > 0 && new.CollateralMultiplier < old.CollateralMultiplier {
+		if pct := (old.CollateralMultiplier - new.CollateralMultiplier) / old.CollateralMultiplier * 100; pct > float64(threshold) {
+			changes["collateralMultiplier"] = map[string]any{"old": old.CollateralMultiplier, "new": new.CollateralMultiplier, "percent": pct}
+		}
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	m.a.Register(alerts.Alert{
+		ID:        frand.Entropy256(),
+		Severity:  alerts.SeverityWarning,
+		Message:   fmt.Sprintf("Settings change raised prices or lowered collateral by more than %d%%", threshold),
+		Data:      changes,
+		Timestamp: time.Now(),
+	})
+}
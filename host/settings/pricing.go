@@ -0,0 +1,508 @@
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/metrics"
+	"go.sia.tech/hostd/internal/threadgroup"
+	"go.uber.org/zap"
+)
+
+const (
+	// pricingCheckInterval is the interval at which the pricing engine checks
+	// whether prices are due to be adjusted.
+	pricingCheckInterval = time.Minute
+	// minPricingInterval is the minimum interval between price adjustments.
+	minPricingInterval = 10 * time.Minute
+
+	exchangeRateTimeout = 30 * time.Second
+
+	// minDemandMultiplier and maxDemandMultiplier limit the multiplier of
+	// the demand rule.
+	minDemandMultiplier = 0.5
+	maxDemandMultiplier = 2
+)
+
+type (
+	// Prices are the settings adjusted by the pricing engine.
+	Prices struct {
+		ContractPrice        types.Currency `json:"contractPrice"`
+		BaseRPCPrice         types.Currency `json:"baseRPCPrice"`
+		SectorAccessPrice    types.Currency `json:"sectorAccessPrice"`
+		StoragePrice         types.Currency `json:"storagePrice"`
+		EgressPrice          types.Currency `json:"egressPrice"`
+		IngressPrice         types.Currency `json:"ingressPrice"`
+		CollateralMultiplier float64        `json:"collateralMultiplier"`
+	}
+
+	// A UtilizationRule adjusts prices based on the fraction of the host's
+	// storage that is used. The multiplier is interpolated linearly between
+	// EmptyMultiplier and FullMultiplier.
+	UtilizationRule struct {
+		EmptyMultiplier float64 `json:"emptyMultiplier"`
+		FullMultiplier  float64 `json:"fullMultiplier"`
+		// FullCollateralMultiplier is the collateral multiplier when the
+		// host's storage is full. It is interpolated linearly from the base
+		// collateral multiplier. If zero, the collateral multiplier is not
+		// adjusted.
+		FullCollateralMultiplier float64 `json:"fullCollateralMultiplier"`
+	}
+
+	// An ExchangeRateRule sets base prices from target prices in a fiat
+	// currency. Zero targets use the corresponding base price.
+	ExchangeRateRule struct {
+		// URL is queried for the value of one siacoin in the target
+		// currency. The response body must be a JSON number.
+		URL string `json:"url"`
+
+		ContractPrice float64 `json:"contractPrice"`
+		// StoragePrice is the target price per TiB (2^40 bytes) per month.
+		StoragePrice float64 `json:"storagePrice"`
+		// EgressPrice and IngressPrice are the target prices per TiB.
+		EgressPrice  float64 `json:"egressPrice"`
+		IngressPrice float64 `json:"ingressPrice"`
+	}
+
+	// A DemandRule adjusts prices based on the number of contracts formed
+	// within Window compared to TargetContracts. Each 100% above or below
+	// the target changes prices by Sensitivity. The multiplier is limited to
+	// between 0.5 and 2.
+	DemandRule struct {
+		Window          time.Duration `json:"window"`
+		TargetContracts uint64        `json:"targetContracts"`
+		Sensitivity     float64       `json:"sensitivity"`
+	}
+
+	// PricingEngineSettings configures the pricing engine. The multipliers of
+	// all rules are multiplied together and applied to the base prices.
+	PricingEngineSettings struct {
+		Enabled bool `json:"enabled"`
+		// Interval is the time between price adjustments.
+		Interval time.Duration `json:"interval"`
+		// MaxChange is the maximum relative change of each price per
+		// adjustment. If zero, the rate of change is not limited.
+		MaxChange float64 `json:"maxChange"`
+
+		Base Prices `json:"base"`
+		// Min and Max bound the adjusted prices. Zero values are not
+		// enforced.
+		Min Prices `json:"min"`
+		Max Prices `json:"max"`
+
+		Utilization  *UtilizationRule  `json:"utilization,omitempty"`
+		ExchangeRate *ExchangeRateRule `json:"exchangeRate,omitempty"`
+		Demand       *DemandRule       `json:"demand,omitempty"`
+	}
+
+	// PricingInputs are the market and utilization inputs of the pricing
+	// rules.
+	PricingInputs struct {
+		// Utilization is the fraction of the host's storage that is used.
+		Utilization float64 `json:"utilization"`
+		// ExchangeRate is the value of one siacoin in the exchange rate
+		// rule's currency.
+		ExchangeRate float64 `json:"exchangeRate"`
+		// Demand is the number of contracts formed within the demand rule's
+		// window.
+		Demand uint64 `json:"demand"`
+	}
+
+	// A PricingStore provides the metrics used by the pricing engine and
+	// records price changes.
+	PricingStore interface {
+		// Metrics returns aggregated metrics for the host as of the
+		// timestamp.
+		Metrics(time.Time) (metrics.Metrics, error)
+		// AddPriceChange records an adjustment of the host's prices.
+		AddPriceChange(metrics.PriceChange) error
+	}
+
+	// A StorageUsage reports the host's used and total sectors.
+	StorageUsage interface {
+		Usage() (usedSectors uint64, totalSectors uint64, err error)
+	}
+
+	// A PricingEngine periodically adjusts the host's prices.
+	PricingEngine struct {
+		tg  *threadgroup.ThreadGroup
+		log *zap.Logger
+
+		cm      *ConfigManager
+		storage StorageUsage
+		store   PricingStore
+
+		mu             sync.Mutex // guards the following fields
+		lastAdjustment time.Time
+	}
+)
+
+func validMultiplier(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0) && f >= 0
+}
+
+// Validate returns an error if the pricing engine settings are invalid.
+func (ps PricingEngineSettings) Validate() error {
+	if !ps.Enabled {
+		return nil
+	}
+
+	switch {
+	case ps.Interval < minPricingInterval:
+		return fmt.Errorf("interval must be at least %v", minPricingInterval)
+	case !validMultiplier(ps.MaxChange):
+		return fmt.Errorf("max change must be a non-negative number, got %v", ps.MaxChange)
+	case !validMultiplier(ps.Base.CollateralMultiplier), !validMultiplier(ps.Min.CollateralMultiplier), !validMultiplier(ps.Max.CollateralMultiplier):
+		return errors.New("collateral multipliers must be non-negative numbers")
+	}
+
+	if r := ps.Utilization; r != nil {
+		if !validMultiplier(r.EmptyMultiplier) || !validMultiplier(r.FullMultiplier) || !validMultiplier(r.FullCollateralMultiplier) {
+			return errors.New("utilization multipliers must be non-negative numbers")
+		}
+	}
+	if r := ps.ExchangeRate; r != nil {
+		u, err := url.Parse(r.URL)
+		if err != nil {
+			return fmt.Errorf("invalid exchange rate url %q: %w", r.URL, err)
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid exchange rate url %q: scheme must be http or https", r.URL)
+		}
+		for _, target := range []float64{r.ContractPrice, r.StoragePrice, r.EgressPrice, r.IngressPrice} {
+			if !validMultiplier(target) {
+				return errors.New("exchange rate targets must be non-negative numbers")
+			}
+		}
+	}
+	if r := ps.Demand; r != nil {
+		if r.Window <= 0 {
+			return errors.New("demand window must be positive")
+		} else if r.TargetContracts == 0 {
+			return errors.New("demand target contracts must be positive")
+		} else if !validMultiplier(r.Sensitivity) {
+			return fmt.Errorf("demand sensitivity must be a non-negative number, got %v", r.Sensitivity)
+		}
+	}
+	return nil
+}
+
+// TargetPrices returns the prices computed by the rules before bounds and
+// rate-of-change limits are applied.
+func (ps PricingEngineSettings) TargetPrices(inputs PricingInputs) Prices {
+	base := ps.Base
+	if r := ps.ExchangeRate; r != nil && inputs.ExchangeRate > 0 {
+		if r.ContractPrice > 0 {
+			base.ContractPrice = mulFloat(types.Siacoins(1), r.ContractPrice/inputs.ExchangeRate)
+		}
+		if r.StoragePrice > 0 {
+			base.StoragePrice = mulFloat(types.Siacoins(1), r.StoragePrice/inputs.ExchangeRate).Div64(1 << 40).Div64(blocksPerMonth)
+		}
+		if r.EgressPrice > 0 {
+			base.EgressPrice = mulFloat(types.Siacoins(1), r.EgressPrice/inputs.ExchangeRate).Div64(1 << 40)
+		}
+		if r.IngressPrice > 0 {
+			base.IngressPrice = mulFloat(types.Siacoins(1), r.IngressPrice/inputs.ExchangeRate).Div64(1 << 40)
+		}
+	}
+
+	multiplier := 1.0
+	if r := ps.Utilization; r != nil {
+		multiplier *= r.EmptyMultiplier + (r.FullMultiplier-r.EmptyMultiplier)*inputs.Utilization
+		if r.FullCollateralMultiplier > 0 {
+			base.CollateralMultiplier += (r.FullCollateralMultiplier - base.CollateralMultiplier) * inputs.Utilization
+		}
+	}
+	if r := ps.Demand; r != nil && r.TargetContracts > 0 {
+		deviation := (float64(inputs.Demand) - float64(r.TargetContracts)) / float64(r.TargetContracts)
+		multiplier *= math.Max(minDemandMultiplier, math.Min(maxDemandMultiplier, 1+r.Sensitivity*deviation))
+	}
+
+	return Prices{
+		ContractPrice:        mulFloat(base.ContractPrice, multiplier),
+		BaseRPCPrice:         mulFloat(base.BaseRPCPrice, multiplier),
+		SectorAccessPrice:    mulFloat(base.SectorAccessPrice, multiplier),
+		StoragePrice:         mulFloat(base.StoragePrice, multiplier),
+		EgressPrice:          mulFloat(base.EgressPrice, multiplier),
+		IngressPrice:         mulFloat(base.IngressPrice, multiplier),
+		CollateralMultiplier: base.CollateralMultiplier,
+	}
+}
+
+// NextPrices returns the prices the host should use after an adjustment. The
+// change from the current prices is limited by MaxChange and the result is
+// bounded by Min and Max.
+func (ps PricingEngineSettings) NextPrices(current Prices, inputs PricingInputs) Prices {
+	target := ps.TargetPrices(inputs)
+
+	limitCurrency := func(current, target, min, max types.Currency) types.Currency {
+		if ps.MaxChange > 0 && !current.IsZero() {
+			if lower := mulFloat(current, math.Max(0, 1-ps.MaxChange)); target.Cmp(lower) < 0 {
+				target = lower
+			} else if upper := mulFloat(current, 1+ps.MaxChange); target.Cmp(upper) > 0 {
+				target = upper
+			}
+		}
+		if !min.IsZero() && target.Cmp(min) < 0 {
+			target = min
+		} else if !max.IsZero() && target.Cmp(max) > 0 {
+			target = max
+		}
+		return target
+	}
+	limitFloat := func(current, target, min, max float64) float64 {
+		if ps.MaxChange > 0 && current != 0 {
+			target = math.Max(current*math.Max(0, 1-ps.MaxChange), math.Min(current*(1+ps.MaxChange), target))
+		}
+		if min != 0 && target < min {
+			target = min
+		} else if max != 0 && target > max {
+			target = max
+		}
+		return target
+	}
+
+	return Prices{
+		ContractPrice:        limitCurrency(current.ContractPrice, target.ContractPrice, ps.Min.ContractPrice, ps.Max.ContractPrice),
+		BaseRPCPrice:         limitCurrency(current.BaseRPCPrice, target.BaseRPCPrice, ps.Min.BaseRPCPrice, ps.Max.BaseRPCPrice),
+		SectorAccessPrice:    limitCurrency(current.SectorAccessPrice, target.SectorAccessPrice, ps.Min.SectorAccessPrice, ps.Max.SectorAccessPrice),
+		StoragePrice:         limitCurrency(current.StoragePrice, target.StoragePrice, ps.Min.StoragePrice, ps.Max.StoragePrice),
+		EgressPrice:          limitCurrency(current.EgressPrice, target.EgressPrice, ps.Min.EgressPrice, ps.Max.EgressPrice),
+		IngressPrice:         limitCurrency(current.IngressPrice, target.IngressPrice, ps.Min.IngressPrice, ps.Max.IngressPrice),
+		CollateralMultiplier: limitFloat(current.CollateralMultiplier, target.CollateralMultiplier, ps.Min.CollateralMultiplier, ps.Max.CollateralMultiplier),
+	}
+}
+
+// Prices returns the prices of the settings.
+func (s Settings) Prices() Prices {
+	return Prices{
+		ContractPrice:        s.ContractPrice,
+		BaseRPCPrice:         s.BaseRPCPrice,
+		SectorAccessPrice:    s.SectorAccessPrice,
+		StoragePrice:         s.StoragePrice,
+		EgressPrice:          s.EgressPrice,
+		IngressPrice:         s.IngressPrice,
+		CollateralMultiplier: s.CollateralMultiplier,
+	}
+}
+
+// setPrices updates the host's prices without changing any other settings.
+func (m *ConfigManager) setPrices(p Prices) (Settings, error) {
+	m.mu.Lock()
+	s := m.settings
+	s.ContractPrice = p.ContractPrice
+	s.BaseRPCPrice = p.BaseRPCPrice
+	s.SectorAccessPrice = p.SectorAccessPrice
+	s.StoragePrice = p.StoragePrice
+	s.EgressPrice = p.EgressPrice
+	s.IngressPrice = p.IngressPrice
+	s.CollateralMultiplier = p.CollateralMultiplier
+	// persist the prices before applying them so a failed write does not
+	// leave the host using prices that are lost on restart. The mutex is held
+	// to prevent a concurrent update from being overwritten.
+	if err := m.store.UpdateSettings(s, SourcePricingEngine); err != nil {
+		m.mu.Unlock()
+		return Settings{}, err
+	}
+	old := m.settings
+	m.settings = s
+	m.mu.Unlock()
+	m.checkPriceChanges(old, s)
+	return s, nil
+}
+
+// mulFloat multiplies a currency by a non-negative float. The result is
+// rounded down.
+func mulFloat(c types.Currency, f float64) types.Currency {
+	if !validMultiplier(f) {
+		return types.ZeroCurrency
+	}
+	r := new(big.Rat).SetFloat64(f)
+	r.Mul(r, new(big.Rat).SetInt(c.Big()))
+	n := new(big.Int).Quo(r.Num(), r.Denom())
+	if n.BitLen() > 128 {
+		return types.MaxCurrency
+	}
+	return types.NewCurrency(n.Uint64(), new(big.Int).Rsh(n, 64).Uint64())
+}
+
+// fetchExchangeRate returns the value of one siacoin from an exchange rate
+// feed. The response body must be a JSON number.
+func fetchExchangeRate(ctx context.Context, feedURL string) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, exchangeRateTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+
+	var rate float64
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1024)).Decode(&rate); err != nil {
+		return 0, fmt.Errorf("failed to decode exchange rate: %w", err)
+	} else if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+		return 0, fmt.Errorf("invalid exchange rate %v", rate)
+	}
+	return rate, nil
+}
+
+// pricingInputs collects the inputs of the enabled rules.
+func (pe *PricingEngine) pricingInputs(ctx context.Context, ps PricingEngineSettings) (inputs PricingInputs, err error) {
+	if ps.Utilization != nil {
+		used, total, err := pe.storage.Usage()
+		if err != nil {
+			return PricingInputs{}, fmt.Errorf("failed to get storage usage: %w", err)
+		} else if total > 0 {
+			inputs.Utilization = math.Min(1, float64(used)/float64(total))
+		}
+	}
+
+	if ps.ExchangeRate != nil {
+		inputs.ExchangeRate, err = fetchExchangeRate(ctx, ps.ExchangeRate.URL)
+		if err != nil {
+			return PricingInputs{}, fmt.Errorf("failed to get exchange rate: %w", err)
+		}
+	}
+
+	if ps.Demand != nil {
+		now := time.Now()
+		current, err := pe.store.Metrics(now)
+		if err != nil {
+			return PricingInputs{}, fmt.Errorf("failed to get current metrics: %w", err)
+		}
+		previous, err := pe.store.Metrics(now.Add(-ps.Demand.Window))
+		if err != nil {
+			return PricingInputs{}, fmt.Errorf("failed to get previous metrics: %w", err)
+		}
+		// contracts only move between statuses after formation, so the
+		// change in the total is the number of contracts formed
+		total := func(c metrics.Contracts) uint64 {
+			return c.Pending + c.Active + c.Rejected + c.Failed + c.Successful
+		}
+		if t, p := total(current.Contracts), total(previous.Contracts); t > p {
+			inputs.Demand = t - p
+		}
+	}
+	return inputs, nil
+}
+
+// Adjust recomputes the host's prices from the pricing engine's rules. The
+// new prices are returned.
+func (pe *PricingEngine) Adjust(ctx context.Context) (Prices, error) {
+	ps := pe.cm.BaseSettings().PricingEngine
+	if !ps.Enabled {
+		return Prices{}, errors.New("pricing engine is disabled")
+	}
+
+	inputs, err := pe.pricingInputs(ctx, ps)
+	if err != nil {
+		return Prices{}, err
+	}
+
+	pe.mu.Lock()
+	pe.lastAdjustment = time.Now()
+	pe.mu.Unlock()
+
+	// profiles override prices temporarily, so the base prices are adjusted
+	current := pe.cm.BaseSettings().Prices()
+	next := ps.NextPrices(current, inputs)
+	if next == current {
+		return current, nil
+	}
+
+	if _, err := pe.cm.setPrices(next); err != nil {
+		return Prices{}, fmt.Errorf("failed to update prices: %w", err)
+	}
+	err = pe.store.AddPriceChange(metrics.PriceChange{
+		Pricing: metrics.Pricing{
+			ContractPrice:        next.ContractPrice,
+			IngressPrice:         next.IngressPrice,
+			EgressPrice:          next.EgressPrice,
+			BaseRPCPrice:         next.BaseRPCPrice,
+			SectorAccessPrice:    next.SectorAccessPrice,
+			StoragePrice:         next.StoragePrice,
+			CollateralMultiplier: next.CollateralMultiplier,
+		},
+		Utilization:  inputs.Utilization,
+		ExchangeRate: inputs.ExchangeRate,
+		Demand:       inputs.Demand,
+		Timestamp:    time.Now(),
+	})
+	if err != nil {
+		return Prices{}, fmt.Errorf("failed to record price change: %w", err)
+	}
+	pe.log.Info("adjusted prices", zap.Float64("utilization", inputs.Utilization), zap.Float64("exchangeRate", inputs.ExchangeRate), zap.Uint64("demand", inputs.Demand), zap.Stringer("storagePrice", next.StoragePrice), zap.Float64("collateralMultiplier", next.CollateralMultiplier))
+	return next, nil
+}
+
+func (pe *PricingEngine) run() {
+	t := time.NewTicker(pricingCheckInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-pe.tg.Done():
+			return
+		case <-t.C:
+		}
+
+		ps := pe.cm.BaseSettings().PricingEngine
+		pe.mu.Lock()
+		due := time.Since(pe.lastAdjustment) >= ps.Interval
+		pe.mu.Unlock()
+		if !ps.Enabled || !due {
+			continue
+		}
+
+		ctx, cancel, err := pe.tg.AddContext(context.Background())
+		if err != nil {
+			return
+		}
+		if _, err := pe.Adjust(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			pe.log.Error("failed to adjust prices", zap.Error(err))
+			// wait for the next interval before retrying
+			pe.mu.Lock()
+			pe.lastAdjustment = time.Now()
+			pe.mu.Unlock()
+		}
+		cancel()
+	}
+}
+
+// Close stops the pricing engine.
+func (pe *PricingEngine) Close() error {
+	pe.tg.Stop()
+	return nil
+}
+
+// NewPricingEngine initializes a new pricing engine. Prices are only adjusted
+// while the engine is enabled in the host's settings.
+func NewPricingEngine(cm *ConfigManager, storage StorageUsage, store PricingStore, log *zap.Logger) *PricingEngine {
+	pe := &PricingEngine{
+		tg:  threadgroup.New(),
+		log: log,
+
+		cm:      cm,
+		storage: storage,
+		store:   store,
+	}
+	go pe.run()
+	return pe
+}
@@ -0,0 +1,133 @@
+package settings_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/alerts"
+	"go.sia.tech/hostd/host/settings"
+	"go.sia.tech/hostd/internal/test"
+	"go.sia.tech/hostd/persist/sqlite"
+	"go.uber.org/zap/zaptest"
+	"lukechampine.com/frand"
+)
+
+type stubStorage struct {
+	used, total uint64
+}
+
+func (s stubStorage) Usage() (uint64, uint64, error) {
+	return s.used, s.total, nil
+}
+
+func TestPricingEngine(t *testing.T) {
+	hostKey := types.NewPrivateKeyFromSeed(frand.Bytes(32))
+	dir := t.TempDir()
+	log := zaptest.NewLogger(t)
+	node, err := test.NewWallet(hostKey, dir, log.Named("wallet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+
+	db, err := sqlite.OpenDatabase(filepath.Join(dir, "hostd.db"), log.Named("sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	manager, err := settings.NewConfigManager(dir, hostKey, "localhost:9882", db, alerts.NewManager(), node.ChainManager(), node.TPool(), node, node.Gateway(), log.Named("settings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	// stub the exchange rate feed
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "0.004")
+	}))
+	defer feed.Close()
+
+	pe := settings.NewPricingEngine(manager, stubStorage{used: 50, total: 100}, db, log.Named("pricing"))
+	defer pe.Close()
+
+	if _, err := pe.Adjust(context.Background()); err == nil {
+		t.Fatal("expected error when the pricing engine is disabled")
+	}
+
+	current := manager.Settings()
+	current.PricingEngine = settings.PricingEngineSettings{
+		Enabled:   true,
+		Interval:  time.Hour,
+		MaxChange: 0.1,
+		Base:      settings.DefaultSettings.Prices(),
+		Utilization: &settings.UtilizationRule{
+			EmptyMultiplier:          1,
+			FullMultiplier:           2,
+			FullCollateralMultiplier: 1,
+		},
+		ExchangeRate: &settings.ExchangeRateRule{
+			URL:          feed.URL,
+			StoragePrice: 1, // 250 SC / TiB / month
+		},
+	}
+	if err := manager.UpdateSettings(current); err != nil {
+		t.Fatal(err)
+	}
+
+	// the target storage price is 375 SC / TiB / month, but the change is
+	// limited to 10%
+	prices, err := pe.Adjust(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedStorage := settings.DefaultSettings.StoragePrice.Mul64(11).Div64(10)
+	if !prices.StoragePrice.Equals(expectedStorage) {
+		t.Fatalf("expected storage price %v, got %v", expectedStorage, prices.StoragePrice)
+	} else if prices.CollateralMultiplier != 1.8 {
+		t.Fatalf("expected collateral multiplier 1.8, got %v", prices.CollateralMultiplier)
+	} else if !manager.Settings().StoragePrice.Equals(prices.StoragePrice) {
+		t.Fatal("expected settings to be updated")
+	}
+
+	// the adjustment should be recorded as a pricing engine revision
+	if revisions, err := manager.History(1, 0); err != nil {
+		t.Fatal(err)
+	} else if len(revisions) != 1 || revisions[0].Source != settings.SourcePricingEngine {
+		t.Fatalf("expected pricing engine revision, got %+v", revisions)
+	}
+
+	// remove the rate limit and bound the storage price
+	maxStorage := types.Siacoins(200).Div64(1 << 40).Div64(144 * 30)
+	current = manager.Settings()
+	current.PricingEngine.MaxChange = 0
+	current.PricingEngine.Max.StoragePrice = maxStorage
+	if err := manager.UpdateSettings(current); err != nil {
+		t.Fatal(err)
+	}
+	prices, err = pe.Adjust(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if !prices.StoragePrice.Equals(maxStorage) {
+		t.Fatalf("expected storage price %v, got %v", maxStorage, prices.StoragePrice)
+	} else if prices.CollateralMultiplier != 1.5 {
+		t.Fatalf("expected collateral multiplier 1.5, got %v", prices.CollateralMultiplier)
+	}
+
+	history, err := db.PriceHistory(time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(history) != 2 {
+		t.Fatalf("expected 2 price changes, got %v", len(history))
+	} else if !history[1].Pricing.StoragePrice.Equals(maxStorage) {
+		t.Fatalf("expected storage price %v, got %v", maxStorage, history[1].Pricing.StoragePrice)
+	} else if history[1].ExchangeRate != 0.004 || history[1].Utilization != 0.5 {
+		t.Fatalf("unexpected inputs %v %v", history[1].ExchangeRate, history[1].Utilization)
+	}
+}
@@ -0,0 +1,191 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// profileCheckInterval is the interval at which the active settings
+	// profile is recomputed.
+	profileCheckInterval = time.Minute
+	// maxProfileDuration is the maximum time a profile can stay active after
+	// its schedule matches.
+	maxProfileDuration = 7 * 24 * time.Hour
+)
+
+// ErrProfileNotFound is returned when a settings profile does not exist.
+var ErrProfileNotFound = errors.New("settings profile not found")
+
+var profileNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// A SettingsProfile overrides some of the host's settings while it is active.
+// A profile becomes active when its schedule matches and stays active for
+// Duration. Nil overrides use the host's settings.
+type SettingsProfile struct {
+	Name string `json:"name"`
+	// Schedule is a cron expression in the host's local time zone.
+	Schedule string        `json:"schedule"`
+	Duration time.Duration `json:"duration"`
+	// Priority determines which profile is applied if multiple profiles are
+	// active. The profile with the highest priority is applied.
+	Priority int `json:"priority"`
+
+	AcceptingContracts *bool           `json:"acceptingContracts,omitempty"`
+	IngressLimit       *uint64         `json:"ingressLimit,omitempty"`
+	EgressLimit        *uint64         `json:"egressLimit,omitempty"`
+	IngressPrice       *types.Currency `json:"ingressPrice,omitempty"`
+	EgressPrice        *types.Currency `json:"egressPrice,omitempty"`
+}
+
+// Validate returns an error if the profile is invalid.
+func (p SettingsProfile) Validate() error {
+	if !profileNameRegex.MatchString(p.Name) {
+		return fmt.Errorf("invalid profile name %q: must only contain letters, numbers, underscores and dashes", p.Name)
+	} else if _, err := ParseSchedule(p.Schedule); err != nil {
+		return fmt.Errorf("invalid schedule %q: %w", p.Schedule, err)
+	} else if p.Duration < time.Minute || p.Duration > maxProfileDuration {
+		return fmt.Errorf("duration must be between %v and %v", time.Minute, maxProfileDuration)
+	}
+	return nil
+}
+
+// ActiveAt returns true if the profile's schedule matched within Duration
+// before t.
+func (p SettingsProfile) ActiveAt(t time.Time) bool {
+	schedule, err := ParseSchedule(p.Schedule)
+	if err != nil {
+		return false
+	}
+	t = t.Truncate(time.Minute)
+	for i := time.Duration(0); i < p.Duration; i += time.Minute {
+		if schedule.Matches(t.Add(-i)) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns the settings with the profile's overrides applied.
+func (p SettingsProfile) apply(s Settings) Settings {
+	if p.AcceptingContracts != nil {
+		s.AcceptingContracts = *p.AcceptingContracts
+	}
+	if p.IngressLimit != nil {
+		s.IngressLimit = *p.IngressLimit
+	}
+	if p.EgressLimit != nil {
+		s.EgressLimit = *p.EgressLimit
+	}
+	if p.IngressPrice != nil {
+		s.IngressPrice = *p.IngressPrice
+	}
+	if p.EgressPrice != nil {
+		s.EgressPrice = *p.EgressPrice
+	}
+	return s
+}
+
+// effectiveSettings returns the host's settings with the active profile
+// applied. The caller must hold the mutex.
+func (m *ConfigManager) effectiveSettings() Settings {
+	for _, p := range m.profiles {
+		if p.Name == m.activeProfile {
+			return p.apply(m.settings)
+		}
+	}
+	return m.settings
+}
+
+// refreshProfiles recomputes the active profile and applies the resulting
+// settings. The caller must hold the mutex.
+func (m *ConfigManager) refreshProfiles(now time.Time) {
+	prevProfile := m.activeProfile
+
+	// profiles are sorted by priority, so the first active profile wins
+	m.activeProfile = ""
+	for _, p := range m.profiles {
+		if p.ActiveAt(now) {
+			m.activeProfile = p.Name
+			break
+		}
+	}
+
+	current := m.effectiveSettings()
+	m.setRateLimit(current.IngressLimit, current.EgressLimit)
+	if m.activeProfile != prevProfile {
+		m.log.Info("settings profile changed", zap.String("previous", prevProfile), zap.String("active", m.activeProfile))
+	}
+}
+
+func (m *ConfigManager) checkProfiles() {
+	m.mu.Lock()
+	m.refreshProfiles(time.Now())
+	m.mu.Unlock()
+	m.profileTimer.Reset(profileCheckInterval)
+}
+
+// setProfiles sorts and sets the host's settings profiles. The caller must
+// hold the mutex.
+func (m *ConfigManager) setProfiles(profiles []SettingsProfile) {
+	sort.SliceStable(profiles, func(i, j int) bool {
+		if profiles[i].Priority != profiles[j].Priority {
+			return profiles[i].Priority > profiles[j].Priority
+		}
+		return profiles[i].Name < profiles[j].Name
+	})
+	m.profiles = profiles
+	m.refreshProfiles(time.Now())
+}
+
+// Profiles returns the host's settings profiles and the name of the active
+// profile. If no profile is active, the name is empty.
+func (m *ConfigManager) Profiles() ([]SettingsProfile, string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]SettingsProfile(nil), m.profiles...), m.activeProfile
+}
+
+// UpdateProfile adds or replaces a settings profile.
+func (m *ConfigManager) UpdateProfile(p SettingsProfile) error {
+	if err := p.Validate(); err != nil {
+		return err
+	} else if err := m.store.UpdateSettingsProfile(p); err != nil {
+		return fmt.Errorf("failed to update settings profile: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	profiles := []SettingsProfile{p}
+	for _, existing := range m.profiles {
+		if existing.Name != p.Name {
+			profiles = append(profiles, existing)
+		}
+	}
+	m.setProfiles(profiles)
+	return nil
+}
+
+// RemoveProfile removes a settings profile.
+func (m *ConfigManager) RemoveProfile(name string) error {
+	if err := m.store.DeleteSettingsProfile(name); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var profiles []SettingsProfile
+	for _, existing := range m.profiles {
+		if existing.Name != name {
+			profiles = append(profiles, existing)
+		}
+	}
+	m.setProfiles(profiles)
+	return nil
+}
@@ -0,0 +1,158 @@
+package settings_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/alerts"
+	"go.sia.tech/hostd/host/settings"
+	"go.sia.tech/hostd/internal/test"
+	"go.sia.tech/hostd/persist/sqlite"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/time/rate"
+	"lukechampine.com/frand"
+)
+
+func TestSchedule(t *testing.T) {
+	// Wednesday, January 3rd 2024
+	wed := time.Date(2024, 1, 3, 22, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		expr    string
+		t       time.Time
+		matches bool
+	}{
+		{"* * * * *", wed, true},
+		{"30 22 * * *", wed, true},
+		{"0 22 * * *", wed, false},
+		{"*/15 18-23 * * *", wed, true},
+		{"*/20 18-23 * * *", wed, false},
+		{"30 22 * * 1-5", wed, true},
+		{"30 22 * * 0,6", wed, false},
+		{"30 22 * * 7", wed.AddDate(0, 0, 4), true},
+		// if both the day of month and day of week are restricted, either
+		// can match
+		{"30 22 1 * 3", wed, true},
+		{"30 22 1 * 4", wed, false},
+		{"30 22 3 2 *", wed, false},
+	}
+	for _, tt := range tests {
+		s, err := settings.ParseSchedule(tt.expr)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", tt.expr, err)
+		} else if s.Matches(tt.t) != tt.matches {
+			t.Fatalf("expected %q matches %v to be %v", tt.expr, tt.t, tt.matches)
+		}
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := settings.ParseSchedule(expr); err == nil {
+			t.Fatalf("expected error parsing %q", expr)
+		}
+	}
+}
+
+func TestSettingsProfiles(t *testing.T) {
+	hostKey := types.NewPrivateKeyFromSeed(frand.Bytes(32))
+	dir := t.TempDir()
+	log := zaptest.NewLogger(t)
+	node, err := test.NewWallet(hostKey, dir, log.Named("wallet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+
+	db, err := sqlite.OpenDatabase(filepath.Join(dir, "hostd.db"), log.Named("sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	manager, err := settings.NewConfigManager(dir, hostKey, "localhost:9882", db, alerts.NewManager(), node.ChainManager(), node.TPool(), node, node.Gateway(), log.Named("settings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	base := manager.Settings()
+	base.IngressLimit = 1 << 20
+	if err := manager.UpdateSettings(base); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.UpdateProfile(settings.SettingsProfile{Name: "bad name", Schedule: "* * * * *", Duration: time.Hour}); err == nil {
+		t.Fatal("expected invalid name error")
+	} else if err := manager.UpdateProfile(settings.SettingsProfile{Name: "bad", Schedule: "* * *", Duration: time.Hour}); err == nil {
+		t.Fatal("expected invalid schedule error")
+	}
+
+	// add a profile that never matches and one that is always active
+	ingressLimit := uint64(1 << 10)
+	egressPrice := types.Siacoins(1)
+	inactive := settings.SettingsProfile{
+		Name:         "inactive",
+		Schedule:     "0 0 31 2 *",
+		Duration:     time.Minute,
+		Priority:     10,
+		IngressLimit: &ingressLimit,
+	}
+	active := settings.SettingsProfile{
+		Name:         "active",
+		Schedule:     "* * * * *",
+		Duration:     time.Hour,
+		IngressLimit: &ingressLimit,
+		EgressPrice:  &egressPrice,
+	}
+	for _, p := range []settings.SettingsProfile{inactive, active} {
+		if err := manager.UpdateProfile(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ingress, _ := manager.BandwidthLimiters()
+	profiles, activeName := manager.Profiles()
+	if len(profiles) != 2 {
+		t.Fatalf("expected 2 profiles, got %v", len(profiles))
+	} else if profiles[0].Name != inactive.Name {
+		t.Fatalf("expected profiles to be sorted by priority, got %v", profiles[0].Name)
+	} else if activeName != active.Name {
+		t.Fatalf("expected active profile %q, got %q", active.Name, activeName)
+	} else if s := manager.Settings(); s.IngressLimit != ingressLimit || !s.EgressPrice.Equals(egressPrice) {
+		t.Fatal("expected profile overrides to be applied")
+	} else if s := manager.BaseSettings(); s.IngressLimit != base.IngressLimit || !s.EgressPrice.Equals(base.EgressPrice) {
+		t.Fatal("expected base settings to be unchanged")
+	} else if ingress.Limit() != rate.Limit(ingressLimit) {
+		t.Fatalf("expected ingress limit %v, got %v", ingressLimit, ingress.Limit())
+	}
+
+	// profiles should be loaded from the store
+	manager.Close()
+	manager, err = settings.NewConfigManager(dir, hostKey, "localhost:9882", db, alerts.NewManager(), node.ChainManager(), node.TPool(), node, node.Gateway(), log.Named("settings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	if _, activeName := manager.Profiles(); activeName != active.Name {
+		t.Fatalf("expected active profile %q, got %q", active.Name, activeName)
+	}
+
+	// removing the profile should restore the base settings
+	if err := manager.RemoveProfile(active.Name); err != nil {
+		t.Fatal(err)
+	} else if err := manager.RemoveProfile(active.Name); !errors.Is(err, settings.ErrProfileNotFound) {
+		t.Fatalf("expected ErrProfileNotFound, got %v", err)
+	}
+
+	ingress, _ = manager.BandwidthLimiters()
+	if _, activeName := manager.Profiles(); activeName != "" {
+		t.Fatalf("expected no active profile, got %q", activeName)
+	} else if s := manager.Settings(); s.IngressLimit != base.IngressLimit || !s.EgressPrice.Equals(base.EgressPrice) {
+		t.Fatal("expected base settings to be restored")
+	} else if ingress.Limit() != rate.Limit(base.IngressLimit) {
+		t.Fatalf("expected ingress limit %v, got %v", base.IngressLimit, ingress.Limit())
+	}
+}
@@ -0,0 +1,93 @@
+package settings
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Schedule is a parsed cron expression with the fields minute, hour, day of
+// month, month, and day of week. Each field can be "*", a number, a range
+// "a-b", a step "*/n" or "a-b/n", or a comma-separated list of those.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar are set if the day of month or day of week is
+	// unrestricted. If both are restricted, either can match.
+	domStar, dowStar bool
+}
+
+func parseCronField(field string, min, max int) (bits uint64, star bool, err error) {
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			rangePart = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, false, fmt.Errorf("invalid step %q", part)
+			}
+		}
+
+		lo, hi := min, max
+		switch {
+		case rangePart == "*":
+			star = star || step == 1
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, false, fmt.Errorf("invalid range %q", part)
+			} else if hi, err = strconv.Atoi(bounds[1]); err != nil {
+				return 0, false, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			if lo, err = strconv.Atoi(rangePart); err != nil {
+				return 0, false, fmt.Errorf("invalid value %q", part)
+			}
+			hi = lo
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, false, fmt.Errorf("value %q out of range %d-%d", part, min, max)
+		}
+		for i := lo; i <= hi; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+	return bits, star, nil
+}
+
+// ParseSchedule parses a cron expression.
+func ParseSchedule(expr string) (s Schedule, err error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+	if s.minute, _, err = parseCronField(fields[0], 0, 59); err != nil {
+		return Schedule{}, fmt.Errorf("invalid minute: %w", err)
+	} else if s.hour, _, err = parseCronField(fields[1], 0, 23); err != nil {
+		return Schedule{}, fmt.Errorf("invalid hour: %w", err)
+	} else if s.dom, s.domStar, err = parseCronField(fields[2], 1, 31); err != nil {
+		return Schedule{}, fmt.Errorf("invalid day of month: %w", err)
+	} else if s.month, _, err = parseCronField(fields[3], 1, 12); err != nil {
+		return Schedule{}, fmt.Errorf("invalid month: %w", err)
+	} else if s.dow, s.dowStar, err = parseCronField(fields[4], 0, 7); err != nil {
+		return Schedule{}, fmt.Errorf("invalid day of week: %w", err)
+	}
+	// 7 is an alias for Sunday
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+// Matches returns true if the schedule matches the minute of t.
+func (s Schedule) Matches(t time.Time) bool {
+	if s.minute&(1<<uint(t.Minute())) == 0 || s.hour&(1<<uint(t.Hour())) == 0 || s.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -6,12 +6,18 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"reflect"
 	"sync"
 	"time"
 
 	"go.sia.tech/core/consensus"
 	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/chain"
+	"go.sia.tech/hostd/host/alerts"
+	"go.sia.tech/hostd/internal/threadgroup"
+	"go.sia.tech/siad/modules"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/acme"
 	"golang.org/x/time/rate"
 )
 
@@ -24,6 +30,10 @@ const (
 	defaultBurstSize = 256 * (1 << 20) // 256 MiB
 
 	dnsUpdateFrequency = 30 * time.Second
+
+	// minScrubRate is the lowest non-zero scrub rate. At lower rates a
+	// large volume cannot be scrubbed within the scrub interval.
+	minScrubRate = 1 << 20 // 1 MiB/s
 )
 
 type (
@@ -32,8 +42,57 @@ type (
 		// Settings returns the host's current settings. If the host has no
 		// settings yet, ErrNoSettings must be returned.
 		Settings() (Settings, error)
-		// UpdateSettings updates the host's settings.
-		UpdateSettings(s Settings) error
+		// UpdateSettings updates the host's settings. Each update must be
+		// recorded as a new revision with its source.
+		UpdateSettings(s Settings, source string) error
+		// SettingsHistory returns the recorded revisions of the host's
+		// settings, newest first.
+		SettingsHistory(limit, offset int) ([]SettingsRevision, error)
+		// SettingsRevision returns a recorded revision of the host's
+		// settings. If the revision does not exist, ErrRevisionNotFound must
+		// be returned.
+		SettingsRevision(revision uint64) (SettingsRevision, error)
+
+		// SettingsProfiles returns the host's settings profiles.
+		SettingsProfiles() ([]SettingsProfile, error)
+		// UpdateSettingsProfile adds or replaces a settings profile.
+		UpdateSettingsProfile(SettingsProfile) error
+		// DeleteSettingsProfile removes a settings profile. If the profile
+		// does not exist, ErrProfileNotFound must be returned.
+		DeleteSettingsProfile(name string) error
+
+		// LastAnnouncement returns the host's last broadcast announcement. If
+		// the host has not announced, an empty announcement must be returned.
+		LastAnnouncement() (Announcement, error)
+		// AddAnnouncement records a broadcast announcement.
+		AddAnnouncement(Announcement) error
+		// LastAnnouncementChange returns the last consensus change processed
+		// by the config manager. If no change has been processed, the last
+		// change processed by the contract manager should be returned.
+		LastAnnouncementChange() (modules.ConsensusChangeID, error)
+		// UpdateAnnouncements atomically unconfirms the reverted announcements,
+		// adds or confirms the confirmed announcements, and sets the last
+		// processed consensus change.
+		UpdateAnnouncements(ccID modules.ConsensusChangeID, reverted []types.TransactionID, confirmed []Announcement) error
+
+		// AddDDNSAttempt records a dynamic DNS update attempt.
+		AddDDNSAttempt(DDNSAttempt) error
+		// PruneDDNSAttempts removes the dynamic DNS update attempts recorded
+		// before the given time. The last successful attempt must be kept.
+		PruneDDNSAttempts(before time.Time) error
+		// DDNSAttempts returns the recorded dynamic DNS update attempts,
+		// newest first.
+		DDNSAttempts(limit, offset int) ([]DDNSAttempt, error)
+		// LastDDNSAttempt returns the last successful or failed dynamic DNS
+		// update attempt. If there is no such attempt, an empty attempt must
+		// be returned.
+		LastDDNSAttempt(success bool) (DDNSAttempt, error)
+	}
+
+	// Alerts registers and dismisses global alerts.
+	Alerts interface {
+		Register(alerts.Alert)
+		Dismiss(...types.Hash256)
 	}
 
 	// Settings contains configuration options for the host.
@@ -72,8 +131,66 @@ type (
 		// DNS settings
 		DDNS DNSSettings `json:"ddns"`
 
+		// ACME configures obtaining the RHP3 WebSocket certificate from an
+		// ACME certificate authority.
+		ACME ACMESettings `json:"acme"`
+
 		SectorCacheSize uint32 `json:"sectorCacheSize"`
 
+		// Tiering settings. Sectors in cold volumes that were accessed within
+		// TierPromoteWindow are moved to hot volumes. Sectors in hot volumes
+		// that have not been accessed for TierDemoteAfter are moved to cold
+		// volumes. A zero duration disables the corresponding migration.
+		// TierMigrationLimit is the maximum number of bytes per second
+		// moved between tiers, 0 is unlimited.
+		TierPromoteWindow  time.Duration `json:"tierPromoteWindow"`
+		TierDemoteAfter    time.Duration `json:"tierDemoteAfter"`
+		TierMigrationLimit uint64        `json:"tierMigrationLimit"`
+
+		// ScrubRate is the maximum number of bytes per second read by the
+		// volume integrity scrubber, 0 is unlimited.
+		ScrubRate uint64 `json:"scrubRate"`
+
+		// Parity settings. If ParityScheme is set, groups of ParityDataShards
+		// sectors stored in different volumes are protected by ParityShards
+		// parity sectors so a failed volume can be rebuilt. An empty scheme
+		// disables parity.
+		ParityScheme     string `json:"parityScheme"`
+		ParityDataShards int    `json:"parityDataShards"`
+		ParityShards     int    `json:"parityShards"`
+
+		// Storage proof fee settings. Unconfirmed storage proofs are
+		// resubmitted every ProofResubmitInterval blocks with the miner fee
+		// increased by ProofFeeIncrease percent, up to ProofMaxFee. A zero
+		// ProofMaxFee only limits the fee to the host's benefit from
+		// submitting the proof.
+		ProofResubmitInterval uint64         `json:"proofResubmitInterval"`
+		ProofFeeIncrease      uint64         `json:"proofFeeIncrease"`
+		ProofMaxFee           types.Currency `json:"proofMaxFee"`
+
+		// ContractWebhooks are the URLs that contract lifecycle events are
+		// posted to.
+		ContractWebhooks []string `json:"contractWebhooks"`
+
+		// PricingEngine configures the automatic adjustment of the host's
+		// prices.
+		PricingEngine PricingEngineSettings `json:"pricingEngine"`
+
+		// PriceAlertThreshold is the percentage a settings change can raise
+		// a price or lower collateral by before an alert is registered. If
+		// zero, no alerts are registered.
+		PriceAlertThreshold uint64 `json:"priceAlertThreshold"`
+
+		// AnnounceInterval is the number of blocks after which the host is
+		// automatically re-announced. The host is also re-announced when its
+		// address or certificate changes. If zero, the host is never
+		// re-announced automatically.
+		AnnounceInterval uint64 `json:"announceInterval"`
+		// AnnounceConfirmationBlocks is the number of blocks a broadcast
+		// announcement has to confirm before an alert is registered. If zero,
+		// no alert is registered.
+		AnnounceConfirmationBlocks uint64 `json:"announceConfirmationBlocks"`
+
 		Revision uint64 `json:"revision"`
 	}
 
@@ -86,6 +203,14 @@ type (
 	// A ChainManager manages the current consensus state
 	ChainManager interface {
 		TipState() consensus.State
+		Subscribe(s modules.ConsensusSetSubscriber, ccID modules.ConsensusChangeID, cancel <-chan struct{}) error
+	}
+
+	// A Syncer is connected to peers on the Sia network. The peers are used
+	// to discover the host's public IP addresses.
+	Syncer interface {
+		Peers() []modules.Peer
+		RPC(addr modules.NetAddress, name string, fn modules.RPCFunc) error
 	}
 
 	// A Wallet manages funds and signs transactions
@@ -100,24 +225,44 @@ type (
 		hostKey           types.PrivateKey
 		discoveredRHPAddr string
 
+		tg    *threadgroup.ThreadGroup
 		store Store
+		a     Alerts
 		log   *zap.Logger
 
 		cm     ChainManager
 		tp     TransactionPool
 		wallet Wallet
+		syncer Syncer
 
 		mu       sync.Mutex // guards the following fields
 		settings Settings   // in-memory cache of the host's settings
 
+		profiles      []SettingsProfile // sorted by priority
+		activeProfile string
+		profileTimer  *time.Timer
+
 		ingressLimit *rate.Limiter
 		egressLimit  *rate.Limiter
 
+		announceMu          sync.Mutex // ensures only one automatic announcement is in progress
+		announceReason      string     // set when a re-announcement is requested
+		lastAnnounceAttempt uint64
+		announceTimer       *time.Timer
+
 		ddnsUpdateTimer *time.Timer
 		lastIPv4        net.IP
 		lastIPv6        net.IP
-
-		rhp3WSTLS *tls.Config
+		publicIPv4      net.IP // detected by the last dynamic DNS check
+		publicIPv6      net.IP
+		lastDDNSCheck   time.Time
+		ddnsFailures    int // consecutive failed dynamic DNS checks
+
+		rhp3WSTLS         *tls.Config
+		rhp3Cert          *tls.Certificate
+		rhp3CertTemp      bool             // true if rhp3Cert is a temporary self-signed certificate
+		acmeChallengeCert *tls.Certificate // set while a TLS-ALPN-01 challenge is pending
+		certTimer         *time.Timer
 	}
 )
 
@@ -146,6 +291,25 @@ var (
 		WindowSize:        144,                 // 144 blocks
 
 		MaxRegistryEntries: 100000,
+
+		TierPromoteWindow:  24 * time.Hour,
+		TierDemoteAfter:    7 * 24 * time.Hour,
+		TierMigrationLimit: 32 * (1 << 20), // 32 MiB/s
+
+		ScrubRate: 32 * (1 << 20), // 32 MiB/s
+
+		ProofResubmitInterval: 3,  // 30 minutes
+		ProofFeeIncrease:      50, // 50% per resubmission
+
+		PriceAlertThreshold: 25,                 // 25%
+		AnnounceInterval:    3 * blocksPerMonth, // 3 months
+
+		AnnounceConfirmationBlocks: 18, // 3 hours
+
+		DDNS: DNSSettings{
+			AlertFailures:    10,
+			HistoryRetention: 7 * 24 * time.Hour, // 7 days
+		},
 	}
 	// ErrNoSettings must be returned by the store if the host has no settings yet
 	ErrNoSettings = errors.New("no settings found")
@@ -153,6 +317,15 @@ var (
 	specifierAnnouncement = types.NewSpecifier("HostAnnouncement")
 )
 
+// validateScrubRate returns an error if a non-zero scrub rate is too low to
+// complete a scrub in a reasonable time.
+func validateScrubRate(rate uint64) error {
+	if rate != 0 && rate < minScrubRate {
+		return fmt.Errorf("scrub rate must be 0 or at least %v bytes per second", minScrubRate)
+	}
+	return nil
+}
+
 // setRateLimit sets the bandwidth rate limit for the host
 func (m *ConfigManager) setRateLimit(ingress, egress uint64) {
 	var ingressLimit rate.Limit
@@ -173,8 +346,12 @@ func (m *ConfigManager) setRateLimit(ingress, egress uint64) {
 	m.egressLimit.SetLimit(rate.Limit(egressLimit))
 }
 
-// Close is a no-op
+// Close stops applying settings profiles and announcing the host
 func (m *ConfigManager) Close() error {
+	m.profileTimer.Stop()
+	m.announceTimer.Stop()
+	m.certTimer.Stop()
+	m.tg.Stop()
 	return nil
 }
 
@@ -211,27 +388,74 @@ func (m *ConfigManager) Announce() error {
 	if err != nil {
 		return fmt.Errorf("failed to broadcast transaction: %w", err)
 	}
+	if err := m.recordAnnouncement(txn, settings.NetAddress); err != nil {
+		return err
+	}
 	m.log.Debug("broadcast announcement", zap.String("transactionID", txn.ID().String()), zap.String("netaddress", settings.NetAddress), zap.String("cost", minerFee.ExactString()))
 	return nil
 }
 
 // UpdateSettings updates the host's settings.
 func (m *ConfigManager) UpdateSettings(s Settings) error {
+	return m.updateSettings(s, SourceOperator)
+}
+
+// updateSettings validates and applies the host's settings and records them
+// as a revision from source.
+func (m *ConfigManager) updateSettings(s Settings, source string) error {
 	// validate DNS settings
 	if err := validateDNSSettings(&s.DDNS); err != nil {
 		return fmt.Errorf("failed to validate DNS settings: %w", err)
 	}
+	// validate ACME settings
+	if err := validateACMESettings(&s.ACME, s.DDNS); err != nil {
+		return fmt.Errorf("failed to validate ACME settings: %w", err)
+	}
+	// validate pricing engine settings
+	if err := s.PricingEngine.Validate(); err != nil {
+		return fmt.Errorf("failed to validate pricing engine settings: %w", err)
+	}
+	// validate the scrub rate
+	if err := validateScrubRate(s.ScrubRate); err != nil {
+		return err
+	}
 
 	m.mu.Lock()
+	old := m.settings
 	m.settings = s
-	m.setRateLimit(s.IngressLimit, s.EgressLimit)
+	m.refreshProfiles(time.Now())
 	m.resetDDNS()
 	m.mu.Unlock()
-	return m.store.UpdateSettings(s)
+	if err := m.store.UpdateSettings(s, source); err != nil {
+		return err
+	}
+	m.checkPriceChanges(old, s)
+
+	// obtain a new certificate if the hostname or ACME settings changed. DNS-01
+	// challenges are also retried when the DNS provider changes.
+	acmeChanged := !reflect.DeepEqual(old.ACME, s.ACME)
+	dnsChanged := s.ACME.Challenge == ACMEChallengeDNS01 && !reflect.DeepEqual(old.DDNS, s.DDNS)
+	if acmeChanged || (s.ACME.Enabled && (old.NetAddress != s.NetAddress || dnsChanged)) {
+		m.certTimer.Reset(0)
+	}
+
+	if old.NetAddress != s.NetAddress {
+		go m.autoAnnounce()
+	}
+	return nil
 }
 
-// Settings returns the host's current settings.
+// Settings returns the host's current settings with the active settings
+// profile applied.
 func (m *ConfigManager) Settings() Settings {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.effectiveSettings()
+}
+
+// BaseSettings returns the host's configured settings without the active
+// settings profile applied.
+func (m *ConfigManager) BaseSettings() Settings {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	return m.settings
@@ -270,33 +494,35 @@ func createAnnouncement(priv types.PrivateKey, netaddress string) []byte {
 }
 
 // NewConfigManager initializes a new config manager
-func NewConfigManager(dir string, hostKey types.PrivateKey, rhp2Addr string, store Store, cm ChainManager, tp TransactionPool, w Wallet, log *zap.Logger) (*ConfigManager, error) {
+func NewConfigManager(dir string, hostKey types.PrivateKey, rhp2Addr string, store Store, a Alerts, cm ChainManager, tp TransactionPool, w Wallet, s Syncer, log *zap.Logger) (*ConfigManager, error) {
 	m := &ConfigManager{
 		dir:               dir,
 		hostKey:           hostKey,
 		discoveredRHPAddr: rhp2Addr,
 
+		tg:     threadgroup.New(),
 		store:  store,
+		a:      a,
 		log:    log,
 		cm:     cm,
 		tp:     tp,
 		wallet: w,
+		syncer: s,
 
 		// initialize the rate limiters
 		ingressLimit: rate.NewLimiter(rate.Inf, defaultBurstSize),
 		egressLimit:  rate.NewLimiter(rate.Inf, defaultBurstSize),
-
-		// rhp3 WebSocket TLS
-		rhp3WSTLS: &tls.Config{},
 	}
-
-	if err := m.reloadCertificates(); err != nil {
-		return nil, fmt.Errorf("failed to load rhp3 WebSocket certificates: %w", err)
+	// rhp3 WebSocket TLS. The certificate is returned by getCertificate so
+	// it can be swapped while the listener is running.
+	m.rhp3WSTLS = &tls.Config{
+		GetCertificate: m.getCertificate,
+		NextProtos:     []string{"http/1.1", acme.ALPNProto},
 	}
 
 	settings, err := m.store.Settings()
 	if errors.Is(err, ErrNoSettings) {
-		if err := store.UpdateSettings(DefaultSettings); err != nil {
+		if err := store.UpdateSettings(DefaultSettings, SourceDefault); err != nil {
 			return nil, fmt.Errorf("failed to initialize settings: %w", err)
 		}
 		settings = DefaultSettings // use the default settings
@@ -304,10 +530,42 @@ func NewConfigManager(dir string, hostKey types.PrivateKey, rhp2Addr string, sto
 		return nil, fmt.Errorf("failed to load settings: %w", err)
 	}
 
+	profiles, err := m.store.SettingsProfiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings profiles: %w", err)
+	}
+
 	m.settings = settings
-	// update the global rate limiters from settings
-	m.setRateLimit(settings.IngressLimit, settings.EgressLimit)
+	if err := m.reloadCertificates(); err != nil {
+		return nil, fmt.Errorf("failed to load rhp3 WebSocket certificates: %w", err)
+	}
+
+	changeID, err := m.store.LastAnnouncementChange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last announcement change: %w", err)
+	} else if changeID == modules.ConsensusChangeBeginning {
+		// only announcements broadcast by the host are tracked, there is no
+		// need to scan the whole chain
+		changeID = modules.ConsensusChangeRecent
+	}
+	if err := m.cm.Subscribe(m, changeID, m.tg.Done()); errors.Is(err, chain.ErrInvalidChangeID) {
+		m.log.Warn("invalid consensus change, resubscribing from the current tip", zap.Stringer("changeID", changeID))
+		if err := m.cm.Subscribe(m, modules.ConsensusChangeRecent, m.tg.Done()); err != nil {
+			return nil, fmt.Errorf("failed to reset consensus change subscription: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to consensus set: %w", err)
+	}
+
+	// renew the ACME certificate immediately, then periodically reload the
+	// certificate
+	m.certTimer = time.AfterFunc(0, m.checkCertificates)
+	// apply the active profile and update the global rate limiters
+	m.setProfiles(profiles)
+	m.profileTimer = time.AfterFunc(profileCheckInterval, m.checkProfiles)
+	m.announceTimer = time.AfterFunc(announceCheckInterval, m.checkAnnouncement)
 	// initialize the DDNS update timer
 	m.resetDDNS()
+
 	return m, nil
 }
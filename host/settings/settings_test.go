@@ -1,11 +1,13 @@
 package settings_test
 
 import (
+	"errors"
 	"path/filepath"
 	"reflect"
 	"testing"
 
 	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/alerts"
 	"go.sia.tech/hostd/host/settings"
 	"go.sia.tech/hostd/internal/test"
 	"go.sia.tech/hostd/persist/sqlite"
@@ -29,7 +31,7 @@ func TestSettings(t *testing.T) {
 	}
 	defer db.Close()
 
-	manager, err := settings.NewConfigManager(dir, hostKey, "localhost:9882", db, node.ChainManager(), node.TPool(), node, log.Named("settings"))
+	manager, err := settings.NewConfigManager(dir, hostKey, "localhost:9882", db, alerts.NewManager(), node.ChainManager(), node.TPool(), node, node.Gateway(), log.Named("settings"))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -49,4 +51,144 @@ func TestSettings(t *testing.T) {
 	} else if !reflect.DeepEqual(manager.Settings(), updated) {
 		t.Fatal("settings not equal to updated")
 	}
+
+	// the scrub rate is either unlimited or at least 1 MiB/s
+	for _, tt := range []struct {
+		rate  uint64
+		valid bool
+	}{
+		{0, true},
+		{1 << 10, false},
+		{1 << 20, true},
+		{64 * (1 << 20), true},
+	} {
+		current := manager.Settings()
+		current.ScrubRate = tt.rate
+		if err := manager.UpdateSettings(current); (err == nil) != tt.valid {
+			t.Fatalf("scrub rate %v: expected valid %v, got %v", tt.rate, tt.valid, err)
+		} else if tt.valid && manager.Settings().ScrubRate != tt.rate {
+			t.Fatalf("expected scrub rate %v, got %v", tt.rate, manager.Settings().ScrubRate)
+		}
+	}
+}
+
+func TestSettingsHistory(t *testing.T) {
+	hostKey := types.NewPrivateKeyFromSeed(frand.Bytes(32))
+	dir := t.TempDir()
+	log := zaptest.NewLogger(t)
+	node, err := test.NewWallet(hostKey, dir, log.Named("wallet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+
+	db, err := sqlite.OpenDatabase(filepath.Join(dir, "hostd.db"), log.Named("sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	am := alerts.NewManager()
+	manager, err := settings.NewConfigManager(dir, hostKey, "localhost:9882", db, am, node.ChainManager(), node.TPool(), node, node.Gateway(), log.Named("settings"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Close()
+
+	initial := manager.Settings()
+
+	// raise the storage price by 50%
+	updated := initial
+	updated.StoragePrice = initial.StoragePrice.Mul64(3).Div64(2)
+	updated.WindowSize = 100
+	if err := manager.UpdateSettings(updated); err != nil {
+		t.Fatal(err)
+	} else if len(am.Active()) != 1 {
+		t.Fatalf("expected 1 alert, got %v", len(am.Active()))
+	}
+
+	history, err := manager.History(100, 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(history) != 2 {
+		t.Fatalf("expected 2 revisions, got %v", len(history))
+	}
+	latest := history[0]
+	if history[1].Source != settings.SourceDefault {
+		t.Fatalf("expected initial revision source %q, got %q", settings.SourceDefault, history[1].Source)
+	} else if latest.Source != settings.SourceOperator {
+		t.Fatalf("expected revision source %q, got %q", settings.SourceOperator, latest.Source)
+	} else if len(latest.Changes) != 2 {
+		t.Fatalf("expected 2 changes, got %v", latest.Changes)
+	} else if latest.Changes[0].Field != "storagePrice" || latest.Changes[1].Field != "windowSize" {
+		t.Fatalf("unexpected changes %v", latest.Changes)
+	}
+
+	// roll back to the initial settings
+	restored, err := manager.Rollback(history[1].Revision)
+	if err != nil {
+		t.Fatal(err)
+	} else if !restored.StoragePrice.Equals(initial.StoragePrice) || restored.WindowSize != initial.WindowSize {
+		t.Fatal("expected settings to be rolled back")
+	} else if !manager.Settings().StoragePrice.Equals(initial.StoragePrice) {
+		t.Fatal("expected current settings to be rolled back")
+	}
+
+	history, err = manager.History(100, 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(history) != 3 {
+		t.Fatalf("expected 3 revisions, got %v", len(history))
+	} else if len(history[0].Changes) != 2 {
+		t.Fatalf("expected rollback to change 2 fields, got %v", history[0].Changes)
+	} else if history[0].Source != settings.SourceRollback {
+		t.Fatalf("expected revision source %q, got %q", settings.SourceRollback, history[0].Source)
+	}
+
+	if _, err := manager.Rollback(1000); !errors.Is(err, settings.ErrRevisionNotFound) {
+		t.Fatalf("expected ErrRevisionNotFound, got %v", err)
+	}
+}
+
+func TestSettingsDiff(t *testing.T) {
+	old := settings.DefaultSettings
+	old.PricingEngine.Utilization = &settings.UtilizationRule{EmptyMultiplier: 1, FullMultiplier: 2}
+	new := settings.DefaultSettings
+	new.WindowSize++
+	new.PricingEngine.MaxChange = 0.1
+
+	changes, err := settings.Diff(old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"pricingEngine.maxChange",
+		"pricingEngine.utilization.emptyMultiplier",
+		"pricingEngine.utilization.fullCollateralMultiplier",
+		"pricingEngine.utilization.fullMultiplier",
+		"windowSize",
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %v changes, got %v", len(expected), changes)
+	}
+	for i, change := range changes {
+		if change.Field != expected[i] {
+			t.Fatalf("expected change %v to be %q, got %q", i, expected[i], change.Field)
+		}
+	}
+
+	// the fields of the removed rule should be reported with an empty new
+	// value
+	for _, removed := range changes[1:4] {
+		if len(removed.Old) == 0 || len(removed.New) != 0 {
+			t.Fatalf("expected %q to be removed, got old %s new %s", removed.Field, removed.Old, removed.New)
+		}
+	}
+
+	// the diff should be symmetric
+	if reversed, err := settings.Diff(new, old); err != nil {
+		t.Fatal(err)
+	} else if len(reversed) != len(expected) {
+		t.Fatalf("expected %v changes, got %v", len(expected), reversed)
+	}
 }
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.sia.tech/hostd/host/storage/s3"
+)
+
+// defines the volume backends
+const (
+	// VolumeBackendFile stores sector data in a flat file.
+	VolumeBackendFile = "file"
+	// VolumeBackendSparse stores sector data in a sparse file. Space is only
+	// allocated when sectors are written.
+	VolumeBackendSparse = "sparse"
+	// VolumeBackendBlock stores sector data directly on a raw block device
+	// using direct I/O.
+	VolumeBackendBlock = "block"
+	// VolumeBackendS3 stores sector data as objects in an S3-compatible
+	// bucket.
+	VolumeBackendS3 = "s3"
+)
+
+type (
+	// VolumeData wraps the methods needed to read and write sector data to a
+	// volume. Reads and writes are always aligned to the sector size.
+	VolumeData interface {
+		io.ReaderAt
+		io.WriterAt
+
+		Sync() error
+		Truncate(int64) error
+		Close() error
+	}
+
+	// A VolumeBackend selects where a volume's sector data is stored.
+	VolumeBackend struct {
+		Type    string          `json:"type"`
+		Options json.RawMessage `json:"options,omitempty"`
+	}
+)
+
+// ErrUnknownBackend is returned when a volume's backend is not recognized.
+var ErrUnknownBackend = errors.New("unknown volume backend")
+
+// Validate checks that the backend is supported and its options are valid. The
+// Options field will be rewritten to ensure the JSON object matches the
+// expected schema. An empty type is replaced with VolumeBackendFile.
+func (vb *VolumeBackend) Validate() error {
+	switch vb.Type {
+	case "":
+		vb.Type = VolumeBackendFile
+		fallthrough
+	case VolumeBackendFile, VolumeBackendSparse, VolumeBackendBlock:
+		vb.Options = nil
+		return nil
+	case VolumeBackendS3:
+		var opts s3.Options
+		if err := json.Unmarshal(vb.Options, &opts); err != nil {
+			return fmt.Errorf("failed to parse s3 options: %w", err)
+		} else if err := s3.ValidateOptions(opts); err != nil {
+			return fmt.Errorf("invalid s3 options: %w", err)
+		}
+		buf, err := json.Marshal(opts)
+		if err != nil {
+			return fmt.Errorf("failed to marshal s3 options: %w", err)
+		}
+		vb.Options = buf
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownBackend, vb.Type)
+	}
+}
+
+// Redacted returns a copy of the backend with any credentials removed from its
+// options. It should be used whenever the backend is shown to a user.
+func (vb VolumeBackend) Redacted() VolumeBackend {
+	if vb.Type != VolumeBackendS3 || len(vb.Options) == 0 {
+		return vb
+	}
+
+	var opts s3.Options
+	if err := json.Unmarshal(vb.Options, &opts); err != nil {
+		// never return options that could not be redacted
+		vb.Options = nil
+		return vb
+	}
+	opts.SecretAccessKey = ""
+	buf, err := json.Marshal(opts)
+	if err != nil {
+		vb.Options = nil
+		return vb
+	}
+	vb.Options = buf
+	return vb
+}
+
+// openVolumeData opens the sector data of a volume. If create is true, new
+// data is initialized and an error is returned if it already exists.
+func openVolumeData(localPath string, backend VolumeBackend, create bool) (VolumeData, error) {
+	switch backend.Type {
+	case "", VolumeBackendFile:
+		flags := os.O_RDWR
+		if create {
+			flags |= os.O_CREATE | os.O_EXCL
+		}
+		f, err := os.OpenFile(localPath, flags, 0600)
+		if errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("volume file already exists: %s", localPath)
+		} else if err != nil {
+			return nil, err
+		}
+		return f, nil
+	case VolumeBackendSparse:
+		return openSparseFile(localPath, create)
+	case VolumeBackendBlock:
+		return openBlockDevice(localPath)
+	case VolumeBackendS3:
+		var opts s3.Options
+		if err := json.Unmarshal(backend.Options, &opts); err != nil {
+			return nil, fmt.Errorf("failed to parse s3 options: %w", err)
+		}
+		vol, err := s3.New(localPath, opts)
+		if err != nil {
+			return nil, err
+		}
+		return vol, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownBackend, backend.Type)
+	}
+}
+
+// removeVolumeData removes any remaining sector data of a volume after it has
+// been truncated and closed. Block devices are never removed.
+func removeVolumeData(localPath string, backend VolumeBackend) error {
+	switch backend.Type {
+	case "", VolumeBackendFile, VolumeBackendSparse:
+		// ignore the error if the file does not exist
+		if err := os.Remove(localPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package storage
+
+import "errors"
+
+func openBlockDevice(string) (VolumeData, error) {
+	return nil, errors.New("block device volumes are only supported on Linux")
+}
@@ -0,0 +1,151 @@
+//go:build linux
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+)
+
+// blockAlignment is the required alignment of offsets, lengths, and buffers
+// for direct I/O.
+const blockAlignment = 4096
+
+// A blockDevice stores sector data directly on a raw block device. All I/O
+// bypasses the page cache using O_DIRECT.
+type blockDevice struct {
+	f    *os.File
+	size int64
+
+	// buffers is a pool of aligned sector buffers used when the caller's
+	// buffer is not aligned.
+	buffers sync.Pool
+}
+
+func isAligned(b []byte) bool {
+	return uintptr(unsafe.Pointer(&b[0]))%blockAlignment == 0
+}
+
+// alignedBuffer returns a buffer of length n aligned to blockAlignment.
+func alignedBuffer(n int) []byte {
+	buf := make([]byte, n+blockAlignment)
+	offset := int(uintptr(unsafe.Pointer(&buf[0])) % blockAlignment)
+	if offset != 0 {
+		offset = blockAlignment - offset
+	}
+	return buf[offset : offset+n]
+}
+
+func (bd *blockDevice) checkAlignment(p []byte, off int64) error {
+	if len(p) == 0 || len(p)%blockAlignment != 0 || off%blockAlignment != 0 {
+		return fmt.Errorf("unaligned block device access: offset %v, length %v", off, len(p))
+	} else if off+int64(len(p)) > bd.size {
+		return fmt.Errorf("access past end of block device: offset %v, length %v, size %v", off, len(p), bd.size)
+	}
+	return nil
+}
+
+// buffer returns an aligned buffer of length n
+func (bd *blockDevice) buffer(n int) []byte {
+	if n == rhpv2.SectorSize {
+		return bd.buffers.Get().([]byte)
+	}
+	return alignedBuffer(n)
+}
+
+func (bd *blockDevice) releaseBuffer(b []byte) {
+	if len(b) == rhpv2.SectorSize {
+		bd.buffers.Put(b)
+	}
+}
+
+// ReadAt implements io.ReaderAt.
+func (bd *blockDevice) ReadAt(p []byte, off int64) (int, error) {
+	if err := bd.checkAlignment(p, off); err != nil {
+		return 0, err
+	} else if isAligned(p) {
+		return bd.f.ReadAt(p, off)
+	}
+
+	buf := bd.buffer(len(p))
+	defer bd.releaseBuffer(buf)
+	n, err := bd.f.ReadAt(buf, off)
+	copy(p, buf[:n])
+	return n, err
+}
+
+// WriteAt implements io.WriterAt.
+func (bd *blockDevice) WriteAt(p []byte, off int64) (int, error) {
+	if err := bd.checkAlignment(p, off); err != nil {
+		return 0, err
+	} else if isAligned(p) {
+		return bd.f.WriteAt(p, off)
+	}
+
+	buf := bd.buffer(len(p))
+	defer bd.releaseBuffer(buf)
+	copy(buf, p)
+	return bd.f.WriteAt(buf, off)
+}
+
+// Sync flushes the device's write cache.
+func (bd *blockDevice) Sync() error {
+	return bd.f.Sync()
+}
+
+// Truncate checks that the device is large enough to hold size bytes. The size
+// of a block device cannot be changed.
+func (bd *blockDevice) Truncate(size int64) error {
+	if size > bd.size {
+		return fmt.Errorf("block device too small: %v > %v", size, bd.size)
+	}
+	return nil
+}
+
+// Close closes the block device.
+func (bd *blockDevice) Close() error {
+	return bd.f.Close()
+}
+
+func openBlockDevice(localPath string) (VolumeData, error) {
+	stat, err := os.Stat(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat block device: %w", err)
+	} else if stat.Mode()&os.ModeDevice == 0 {
+		return nil, errors.New("volume path is not a block device")
+	}
+
+	f, err := os.OpenFile(localPath, os.O_RDWR|syscall.O_DIRECT, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open block device: %w", err)
+	}
+	bd, err := newBlockDevice(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return bd, nil
+}
+
+// newBlockDevice wraps an open block device. The size of the device is
+// determined by seeking to its end.
+func newBlockDevice(f *os.File) (*blockDevice, error) {
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block device size: %w", err)
+	}
+	return &blockDevice{
+		f:    f,
+		size: size,
+		buffers: sync.Pool{
+			New: func() any { return alignedBuffer(rhpv2.SectorSize) },
+		},
+	}, nil
+}
@@ -0,0 +1,134 @@
+//go:build linux
+
+package storage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	"lukechampine.com/frand"
+)
+
+// testBlockDevice returns a block device backed by a regular file. Regular
+// files do not require direct I/O, but the alignment rules are enforced by
+// the wrapper.
+func testBlockDevice(t *testing.T, sectors int64) *blockDevice {
+	f, err := os.Create(filepath.Join(t.TempDir(), "device"))
+	if err != nil {
+		t.Fatal(err)
+	} else if err := f.Truncate(sectors * rhpv2.SectorSize); err != nil {
+		t.Fatal(err)
+	}
+	bd, err := newBlockDevice(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bd.Close() })
+	return bd
+}
+
+func TestOpenBlockDevice(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "volume.dat")
+	if err := os.WriteFile(path, make([]byte, rhpv2.SectorSize), 0600); err != nil {
+		t.Fatal(err)
+	} else if _, err := openBlockDevice(path); err == nil {
+		t.Fatal("expected regular file to be rejected")
+	} else if _, err := openBlockDevice(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatal("expected missing device to be rejected")
+	}
+}
+
+func TestBlockDeviceReadWrite(t *testing.T) {
+	const sectors = 4
+	bd := testBlockDevice(t, sectors)
+	if bd.size != sectors*rhpv2.SectorSize {
+		t.Fatalf("expected size %v, got %v", sectors*rhpv2.SectorSize, bd.size)
+	}
+
+	// aligned buffer
+	aligned := alignedBuffer(rhpv2.SectorSize)
+	frand.Read(aligned)
+	if !isAligned(aligned) {
+		t.Fatal("expected aligned buffer")
+	} else if _, err := bd.WriteAt(aligned, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// unaligned buffers are copied through the buffer pool
+	unaligned := make([]byte, rhpv2.SectorSize+1)[1:]
+	frand.Read(unaligned)
+	if isAligned(unaligned) {
+		t.Fatal("expected unaligned buffer")
+	} else if _, err := bd.WriteAt(unaligned, rhpv2.SectorSize); err != nil {
+		t.Fatal(err)
+	} else if err := bd.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, expected := range [][]byte{aligned, unaligned} {
+		buf := make([]byte, rhpv2.SectorSize+1)[1:]
+		if _, err := bd.ReadAt(buf, int64(i)*rhpv2.SectorSize); err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(buf, expected) {
+			t.Fatalf("sector %v data mismatch", i)
+		}
+	}
+
+	// partial reads must still be aligned to the block size
+	buf := alignedBuffer(blockAlignment)
+	if _, err := bd.ReadAt(buf, rhpv2.SectorSize); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(buf, unaligned[:blockAlignment]) {
+		t.Fatal("block data mismatch")
+	}
+}
+
+func TestBlockDeviceAlignment(t *testing.T) {
+	const sectors = 2
+	bd := testBlockDevice(t, sectors)
+
+	tests := []struct {
+		name   string
+		length int
+		offset int64
+	}{
+		{"empty", 0, 0},
+		{"unaligned length", blockAlignment + 1, 0},
+		{"unaligned offset", blockAlignment, 1},
+		{"past end", rhpv2.SectorSize, sectors * rhpv2.SectorSize},
+		{"overlaps end", 2 * rhpv2.SectorSize, rhpv2.SectorSize},
+	}
+	for _, test := range tests {
+		buf := make([]byte, test.length)
+		if _, err := bd.WriteAt(buf, test.offset); err == nil {
+			t.Fatalf("%s: expected write error", test.name)
+		} else if _, err := bd.ReadAt(buf, test.offset); err == nil {
+			t.Fatalf("%s: expected read error", test.name)
+		}
+	}
+}
+
+func TestBlockDeviceTruncate(t *testing.T) {
+	const sectors = 2
+	bd := testBlockDevice(t, sectors)
+
+	// the device can hold fewer sectors than its size, but cannot grow
+	if err := bd.Truncate(rhpv2.SectorSize); err != nil {
+		t.Fatal(err)
+	} else if err := bd.Truncate(sectors * rhpv2.SectorSize); err != nil {
+		t.Fatal(err)
+	} else if err := bd.Truncate((sectors + 1) * rhpv2.SectorSize); err == nil {
+		t.Fatal("expected error growing block device")
+	}
+
+	// the size of the device is not changed
+	stat, err := bd.f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	} else if stat.Size() != sectors*rhpv2.SectorSize {
+		t.Fatalf("expected device size %v, got %v", sectors*rhpv2.SectorSize, stat.Size())
+	}
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// A sparseFile is a flat file that is not preallocated when the volume grows.
+// The file is only extended when sectors are written to it.
+type sparseFile struct {
+	*os.File
+}
+
+// ReadAt implements io.ReaderAt. Sectors past the end of the file have not
+// been written yet and are read as zeroes.
+func (sf *sparseFile) ReadAt(p []byte, off int64) (int, error) {
+	n, err := sf.File.ReadAt(p, off)
+	if errors.Is(err, io.EOF) {
+		for i := n; i < len(p); i++ {
+			p[i] = 0
+		}
+		return len(p), nil
+	}
+	return n, err
+}
+
+// Truncate shrinks the file to size. Growing the file is a no-op.
+func (sf *sparseFile) Truncate(size int64) error {
+	stat, err := sf.File.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	} else if size >= stat.Size() {
+		return nil
+	}
+	return sf.File.Truncate(size)
+}
+
+func openSparseFile(localPath string, create bool) (VolumeData, error) {
+	flags := os.O_RDWR
+	if create {
+		flags |= os.O_CREATE | os.O_EXCL
+	}
+	f, err := os.OpenFile(localPath, flags, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("volume file already exists: %s", localPath)
+	} else if err != nil {
+		return nil, err
+	}
+	return &sparseFile{f}, nil
+}
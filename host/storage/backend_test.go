@@ -0,0 +1,53 @@
+package storage_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go.sia.tech/hostd/host/storage"
+	"go.sia.tech/hostd/host/storage/s3"
+)
+
+func TestVolumeBackendRedacted(t *testing.T) {
+	opts := s3.Options{
+		Endpoint:        "https://s3.example.com",
+		Bucket:          "hostd",
+		AccessKeyID:     "access",
+		SecretAccessKey: "supersecret",
+		PathStyle:       true,
+	}
+	buf, err := json.Marshal(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := storage.VolumeBackend{Type: storage.VolumeBackendS3, Options: buf}
+	if err := backend.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	redacted := backend.Redacted()
+	if strings.Contains(string(redacted.Options), opts.SecretAccessKey) {
+		t.Fatalf("expected secret to be redacted, got %s", redacted.Options)
+	}
+	var got s3.Options
+	if err := json.Unmarshal(redacted.Options, &got); err != nil {
+		t.Fatal(err)
+	}
+	expected := opts
+	expected.SecretAccessKey = ""
+	if got != expected {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+
+	// the original backend must not be modified
+	if !strings.Contains(string(backend.Options), opts.SecretAccessKey) {
+		t.Fatal("expected original options to keep the secret")
+	}
+
+	// backends without options are unchanged
+	file := storage.VolumeBackend{Type: storage.VolumeBackendFile}
+	if r := file.Redacted(); r.Type != file.Type || r.Options != nil {
+		t.Fatalf("expected file backend to be unchanged, got %+v", r)
+	}
+}
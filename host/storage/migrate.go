@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	// migrateReaders is the number of sectors read concurrently from the
+	// source volumes during a migration
+	migrateReaders = 4
+	// migrateWriteBuffer is the number of sectors buffered for each
+	// destination volume. Reads block when the buffer is full to limit the
+	// memory used by a migration.
+	migrateWriteBuffer = 2
+)
+
+type (
+	// MigrationProgress is the progress of a running sector migration out of a
+	// volume.
+	MigrationProgress struct {
+		Migrated uint64 `json:"migrated"`
+		// Total is the estimated number of sectors that need to be migrated
+		Total   uint64    `json:"total"`
+		Started time.Time `json:"started"`
+		// Throughput is the average number of bytes migrated per second
+		Throughput float64 `json:"throughput"`
+		// ETA is the estimated completion time of the migration. It is zero
+		// until the first sectors have been migrated.
+		ETA time.Time `json:"eta"`
+	}
+
+	// sectorMigration is a sector that has been read from its old location
+	// and is waiting to be written to its new location
+	sectorMigration struct {
+		batch  *migrationBatch
+		loc    SectorLocation
+		sector *[rhpv2.SectorSize]byte
+	}
+
+	// A migrationBatch is a set of sectors migrated by a single call to
+	// migrationPipeline.migrate.
+	migrationBatch struct {
+		ctx    context.Context
+		cancel context.CancelFunc
+		// wg tracks the sectors of the batch that have not been written or
+		// skipped
+		wg       sync.WaitGroup
+		migrated int64
+
+		mu  sync.Mutex
+		err error
+	}
+
+	// A migrationPipeline migrates sectors to new locations. Sectors are read
+	// from their current locations by a pool of readers and handed to a
+	// writer for each destination volume so reads and writes on different
+	// volumes overlap. The pipeline is shared by consecutive batches so it
+	// keeps running while the previous batch is finished and committed.
+	migrationPipeline struct {
+		vm    *VolumeManager
+		force bool
+		log   *zap.Logger
+
+		work    chan sectorMigration
+		readWG  sync.WaitGroup
+		writeWG sync.WaitGroup
+
+		mu      sync.Mutex
+		writers map[int]chan sectorMigration
+	}
+)
+
+// fail records a failed sector migration. If force is false, the batch is
+// stopped at the first failure.
+func (p *migrationPipeline) fail(b *migrationBatch, loc SectorLocation, err error) {
+	p.log.Error("failed to migrate sector", zap.Error(err), zap.Stringer("root", loc.Root), zap.Int("newVolumeID", loc.Volume), zap.Uint64("newIndex", loc.Index))
+	if p.force {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err == nil {
+		b.err = fmt.Errorf("failed to migrate sector %v: %w", loc.Root, err)
+		b.cancel()
+	}
+}
+
+// writer returns the channel of the writer for the destination volume,
+// starting the writer if necessary.
+func (p *migrationPipeline) writer(volumeID int) chan<- sectorMigration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ch, ok := p.writers[volumeID]; ok {
+		return ch
+	}
+	ch := make(chan sectorMigration, migrateWriteBuffer)
+	p.writers[volumeID] = ch
+	p.writeWG.Add(1)
+	go func() {
+		defer p.writeWG.Done()
+		for m := range ch {
+			// skip the sector if its batch was stopped
+			if m.batch.ctx.Err() != nil {
+				m.batch.wg.Done()
+				continue
+			}
+			if err := p.vm.writeSector(m.sector, m.loc); err != nil {
+				p.fail(m.batch, m.loc, fmt.Errorf("failed to write sector: %w", err))
+			} else {
+				atomic.AddInt64(&m.batch.migrated, 1)
+			}
+			m.batch.wg.Done()
+		}
+	}()
+	return ch
+}
+
+// read reads sectors from their old locations and hands them to the writers.
+func (p *migrationPipeline) read() {
+	defer p.readWG.Done()
+	for m := range p.work {
+		if m.batch.ctx.Err() != nil {
+			m.batch.wg.Done()
+			continue
+		}
+		// read the sector from the old location. Read verifies the sector's
+		// root and recovers or quarantines corrupt sectors, so the data does
+		// not need to be hashed again.
+		sector, err := p.vm.Read(m.loc.Root)
+		if err != nil {
+			p.fail(m.batch, m.loc, fmt.Errorf("failed to read sector: %w", err))
+			m.batch.wg.Done()
+			continue
+		}
+		m.sector = sector
+		p.writer(m.loc.Volume) <- m
+	}
+}
+
+// migrate migrates a batch of sectors to new locations. Changed volumes are
+// synced after all sectors of the batch have been written. It is safe to
+// call migrate concurrently; the batches share the pipeline's readers and
+// writers.
+func (p *migrationPipeline) migrate(locations []SectorLocation) (int, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := &migrationBatch{ctx: ctx, cancel: cancel}
+
+	b.wg.Add(len(locations))
+	for i, loc := range locations {
+		if ctx.Err() != nil {
+			// the batch was stopped, skip the remaining sectors
+			for range locations[i:] {
+				b.wg.Done()
+			}
+			break
+		}
+		p.work <- sectorMigration{batch: b, loc: loc}
+	}
+	b.wg.Wait()
+
+	migrated := int(atomic.LoadInt64(&b.migrated))
+	b.mu.Lock()
+	err := b.err
+	b.mu.Unlock()
+	if err != nil {
+		return migrated, err
+	}
+	return migrated, p.vm.Sync()
+}
+
+// close stops the pipeline's readers and writers. migrate must not be called
+// after close.
+func (p *migrationPipeline) close() {
+	close(p.work)
+	p.readWG.Wait()
+	p.mu.Lock()
+	for _, ch := range p.writers {
+		close(ch)
+	}
+	p.mu.Unlock()
+	p.writeWG.Wait()
+}
+
+// newMigrationPipeline starts a pipeline for migrating sectors. If force is
+// false, a batch stops at its first failure.
+func (vm *VolumeManager) newMigrationPipeline(force bool, log *zap.Logger) *migrationPipeline {
+	p := &migrationPipeline{
+		vm:    vm,
+		force: force,
+		log:   log,
+
+		work:    make(chan sectorMigration),
+		writers: make(map[int]chan sectorMigration),
+	}
+	for i := 0; i < migrateReaders; i++ {
+		p.readWG.Add(1)
+		go p.read()
+	}
+	return p
+}
+
+// migrateSectors migrates a single batch of sectors to new locations using a
+// new pipeline. If force is false, the migration stops at the first failure.
+func (vm *VolumeManager) migrateSectors(locations []SectorLocation, force bool, log *zap.Logger) (int, error) {
+	p := vm.newMigrationPipeline(force, log)
+	defer p.close()
+	return p.migrate(locations)
+}
+
+// trackMigration tracks the progress of a migration out of a volume in the
+// volume's stats. The returned update function should be called after each
+// batch of sectors is migrated. The returned done function clears the
+// progress.
+func (vm *VolumeManager) trackMigration(id int, total uint64) (update func(migrated int), done func()) {
+	progress := MigrationProgress{
+		Total:   total,
+		Started: time.Now(),
+	}
+	set := func(p *MigrationProgress) {
+		vm.mu.Lock()
+		defer vm.mu.Unlock()
+		if v, ok := vm.volumes[id]; ok {
+			v.stats.Migration = p
+		}
+	}
+	initial := progress
+	set(&initial)
+
+	var mu sync.Mutex
+	update = func(migrated int) {
+		mu.Lock()
+		defer mu.Unlock()
+		progress.Migrated += uint64(migrated)
+		if progress.Migrated > progress.Total {
+			progress.Total = progress.Migrated
+		}
+		elapsed := time.Since(progress.Started)
+		if elapsed > 0 {
+			progress.Throughput = float64(progress.Migrated*rhpv2.SectorSize) / elapsed.Seconds()
+		}
+		if progress.Throughput > 0 {
+			remaining := float64((progress.Total - progress.Migrated) * rhpv2.SectorSize)
+			progress.ETA = time.Now().Add(time.Duration(remaining / progress.Throughput * float64(time.Second)))
+		}
+		// copy the progress so readers of the stats do not race with updates
+		p := progress
+		set(&p)
+	}
+	return update, func() { set(nil) }
+}
@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defines the volume operation types
+const (
+	VolumeOperationAdd    = "add"
+	VolumeOperationResize = "resize"
+	VolumeOperationRemove = "remove"
+)
+
+type (
+	// A VolumeOperation is a long-running add, resize, or remove operation on
+	// a volume. Operations are persisted so they can be resumed if the host
+	// restarts before they complete.
+	VolumeOperation struct {
+		ID       int64  `json:"id"`
+		VolumeID int    `json:"volumeID"`
+		Type     string `json:"type"`
+		// TargetSectors is the number of sectors in the volume when an add or
+		// resize operation completes.
+		TargetSectors uint64 `json:"targetSectors"`
+		// Cursor is the progress of the operation. For add and resize
+		// operations it is the current number of sectors in the volume. For
+		// remove operations it is the number of sectors migrated.
+		Cursor uint64 `json:"cursor"`
+		Force  bool   `json:"force"`
+		// RestoreReadOnly is the read-only status the volume is restored to
+		// when a resize completes or a removal is cancelled.
+		RestoreReadOnly bool      `json:"restoreReadOnly"`
+		Started         time.Time `json:"started"`
+	}
+
+	// volumeOperationJob tracks a running volume operation
+	volumeOperationJob struct {
+		op     VolumeOperation
+		cancel context.CancelFunc
+		// cancelled is set when the operation is cancelled by the user
+		// rather than interrupted by shutdown
+		cancelled bool
+		done      chan struct{}
+	}
+)
+
+// ErrOperationNotFound is returned when a volume does not have a running
+// operation.
+var ErrOperationNotFound = errors.New("volume operation not found")
+
+// startOperation persists a new operation and tracks it so it can be
+// cancelled. Operations that already have an ID are being resumed and are not
+// persisted again. The returned context is cancelled when the operation is
+// cancelled or the volume manager is closed.
+func (vm *VolumeManager) startOperation(op VolumeOperation) (context.Context, *volumeOperationJob, error) {
+	ctx, cancel, err := vm.tg.AddContext(context.Background())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if op.ID == 0 {
+		op.Started = time.Now()
+		op.ID, err = vm.vs.AddVolumeOperation(op)
+		if err != nil {
+			cancel()
+			return nil, nil, fmt.Errorf("failed to persist volume operation: %w", err)
+		}
+	}
+
+	job := &volumeOperationJob{
+		op:     op,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	vm.mu.Lock()
+	vm.operations[op.VolumeID] = job
+	vm.mu.Unlock()
+	return ctx, job, nil
+}
+
+// finishOperation stops tracking an operation. The persisted operation is
+// removed unless it was interrupted by shutdown, in which case it is resumed
+// the next time the volume manager is started.
+func (vm *VolumeManager) finishOperation(job *volumeOperationJob) {
+	vm.mu.Lock()
+	delete(vm.operations, job.op.VolumeID)
+	cancelled := job.cancelled
+	vm.mu.Unlock()
+
+	var interrupted bool
+	select {
+	case <-vm.tg.Done():
+		interrupted = !cancelled
+	default:
+	}
+
+	if !interrupted {
+		if err := vm.vs.RemoveVolumeOperation(job.op.ID); err != nil {
+			vm.log.Error("failed to remove volume operation", zap.Int64("id", job.op.ID), zap.Int("volumeID", job.op.VolumeID), zap.Error(err))
+		}
+	}
+	job.cancel()
+	close(job.done)
+}
+
+// updateOperationCursor updates the progress of a volume's running operation.
+// If add is true, cursor is added to the current progress.
+func (vm *VolumeManager) updateOperationCursor(volumeID int, cursor uint64, add bool) {
+	vm.mu.Lock()
+	job, ok := vm.operations[volumeID]
+	if ok {
+		if add {
+			cursor += job.op.Cursor
+		}
+		job.op.Cursor = cursor
+	}
+	vm.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	if err := vm.vs.UpdateVolumeOperation(job.op.ID, cursor); err != nil {
+		vm.log.Error("failed to update volume operation", zap.Int64("id", job.op.ID), zap.Int("volumeID", volumeID), zap.Error(err))
+	}
+}
+
+// operationCancelled returns true if the volume's running operation was
+// cancelled by the user
+func (vm *VolumeManager) operationCancelled(job *volumeOperationJob) bool {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	return job.cancelled
+}
+
+// resumeOperations restarts any volume operations that were interrupted by a
+// shutdown.
+func (vm *VolumeManager) resumeOperations() error {
+	ops, err := vm.vs.VolumeOperations()
+	if err != nil {
+		return fmt.Errorf("failed to get volume operations: %w", err)
+	}
+
+	for _, op := range ops {
+		log := vm.log.Named("resume").With(zap.Int("volumeID", op.VolumeID), zap.String("type", op.Type))
+		vol, err := vm.vs.Volume(op.VolumeID)
+		if err != nil {
+			log.Error("failed to get volume", zap.Error(err))
+			continue
+		} else if !vol.Available {
+			// the operation will be resumed the next time the volume is
+			// available at startup
+			log.Warn("volume unavailable, not resuming operation")
+			continue
+		}
+
+		release, err := vm.lockVolume(op.VolumeID)
+		if err != nil {
+			log.Error("failed to lock volume", zap.Error(err))
+			continue
+		}
+		ctx, job, err := vm.startOperation(op)
+		if err != nil {
+			release()
+			return fmt.Errorf("failed to resume volume operation %v: %w", op.ID, err)
+		}
+
+		log.Info("resuming volume operation", zap.Uint64("cursor", op.Cursor), zap.Uint64("targetSectors", op.TargetSectors))
+		if op.Type == VolumeOperationResize || op.Type == VolumeOperationRemove {
+			// prevent new sectors from being added while the operation runs
+			if err := vm.vs.SetReadOnly(op.VolumeID, true); err != nil {
+				release()
+				vm.finishOperation(job)
+				return fmt.Errorf("failed to set volume %v to read-only: %w", op.VolumeID, err)
+			}
+		}
+		switch op.Type {
+		case VolumeOperationAdd:
+			vm.setVolumeStatus(op.VolumeID, VolumeStatusCreating)
+			go vm.initializeVolume(ctx, job, vol.TotalSectors, release, nil)
+		case VolumeOperationResize:
+			vm.setVolumeStatus(op.VolumeID, VolumeStatusResizing)
+			go vm.resizeVolume(ctx, job, vol.TotalSectors, release, nil)
+		case VolumeOperationRemove:
+			go vm.removeVolume(ctx, job, vol, release, nil)
+		default:
+			log.Error("unknown volume operation")
+			release()
+			vm.finishOperation(job)
+		}
+	}
+	return nil
+}
+
+// VolumeOperations returns the unfinished volume operations.
+func (vm *VolumeManager) VolumeOperations() ([]VolumeOperation, error) {
+	done, err := vm.tg.Add()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	ops, err := vm.vs.VolumeOperations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get volume operations: %w", err)
+	}
+	return ops, nil
+}
+
+// CancelVolumeOperation stops the running operation on a volume. Added or
+// resized volumes are left at their current size. Volumes being removed are
+// left in place with any sectors that have not been migrated.
+func (vm *VolumeManager) CancelVolumeOperation(volumeID int) error {
+	vm.mu.Lock()
+	job, ok := vm.operations[volumeID]
+	if ok {
+		job.cancelled = true
+	}
+	vm.mu.Unlock()
+	if !ok {
+		return ErrOperationNotFound
+	}
+	job.cancel()
+	<-job.done
+	return nil
+}
@@ -0,0 +1,569 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/klauspost/reedsolomon"
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/alerts"
+	"go.uber.org/zap"
+	"lukechampine.com/frand"
+)
+
+// defines the parity schemes
+const (
+	// ParitySchemeNone disables parity. Existing parity groups are kept.
+	ParitySchemeNone = ""
+	// ParitySchemeXOR protects a group of data sectors with a single XOR
+	// parity sector. Any one sector of the group can be reconstructed.
+	ParitySchemeXOR = "xor"
+	// ParitySchemeReedSolomon protects a group of data sectors with one or
+	// more Reed-Solomon parity sectors. Any sectors of the group up to the
+	// number of parity sectors can be reconstructed.
+	ParitySchemeReedSolomon = "rs"
+)
+
+const (
+	// parityCheckInterval is the interval between parity encoding passes
+	parityCheckInterval = 10 * time.Minute
+	// parityBatchSize is the maximum number of unprotected sectors per volume
+	// considered in a single batch
+	parityBatchSize = 64 // 256 MiB
+
+	// rebuildBatchSize is the maximum number of sectors moved to the
+	// replacement volume in a single batch
+	rebuildBatchSize = 64 // 256 MiB
+)
+
+type (
+	// ParitySettings configures the parity encoder.
+	ParitySettings struct {
+		Scheme string `json:"scheme"`
+		// DataShards is the number of data sectors, each stored in a
+		// different volume, protected by a parity group
+		DataShards int `json:"dataShards"`
+		// ParityShards is the number of parity sectors in a parity group.
+		// XOR parity always uses a single parity sector.
+		ParityShards int `json:"parityShards"`
+	}
+
+	// A ParityGroup is a set of data sectors stored in different volumes and
+	// the parity sectors protecting them.
+	ParityGroup struct {
+		ID           int64  `json:"id"`
+		Scheme       string `json:"scheme"`
+		DataShards   int    `json:"dataShards"`
+		ParityShards int    `json:"parityShards"`
+		// Roots contains the roots of the data sectors followed by the roots
+		// of the parity sectors
+		Roots []types.Hash256 `json:"roots"`
+	}
+)
+
+var (
+	// ErrNoParity is returned when a sector is not protected by a parity
+	// group.
+	ErrNoParity = errors.New("sector is not protected by parity")
+	// ErrInvalidParityScheme is returned when a parity scheme is not
+	// recognized.
+	ErrInvalidParityScheme = errors.New("invalid parity scheme")
+)
+
+// Validate returns an error if the parity settings are invalid.
+func (ps ParitySettings) Validate() error {
+	switch ps.Scheme {
+	case ParitySchemeNone:
+	case ParitySchemeXOR:
+		if ps.DataShards < 2 {
+			return errors.New("xor parity requires at least 2 data shards")
+		} else if ps.ParityShards != 1 {
+			return errors.New("xor parity requires exactly 1 parity shard")
+		}
+	case ParitySchemeReedSolomon:
+		if ps.DataShards < 1 {
+			return errors.New("reed-solomon parity requires at least 1 data shard")
+		} else if ps.ParityShards < 1 {
+			return errors.New("reed-solomon parity requires at least 1 parity shard")
+		} else if ps.DataShards+ps.ParityShards > 256 {
+			return errors.New("reed-solomon parity supports at most 256 total shards")
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidParityScheme, ps.Scheme)
+	}
+	return nil
+}
+
+// xorSectors sets dst to the XOR of the given sectors
+func xorSectors(dst *[rhpv2.SectorSize]byte, sectors ...*[rhpv2.SectorSize]byte) {
+	*dst = [rhpv2.SectorSize]byte{}
+	for _, sector := range sectors {
+		for i := range dst {
+			dst[i] ^= sector[i]
+		}
+	}
+}
+
+// encodeParity calculates the parity sectors of a group's data sectors
+func encodeParity(scheme string, parityShards int, data []*[rhpv2.SectorSize]byte) ([]*[rhpv2.SectorSize]byte, error) {
+	parity := make([]*[rhpv2.SectorSize]byte, parityShards)
+	for i := range parity {
+		parity[i] = new([rhpv2.SectorSize]byte)
+	}
+
+	switch scheme {
+	case ParitySchemeXOR:
+		xorSectors(parity[0], data...)
+	case ParitySchemeReedSolomon:
+		enc, err := reedsolomon.New(len(data), parityShards)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create encoder: %w", err)
+		}
+		shards := make([][]byte, 0, len(data)+parityShards)
+		for _, sector := range data {
+			shards = append(shards, sector[:])
+		}
+		for _, sector := range parity {
+			shards = append(shards, sector[:])
+		}
+		if err := enc.Encode(shards); err != nil {
+			return nil, fmt.Errorf("failed to encode parity: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidParityScheme, scheme)
+	}
+	return parity, nil
+}
+
+// reconstructShard reconstructs the shard at index from the other shards of
+// its group. Missing shards are nil.
+func reconstructShard(group ParityGroup, shards []*[rhpv2.SectorSize]byte, index int) (*[rhpv2.SectorSize]byte, error) {
+	switch group.Scheme {
+	case ParitySchemeXOR:
+		// the XOR of every other shard is the missing shard
+		others := make([]*[rhpv2.SectorSize]byte, 0, len(shards)-1)
+		for i, shard := range shards {
+			if i == index {
+				continue
+			} else if shard == nil {
+				return nil, fmt.Errorf("shard %v is missing", i)
+			}
+			others = append(others, shard)
+		}
+		sector := new([rhpv2.SectorSize]byte)
+		xorSectors(sector, others...)
+		return sector, nil
+	case ParitySchemeReedSolomon:
+		enc, err := reedsolomon.New(group.DataShards, group.ParityShards)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create encoder: %w", err)
+		}
+		buf := make([][]byte, len(shards))
+		for i, shard := range shards {
+			if i != index && shard != nil {
+				buf[i] = shard[:]
+			}
+		}
+		if err := enc.Reconstruct(buf); err != nil {
+			return nil, fmt.Errorf("failed to reconstruct shard: %w", err)
+		}
+		sector := new([rhpv2.SectorSize]byte)
+		copy(sector[:], buf[index])
+		return sector, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidParityScheme, group.Scheme)
+	}
+}
+
+// readShard reads a member of a parity group from its volume without
+// attempting reconstruction.
+func (vm *VolumeManager) readShard(root types.Hash256) (*[rhpv2.SectorSize]byte, error) {
+	if sector, ok := vm.cache.Peek(root); ok {
+		return sector, nil
+	}
+
+	loc, release, err := vm.vs.SectorLocation(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate sector: %w", err)
+	}
+	defer release()
+
+	vm.mu.Lock()
+	v, ok := vm.volumes[loc.Volume]
+	vm.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("volume %v not found", loc.Volume)
+	}
+	sector, err := v.ReadSector(loc.Index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sector: %w", err)
+	} else if calculated := rhpv2.SectorRoot(sector); calculated != root {
+		return nil, fmt.Errorf("sector corrupt: expected root %v, got %v", root, calculated)
+	}
+	return sector, nil
+}
+
+// reconstructSector rebuilds a sector from the other members of its parity
+// group. If the sector is not protected, ErrNoParity is returned.
+func (vm *VolumeManager) reconstructSector(root types.Hash256) (*[rhpv2.SectorSize]byte, error) {
+	group, err := vm.vs.ParityGroup(root)
+	if err != nil {
+		return nil, err
+	}
+
+	log := vm.log.Named("parity").With(zap.Stringer("root", root), zap.Int64("groupID", group.ID))
+	index := -1
+	shards := make([]*[rhpv2.SectorSize]byte, len(group.Roots))
+	var available int
+	for i, shardRoot := range group.Roots {
+		if shardRoot == root {
+			index = i
+			continue
+		} else if available >= group.DataShards {
+			// any DataShards shards are enough to reconstruct
+			continue
+		}
+
+		shard, err := vm.readShard(shardRoot)
+		if err != nil {
+			log.Debug("failed to read shard", zap.Stringer("shardRoot", shardRoot), zap.Error(err))
+			continue
+		}
+		shards[i] = shard
+		available++
+	}
+	if index == -1 {
+		return nil, fmt.Errorf("sector not found in parity group %v", group.ID)
+	} else if available < group.DataShards {
+		return nil, fmt.Errorf("not enough shards to reconstruct sector: %v of %v available", available, group.DataShards)
+	}
+
+	sector, err := reconstructShard(group, shards, index)
+	if err != nil {
+		return nil, err
+	} else if calculated := rhpv2.SectorRoot(sector); calculated != root {
+		return nil, fmt.Errorf("reconstructed sector corrupt: expected root %v, got %v", root, calculated)
+	}
+	log.Info("reconstructed sector from parity")
+	return sector, nil
+}
+
+// storeParityGroup calculates the parity of a set of sectors stored in
+// different volumes and stores it.
+func (vm *VolumeManager) storeParityGroup(settings ParitySettings, members []SectorLocation) error {
+	group := ParityGroup{
+		Scheme:       settings.Scheme,
+		DataShards:   len(members),
+		ParityShards: settings.ParityShards,
+	}
+	data := make([]*[rhpv2.SectorSize]byte, 0, len(members))
+	exclude := make([]int, 0, len(members))
+	for _, loc := range members {
+		sector, err := vm.readShard(loc.Root)
+		if err != nil {
+			return fmt.Errorf("failed to read sector %v: %w", loc.Root, err)
+		}
+		data = append(data, sector)
+		group.Roots = append(group.Roots, loc.Root)
+		exclude = append(exclude, loc.Volume)
+	}
+
+	parity, err := encodeParity(settings.Scheme, settings.ParityShards, data)
+	if err != nil {
+		return err
+	}
+	for _, sector := range parity {
+		group.Roots = append(group.Roots, rhpv2.SectorRoot(sector))
+	}
+
+	return vm.vs.StoreParityGroup(group, exclude, func(locations []SectorLocation) error {
+		for i, loc := range locations {
+			if err := vm.writeSector(parity[i], loc); err != nil {
+				return fmt.Errorf("failed to write parity sector: %w", err)
+			}
+		}
+		return vm.Sync()
+	})
+}
+
+// encodeParity groups unprotected sectors stored in different volumes and
+// stores their parity. Sectors are taken from the volumes with the most
+// unprotected sectors first. The number of groups created is returned.
+func (vm *VolumeManager) encodeParity(ctx context.Context) (created int, err error) {
+	vm.mu.Lock()
+	settings := vm.paritySettings
+	vm.mu.Unlock()
+	if settings.Scheme == ParitySchemeNone {
+		return 0, nil
+	}
+
+	log := vm.log.Named("parity")
+	for {
+		candidates, err := vm.vs.ParityCandidates(parityBatchSize)
+		if err != nil {
+			return created, fmt.Errorf("failed to get unprotected sectors: %w", err)
+		}
+		byVolume := make(map[int][]SectorLocation)
+		for _, loc := range candidates {
+			byVolume[loc.Volume] = append(byVolume[loc.Volume], loc)
+		}
+
+		var batch int
+		for {
+			select {
+			case <-ctx.Done():
+				return created, ctx.Err()
+			default:
+			}
+
+			volumes := make([]int, 0, len(byVolume))
+			for id, locations := range byVolume {
+				if len(locations) > 0 {
+					volumes = append(volumes, id)
+				}
+			}
+			if len(volumes) < settings.DataShards {
+				break
+			}
+			sort.Slice(volumes, func(i, j int) bool {
+				a, b := len(byVolume[volumes[i]]), len(byVolume[volumes[j]])
+				if a != b {
+					return a > b
+				}
+				return volumes[i] < volumes[j]
+			})
+
+			members := make([]SectorLocation, 0, settings.DataShards)
+			for _, id := range volumes[:settings.DataShards] {
+				members = append(members, byVolume[id][0])
+				byVolume[id] = byVolume[id][1:]
+			}
+
+			err := vm.storeParityGroup(settings, members)
+			if errors.Is(err, ErrNotEnoughStorage) {
+				log.Warn("not enough storage for parity sectors")
+				return created, nil
+			} else if err != nil {
+				log.Error("failed to store parity group", zap.Error(err))
+				continue
+			}
+			batch++
+			created++
+		}
+		if batch == 0 {
+			return created, nil
+		}
+	}
+}
+
+// runParityEncoder periodically protects new sectors with parity.
+func (vm *VolumeManager) runParityEncoder() {
+	t := time.NewTicker(parityCheckInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-vm.tg.Done():
+			return
+		case <-t.C:
+		}
+
+		ctx, cancel, err := vm.tg.AddContext(context.Background())
+		if err != nil {
+			return
+		}
+		if n, err := vm.encodeParity(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			vm.log.Error("failed to encode parity", zap.Error(err))
+		} else if n > 0 {
+			vm.log.Named("parity").Info("protected sectors with parity", zap.Int("groups", n))
+		}
+		cancel()
+	}
+}
+
+// EncodeParity immediately protects unprotected sectors with parity instead
+// of waiting for the next encoding pass. The number of parity groups created
+// is returned.
+func (vm *VolumeManager) EncodeParity() (int, error) {
+	ctx, cancel, err := vm.tg.AddContext(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+	return vm.encodeParity(ctx)
+}
+
+// SetParitySettings updates the scheme and group size of the parity encoder.
+// Existing parity groups are not changed.
+func (vm *VolumeManager) SetParitySettings(settings ParitySettings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	vm.mu.Lock()
+	vm.paritySettings = settings
+	vm.mu.Unlock()
+	return nil
+}
+
+// rebuildSectors copies sectors to their new locations. Sectors that cannot be
+// read from their current location are reconstructed from parity. Unlike
+// Read, unreadable locations are not quarantined since the sectors are
+// already being moved. The locations that were written are returned. The
+// current location of a sector that cannot be rebuilt is quarantined so the
+// sector is not moved again and stays unavailable until it is uploaded again.
+func (vm *VolumeManager) rebuildSectors(ctx context.Context, locations []SectorLocation, log *zap.Logger) ([]SectorLocation, error) {
+	var written []SectorLocation
+	for _, loc := range locations {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		sector, err := vm.readShard(loc.Root)
+		if err != nil {
+			sector, err = vm.reconstructSector(loc.Root)
+		}
+		if err != nil {
+			log.Error("failed to rebuild sector", zap.Stringer("root", loc.Root), zap.Error(err))
+			if err := vm.quarantineLost(loc.Root, err); err != nil {
+				return nil, fmt.Errorf("failed to quarantine sector %v: %w", loc.Root, err)
+			}
+			continue
+		} else if err := vm.writeSector(sector, loc); err != nil {
+			return nil, fmt.Errorf("failed to write sector %v: %w", loc.Root, err)
+		}
+		written = append(written, loc)
+	}
+	if err := vm.Sync(); err != nil {
+		return nil, err
+	}
+	return written, nil
+}
+
+// quarantineLost quarantines the current location of a sector that could not
+// be read or reconstructed.
+func (vm *VolumeManager) quarantineLost(root types.Hash256, reason error) error {
+	loc, release, err := vm.vs.SectorLocation(root)
+	if err != nil {
+		return fmt.Errorf("failed to locate sector: %w", err)
+	}
+	defer release()
+	return vm.vs.QuarantineLocation(loc, fmt.Sprintf("failed to rebuild sector: %v", reason))
+}
+
+// rebuildVolume moves every sector of a failed volume to its replacement.
+// Only sectors that were written to the replacement are moved. Sectors that
+// cannot be read or reconstructed are lost and stay in the failed volume,
+// which must be forcibly removed.
+func (vm *VolumeManager) rebuildVolume(ctx context.Context, from, to int, log *zap.Logger) (moved, lost int, err error) {
+	for {
+		var batchLost int
+		n, err := vm.vs.MoveSectors(from, to, rebuildBatchSize, func(locations []SectorLocation) ([]SectorLocation, error) {
+			written, err := vm.rebuildSectors(ctx, locations, log)
+			if err != nil {
+				return nil, err
+			}
+			batchLost = len(locations) - len(written)
+			return written, nil
+		})
+		if err != nil {
+			return moved, lost, err
+		}
+		moved += n
+		lost += batchLost
+		if n == 0 && batchLost == 0 {
+			return moved, lost, nil
+		}
+	}
+}
+
+// RebuildVolume repopulates a replacement volume with the sectors of a failed
+// volume in the background. Sectors that cannot be read from the failed
+// volume are reconstructed from their parity groups. The failed volume is set
+// to read-only and should be removed once the rebuild completes. If sectors
+// were lost, the failed volume must be forcibly removed.
+func (vm *VolumeManager) RebuildVolume(from, to int, result chan<- error) error {
+	ctx, cancel, err := vm.tg.AddContext(context.Background())
+	if err != nil {
+		return err
+	} else if from == to {
+		cancel()
+		return errors.New("replacement volume must be different from the failed volume")
+	}
+
+	releaseFrom, err := vm.lockVolume(from)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to lock volume %v: %w", from, err)
+	}
+	releaseTo, err := vm.lockVolume(to)
+	if err != nil {
+		releaseFrom()
+		cancel()
+		return fmt.Errorf("failed to lock volume %v: %w", to, err)
+	}
+
+	// prevent new sectors from being stored in the failed volume
+	if err := vm.vs.SetReadOnly(from, true); err != nil {
+		releaseTo()
+		releaseFrom()
+		cancel()
+		return fmt.Errorf("failed to set volume %v to read-only: %w", from, err)
+	}
+
+	go func() {
+		defer cancel()
+
+		log := vm.log.Named("rebuild").With(zap.Int("from", from), zap.Int("to", to))
+		start := time.Now()
+		moved, lost, err := func() (int, int, error) {
+			defer releaseFrom()
+			defer releaseTo()
+
+			vm.setVolumeStatus(from, VolumeStatusRebuilding)
+			defer vm.setVolumeStatus(from, VolumeStatusReady)
+			vm.setVolumeStatus(to, VolumeStatusRebuilding)
+			defer vm.setVolumeStatus(to, VolumeStatusReady)
+			return vm.rebuildVolume(ctx, from, to, log)
+		}()
+
+		alert := alerts.Alert{
+			ID: frand.Entropy256(),
+			Data: map[string]any{
+				"from":    from,
+				"to":      to,
+				"elapsed": time.Since(start),
+				"moved":   moved,
+				"lost":    lost,
+			},
+			Timestamp: time.Now(),
+		}
+		if errors.Is(err, context.Canceled) {
+			alert.Message = "Volume rebuild cancelled"
+			alert.Severity = alerts.SeverityInfo
+		} else if err != nil {
+			log.Error("failed to rebuild volume", zap.Error(err))
+			alert.Message = "Volume rebuild failed"
+			alert.Severity = alerts.SeverityError
+			alert.Data["error"] = err.Error()
+		} else if lost > 0 {
+			alert.Message = "Volume rebuilt with lost sectors"
+			alert.Severity = alerts.SeverityError
+		} else {
+			alert.Message = "Volume rebuilt"
+			alert.Severity = alerts.SeverityInfo
+		}
+		vm.a.Register(alert)
+
+		select {
+		case result <- err:
+		default:
+		}
+	}()
+	return nil
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"errors"
+	"time"
 
 	"go.sia.tech/core/types"
 )
@@ -19,7 +20,7 @@ type (
 		// AddVolume initializes a new storage volume and adds it to the volume
 		// store. GrowVolume must be called afterwards to initialize the volume
 		// to its desired size.
-		AddVolume(localPath string, readOnly bool) (int, error)
+		AddVolume(localPath string, readOnly bool, backend VolumeBackend) (int, error)
 		// RemoveVolume removes a storage volume from the volume store. If there
 		// are used sectors in the volume, ErrVolumeNotEmpty is returned. If
 		// force is true, the volume is removed even if it is not empty.
@@ -36,12 +37,62 @@ type (
 		SetReadOnly(volumeID int, readOnly bool) error
 		// SetAvailable sets the available flag on a volume.
 		SetAvailable(volumeID int, available bool) error
+		// SetVolumeTier sets the storage tier of a volume.
+		SetVolumeTier(volumeID int, tier string) error
 
 		// MigrateSectors returns a new location for each occupied sector of a volume
 		// starting at min. The sector data should be copied to the new volume and
 		// synced to disk during migrateFn. Iteration is stopped if migrateFn returns an
-		// error.
+		// error. migrateFn may be called concurrently for consecutive batches.
 		MigrateSectors(volumeID int, min uint64, migrateFn func(newLocations []SectorLocation) error) error
+		// MigrateTierSectors moves at most limit sectors stored in volumes of
+		// the from tier to empty locations in volumes of the to tier. Only
+		// sectors last accessed within [accessedAfter, accessedBefore) are
+		// moved. The sector data should be copied to the new locations and
+		// synced to disk during migrateFn. The number of migrated sectors is
+		// returned.
+		MigrateTierSectors(from, to string, accessedAfter, accessedBefore time.Time, limit int, migrateFn func(newLocations []SectorLocation) error) (int, error)
+		// MoveSectors moves at most limit sectors from the end of one volume
+		// to empty locations in another writable volume. The sector data
+		// should be copied to the new locations and synced to disk during
+		// migrateFn, which returns the new locations that were written.
+		// Sectors that were not written are not moved. The number of moved
+		// sectors is returned.
+		MoveSectors(fromVolumeID, toVolumeID int, limit int, migrateFn func(newLocations []SectorLocation) ([]SectorLocation, error)) (int, error)
+		// ScrubSectors returns at most limit occupied sector locations of a
+		// volume starting at startIndex ordered by volume index. The locations
+		// are locked until release is called.
+		ScrubSectors(volumeID int, startIndex uint64, limit int) (locations []SectorLocation, release func() error, err error)
+		// VolumeScrub returns the persisted scrub status of a volume.
+		VolumeScrub(volumeID int) (ScrubStatus, error)
+		// SetVolumeScrub updates the persisted scrub status of a volume.
+		SetVolumeScrub(ScrubStatus) error
+
+		// AddVolumeOperation persists a volume operation so it can be
+		// resumed after a restart. The ID of the operation is returned.
+		AddVolumeOperation(VolumeOperation) (int64, error)
+		// UpdateVolumeOperation updates the progress cursor of a volume
+		// operation.
+		UpdateVolumeOperation(id int64, cursor uint64) error
+		// RemoveVolumeOperation removes a completed or cancelled volume
+		// operation.
+		RemoveVolumeOperation(id int64) error
+		// VolumeOperations returns all unfinished volume operations.
+		VolumeOperations() ([]VolumeOperation, error)
+
+		// ParityCandidates returns at most limit sector locations per
+		// available volume that are not protected by a parity group.
+		ParityCandidates(limit int) ([]SectorLocation, error)
+		// StoreParityGroup stores the parity sectors of a group in writable
+		// volumes not in excludeVolumes, each in a different volume. The
+		// parity data must be written to the locations passed to fn. The
+		// group is committed after fn returns. If no space is available,
+		// ErrNotEnoughStorage is returned.
+		StoreParityGroup(group ParityGroup, excludeVolumes []int, fn func(locations []SectorLocation) error) error
+		// ParityGroup returns the parity group protecting a sector. If the
+		// sector is not protected, ErrNoParity is returned.
+		ParityGroup(root types.Hash256) (ParityGroup, error)
+
 		// StoreSector calls fn with an empty location in a writable volume. If
 		// the sector root already exists, fn is called with the existing
 		// location and exists is true. Unless exists is true, The sector must
@@ -49,6 +100,10 @@ type (
 		// rolled back. If no space is available, ErrNotEnoughStorage is
 		// returned. The location is locked until release is called.
 		//
+		// If the existing location of the sector has been quarantined, fn is
+		// called with a new empty location and exists is false. The sector's
+		// metadata is moved to the new location after fn returns.
+		//
 		// The sector should be referenced by either a contract or temp store
 		// before release is called to prevent Prune() from removing it.
 		StoreSector(root types.Hash256, fn func(loc SectorLocation, exists bool) error) (release func() error, err error)
@@ -59,6 +114,14 @@ type (
 		// sector is not found. The location is locked until release is
 		// called.
 		SectorLocation(root types.Hash256) (loc SectorLocation, release func() error, err error)
+		// QuarantineLocation marks a sector location as corrupt. Quarantined
+		// locations are never reused. The sector's metadata continues to
+		// reference the location until a good copy of the sector is stored
+		// with StoreSector.
+		QuarantineLocation(loc SectorLocation, reason string) error
+		// SectorContracts returns the IDs of all contracts referencing a
+		// sector.
+		SectorContracts(root types.Hash256) ([]types.FileContractID, error)
 		// PruneSectors removes the metadata of all sectors that are no longer
 		// referenced by either a contract or temporary storage.
 		PruneSectors() (int, error)
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/alerts"
+	"go.uber.org/zap"
+	"lukechampine.com/frand"
+)
+
+// relocateSector writes a good copy of a quarantined sector to a new location
+// and moves the sector's metadata.
+func (vm *VolumeManager) relocateSector(root types.Hash256, data *[rhpv2.SectorSize]byte) error {
+	release, err := vm.vs.StoreSector(root, func(loc SectorLocation, exists bool) error {
+		if exists {
+			return errors.New("sector location is not quarantined")
+		}
+		return vm.writeSector(data, loc)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store sector: %w", err)
+	}
+	defer release()
+	return vm.Sync()
+}
+
+// quarantineSector marks the location of a sector as corrupt so that it will
+// not be reused. If a good copy of the sector is in the cache or can be
+// reconstructed from parity, it is immediately moved to a new location and
+// returned. Otherwise, the sector stays unavailable until it is uploaded
+// again and an alert is registered with the affected contracts.
+func (vm *VolumeManager) quarantineSector(loc SectorLocation, reason error) *[rhpv2.SectorSize]byte {
+	log := vm.log.Named("quarantine").With(zap.Stringer("root", loc.Root), zap.Int("volumeID", loc.Volume), zap.Uint64("index", loc.Index))
+	if err := vm.vs.QuarantineLocation(loc, reason.Error()); err != nil {
+		log.Error("failed to quarantine sector location", zap.Error(err))
+		return nil
+	}
+	log.Warn("quarantined sector location", zap.Error(reason))
+
+	contracts, err := vm.vs.SectorContracts(loc.Root)
+	if err != nil {
+		log.Error("failed to get sector contracts", zap.Error(err))
+	}
+
+	// reuse the alert ID if the sector was already quarantined to prevent
+	// duplicate alerts
+	vm.mu.Lock()
+	alertID, ok := vm.quarantined[loc.Root]
+	if !ok {
+		alertID = frand.Entropy256()
+		vm.quarantined[loc.Root] = alertID
+	}
+	vm.mu.Unlock()
+
+	alert := alerts.Alert{
+		ID:       alertID,
+		Severity: alerts.SeverityError,
+		Message:  "Sector quarantined",
+		Data: map[string]any{
+			"volumeID":  loc.Volume,
+			"index":     loc.Index,
+			"root":      loc.Root,
+			"contracts": contracts,
+			"error":     reason.Error(),
+		},
+		Timestamp: time.Now(),
+	}
+
+	// if the cache has a good copy of the sector, move it to a new location.
+	// Otherwise, try to reconstruct the sector from its parity group.
+	sector, ok := vm.cache.Peek(loc.Root)
+	if !ok || rhpv2.SectorRoot(sector) != loc.Root {
+		sector = nil
+		recovered, err := vm.reconstructSector(loc.Root)
+		if err == nil {
+			sector = recovered
+		} else if !errors.Is(err, ErrNoParity) {
+			log.Warn("failed to reconstruct sector", zap.Error(err))
+		}
+	}
+	if sector == nil {
+		vm.a.Register(alert)
+		return nil
+	}
+
+	if err := vm.relocateSector(loc.Root, sector); err != nil {
+		log.Error("failed to relocate sector", zap.Error(err))
+		vm.a.Register(alert)
+	} else {
+		vm.recoveredSector(loc.Root)
+	}
+	return sector
+}
+
+// recoveredSector replaces the quarantine alert of a sector after a good copy
+// has been stored in a new location.
+func (vm *VolumeManager) recoveredSector(root types.Hash256) {
+	vm.mu.Lock()
+	alertID, ok := vm.quarantined[root]
+	delete(vm.quarantined, root)
+	vm.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	vm.log.Named("quarantine").Info("recovered quarantined sector", zap.Stringer("root", root))
+	vm.a.Register(alerts.Alert{
+		ID:       alertID,
+		Severity: alerts.SeverityInfo,
+		Message:  "Quarantined sector recovered",
+		Data: map[string]any{
+			"root": root,
+		},
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,341 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	"go.sia.tech/hostd/host/alerts"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+	"lukechampine.com/frand"
+)
+
+const (
+	// rebalanceBatchSize is the maximum number of sectors moved in a single
+	// batch
+	rebalanceBatchSize = 64 // 256 MiB
+	// rebalanceTolerance is the maximum difference between a volume's
+	// utilization and the target utilization before sectors are moved.
+	rebalanceTolerance = 0.01
+)
+
+type (
+	// A RebalanceMove is a planned move of sectors between two volumes.
+	RebalanceMove struct {
+		From    int    `json:"from"`
+		To      int    `json:"to"`
+		Sectors uint64 `json:"sectors"`
+	}
+
+	// A RebalancePlan is the set of moves needed to bring every writable
+	// volume to the same utilization.
+	RebalancePlan struct {
+		TargetUtilization float64         `json:"targetUtilization"`
+		Moves             []RebalanceMove `json:"moves"`
+	}
+
+	// RebalanceStatus is the progress of a running rebalance.
+	RebalanceStatus struct {
+		Running bool          `json:"running"`
+		Plan    RebalancePlan `json:"plan"`
+		Moved   uint64        `json:"moved"`
+		Total   uint64        `json:"total"`
+		Started time.Time     `json:"started"`
+	}
+
+	// rebalanceJob tracks a running rebalance
+	rebalanceJob struct {
+		cancel context.CancelFunc
+		done   chan struct{}
+		status RebalanceStatus
+	}
+)
+
+// ErrRebalanceRunning is returned when a rebalance is already running.
+var ErrRebalanceRunning = errors.New("rebalance already running")
+
+// planRebalance calculates the moves needed to bring every writable volume to
+// the average utilization of all writable volumes.
+func planRebalance(volumes []Volume) RebalancePlan {
+	var used, total uint64
+	var eligible []Volume
+	for _, vol := range volumes {
+		if !vol.Available || vol.ReadOnly || vol.TotalSectors == 0 {
+			continue
+		}
+		used += vol.UsedSectors
+		total += vol.TotalSectors
+		eligible = append(eligible, vol)
+	}
+	if total == 0 {
+		return RebalancePlan{}
+	}
+
+	type delta struct {
+		id      int
+		sectors uint64
+	}
+	target := float64(used) / float64(total)
+	var over, under []delta
+	for _, vol := range eligible {
+		utilization := float64(vol.UsedSectors) / float64(vol.TotalSectors)
+		if math.Abs(utilization-target) <= rebalanceTolerance {
+			continue
+		}
+		ideal := uint64(math.Round(target * float64(vol.TotalSectors)))
+		if vol.UsedSectors > ideal {
+			over = append(over, delta{vol.ID, vol.UsedSectors - ideal})
+		} else {
+			under = append(under, delta{vol.ID, ideal - vol.UsedSectors})
+		}
+	}
+	// move the largest differences first
+	sort.Slice(over, func(i, j int) bool { return over[i].sectors > over[j].sectors })
+	sort.Slice(under, func(i, j int) bool { return under[i].sectors > under[j].sectors })
+
+	plan := RebalancePlan{TargetUtilization: target}
+	for i, j := 0, 0; i < len(over) && j < len(under); {
+		n := over[i].sectors
+		if under[j].sectors < n {
+			n = under[j].sectors
+		}
+		if n > 0 {
+			plan.Moves = append(plan.Moves, RebalanceMove{
+				From:    over[i].id,
+				To:      under[j].id,
+				Sectors: n,
+			})
+		}
+		over[i].sectors -= n
+		under[j].sectors -= n
+		if over[i].sectors == 0 {
+			i++
+		}
+		if under[j].sectors == 0 {
+			j++
+		}
+	}
+	return plan
+}
+
+// rebalanceMove moves sectors from one volume to another in batches.
+func (vm *VolumeManager) rebalanceMove(ctx context.Context, move RebalanceMove, log *zap.Logger) error {
+	releaseFrom, err := vm.lockVolume(move.From)
+	if err != nil {
+		return fmt.Errorf("failed to lock volume %v: %w", move.From, err)
+	}
+	defer releaseFrom()
+	releaseTo, err := vm.lockVolume(move.To)
+	if err != nil {
+		return fmt.Errorf("failed to lock volume %v: %w", move.To, err)
+	}
+	defer releaseTo()
+
+	vm.setVolumeStatus(move.From, VolumeStatusRebalancing)
+	defer vm.setVolumeStatus(move.From, VolumeStatusReady)
+	vm.setVolumeStatus(move.To, VolumeStatusRebalancing)
+	defer vm.setVolumeStatus(move.To, VolumeStatusReady)
+
+	for remaining := move.Sectors; remaining > 0; {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		limit := uint64(rebalanceBatchSize)
+		if remaining < limit {
+			limit = remaining
+		}
+		n, err := vm.vs.MoveSectors(move.From, move.To, int(limit), func(locations []SectorLocation) ([]SectorLocation, error) {
+			for range locations {
+				if err := vm.rebalanceLimit.WaitN(ctx, rhpv2.SectorSize); err != nil {
+					return nil, err
+				}
+			}
+			if _, err := vm.migrateSectors(locations, false, log); err != nil {
+				return nil, err
+			}
+			return locations, nil
+		})
+		if errors.Is(err, ErrNotEnoughStorage) {
+			// the destination volume filled up since the plan was made
+			return nil
+		} else if err != nil {
+			return err
+		} else if n == 0 {
+			return nil // no more sectors in the source volume
+		}
+		remaining -= uint64(n)
+
+		vm.mu.Lock()
+		if vm.rebalance != nil {
+			vm.rebalance.status.Moved += uint64(n)
+		}
+		vm.mu.Unlock()
+	}
+	return nil
+}
+
+// PlanRebalance returns the moves needed to bring every writable volume to a
+// similar utilization without moving any sectors.
+func (vm *VolumeManager) PlanRebalance() (RebalancePlan, error) {
+	done, err := vm.tg.Add()
+	if err != nil {
+		return RebalancePlan{}, err
+	}
+	defer done()
+
+	volumes, err := vm.vs.Volumes()
+	if err != nil {
+		return RebalancePlan{}, fmt.Errorf("failed to get volumes: %w", err)
+	}
+	return planRebalance(volumes), nil
+}
+
+// Rebalance moves sectors between writable volumes in the background until
+// every volume has a similar utilization. The maximum number of bytes per
+// second moved is limited by maxBandwidth, 0 is unlimited. The planned moves
+// are returned.
+func (vm *VolumeManager) Rebalance(maxBandwidth uint64, result chan<- error) (RebalancePlan, error) {
+	ctx, cancel, err := vm.tg.AddContext(context.Background())
+	if err != nil {
+		return RebalancePlan{}, err
+	}
+
+	vm.mu.Lock()
+	running := vm.rebalance != nil
+	vm.mu.Unlock()
+	if running {
+		cancel()
+		return RebalancePlan{}, ErrRebalanceRunning
+	}
+
+	volumes, err := vm.vs.Volumes()
+	if err != nil {
+		cancel()
+		return RebalancePlan{}, fmt.Errorf("failed to get volumes: %w", err)
+	}
+	plan := planRebalance(volumes)
+	if len(plan.Moves) == 0 {
+		cancel()
+		select {
+		case result <- nil:
+		default:
+		}
+		return plan, nil
+	}
+
+	var total uint64
+	for _, move := range plan.Moves {
+		total += move.Sectors
+	}
+
+	vm.mu.Lock()
+	if vm.rebalance != nil {
+		vm.mu.Unlock()
+		cancel()
+		return RebalancePlan{}, ErrRebalanceRunning
+	}
+	job := &rebalanceJob{
+		cancel: cancel,
+		done:   make(chan struct{}),
+		status: RebalanceStatus{
+			Running: true,
+			Plan:    plan,
+			Total:   total,
+			Started: time.Now(),
+		},
+	}
+	vm.rebalance = job
+	vm.mu.Unlock()
+
+	if maxBandwidth == 0 {
+		vm.rebalanceLimit.SetLimit(rate.Inf)
+	} else {
+		vm.rebalanceLimit.SetLimit(rate.Limit(maxBandwidth))
+	}
+
+	go func() {
+		defer func() {
+			vm.mu.Lock()
+			vm.rebalance = nil
+			vm.mu.Unlock()
+			cancel()
+			close(job.done)
+		}()
+
+		log := vm.log.Named("rebalance")
+		start := time.Now()
+		err := func() error {
+			for _, move := range plan.Moves {
+				if err := vm.rebalanceMove(ctx, move, log.With(zap.Int("from", move.From), zap.Int("to", move.To))); err != nil {
+					return fmt.Errorf("failed to move sectors from volume %v to volume %v: %w", move.From, move.To, err)
+				}
+			}
+			return nil
+		}()
+
+		vm.mu.Lock()
+		moved := job.status.Moved
+		vm.mu.Unlock()
+
+		alert := alerts.Alert{
+			ID: frand.Entropy256(),
+			Data: map[string]any{
+				"elapsed": time.Since(start),
+				"moved":   moved,
+				"planned": total,
+			},
+			Timestamp: time.Now(),
+		}
+		if errors.Is(err, context.Canceled) {
+			alert.Message = "Volume rebalance cancelled"
+			alert.Severity = alerts.SeverityInfo
+		} else if err != nil {
+			log.Error("failed to rebalance volumes", zap.Error(err))
+			alert.Message = "Volume rebalance failed"
+			alert.Severity = alerts.SeverityError
+			alert.Data["error"] = err.Error()
+		} else {
+			alert.Message = "Volumes rebalanced"
+			alert.Severity = alerts.SeverityInfo
+		}
+		vm.a.Register(alert)
+
+		select {
+		case result <- err:
+		default:
+		}
+	}()
+	return plan, nil
+}
+
+// RebalanceStatus returns the progress of the running rebalance.
+func (vm *VolumeManager) RebalanceStatus() RebalanceStatus {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	if vm.rebalance == nil {
+		return RebalanceStatus{}
+	}
+	return vm.rebalance.status
+}
+
+// CancelRebalance stops the running rebalance. Sectors that have already been
+// moved are not moved back.
+func (vm *VolumeManager) CancelRebalance() error {
+	vm.mu.Lock()
+	job := vm.rebalance
+	vm.mu.Unlock()
+	if job == nil {
+		return errors.New("rebalance not running")
+	}
+	job.cancel()
+	<-job.done
+	return nil
+}
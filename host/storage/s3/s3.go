@@ -0,0 +1,217 @@
+package s3
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	rhpv2 "go.sia.tech/core/rhp/v2"
+)
+
+const (
+	// objectSize is the size of each object in the bucket. Each sector is
+	// stored as a separate object.
+	objectSize = rhpv2.SectorSize
+
+	// requestTimeout is the maximum time a request to the object store may
+	// take, including reading the response body. It prevents a stalled
+	// endpoint from blocking volume I/O indefinitely.
+	requestTimeout = 2 * time.Minute
+)
+
+type (
+	// Options is the set of configuration options for an S3 volume.
+	Options struct {
+		Endpoint        string `json:"endpoint"`
+		Region          string `json:"region"`
+		Bucket          string `json:"bucket"`
+		AccessKeyID     string `json:"accessKeyID"`
+		SecretAccessKey string `json:"secretAccessKey"`
+		// PathStyle forces path-style bucket addressing. It is required by
+		// most self-hosted S3-compatible services.
+		PathStyle bool `json:"pathStyle"`
+	}
+
+	// A Volume stores sector data as objects in an S3-compatible bucket. Each
+	// sector is stored in a separate object keyed by its index. Sectors that
+	// have not been written are read as zeroes.
+	Volume struct {
+		client *s3.S3
+		bucket string
+		prefix string
+	}
+)
+
+var (
+	// ErrUnaligned is returned when a write does not cover exactly one object.
+	ErrUnaligned = errors.New("writes must be aligned to the sector size")
+)
+
+func (v *Volume) objectKey(index int64) string {
+	return fmt.Sprintf("%s/%016x", v.prefix, index)
+}
+
+func isNotFound(err error) bool {
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		return false
+	}
+	switch awsErr.Code() {
+	case s3.ErrCodeNoSuchKey, "NotFound":
+		return true
+	}
+	return false
+}
+
+// ReadAt implements io.ReaderAt. Reads must not span multiple sectors.
+func (v *Volume) ReadAt(p []byte, off int64) (int, error) {
+	index, start := off/objectSize, off%objectSize
+	if start+int64(len(p)) > objectSize {
+		return 0, errors.New("reads must not cross sector boundaries")
+	} else if len(p) == 0 {
+		return 0, nil
+	}
+
+	resp, err := v.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(v.bucket),
+		Key:    aws.String(v.objectKey(index)),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", start, start+int64(len(p))-1)),
+	})
+	if isNotFound(err) {
+		// the sector has not been written yet
+		for i := range p {
+			p[i] = 0
+		}
+		return len(p), nil
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to get object: %w", err)
+	}
+	defer resp.Body.Close()
+	return io.ReadFull(resp.Body, p)
+}
+
+// WriteAt implements io.WriterAt. Each write must cover exactly one sector.
+func (v *Volume) WriteAt(p []byte, off int64) (int, error) {
+	if off%objectSize != 0 || len(p) != objectSize {
+		return 0, ErrUnaligned
+	}
+
+	_, err := v.client.PutObject(&s3.PutObjectInput{
+		Bucket:        aws.String(v.bucket),
+		Key:           aws.String(v.objectKey(off / objectSize)),
+		Body:          bytes.NewReader(p),
+		ContentLength: aws.Int64(int64(len(p))),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to put object: %w", err)
+	}
+	return len(p), nil
+}
+
+// Sync is a no-op. Objects are durable once PutObject returns.
+func (v *Volume) Sync() error {
+	return nil
+}
+
+// Truncate removes all objects at or beyond size. Growing the volume is a
+// no-op since objects are only created when sectors are written.
+func (v *Volume) Truncate(size int64) error {
+	maxIndex := size / objectSize
+	if size%objectSize != 0 {
+		maxIndex++
+	}
+
+	var toDelete []string
+	err := v.client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(v.bucket),
+		Prefix: aws.String(v.prefix + "/"),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, obj := range page.Contents {
+			key := aws.StringValue(obj.Key)
+			index, err := strconv.ParseInt(strings.TrimPrefix(key, v.prefix+"/"), 16, 64)
+			if err != nil {
+				// ignore objects not created by the volume
+				continue
+			} else if index >= maxIndex {
+				toDelete = append(toDelete, key)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	for _, key := range toDelete {
+		_, err := v.client.DeleteObject(&s3.DeleteObjectInput{
+			Bucket: aws.String(v.bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil && !isNotFound(err) {
+			return fmt.Errorf("failed to delete object %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// Close is a no-op.
+func (v *Volume) Close() error {
+	return nil
+}
+
+// ValidateOptions validates the options for an S3 volume.
+func ValidateOptions(opts Options) error {
+	switch {
+	case opts.Bucket == "":
+		return errors.New("bucket is required")
+	case opts.AccessKeyID == "":
+		return errors.New("access key ID is required")
+	case opts.SecretAccessKey == "":
+		return errors.New("secret access key is required")
+	case opts.Endpoint == "" && opts.Region == "":
+		return errors.New("either endpoint or region is required")
+	}
+	return nil
+}
+
+// New returns a new S3 volume storing its sectors under prefix.
+func New(prefix string, opts Options) (*Volume, error) {
+	if err := ValidateOptions(opts); err != nil {
+		return nil, err
+	}
+
+	region := opts.Region
+	if region == "" {
+		// most S3-compatible services ignore the region, but the SDK requires
+		// one to sign requests.
+		region = "us-east-1"
+	}
+	cfg := &aws.Config{
+		Credentials:      credentials.NewStaticCredentials(opts.AccessKeyID, opts.SecretAccessKey, ""),
+		Region:           aws.String(region),
+		S3ForcePathStyle: aws.Bool(opts.PathStyle),
+		HTTPClient:       &http.Client{Timeout: requestTimeout},
+	}
+	if opts.Endpoint != "" {
+		cfg.Endpoint = aws.String(opts.Endpoint)
+	}
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	return &Volume{
+		client: s3.New(sess),
+		bucket: opts.Bucket,
+		prefix: strings.Trim(prefix, "/"),
+	}, nil
+}
@@ -0,0 +1,182 @@
+package s3_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	"go.sia.tech/hostd/host/storage/s3"
+	"lukechampine.com/frand"
+)
+
+// objectStore is a minimal in-memory stand-in for an S3-compatible object
+// store. Only path-style requests to a single bucket are supported.
+type objectStore struct {
+	bucket string
+
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+type listBucketResult struct {
+	XMLName  xml.Name `xml:"ListBucketResult"`
+	Name     string   `xml:"Name"`
+	Prefix   string   `xml:"Prefix"`
+	KeyCount int      `xml:"KeyCount"`
+	Contents []struct {
+		Key  string `xml:"Key"`
+		Size int    `xml:"Size"`
+	} `xml:"Contents"`
+	IsTruncated bool `xml:"IsTruncated"`
+}
+
+func (s *objectStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	bucket, key, _ := strings.Cut(path, "/")
+	if bucket != s.bucket {
+		http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		prefix := r.URL.Query().Get("prefix")
+		var resp listBucketResult
+		resp.Name, resp.Prefix = s.bucket, prefix
+		keys := make([]string, 0, len(s.objects))
+		for k := range s.objects {
+			if strings.HasPrefix(k, prefix) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			resp.Contents = append(resp.Contents, struct {
+				Key  string `xml:"Key"`
+				Size int    `xml:"Size"`
+			}{k, len(s.objects[k])})
+		}
+		resp.KeyCount = len(keys)
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(resp)
+	case r.Method == http.MethodGet:
+		buf, ok := s.objects[key]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "<Error><Code>NoSuchKey</Code><Message>not found</Message></Error>")
+			return
+		}
+		http.ServeContent(w, r, key, time.Time{}, bytes.NewReader(buf))
+	case r.Method == http.MethodPut:
+		buf, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.objects[key] = buf
+	case r.Method == http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "not implemented", http.StatusNotImplemented)
+	}
+}
+
+func (s *objectStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.objects)
+}
+
+func TestVolume(t *testing.T) {
+	store := &objectStore{
+		bucket:  "hostd",
+		objects: make(map[string][]byte),
+	}
+	srv := httptest.NewServer(store)
+	defer srv.Close()
+
+	vol, err := s3.New("volumes/test", s3.Options{
+		Endpoint:        srv.URL,
+		Bucket:          "hostd",
+		AccessKeyID:     "test",
+		SecretAccessKey: "test",
+		PathStyle:       true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// unwritten sectors should be read as zeroes
+	buf := make([]byte, 64)
+	frand.Read(buf)
+	if _, err := vol.ReadAt(buf, 10*rhpv2.SectorSize); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(buf, make([]byte, 64)) {
+		t.Fatal("expected unwritten sector to be zeroes")
+	}
+
+	// unaligned writes should be rejected
+	if _, err := vol.WriteAt(make([]byte, 64), 0); err != s3.ErrUnaligned {
+		t.Fatalf("expected ErrUnaligned, got %v", err)
+	}
+
+	sectors := make([][]byte, 4)
+	for i := range sectors {
+		sectors[i] = frand.Bytes(rhpv2.SectorSize)
+		if _, err := vol.WriteAt(sectors[i], int64(i)*rhpv2.SectorSize); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := range sectors {
+		// read the full sector
+		buf := make([]byte, rhpv2.SectorSize)
+		if _, err := vol.ReadAt(buf, int64(i)*rhpv2.SectorSize); err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(buf, sectors[i]) {
+			t.Fatalf("sector %v mismatch", i)
+		}
+
+		// read a range within the sector
+		buf = make([]byte, 128)
+		if _, err := vol.ReadAt(buf, int64(i)*rhpv2.SectorSize+512); err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(buf, sectors[i][512:640]) {
+			t.Fatalf("sector %v range mismatch", i)
+		}
+	}
+
+	// shrink the volume to 2 sectors
+	if err := vol.Truncate(2 * rhpv2.SectorSize); err != nil {
+		t.Fatal(err)
+	} else if n := store.len(); n != 2 {
+		t.Fatalf("expected 2 objects, got %v", n)
+	}
+
+	// truncated sectors should be read as zeroes
+	if _, err := vol.ReadAt(buf, 3*rhpv2.SectorSize); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(buf, make([]byte, len(buf))) {
+		t.Fatal("expected truncated sector to be zeroes")
+	}
+
+	// remove the remaining sectors
+	if err := vol.Truncate(0); err != nil {
+		t.Fatal(err)
+	} else if n := store.len(); n != 0 {
+		t.Fatalf("expected 0 objects, got %v", n)
+	}
+}
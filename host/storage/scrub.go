@@ -0,0 +1,295 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// scrubBatchSize is the number of sectors checked between cursor updates
+	scrubBatchSize = 64 // 256 MiB
+
+	// scrubCheckInterval is the interval between checks for volumes that
+	// need to be scrubbed
+	scrubCheckInterval = time.Hour
+	// scrubInterval is the minimum time between full scrubs of a volume
+	scrubInterval = 30 * 24 * time.Hour
+)
+
+type (
+	// ScrubStatus is the progress of a volume's integrity scrub. The scrub
+	// walks every occupied sector in the volume and verifies its Merkle root.
+	ScrubStatus struct {
+		VolumeID int  `json:"volumeID"`
+		Running  bool `json:"running"`
+		// Cursor is the volume index of the next sector to check. It is reset
+		// to 0 when the scrub is complete.
+		Cursor  uint64 `json:"cursor"`
+		Checked uint64 `json:"checked"`
+		Corrupt uint64 `json:"corrupt"`
+		Missing uint64 `json:"missing"`
+
+		LastStarted   time.Time `json:"lastStarted"`
+		LastCompleted time.Time `json:"lastCompleted"`
+	}
+
+	// scrubJob tracks a running scrub
+	scrubJob struct {
+		cancel context.CancelFunc
+		done   chan struct{}
+	}
+)
+
+// ErrScrubRunning is returned when a scrub is already running on a volume.
+var ErrScrubRunning = errors.New("scrub already running")
+
+// scrubLocation verifies the sector stored at loc. The returned error is nil
+// if the sector data matches its root.
+func (vm *VolumeManager) scrubLocation(vol *volume, loc SectorLocation) (missing bool, err error) {
+	sector, err := vol.ReadSector(loc.Index)
+	if err != nil {
+		return true, fmt.Errorf("failed to read sector: %w", err)
+	} else if calculated := rhpv2.SectorRoot(sector); calculated != loc.Root {
+		return false, fmt.Errorf("sector corrupt: expected root %v, got %v", loc.Root, calculated)
+	}
+	return false, nil
+}
+
+// scrubVolume checks every occupied sector in a volume starting at the
+// persisted cursor. The cursor is updated after every batch so the scrub can
+// be resumed after it is interrupted.
+func (vm *VolumeManager) scrubVolume(ctx context.Context, id int) error {
+	log := vm.log.Named("scrub").With(zap.Int("volumeID", id))
+	status, err := vm.vs.VolumeScrub(id)
+	if err != nil {
+		return fmt.Errorf("failed to get scrub status: %w", err)
+	}
+	// start a new scrub if the previous one completed
+	if status.Cursor == 0 {
+		status.Checked, status.Corrupt, status.Missing = 0, 0, 0
+		status.LastStarted = time.Now()
+		if err := vm.vs.SetVolumeScrub(status); err != nil {
+			return fmt.Errorf("failed to update scrub status: %w", err)
+		}
+	}
+	vm.setScrubStatus(status)
+
+	log.Debug("scrubbing volume", zap.Uint64("cursor", status.Cursor))
+	for {
+		locations, release, err := vm.vs.ScrubSectors(id, status.Cursor, scrubBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to get sectors: %w", err)
+		} else if len(locations) == 0 {
+			break
+		}
+
+		err = func() error {
+			defer release()
+
+			vol, err := vm.getVolume(id)
+			if err != nil {
+				return fmt.Errorf("failed to get volume: %w", err)
+			}
+
+			for _, loc := range locations {
+				if err := vm.scrubLimit.WaitN(ctx, rhpv2.SectorSize); err != nil {
+					return err
+				}
+
+				missing, err := vm.scrubLocation(vol, loc)
+				if errors.Is(err, ErrVolumeNotAvailable) {
+					return err
+				} else if err != nil {
+					log.Error("sector failed integrity check", zap.Stringer("root", loc.Root), zap.Uint64("index", loc.Index), zap.Error(err))
+					if missing {
+						status.Missing++
+					} else {
+						status.Corrupt++
+					}
+					vm.quarantineSector(loc, err)
+				}
+				status.Checked++
+				status.Cursor = loc.Index + 1
+			}
+			return nil
+		}()
+		// persist the cursor even if the batch was interrupted
+		if err := vm.vs.SetVolumeScrub(status); err != nil {
+			return fmt.Errorf("failed to update scrub status: %w", err)
+		}
+		vm.setScrubStatus(status)
+		if err != nil {
+			return err
+		}
+	}
+
+	status.Cursor = 0
+	status.LastCompleted = time.Now()
+	if err := vm.vs.SetVolumeScrub(status); err != nil {
+		return fmt.Errorf("failed to update scrub status: %w", err)
+	}
+	vm.setScrubStatus(status)
+	log.Info("volume scrub complete", zap.Uint64("checked", status.Checked), zap.Uint64("corrupt", status.Corrupt), zap.Uint64("missing", status.Missing))
+	return nil
+}
+
+// setScrubStatus updates the in-memory status of a running scrub
+func (vm *VolumeManager) setScrubStatus(status ScrubStatus) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	status.Running = true
+	vm.scrubStatus[status.VolumeID] = status
+}
+
+// startScrub starts scrubbing a volume in a new goroutine. The returned channel
+// is closed when the scrub stops.
+func (vm *VolumeManager) startScrub(id int) (<-chan struct{}, error) {
+	ctx, cancel, err := vm.tg.AddContext(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	vm.mu.Lock()
+	if _, ok := vm.volumes[id]; !ok {
+		vm.mu.Unlock()
+		cancel()
+		return nil, ErrVolumeNotFound
+	} else if _, ok := vm.scrubs[id]; ok {
+		vm.mu.Unlock()
+		cancel()
+		return nil, ErrScrubRunning
+	}
+	job := scrubJob{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	vm.scrubs[id] = job
+	vm.mu.Unlock()
+
+	go func() {
+		defer func() {
+			vm.mu.Lock()
+			delete(vm.scrubs, id)
+			delete(vm.scrubStatus, id)
+			vm.mu.Unlock()
+			cancel()
+			close(job.done)
+		}()
+
+		err := vm.scrubVolume(ctx, id)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			vm.log.Error("failed to scrub volume", zap.Int("volumeID", id), zap.Error(err))
+		}
+	}()
+	return job.done, nil
+}
+
+// scrubVolumes periodically scrubs any volumes that have an interrupted scrub
+// or have not been scrubbed recently. Volumes are scrubbed one at a time.
+func (vm *VolumeManager) scrubVolumes() {
+	t := time.NewTicker(scrubCheckInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-vm.tg.Done():
+			return
+		case <-t.C:
+		}
+
+		volumes, err := vm.vs.Volumes()
+		if err != nil {
+			vm.log.Error("failed to get volumes", zap.Error(err))
+			continue
+		}
+
+		for _, vol := range volumes {
+			if !vol.Available {
+				continue
+			}
+
+			status, err := vm.vs.VolumeScrub(vol.ID)
+			if err != nil {
+				vm.log.Error("failed to get scrub status", zap.Int("volumeID", vol.ID), zap.Error(err))
+				continue
+			} else if status.Cursor == 0 && time.Since(status.LastCompleted) < scrubInterval {
+				continue
+			}
+
+			done, err := vm.startScrub(vol.ID)
+			if errors.Is(err, ErrScrubRunning) || errors.Is(err, ErrVolumeNotFound) {
+				continue
+			} else if err != nil {
+				return
+			}
+
+			select {
+			case <-vm.tg.Done():
+				return
+			case <-done:
+			}
+		}
+	}
+}
+
+// ScrubVolume starts scrubbing a volume in the background. If a previous scrub
+// was interrupted, it is resumed from its last position.
+func (vm *VolumeManager) ScrubVolume(id int) error {
+	done, err := vm.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	_, err = vm.startScrub(id)
+	return err
+}
+
+// CancelScrub stops a running scrub. The scrub's progress is persisted and
+// will be resumed the next time the volume is scrubbed.
+func (vm *VolumeManager) CancelScrub(id int) error {
+	vm.mu.Lock()
+	job, ok := vm.scrubs[id]
+	vm.mu.Unlock()
+	if !ok {
+		return errors.New("scrub not running")
+	}
+	job.cancel()
+	<-job.done
+	return nil
+}
+
+// ScrubStatus returns the scrub status of a volume.
+func (vm *VolumeManager) ScrubStatus(id int) (ScrubStatus, error) {
+	done, err := vm.tg.Add()
+	if err != nil {
+		return ScrubStatus{}, err
+	}
+	defer done()
+
+	vm.mu.Lock()
+	status, ok := vm.scrubStatus[id]
+	vm.mu.Unlock()
+	if ok {
+		return status, nil
+	} else if _, err := vm.vs.Volume(id); err != nil {
+		return ScrubStatus{}, err
+	}
+	return vm.vs.VolumeScrub(id)
+}
+
+// SetScrubRate sets the maximum number of bytes per second read by the
+// scrubber. A rate of 0 removes the limit.
+func (vm *VolumeManager) SetScrubRate(bytesPerSecond uint64) {
+	if bytesPerSecond == 0 {
+		vm.scrubLimit.SetLimit(rate.Inf)
+		return
+	}
+	vm.scrubLimit.SetLimit(rate.Limit(bytesPerSecond))
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -16,6 +15,7 @@ import (
 	"go.sia.tech/hostd/host/alerts"
 	"go.sia.tech/hostd/internal/threadgroup"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
 	"lukechampine.com/frand"
 )
 
@@ -31,6 +31,8 @@ const (
 	VolumeStatusCreating    = "creating"
 	VolumeStatusResizing    = "resizing"
 	VolumeStatusRemoving    = "removing"
+	VolumeStatusRebalancing = "rebalancing"
+	VolumeStatusRebuilding  = "rebuilding"
 	VolumeStatusReady       = "ready"
 )
 
@@ -80,6 +82,25 @@ type (
 		// changedVolumes tracks volumes that need to be fsynced
 		changedVolumes map[int]bool
 		cache          *lru.Cache[types.Hash256, *[rhpv2.SectorSize]byte] // Added cache
+		// scrubs tracks the running volume scrubs
+		scrubs      map[int]scrubJob
+		scrubStatus map[int]ScrubStatus
+		scrubLimit  *rate.Limiter
+		// quarantined maps the roots of quarantined sectors to their alert
+		// ID
+		quarantined map[types.Hash256]types.Hash256
+
+		tierSettings TierSettings
+		tierLimit    *rate.Limiter
+
+		paritySettings ParitySettings
+
+		rebalance      *rebalanceJob
+		rebalanceLimit *rate.Limiter
+
+		// operations tracks the running add, resize and remove operations
+		// by volume ID
+		operations map[int]*volumeOperationJob
 	}
 )
 
@@ -152,7 +173,8 @@ func (vm *VolumeManager) cleanup() {
 	}
 }
 
-// loadVolumes opens all volumes. Volumes that are already loaded are skipped.
+// loadVolumes opens all volumes and resumes any interrupted volume
+// operations. Volumes that are already loaded are skipped.
 func (vm *VolumeManager) loadVolumes() error {
 	done, err := vm.tg.Add()
 	if err != nil {
@@ -164,84 +186,58 @@ func (vm *VolumeManager) loadVolumes() error {
 	if err != nil {
 		return fmt.Errorf("failed to load volumes: %w", err)
 	}
-	vm.mu.Lock()
-	defer vm.mu.Unlock()
-	// load the volumes into memory
-	for _, vol := range volumes {
-		// if the volume has not been loaded yet, create a new volume
-		v := vm.volumes[vol.ID]
-		if v == nil {
-			v = &volume{
-				stats: VolumeStats{
-					Status: VolumeStatusUnavailable,
-				},
+	err = func() error {
+		vm.mu.Lock()
+		defer vm.mu.Unlock()
+		// load the volumes into memory
+		for _, vol := range volumes {
+			// if the volume has not been loaded yet, create a new volume
+			v := vm.volumes[vol.ID]
+			if v == nil {
+				v = &volume{
+					stats: VolumeStats{
+						Status: VolumeStatusUnavailable,
+					},
+				}
+				vm.volumes[vol.ID] = v
 			}
-			vm.volumes[vol.ID] = v
-		}
 
-		if err := v.OpenVolume(vol.LocalPath, false); err != nil {
-			v.appendError(fmt.Errorf("failed to open volume: %w", err))
-			vm.log.Error("unable to open volume", zap.Error(err), zap.Int("id", vol.ID), zap.String("path", vol.LocalPath))
-			// mark the volume as unavailable
-			if err := vm.vs.SetAvailable(vol.ID, false); err != nil {
-				return fmt.Errorf("failed to mark volume '%v' as unavailable: %w", vol.LocalPath, err)
-			}
+			if err := v.OpenVolume(vol.LocalPath, vol.Backend, false); err != nil {
+				v.appendError(fmt.Errorf("failed to open volume: %w", err))
+				vm.log.Error("unable to open volume", zap.Error(err), zap.Int("id", vol.ID), zap.String("path", vol.LocalPath))
+				// mark the volume as unavailable
+				if err := vm.vs.SetAvailable(vol.ID, false); err != nil {
+					return fmt.Errorf("failed to mark volume '%v' as unavailable: %w", vol.LocalPath, err)
+				}
 
-			// register an alert
-			vm.a.Register(alerts.Alert{
-				ID:       frand.Entropy256(),
-				Severity: alerts.SeverityError,
-				Message:  "Failed to open volume",
-				Data: map[string]any{
-					"volume": vol.LocalPath,
-					"error":  err.Error(),
-				},
-				Timestamp: time.Now(),
-			})
-
-			continue
-		}
-		// mark the volume as available
-		if err := vm.vs.SetAvailable(vol.ID, true); err != nil {
-			return fmt.Errorf("failed to mark volume '%v' as available: %w", vol.LocalPath, err)
-		}
-		v.SetStatus(VolumeStatusReady)
-		vm.log.Debug("loaded volume", zap.Int("id", vol.ID), zap.String("path", vol.LocalPath))
-	}
-	return nil
-}
+				// register an alert
+				vm.a.Register(alerts.Alert{
+					ID:       frand.Entropy256(),
+					Severity: alerts.SeverityError,
+					Message:  "Failed to open volume",
+					Data: map[string]any{
+						"volume": vol.LocalPath,
+						"error":  err.Error(),
+					},
+					Timestamp: time.Now(),
+				})
 
-// migrateSector migrates sectors to new locations. The sectors are read from
-// their current locations and written to their new locations. Changed volumes
-// are synced after all sectors have been written.
-func (vm *VolumeManager) migrateSectors(locations []SectorLocation, force bool, log *zap.Logger) (migrated int, _ error) {
-	for _, loc := range locations {
-		err := func() error {
-			// read the sector from the old location
-			sector, err := vm.Read(loc.Root)
-			if err != nil {
-				return fmt.Errorf("failed to read sector: %w", err)
-			}
-			// calculate the returned root
-			root := rhpv2.SectorRoot(sector)
-			if root != loc.Root {
-				return fmt.Errorf("sector corrupt: %v != %v", loc.Root, root)
-			}
-			if err := vm.writeSector(sector, loc); err != nil { // write the sector to the new location
-				return fmt.Errorf("failed to write sector: %w", err)
-			}
-			return nil
-		}()
-		if err != nil {
-			log.Error("failed to migrate sector", zap.Error(err), zap.Stringer("root", loc.Root), zap.Int("newVolumeID", loc.Volume), zap.Uint64("newIndex", loc.Index))
-			if force {
 				continue
 			}
-			return migrated, fmt.Errorf("failed to migrate sector %v: %w", loc.Root, err)
+			// mark the volume as available
+			if err := vm.vs.SetAvailable(vol.ID, true); err != nil {
+				return fmt.Errorf("failed to mark volume '%v' as available: %w", vol.LocalPath, err)
+			}
+			v.SetStatus(VolumeStatusReady)
+			vm.log.Debug("loaded volume", zap.Int("id", vol.ID), zap.String("path", vol.LocalPath))
 		}
-		migrated++
+		return nil
+	}()
+	if err != nil {
+		return err
 	}
-	return migrated, vm.Sync()
+	// resume any operations interrupted by a shutdown
+	return vm.resumeOperations()
 }
 
 // growVolume grows a volume by adding sectors to the end of the volume.
@@ -288,11 +284,16 @@ func (vm *VolumeManager) growVolume(ctx context.Context, id int, oldMaxSectors,
 		// truncate the file and add the indices to the volume store. resize is
 		// done in chunks to prevent holding a lock for too long and to allow
 		// progress tracking.
-		if v.Resize(target); err != nil {
+		if err := v.Resize(target); err != nil {
+			// the metadata has not been grown past current, truncate any
+			// partially expanded data to match it
+			vm.rollbackGrow(v, id, current)
 			return fmt.Errorf("failed to expand volume data: %w", err)
 		} else if err := vm.vs.GrowVolume(id, target); err != nil {
+			vm.rollbackGrow(v, id, current)
 			return fmt.Errorf("failed to expand volume metadata: %w", err)
 		}
+		vm.updateOperationCursor(id, target, false)
 
 		// update the alert
 		alert.Data["currentSectors"] = target
@@ -303,6 +304,14 @@ func (vm *VolumeManager) growVolume(ctx context.Context, id int, oldMaxSectors,
 	return nil
 }
 
+// rollbackGrow truncates a volume's data back to the last size recorded in
+// its metadata after a failed grow.
+func (vm *VolumeManager) rollbackGrow(v *volume, id int, sectors uint64) {
+	if err := v.Resize(sectors); err != nil {
+		vm.log.Error("failed to roll back volume data", zap.Int("volumeID", id), zap.Uint64("sectors", sectors), zap.Error(err))
+	}
+}
+
 // shrinkVolume shrinks a volume by removing sectors from the end of the volume.
 func (vm *VolumeManager) shrinkVolume(ctx context.Context, id int, oldMaxSectors, newMaxSectors uint64) error {
 	log := vm.log.Named("shrinkVolume").With(zap.Int("volumeID", id), zap.Uint64("oldMaxSectors", oldMaxSectors), zap.Uint64("newMaxSectors", newMaxSectors))
@@ -334,8 +343,23 @@ func (vm *VolumeManager) shrinkVolume(ctx context.Context, id int, oldMaxSectors
 	// responsibility to register a completion alert
 	defer vm.a.Dismiss(a.ID)
 
-	// migrate any sectors outside of the target range. migrateSectors will be
-	// called on chunks of 64 sectors
+	// estimate the number of sectors that need to be migrated for progress
+	// tracking
+	vol, err := vm.vs.Volume(id)
+	if err != nil {
+		return fmt.Errorf("failed to get volume: %w", err)
+	}
+	total := oldMaxSectors - newMaxSectors
+	if vol.UsedSectors < total {
+		total = vol.UsedSectors
+	}
+	updateProgress, doneProgress := vm.trackMigration(id, total)
+	defer doneProgress()
+
+	// migrate any sectors outside of the target range. The store migrates
+	// chunks of 256 sectors and may migrate consecutive chunks concurrently
+	pipeline := vm.newMigrationPipeline(false, log.Named("migrateSectors"))
+	var mu sync.Mutex
 	var migrated int
 	err = vm.vs.MigrateSectors(id, newMaxSectors, func(newLocations []SectorLocation) error {
 		select {
@@ -344,13 +368,17 @@ func (vm *VolumeManager) shrinkVolume(ctx context.Context, id int, oldMaxSectors
 		default:
 		}
 
-		n, err := vm.migrateSectors(newLocations, false, log.Named("migrateSectors"))
+		n, err := pipeline.migrate(newLocations)
+		updateProgress(n)
+		mu.Lock()
+		defer mu.Unlock()
 		migrated += n
 		// update the alert
 		a.Data["migratedSectors"] = migrated
 		vm.a.Register(a)
 		return err
 	})
+	pipeline.close()
 	log.Info("migrated sectors", zap.Int("count", migrated))
 	if err != nil {
 		return fmt.Errorf("failed to migrate sectors: %w", err)
@@ -379,6 +407,7 @@ func (vm *VolumeManager) shrinkVolume(ctx context.Context, id int, oldMaxSectors
 		} else if err := volume.Resize(target); err != nil {
 			return fmt.Errorf("failed to shrink volume data to %v sectors: %w", target, err)
 		}
+		vm.updateOperationCursor(id, target, false)
 		// update the alert
 		a.Data["currentSectors"] = target
 		vm.a.Register(a)
@@ -409,13 +438,7 @@ func (vm *VolumeManager) setVolumeStatus(id int, status string) {
 	v.stats.Status = status
 }
 
-func (vm *VolumeManager) doResize(volumeID int, current, target uint64) error {
-	ctx, cancel, err := vm.tg.AddContext(context.Background())
-	if err != nil {
-		return err
-	}
-	defer cancel()
-
+func (vm *VolumeManager) doResize(ctx context.Context, volumeID int, current, target uint64) error {
 	switch {
 	case current > target:
 		// volume is shrinking
@@ -427,13 +450,7 @@ func (vm *VolumeManager) doResize(volumeID int, current, target uint64) error {
 	return nil
 }
 
-func (vm *VolumeManager) migrateForRemoval(id int, localPath string, force bool, log *zap.Logger) (int, error) {
-	ctx, cancel, err := vm.tg.AddContext(context.Background())
-	if err != nil {
-		return 0, err
-	}
-	defer cancel()
-
+func (vm *VolumeManager) migrateForRemoval(ctx context.Context, id int, localPath string, backend VolumeBackend, force bool, log *zap.Logger) (int, error) {
 	// add an alert for the migration
 	a := alerts.Alert{
 		ID:       frand.Entropy256(),
@@ -451,7 +468,17 @@ func (vm *VolumeManager) migrateForRemoval(id int, localPath string, force bool,
 	// responsibility to register a completion alert
 	defer vm.a.Dismiss(a.ID)
 
-	// migrate sectors to other volumes
+	vol, err := vm.vs.Volume(id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get volume: %w", err)
+	}
+	updateProgress, doneProgress := vm.trackMigration(id, vol.UsedSectors)
+	defer doneProgress()
+
+	// migrate sectors to other volumes. The store may migrate consecutive
+	// batches concurrently
+	pipeline := vm.newMigrationPipeline(force, log.Named("migrateSectors"))
+	var mu sync.Mutex
 	var migrated int
 	err = vm.vs.MigrateSectors(id, 0, func(locations []SectorLocation) error {
 		select {
@@ -459,14 +486,20 @@ func (vm *VolumeManager) migrateForRemoval(id int, localPath string, force bool,
 			return ctx.Err()
 		default:
 		}
-		n, err := vm.migrateSectors(locations, force, log.Named("migrateSectors"))
+		n, err := pipeline.migrate(locations)
+		updateProgress(n)
+		mu.Lock()
+		defer mu.Unlock()
+		vm.updateOperationCursor(id, uint64(n), true)
 		migrated += n
 		// update the alert
 		a.Data["migrated"] = migrated
 		vm.a.Register(a)
 		return err
 	})
-	if err != nil && !force {
+	pipeline.close()
+	// a forced removal ignores migration failures, but not cancellation
+	if err != nil && (!force || errors.Is(err, context.Canceled)) {
 		return migrated, fmt.Errorf("failed to migrate sector data: %w", err)
 	} else if err := vm.vs.RemoveVolume(id, force); err != nil {
 		return migrated, fmt.Errorf("failed to remove volume: %w", err)
@@ -474,17 +507,168 @@ func (vm *VolumeManager) migrateForRemoval(id int, localPath string, force bool,
 
 	vm.mu.Lock()
 	defer vm.mu.Unlock()
+	// truncate the volume's data so that backends without a backing file
+	// release their storage
+	v := vm.volumes[id]
+	if err := v.Resize(0); err != nil && !errors.Is(err, ErrVolumeNotAvailable) {
+		log.Error("failed to truncate volume data", zap.Error(err))
+	}
 	// close the volume
-	vm.volumes[id].Close()
+	v.Close()
 	// delete the volume from memory
 	delete(vm.volumes, id)
-	// remove the volume file, ignore error if the file does not exist
-	if err := os.Remove(localPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+	// remove the volume data, ignore error if it does not exist
+	if err := removeVolumeData(localPath, backend); err != nil {
 		return migrated, fmt.Errorf("failed to remove volume file: %w", err)
 	}
 	return migrated, nil
 }
 
+// initializeVolume grows a new volume from current to the operation's target
+// size. The volume must be locked by the caller. The lock is released when
+// initialization stops.
+func (vm *VolumeManager) initializeVolume(ctx context.Context, job *volumeOperationJob, current uint64, release func(), result chan<- error) {
+	volumeID, maxSectors := job.op.VolumeID, job.op.TargetSectors
+	log := vm.log.Named("initialize").With(zap.Int("volumeID", volumeID), zap.Uint64("maxSectors", maxSectors))
+	start := time.Now()
+	err := func() error {
+		defer vm.vs.SetAvailable(volumeID, true)
+		defer vm.setVolumeStatus(volumeID, VolumeStatusReady)
+		defer release()
+		return vm.doResize(ctx, volumeID, current, maxSectors)
+	}()
+	cancelled := vm.operationCancelled(job)
+	vm.finishOperation(job)
+
+	alert := alerts.Alert{
+		ID: frand.Entropy256(),
+		Data: map[string]interface{}{
+			"volumeID": volumeID,
+			"elapsed":  time.Since(start),
+			"target":   maxSectors,
+		},
+		Timestamp: time.Now(),
+	}
+	if err != nil && cancelled {
+		alert.Message = "Volume initialization cancelled"
+		alert.Severity = alerts.SeverityInfo
+	} else if err != nil {
+		log.Error("failed to initialize volume", zap.Error(err))
+		alert.Message = "Failed to initialize volume"
+		alert.Severity = alerts.SeverityError
+		alert.Data["error"] = err.Error()
+	} else {
+		alert.Message = "Volume initialized"
+		alert.Severity = alerts.SeverityInfo
+	}
+	vm.a.Register(alert)
+
+	select {
+	case result <- err:
+	default:
+	}
+}
+
+// resizeVolume resizes a volume from current to the operation's target size.
+// The volume must be locked and set to read-only by the caller. The lock is
+// released and the volume's read-only status is restored when the resize
+// stops.
+func (vm *VolumeManager) resizeVolume(ctx context.Context, job *volumeOperationJob, current uint64, release func(), result chan<- error) {
+	id, maxSectors := job.op.VolumeID, job.op.TargetSectors
+	log := vm.log.Named("resize").With(zap.Int("volumeID", id))
+	start := time.Now()
+	err := func() error {
+		defer func() {
+			// restore the volume to its original read-only status
+			if err := vm.vs.SetReadOnly(id, job.op.RestoreReadOnly); err != nil {
+				log.Error("failed to restore volume read-only status", zap.Error(err))
+			}
+			vm.setVolumeStatus(id, VolumeStatusReady)
+		}()
+		defer release()
+		return vm.doResize(ctx, id, current, maxSectors)
+	}()
+	cancelled := vm.operationCancelled(job)
+	vm.finishOperation(job)
+
+	alert := alerts.Alert{
+		ID: frand.Entropy256(),
+		Data: map[string]interface{}{
+			"volumeID":      id,
+			"elapsed":       time.Since(start),
+			"targetSectors": maxSectors,
+		},
+		Timestamp: time.Now(),
+	}
+	if err != nil && cancelled {
+		alert.Message = "Volume resize cancelled"
+		alert.Severity = alerts.SeverityInfo
+	} else if err != nil {
+		log.Error("failed to resize volume", zap.Error(err))
+		alert.Message = "Volume resize failed"
+		alert.Severity = alerts.SeverityError
+		alert.Data["error"] = err.Error()
+	} else {
+		alert.Message = "Volume resized"
+		alert.Severity = alerts.SeverityInfo
+	}
+	vm.a.Register(alert)
+	select {
+	case result <- err:
+	default:
+	}
+}
+
+// removeVolume migrates a volume's sectors to other volumes and removes it.
+// The volume must be locked and set to read-only by the caller. The lock is
+// released when the removal stops. If the removal is cancelled, the volume's
+// read-only status is restored.
+func (vm *VolumeManager) removeVolume(ctx context.Context, job *volumeOperationJob, vol Volume, release func(), result chan<- error) {
+	id := vol.ID
+	log := vm.log.Named("remove").With(zap.Int("volumeID", id))
+	start := time.Now()
+	migrated, err := func() (int, error) {
+		vm.setVolumeStatus(id, VolumeStatusRemoving)
+		defer vm.setVolumeStatus(id, VolumeStatusReady)
+		defer release()
+		return vm.migrateForRemoval(ctx, id, vol.LocalPath, vol.Backend, job.op.Force, log)
+	}()
+	cancelled := err != nil && vm.operationCancelled(job)
+	if cancelled {
+		if err := vm.vs.SetReadOnly(id, job.op.RestoreReadOnly); err != nil {
+			log.Error("failed to restore volume read-only status", zap.Error(err))
+		}
+	}
+	vm.finishOperation(job)
+
+	alert := alerts.Alert{
+		ID: frand.Entropy256(),
+		Data: map[string]interface{}{
+			"volumeID":        id,
+			"elapsed":         time.Since(start),
+			"migratedSectors": migrated,
+		},
+		Timestamp: time.Now(),
+	}
+	if cancelled {
+		alert.Message = "Volume removal cancelled"
+		alert.Severity = alerts.SeverityInfo
+	} else if err != nil {
+		alert.Message = "Volume removal failed"
+		alert.Severity = alerts.SeverityError
+		alert.Data["error"] = err.Error()
+	} else {
+		alert.Message = "Volume removed"
+		alert.Severity = alerts.SeverityInfo
+	}
+	vm.a.Register(alert)
+
+	select {
+	case result <- err:
+	default:
+	}
+}
+
 // Close gracefully shutsdown the volume manager.
 func (vm *VolumeManager) Close() error {
 	// wait for all operations to stop
@@ -565,10 +749,14 @@ func (vm *VolumeManager) Volume(id int) (VolumeMeta, error) {
 	}, nil
 }
 
-// AddVolume adds a new volume to the storage manager
-func (vm *VolumeManager) AddVolume(localPath string, maxSectors uint64, result chan<- error) (Volume, error) {
+// AddVolume adds a new volume to the storage manager. The backend determines
+// where the volume's sector data is stored. An empty backend type stores the
+// data in a flat file at localPath.
+func (vm *VolumeManager) AddVolume(localPath string, maxSectors uint64, backend VolumeBackend, result chan<- error) (Volume, error) {
 	if maxSectors == 0 {
 		return Volume{}, errors.New("max sectors must be greater than 0")
+	} else if err := backend.Validate(); err != nil {
+		return Volume{}, fmt.Errorf("invalid volume backend: %w", err)
 	}
 
 	done, err := vm.tg.Add()
@@ -577,27 +765,21 @@ func (vm *VolumeManager) AddVolume(localPath string, maxSectors uint64, result c
 	}
 	defer done()
 
-	// check that the volume file does not already exist
-	if _, err := os.Stat(localPath); err == nil {
-		return Volume{}, fmt.Errorf("volume file already exists: %s", localPath)
-	} else if !errors.Is(err, os.ErrNotExist) {
-		return Volume{}, fmt.Errorf("failed to stat volume file: %w", err)
-	}
-
-	f, err := os.Create(localPath)
+	data, err := openVolumeData(localPath, backend, true)
 	if err != nil {
-		return Volume{}, fmt.Errorf("failed to create volume file: %w", err)
+		return Volume{}, fmt.Errorf("failed to create volume data: %w", err)
 	}
 
-	volumeID, err := vm.vs.AddVolume(localPath, false)
+	volumeID, err := vm.vs.AddVolume(localPath, false, backend)
 	if err != nil {
+		data.Close()
 		return Volume{}, fmt.Errorf("failed to add volume to store: %w", err)
 	}
 
 	// add the new volume to the volume map
 	vm.mu.Lock()
 	vm.volumes[volumeID] = &volume{
-		data: f,
+		data: data,
 		stats: VolumeStats{
 			Status: VolumeStatusCreating,
 		},
@@ -610,40 +792,17 @@ func (vm *VolumeManager) AddVolume(localPath string, maxSectors uint64, result c
 		return Volume{}, fmt.Errorf("failed to lock volume: %w", err)
 	}
 
-	go func() {
-		log := vm.log.Named("initialize").With(zap.Int("volumeID", volumeID), zap.Uint64("maxSectors", maxSectors))
-		start := time.Now()
-		err := func() error {
-			defer vm.vs.SetAvailable(volumeID, true)
-			defer vm.setVolumeStatus(volumeID, VolumeStatusReady)
-			defer release()
-			return vm.doResize(volumeID, 0, maxSectors)
-		}()
-		alert := alerts.Alert{
-			ID: frand.Entropy256(),
-			Data: map[string]interface{}{
-				"volumeID": volumeID,
-				"elapsed":  time.Since(start),
-				"target":   maxSectors,
-			},
-			Timestamp: time.Now(),
-		}
-		if err != nil {
-			log.Error("failed to initialize volume", zap.Error(err))
-			alert.Message = "Failed to initialize volume"
-			alert.Severity = alerts.SeverityError
-			alert.Data["error"] = err.Error()
-		} else {
-			alert.Message = "Volume initialized"
-			alert.Severity = alerts.SeverityInfo
-		}
-		vm.a.Register(alert)
+	ctx, job, err := vm.startOperation(VolumeOperation{
+		VolumeID:      volumeID,
+		Type:          VolumeOperationAdd,
+		TargetSectors: maxSectors,
+	})
+	if err != nil {
+		release()
+		return Volume{}, fmt.Errorf("failed to start volume operation: %w", err)
+	}
 
-		select {
-		case result <- err:
-		default:
-		}
-	}()
+	go vm.initializeVolume(ctx, job, 0, release, result)
 	return vm.vs.Volume(volumeID)
 }
 
@@ -669,7 +828,6 @@ func (vm *VolumeManager) SetReadOnly(id int, readOnly bool) error {
 
 // RemoveVolume removes a volume from the manager.
 func (vm *VolumeManager) RemoveVolume(id int, force bool, result chan<- error) error {
-	log := vm.log.Named("remove").With(zap.Int("volumeID", id))
 	done, err := vm.tg.Add()
 	if err != nil {
 		return err
@@ -688,46 +846,25 @@ func (vm *VolumeManager) RemoveVolume(id int, force bool, result chan<- error) e
 		return fmt.Errorf("failed to get volume: %w", err)
 	}
 
+	ctx, job, err := vm.startOperation(VolumeOperation{
+		VolumeID:        id,
+		Type:            VolumeOperationRemove,
+		Force:           force,
+		RestoreReadOnly: vol.ReadOnly,
+	})
+	if err != nil {
+		release()
+		return fmt.Errorf("failed to start volume operation: %w", err)
+	}
+
 	// set the volume to read-only to prevent new sectors from being added
 	if err := vm.vs.SetReadOnly(id, true); err != nil {
 		release()
+		vm.finishOperation(job)
 		return fmt.Errorf("failed to set volume %v to read-only: %w", id, err)
 	}
 
-	go func() {
-		start := time.Now()
-		migrated, err := func() (int, error) {
-			vm.setVolumeStatus(id, VolumeStatusRemoving)
-			defer vm.setVolumeStatus(id, VolumeStatusReady)
-			defer release()
-			return vm.migrateForRemoval(id, vol.LocalPath, force, log)
-		}()
-
-		alert := alerts.Alert{
-			ID: frand.Entropy256(),
-			Data: map[string]interface{}{
-				"volumeID":        id,
-				"elapsed":         time.Since(start),
-				"migratedSectors": migrated,
-			},
-			Timestamp: time.Now(),
-		}
-		if err != nil {
-			alert.Message = "Volume removal failed"
-			alert.Severity = alerts.SeverityError
-			alert.Data["error"] = err.Error()
-		} else {
-			alert.Message = "Volume removed"
-			alert.Severity = alerts.SeverityInfo
-		}
-		vm.a.Register(alert)
-
-		select {
-		case result <- err:
-		default:
-		}
-	}()
-
+	go vm.removeVolume(ctx, job, vol, release, result)
 	return nil
 }
 
@@ -751,51 +888,26 @@ func (vm *VolumeManager) ResizeVolume(id int, maxSectors uint64, result chan<- e
 		return fmt.Errorf("failed to get volume: %w", err)
 	}
 
-	oldReadonly := vol.ReadOnly
+	ctx, job, err := vm.startOperation(VolumeOperation{
+		VolumeID:        id,
+		Type:            VolumeOperationResize,
+		TargetSectors:   maxSectors,
+		Cursor:          vol.TotalSectors,
+		RestoreReadOnly: vol.ReadOnly,
+	})
+	if err != nil {
+		release()
+		return fmt.Errorf("failed to start volume operation: %w", err)
+	}
+
 	// set the volume to read-only to prevent new sectors from being added
 	if err := vm.vs.SetReadOnly(id, true); err != nil {
 		release()
+		vm.finishOperation(job)
 		return fmt.Errorf("failed to set volume %v to read-only: %w", id, err)
 	}
 
-	go func() {
-		log := vm.log.Named("resize").With(zap.Int("volumeID", id))
-		start := time.Now()
-		err := func() error {
-			defer func() {
-				// restore the volume to its original read-only status
-				if err := vm.vs.SetReadOnly(id, oldReadonly); err != nil {
-					log.Error("failed to restore volume read-only status", zap.Error(err))
-				}
-				vm.setVolumeStatus(id, VolumeStatusReady)
-			}()
-			defer release()
-			return vm.doResize(id, vol.TotalSectors, maxSectors)
-		}()
-		alert := alerts.Alert{
-			ID: frand.Entropy256(),
-			Data: map[string]interface{}{
-				"volumeID":      id,
-				"elapsed":       time.Since(start),
-				"targetSectors": maxSectors,
-			},
-			Timestamp: time.Now(),
-		}
-		if err != nil {
-			log.Error("failed to resize volume", zap.Error(err))
-			alert.Message = "Volume resize failed"
-			alert.Severity = alerts.SeverityError
-			alert.Data["error"] = err.Error()
-		} else {
-			alert.Message = "Volume resized"
-			alert.Severity = alerts.SeverityInfo
-		}
-		vm.a.Register(alert)
-		select {
-		case result <- err:
-		default:
-		}
-	}()
+	go vm.resizeVolume(ctx, job, vol.TotalSectors, release, result)
 	return nil
 }
 
@@ -883,8 +995,31 @@ func (vm *VolumeManager) Read(root types.Hash256) (*[rhpv2.SectorSize]byte, erro
 	}
 	vm.mu.Unlock()
 	sector, err := v.ReadSector(loc.Index)
+	if err == nil {
+		if calculated := rhpv2.SectorRoot(sector); calculated != root {
+			err = fmt.Errorf("sector corrupt: expected root %v, got %v", root, calculated)
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to read sector %v: %w", root, err)
+		var recovered *[rhpv2.SectorSize]byte
+		if errors.Is(err, ErrVolumeNotAvailable) {
+			// the volume may become available again, reconstruct the sector
+			// from its parity group without quarantining the location
+			var parityErr error
+			recovered, parityErr = vm.reconstructSector(root)
+			if parityErr != nil && !errors.Is(parityErr, ErrNoParity) {
+				vm.log.Warn("failed to reconstruct sector", zap.Stringer("root", root), zap.Error(parityErr))
+			}
+		} else {
+			// quarantine the location to prevent it from being reused
+			recovered = vm.quarantineSector(loc, err)
+		}
+		if recovered == nil {
+			return nil, fmt.Errorf("failed to read sector %v: %w", root, err)
+		}
+		vm.cache.Add(root, recovered)
+		vm.recorder.AddRead()
+		return recovered, nil
 	}
 
 	// Add sector to cache
@@ -945,6 +1080,9 @@ func (vm *VolumeManager) Write(root types.Hash256, data *[rhpv2.SectorSize]byte)
 
 		// Add newly written sector to cache
 		vm.cache.Add(root, data)
+		// if the sector was previously quarantined, it has now been moved
+		// to a new location
+		vm.recoveredSector(root)
 
 		vm.log.Debug("wrote sector", zap.String("root", root.String()), zap.Int("volume", loc.Volume), zap.Uint64("index", loc.Index), zap.Duration("elapsed", time.Since(start)))
 		return nil
@@ -1013,6 +1151,16 @@ func NewVolumeManager(vs VolumeStore, a Alerts, cm ChainManager, log *zap.Logger
 		changedVolumes: make(map[int]bool),
 		cache:          cache,
 		tg:             threadgroup.New(),
+
+		scrubs:      make(map[int]scrubJob),
+		scrubStatus: make(map[int]ScrubStatus),
+		scrubLimit:  rate.NewLimiter(rate.Inf, rhpv2.SectorSize),
+		quarantined: make(map[types.Hash256]types.Hash256),
+		tierLimit:   rate.NewLimiter(rate.Inf, rhpv2.SectorSize),
+
+		rebalanceLimit: rate.NewLimiter(rate.Inf, rhpv2.SectorSize),
+
+		operations: make(map[int]*volumeOperationJob),
 	}
 	if err := vm.loadVolumes(); err != nil {
 		return nil, err
@@ -1020,5 +1168,8 @@ func NewVolumeManager(vs VolumeStore, a Alerts, cm ChainManager, log *zap.Logger
 
 	go vm.recorder.Run(vm.tg.Done())
 	go vm.cleanup()
+	go vm.scrubVolumes()
+	go vm.runTierMigrator()
+	go vm.runParityEncoder()
 	return vm, nil
 }
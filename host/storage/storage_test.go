@@ -1,17 +1,25 @@
 package storage_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	rhpv2 "go.sia.tech/core/rhp/v2"
 	"go.sia.tech/core/types"
 	"go.sia.tech/hostd/chain"
 	"go.sia.tech/hostd/host/alerts"
 	"go.sia.tech/hostd/host/storage"
+	"go.sia.tech/hostd/host/storage/s3"
 	"go.sia.tech/hostd/persist/sqlite"
 	"go.sia.tech/siad/modules/consensus"
 	"go.sia.tech/siad/modules/gateway"
@@ -71,7 +79,7 @@ func TestVolumeLoad(t *testing.T) {
 	defer vm.Close()
 
 	result := make(chan error, 1)
-	volume, err := vm.AddVolume(filepath.Join(t.TempDir(), "hostdata.dat"), expectedSectors, result)
+	volume, err := vm.AddVolume(filepath.Join(t.TempDir(), "hostdata.dat"), expectedSectors, storage.VolumeBackend{}, result)
 	if err != nil {
 		t.Fatal(err)
 	} else if err := <-result; err != nil {
@@ -173,7 +181,7 @@ func TestAddVolume(t *testing.T) {
 	defer vm.Close()
 
 	result := make(chan error, 1)
-	volume, err := vm.AddVolume(filepath.Join(t.TempDir(), "hostdata.dat"), expectedSectors, result)
+	volume, err := vm.AddVolume(filepath.Join(t.TempDir(), "hostdata.dat"), expectedSectors, storage.VolumeBackend{}, result)
 	if err != nil {
 		t.Fatal(err)
 	} else if err := <-result; err != nil {
@@ -200,6 +208,166 @@ func TestAddVolume(t *testing.T) {
 	}
 }
 
+func TestAddVolumeSparse(t *testing.T) {
+	const expectedSectors = 500
+	dir := t.TempDir()
+
+	log := zaptest.NewLogger(t)
+	db, err := sqlite.OpenDatabase(filepath.Join(dir, "hostd.db"), log.Named("sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	g, err := gateway.New(":0", false, filepath.Join(dir, "gateway"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	cs, errCh := consensus.New(g, false, filepath.Join(dir, "consensus"))
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatal(err)
+		}
+	default:
+	}
+	cm, err := chain.NewManager(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cm.Close()
+
+	am := alerts.NewManager()
+	vm, err := storage.NewVolumeManager(db, am, cm, log.Named("volumes"), sectorCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vm.Close()
+
+	volumePath := filepath.Join(t.TempDir(), "hostdata.dat")
+	result := make(chan error, 1)
+	volume, err := vm.AddVolume(volumePath, expectedSectors, storage.VolumeBackend{Type: storage.VolumeBackendSparse}, result)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+
+	// the sparse backend should not preallocate the volume
+	if err := checkFileSize(volumePath, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := vm.Volume(volume.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if meta.TotalSectors != expectedSectors {
+		t.Fatalf("expected %v total sectors, got %v", expectedSectors, meta.TotalSectors)
+	} else if meta.Backend.Type != storage.VolumeBackendSparse {
+		t.Fatalf("expected backend %q, got %q", storage.VolumeBackendSparse, meta.Backend.Type)
+	}
+
+	var sector [rhpv2.SectorSize]byte
+	frand.Read(sector[:256])
+	root := rhpv2.SectorRoot(&sector)
+	release, err := vm.Write(root, &sector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	// the file should only grow to fit the written sector
+	stat, err := os.Stat(volumePath)
+	if err != nil {
+		t.Fatal(err)
+	} else if stat.Size() == 0 || stat.Size() > int64(expectedSectors*rhpv2.SectorSize) {
+		t.Fatalf("unexpected volume size %v", stat.Size())
+	}
+
+	read, err := vm.Read(root)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(read[:], sector[:]) {
+		t.Fatal("sector data mismatch")
+	}
+}
+
+func TestAddVolumeGrowFailure(t *testing.T) {
+	const expectedSectors = 500
+	dir := t.TempDir()
+
+	log := zaptest.NewLogger(t)
+	db, err := sqlite.OpenDatabase(filepath.Join(dir, "hostd.db"), log.Named("sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	g, err := gateway.New(":0", false, filepath.Join(dir, "gateway"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	cs, errCh := consensus.New(g, false, filepath.Join(dir, "consensus"))
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatal(err)
+		}
+	default:
+	}
+	cm, err := chain.NewManager(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cm.Close()
+
+	am := alerts.NewManager()
+	vm, err := storage.NewVolumeManager(db, am, cm, log.Named("volumes"), sectorCacheSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vm.Close()
+
+	// an object store that rejects every request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "<Error><Code>AccessDenied</Code><Message>access denied</Message></Error>")
+	}))
+	defer server.Close()
+
+	opts, err := json.Marshal(s3.Options{
+		Endpoint:        server.URL,
+		Bucket:          "hostd",
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+		PathStyle:       true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := make(chan error, 1)
+	volume, err := vm.AddVolume("volume", expectedSectors, storage.VolumeBackend{Type: storage.VolumeBackendS3, Options: opts}, result)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := <-result; err == nil {
+		t.Fatal("expected volume initialization to fail")
+	}
+
+	// the metadata should not be grown past the data
+	meta, err := vm.Volume(volume.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if meta.TotalSectors != 0 {
+		t.Fatalf("expected 0 total sectors, got %v", meta.TotalSectors)
+	}
+}
+
 func TestRemoveVolume(t *testing.T) {
 	const expectedSectors = 50
 	dir := t.TempDir()
@@ -243,7 +411,7 @@ func TestRemoveVolume(t *testing.T) {
 
 	result := make(chan error, 1)
 	volumePath := filepath.Join(t.TempDir(), "hostdata.dat")
-	volume, err := vm.AddVolume(volumePath, expectedSectors, result)
+	volume, err := vm.AddVolume(volumePath, expectedSectors, storage.VolumeBackend{}, result)
 	if err != nil {
 		t.Fatal(err)
 	} else if err := <-result; err != nil {
@@ -331,7 +499,7 @@ func TestRemoveCorrupt(t *testing.T) {
 
 	result := make(chan error, 1)
 	volumePath := filepath.Join(t.TempDir(), "hostdata.dat")
-	volume, err := vm.AddVolume(volumePath, expectedSectors, result)
+	volume, err := vm.AddVolume(volumePath, expectedSectors, storage.VolumeBackend{}, result)
 	if err != nil {
 		t.Fatal(err)
 	} else if err := <-result; err != nil {
@@ -364,7 +532,7 @@ func TestRemoveCorrupt(t *testing.T) {
 	}
 
 	// add a second volume to the manager
-	_, err = vm.AddVolume(filepath.Join(t.TempDir(), "vol2.dat"), expectedSectors, result)
+	_, err = vm.AddVolume(filepath.Join(t.TempDir(), "vol2.dat"), expectedSectors, storage.VolumeBackend{}, result)
 	if err != nil {
 		t.Fatal(err)
 	} else if err := <-result; err != nil {
@@ -445,7 +613,7 @@ func TestRemoveMissing(t *testing.T) {
 
 	result := make(chan error, 1)
 	volumePath := filepath.Join(t.TempDir(), "hostdata.dat")
-	volume, err := vm.AddVolume(volumePath, expectedSectors, result)
+	volume, err := vm.AddVolume(volumePath, expectedSectors, storage.VolumeBackend{}, result)
 	if err != nil {
 		t.Fatal(err)
 	} else if err := <-result; err != nil {
@@ -476,7 +644,7 @@ func TestRemoveMissing(t *testing.T) {
 	}
 
 	// add a second volume to the manager
-	_, err = vm.AddVolume(filepath.Join(t.TempDir(), "vol2.dat"), expectedSectors, result)
+	_, err = vm.AddVolume(filepath.Join(t.TempDir(), "vol2.dat"), expectedSectors, storage.VolumeBackend{}, result)
 	if err != nil {
 		t.Fatal(err)
 	} else if err := <-result; err != nil {
@@ -564,7 +732,7 @@ func TestVolumeGrow(t *testing.T) {
 
 	result := make(chan error, 1)
 	volumeFilePath := filepath.Join(t.TempDir(), "hostdata.dat")
-	volume, err := vm.AddVolume(volumeFilePath, initialSectors, result)
+	volume, err := vm.AddVolume(volumeFilePath, initialSectors, storage.VolumeBackend{}, result)
 	if err != nil {
 		t.Fatal(err)
 	} else if err := <-result; err != nil {
@@ -653,7 +821,7 @@ func TestVolumeShrink(t *testing.T) {
 
 	result := make(chan error, 1)
 	volumeFilePath := filepath.Join(t.TempDir(), "hostdata.dat")
-	vol, err := vm.AddVolume(volumeFilePath, sectors, result)
+	vol, err := vm.AddVolume(volumeFilePath, sectors, storage.VolumeBackend{}, result)
 	if err != nil {
 		t.Fatal(err)
 	} else if err := <-result; err != nil {
@@ -815,7 +983,7 @@ func TestVolumeManagerReadWrite(t *testing.T) {
 
 	result := make(chan error, 1)
 	volumeFilePath := filepath.Join(t.TempDir(), "hostdata.dat")
-	vol, err := vm.AddVolume(volumeFilePath, sectors, result)
+	vol, err := vm.AddVolume(volumeFilePath, sectors, storage.VolumeBackend{}, result)
 	if err != nil {
 		t.Fatal(err)
 	} else if err := <-result; err != nil {
@@ -918,7 +1086,7 @@ func TestSectorCache(t *testing.T) {
 
 	result := make(chan error, 1)
 	volumeFilePath := filepath.Join(t.TempDir(), "hostdata.dat")
-	vol, err := vm.AddVolume(volumeFilePath, sectors, result)
+	vol, err := vm.AddVolume(volumeFilePath, sectors, storage.VolumeBackend{}, result)
 	if err != nil {
 		t.Fatal(err)
 	} else if err := <-result; err != nil {
@@ -1051,7 +1219,7 @@ func BenchmarkVolumeManagerWrite(b *testing.B) {
 
 	result := make(chan error, 1)
 	volumeFilePath := filepath.Join(b.TempDir(), "hostdata.dat")
-	_, err = vm.AddVolume(volumeFilePath, uint64(b.N), result)
+	_, err = vm.AddVolume(volumeFilePath, uint64(b.N), storage.VolumeBackend{}, result)
 	if err != nil {
 		b.Fatal(err)
 	} else if err := <-result; err != nil {
@@ -1124,7 +1292,7 @@ func BenchmarkNewVolume(b *testing.B) {
 
 	result := make(chan error, 1)
 	volumeFilePath := filepath.Join(b.TempDir(), "hostdata.dat")
-	_, err = vm.AddVolume(volumeFilePath, uint64(b.N), result)
+	_, err = vm.AddVolume(volumeFilePath, uint64(b.N), storage.VolumeBackend{}, result)
 	if err != nil {
 		b.Fatal(err)
 	} else if err := <-result; err != nil {
@@ -1171,7 +1339,7 @@ func BenchmarkVolumeManagerRead(b *testing.B) {
 
 	result := make(chan error, 1)
 	volumeFilePath := filepath.Join(b.TempDir(), "hostdata.dat")
-	_, err = vm.AddVolume(volumeFilePath, uint64(b.N), result)
+	_, err = vm.AddVolume(volumeFilePath, uint64(b.N), storage.VolumeBackend{}, result)
 	if err != nil {
 		b.Fatal(err)
 	} else if err := <-result; err != nil {
@@ -1243,7 +1411,7 @@ func BenchmarkVolumeRemove(b *testing.B) {
 
 	result := make(chan error, 1)
 	volumeFilePath := filepath.Join(b.TempDir(), "hostdata.dat")
-	volume1, err := vm.AddVolume(volumeFilePath, uint64(b.N), result)
+	volume1, err := vm.AddVolume(volumeFilePath, uint64(b.N), storage.VolumeBackend{}, result)
 	if err != nil {
 		b.Fatal(err)
 	} else if err := <-result; err != nil {
@@ -1265,7 +1433,7 @@ func BenchmarkVolumeRemove(b *testing.B) {
 
 	// add a new volume
 	volume2FilePath := filepath.Join(b.TempDir(), "hostdata2.dat")
-	_, err = vm.AddVolume(volume2FilePath, uint64(b.N), result)
+	_, err = vm.AddVolume(volume2FilePath, uint64(b.N), storage.VolumeBackend{}, result)
 	if err != nil {
 		b.Fatal(err)
 	} else if err := <-result; err != nil {
@@ -1283,3 +1451,1162 @@ func BenchmarkVolumeRemove(b *testing.B) {
 		b.Fatal(err)
 	}
 }
+
+func TestVolumeScrub(t *testing.T) {
+	const sectors = 64
+	dir := t.TempDir()
+
+	log := zaptest.NewLogger(t)
+	db, err := sqlite.OpenDatabase(filepath.Join(dir, "hostd.db"), log.Named("sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	g, err := gateway.New(":0", false, filepath.Join(dir, "gateway"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	cs, errCh := consensus.New(g, false, filepath.Join(dir, "consensus"))
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatal(err)
+		}
+	default:
+	}
+	cm, err := chain.NewManager(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cm.Close()
+
+	am := alerts.NewManager()
+	vm, err := storage.NewVolumeManager(db, am, cm, log.Named("volumes"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vm.Close()
+	vm.SetScrubRate(0)
+
+	result := make(chan error, 1)
+	volumePath := filepath.Join(t.TempDir(), "hostdata.dat")
+	volume, err := vm.AddVolume(volumePath, sectors, storage.VolumeBackend{}, result)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		var sector [rhpv2.SectorSize]byte
+		frand.Read(sector[:256])
+		root := rhpv2.SectorRoot(&sector)
+		release, err := vm.Write(root, &sector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer release()
+	}
+
+	waitForScrub := func() storage.ScrubStatus {
+		t.Helper()
+		for i := 0; i < 100; i++ {
+			status, err := vm.ScrubStatus(volume.ID)
+			if err != nil {
+				t.Fatal(err)
+			} else if !status.Running && !status.LastCompleted.IsZero() {
+				return status
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		t.Fatal("scrub did not complete")
+		return storage.ScrubStatus{}
+	}
+
+	// scrub the volume, no errors should be found
+	if err := vm.ScrubVolume(volume.ID); err != nil {
+		t.Fatal(err)
+	}
+	status := waitForScrub()
+	if status.Checked != 10 {
+		t.Fatalf("expected 10 checked sectors, got %v", status.Checked)
+	} else if status.Corrupt != 0 || status.Missing != 0 {
+		t.Fatalf("expected no errors, got %v corrupt %v missing", status.Corrupt, status.Missing)
+	} else if status.Cursor != 0 {
+		t.Fatalf("expected cursor to be reset, got %v", status.Cursor)
+	}
+	lastCompleted := status.LastCompleted
+
+	// corrupt a sector in the volume
+	f, err := os.OpenFile(volumePath, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteAt(frand.Bytes(512), int64(rhpv2.SectorSize*frand.Intn(10))); err != nil {
+		t.Fatal(err)
+	} else if err := f.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	// wait for the next second since timestamps are stored with second
+	// precision
+	time.Sleep(time.Until(lastCompleted.Truncate(time.Second).Add(time.Second)))
+	if err := vm.ScrubVolume(volume.ID); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		status, err = vm.ScrubStatus(volume.ID)
+		if err != nil {
+			t.Fatal(err)
+		} else if !status.Running && status.LastCompleted.After(lastCompleted) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if status.Checked != 10 {
+		t.Fatalf("expected 10 checked sectors, got %v", status.Checked)
+	} else if status.Corrupt != 1 {
+		t.Fatalf("expected 1 corrupt sector, got %v", status.Corrupt)
+	}
+
+	var found bool
+	for _, a := range am.Active() {
+		if a.Message == "Sector quarantined" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Fatal("expected quarantine alert")
+	}
+}
+
+func TestQuarantineSector(t *testing.T) {
+	const sectors = 64
+	dir := t.TempDir()
+
+	log := zaptest.NewLogger(t)
+	db, err := sqlite.OpenDatabase(filepath.Join(dir, "hostd.db"), log.Named("sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	g, err := gateway.New(":0", false, filepath.Join(dir, "gateway"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	cs, errCh := consensus.New(g, false, filepath.Join(dir, "consensus"))
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatal(err)
+		}
+	default:
+	}
+	cm, err := chain.NewManager(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cm.Close()
+
+	// disable the cache so reads always hit the disk
+	am := alerts.NewManager()
+	vm, err := storage.NewVolumeManager(db, am, cm, log.Named("volumes"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vm.Close()
+
+	result := make(chan error, 1)
+	volumePath := filepath.Join(t.TempDir(), "hostdata.dat")
+	volume, err := vm.AddVolume(volumePath, sectors, storage.VolumeBackend{}, result)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+
+	var sector [rhpv2.SectorSize]byte
+	frand.Read(sector[:256])
+	root := rhpv2.SectorRoot(&sector)
+	release, err := vm.Write(root, &sector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	// the first sector is always stored at index 0
+	f, err := os.OpenFile(volumePath, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteAt(frand.Bytes(512), 0); err != nil {
+		t.Fatal(err)
+	} else if err := f.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	// reading the sector should fail and quarantine the location
+	if _, err := vm.Read(root); err == nil {
+		t.Fatal("expected corrupt sector error")
+	}
+
+	hasAlert := func(msg string) bool {
+		for _, a := range am.Active() {
+			if a.Message == msg {
+				return true
+			}
+		}
+		return false
+	}
+	if !hasAlert("Sector quarantined") {
+		t.Fatal("expected quarantine alert")
+	}
+
+	// write the sector again, it should be moved to a new location
+	release, err = vm.Write(root, &sector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	if read, err := vm.Read(root); err != nil {
+		t.Fatal(err)
+	} else if *read != sector {
+		t.Fatal("sector data mismatch")
+	} else if hasAlert("Sector quarantined") {
+		t.Fatal("expected quarantine alert to be replaced")
+	} else if !hasAlert("Quarantined sector recovered") {
+		t.Fatal("expected recovery alert")
+	}
+
+	// the quarantined location should not be reused
+	var sector2 [rhpv2.SectorSize]byte
+	frand.Read(sector2[:256])
+	root2 := rhpv2.SectorRoot(&sector2)
+	release, err = vm.Write(root2, &sector2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	buf := make([]byte, 256)
+	if _, err := f.ReadAt(buf, 0); err != nil {
+		t.Fatal(err)
+	} else if bytes.Equal(buf, sector2[:256]) {
+		t.Fatal("quarantined location was reused")
+	}
+
+	vol, err := vm.Volume(volume.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if vol.UsedSectors != 2 {
+		t.Fatalf("expected 2 used sectors, got %v", vol.UsedSectors)
+	}
+}
+
+func TestVolumeRebalance(t *testing.T) {
+	const sectors = 32
+	dir := t.TempDir()
+
+	log := zaptest.NewLogger(t)
+	db, err := sqlite.OpenDatabase(filepath.Join(dir, "hostd.db"), log.Named("sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	g, err := gateway.New(":0", false, filepath.Join(dir, "gateway"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	cs, errCh := consensus.New(g, false, filepath.Join(dir, "consensus"))
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatal(err)
+		}
+	default:
+	}
+	cm, err := chain.NewManager(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cm.Close()
+
+	am := alerts.NewManager()
+	vm, err := storage.NewVolumeManager(db, am, cm, log.Named("volumes"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vm.Close()
+
+	result := make(chan error, 1)
+	volume1, err := vm.AddVolume(filepath.Join(t.TempDir(), "vol1.dat"), sectors, storage.VolumeBackend{}, result)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+
+	// fill half of the first volume
+	roots := make([]types.Hash256, sectors/2)
+	for i := range roots {
+		var sector [rhpv2.SectorSize]byte
+		frand.Read(sector[:256])
+		roots[i] = rhpv2.SectorRoot(&sector)
+		// release the sector immediately so it can be moved
+		release, err := vm.Write(roots[i], &sector)
+		if err != nil {
+			t.Fatal(err)
+		} else if err := release(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	volume2, err := vm.AddVolume(filepath.Join(t.TempDir(), "vol2.dat"), sectors, storage.VolumeBackend{}, result)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+
+	// check the planned moves without moving any sectors
+	plan, err := vm.PlanRebalance()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(plan.Moves) != 1 {
+		t.Fatalf("expected 1 move, got %v", len(plan.Moves))
+	} else if move := plan.Moves[0]; move.From != volume1.ID || move.To != volume2.ID || move.Sectors != sectors/4 {
+		t.Fatalf("unexpected move %+v", move)
+	}
+
+	if meta, err := vm.Volume(volume1.ID); err != nil {
+		t.Fatal(err)
+	} else if meta.UsedSectors != sectors/2 {
+		t.Fatalf("expected %v used sectors, got %v", sectors/2, meta.UsedSectors)
+	}
+
+	// start a rebalance that is limited to 1 byte per second so it is still
+	// running when the second rebalance is requested
+	if _, err := vm.Rebalance(1, result); err != nil {
+		t.Fatal(err)
+	} else if _, err := vm.Rebalance(0, nil); !errors.Is(err, storage.ErrRebalanceRunning) {
+		t.Fatalf("expected ErrRebalanceRunning, got %v", err)
+	} else if err := vm.CancelRebalance(); err != nil {
+		t.Fatal(err)
+	} else if err := <-result; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	// rebalance the volumes
+	if _, err := vm.Rebalance(0, result); err != nil {
+		t.Fatal(err)
+	} else if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []int{volume1.ID, volume2.ID} {
+		meta, err := vm.Volume(id)
+		if err != nil {
+			t.Fatal(err)
+		} else if meta.UsedSectors != sectors/4 {
+			t.Fatalf("volume %v: expected %v used sectors, got %v", id, sectors/4, meta.UsedSectors)
+		} else if meta.Status != storage.VolumeStatusReady {
+			t.Fatalf("volume %v: expected status %v, got %v", id, storage.VolumeStatusReady, meta.Status)
+		}
+	}
+
+	// all sectors should still be readable
+	for _, root := range roots {
+		if _, err := vm.Read(root); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the volumes are balanced, no moves should be planned
+	if plan, err := vm.PlanRebalance(); err != nil {
+		t.Fatal(err)
+	} else if len(plan.Moves) != 0 {
+		t.Fatalf("expected no moves, got %v", len(plan.Moves))
+	}
+}
+
+func TestRemoveVolumeMultipleDestinations(t *testing.T) {
+	const sectors = 32
+	dir := t.TempDir()
+
+	log := zaptest.NewLogger(t)
+	db, err := sqlite.OpenDatabase(filepath.Join(dir, "hostd.db"), log.Named("sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	g, err := gateway.New(":0", false, filepath.Join(dir, "gateway"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	cs, errCh := consensus.New(g, false, filepath.Join(dir, "consensus"))
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatal(err)
+		}
+	default:
+	}
+	cm, err := chain.NewManager(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cm.Close()
+
+	am := alerts.NewManager()
+	vm, err := storage.NewVolumeManager(db, am, cm, log.Named("volumes"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vm.Close()
+
+	result := make(chan error, 1)
+	volume1, err := vm.AddVolume(filepath.Join(t.TempDir(), "vol1.dat"), sectors, storage.VolumeBackend{}, result)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+
+	// fill most of the first volume
+	roots := make([]types.Hash256, sectors*3/4)
+	for i := range roots {
+		var sector [rhpv2.SectorSize]byte
+		frand.Read(sector[:256])
+		roots[i] = rhpv2.SectorRoot(&sector)
+		release, err := vm.Write(roots[i], &sector)
+		if err != nil {
+			t.Fatal(err)
+		} else if err := release(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// add two smaller volumes. Neither can hold all of the sectors on its
+	// own so the migration must write to both.
+	var destinations []int
+	for _, name := range []string{"vol2.dat", "vol3.dat"} {
+		vol, err := vm.AddVolume(filepath.Join(t.TempDir(), name), sectors/2, storage.VolumeBackend{}, result)
+		if err != nil {
+			t.Fatal(err)
+		} else if err := <-result; err != nil {
+			t.Fatal(err)
+		}
+		destinations = append(destinations, vol.ID)
+	}
+
+	if err := vm.RemoveVolume(volume1.ID, false, result); err != nil {
+		t.Fatal(err)
+	} else if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+
+	var used uint64
+	for _, id := range destinations {
+		meta, err := vm.Volume(id)
+		if err != nil {
+			t.Fatal(err)
+		} else if meta.UsedSectors == 0 {
+			t.Fatalf("volume %v: expected sectors to be migrated", id)
+		} else if meta.Migration != nil {
+			t.Fatalf("volume %v: expected no migration progress", id)
+		}
+		used += meta.UsedSectors
+	}
+	if used != uint64(len(roots)) {
+		t.Fatalf("expected %v used sectors, got %v", len(roots), used)
+	}
+
+	// all sectors should still be readable
+	for _, root := range roots {
+		if _, err := vm.Read(root); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResumeVolumeOperation(t *testing.T) {
+	const initialSectors, targetSectors = 16, 48
+	dir := t.TempDir()
+
+	log := zaptest.NewLogger(t)
+	db, err := sqlite.OpenDatabase(filepath.Join(dir, "hostd.db"), log.Named("sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	g, err := gateway.New(":0", false, filepath.Join(dir, "gateway"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	cs, errCh := consensus.New(g, false, filepath.Join(dir, "consensus"))
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatal(err)
+		}
+	default:
+	}
+	cm, err := chain.NewManager(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cm.Close()
+
+	am := alerts.NewManager()
+	vm, err := storage.NewVolumeManager(db, am, cm, log.Named("volumes"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := make(chan error, 1)
+	volumePath := filepath.Join(t.TempDir(), "hostdata.dat")
+	volume, err := vm.AddVolume(volumePath, initialSectors, storage.VolumeBackend{}, result)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := <-result; err != nil {
+		t.Fatal(err)
+	} else if ops, err := vm.VolumeOperations(); err != nil {
+		t.Fatal(err)
+	} else if len(ops) != 0 {
+		t.Fatalf("expected no operations, got %v", len(ops))
+	} else if err := vm.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// simulate a resize that was interrupted by a restart
+	_, err = db.AddVolumeOperation(storage.VolumeOperation{
+		VolumeID:      volume.ID,
+		Type:          storage.VolumeOperationResize,
+		TargetSectors: targetSectors,
+		Cursor:        initialSectors,
+		Started:       time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if err := db.SetReadOnly(volume.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// the resize should be resumed when the volume manager is started
+	vm, err = storage.NewVolumeManager(db, am, cm, log.Named("volumes"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vm.Close()
+
+	for i := 0; ; i++ {
+		ops, err := vm.VolumeOperations()
+		if err != nil {
+			t.Fatal(err)
+		} else if len(ops) == 0 {
+			break
+		} else if i >= 100 {
+			t.Fatal("volume operation was not completed")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	meta, err := vm.Volume(volume.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if meta.TotalSectors != targetSectors {
+		t.Fatalf("expected %v total sectors, got %v", targetSectors, meta.TotalSectors)
+	} else if meta.ReadOnly {
+		t.Fatal("expected volume to be writable")
+	} else if err := checkFileSize(volumePath, targetSectors*rhpv2.SectorSize); err != nil {
+		t.Fatal(err)
+	}
+
+	// cancelling a volume without a running operation should fail
+	if err := vm.CancelVolumeOperation(volume.ID); !errors.Is(err, storage.ErrOperationNotFound) {
+		t.Fatalf("expected ErrOperationNotFound, got %v", err)
+	}
+}
+
+func TestVolumeParity(t *testing.T) {
+	for _, settings := range []storage.ParitySettings{
+		{Scheme: storage.ParitySchemeXOR, DataShards: 2, ParityShards: 1},
+		{Scheme: storage.ParitySchemeReedSolomon, DataShards: 2, ParityShards: 1},
+	} {
+		t.Run(settings.Scheme, func(t *testing.T) {
+			testVolumeParity(t, settings)
+		})
+	}
+}
+
+func testVolumeParity(t *testing.T, settings storage.ParitySettings) {
+	const sectors = 32
+	dir := t.TempDir()
+
+	log := zaptest.NewLogger(t)
+	db, err := sqlite.OpenDatabase(filepath.Join(dir, "hostd.db"), log.Named("sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	g, err := gateway.New(":0", false, filepath.Join(dir, "gateway"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	cs, errCh := consensus.New(g, false, filepath.Join(dir, "consensus"))
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatal(err)
+		}
+	default:
+	}
+	cm, err := chain.NewManager(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cm.Close()
+
+	// disable the cache so reads always hit the disk
+	am := alerts.NewManager()
+	vm, err := storage.NewVolumeManager(db, am, cm, log.Named("volumes"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vm.Close()
+
+	result := make(chan error, 1)
+	addVolume := func(name string) (storage.Volume, string) {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), name)
+		vol, err := vm.AddVolume(path, sectors, storage.VolumeBackend{}, result)
+		if err != nil {
+			t.Fatal(err)
+		} else if err := <-result; err != nil {
+			t.Fatal(err)
+		}
+		return vol, path
+	}
+
+	data := make(map[types.Hash256][rhpv2.SectorSize]byte)
+	writeSectors := func(n int) (roots []types.Hash256) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			var sector [rhpv2.SectorSize]byte
+			frand.Read(sector[:256])
+			root := rhpv2.SectorRoot(&sector)
+			// reference the sector and release it immediately so it can be
+			// protected
+			release, err := vm.Write(root, &sector)
+			if err != nil {
+				t.Fatal(err)
+			} else if err := vm.AddTemporarySectors([]storage.TempSector{{Root: root, Expiration: 1000}}); err != nil {
+				t.Fatal(err)
+			} else if err := release(); err != nil {
+				t.Fatal(err)
+			}
+			data[root] = sector
+			roots = append(roots, root)
+		}
+		return
+	}
+	checkSectors := func(roots []types.Hash256) {
+		t.Helper()
+		for _, root := range roots {
+			sector, err := vm.Read(root)
+			if err != nil {
+				t.Fatal(err)
+			} else if *sector != data[root] {
+				t.Fatalf("sector %v data mismatch", root)
+			}
+		}
+	}
+
+	// write sectors to two data volumes, only one of which is writable at a
+	// time
+	volume1, path1 := addVolume("vol1.dat")
+	roots1 := writeSectors(4)
+	if err := vm.SetReadOnly(volume1.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	volume2, path2 := addVolume("vol2.dat")
+	roots2 := writeSectors(4)
+	if err := vm.SetReadOnly(volume2.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// parity is disabled by default
+	if n, err := vm.EncodeParity(); err != nil {
+		t.Fatal(err)
+	} else if n != 0 {
+		t.Fatalf("expected 0 parity groups, got %v", n)
+	}
+
+	// the parity sectors must be stored in the third volume
+	volume3, _ := addVolume("vol3.dat")
+	if err := vm.SetParitySettings(settings); err != nil {
+		t.Fatal(err)
+	} else if n, err := vm.EncodeParity(); err != nil {
+		t.Fatal(err)
+	} else if n != 4 {
+		t.Fatalf("expected 4 parity groups, got %v", n)
+	} else if n, err := vm.EncodeParity(); err != nil {
+		t.Fatal(err)
+	} else if n != 0 {
+		t.Fatalf("expected protected sectors to be skipped, got %v groups", n)
+	} else if vol, err := vm.Volume(volume3.ID); err != nil {
+		t.Fatal(err)
+	} else if vol.UsedSectors != 4 {
+		t.Fatalf("expected 4 parity sectors, got %v", vol.UsedSectors)
+	}
+
+	// parity sectors are not referenced by a contract, but must not be pruned
+	if _, err := db.PruneSectors(); err != nil {
+		t.Fatal(err)
+	} else if vol, err := vm.Volume(volume3.ID); err != nil {
+		t.Fatal(err)
+	} else if vol.UsedSectors != 4 {
+		t.Fatalf("expected parity sectors to be kept, got %v", vol.UsedSectors)
+	}
+
+	// corrupt the first sector of the first volume. The sector should be
+	// reconstructed and moved to a new location. The parity volume is set to
+	// read-only so the sector is not moved into the same volume as its
+	// group's parity sector.
+	if err := vm.SetReadOnly(volume3.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	addVolume("spare.dat")
+	f, err := os.OpenFile(path1, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteAt(frand.Bytes(512), 0); err != nil {
+		t.Fatal(err)
+	} else if err := f.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	checkSectors(roots1[:1])
+	for _, a := range am.Active() {
+		if a.Message == "Sector quarantined" {
+			t.Fatal("expected reconstructed sector to be recovered")
+		}
+	}
+
+	// fail the second volume and rebuild it on a new volume
+	if err := os.Truncate(path2, 0); err != nil {
+		t.Fatal(err)
+	}
+	volume4, _ := addVolume("vol4.dat")
+	if err := vm.RebuildVolume(volume2.ID, volume4.ID, result); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("timed out waiting for rebuild")
+	}
+
+	if vol, err := vm.Volume(volume2.ID); err != nil {
+		t.Fatal(err)
+	} else if vol.UsedSectors != 0 {
+		t.Fatalf("expected failed volume to be empty, got %v sectors", vol.UsedSectors)
+	} else if vol, err := vm.Volume(volume4.ID); err != nil {
+		t.Fatal(err)
+	} else if vol.UsedSectors != uint64(len(roots2)) {
+		t.Fatalf("expected %v rebuilt sectors, got %v", len(roots2), vol.UsedSectors)
+	}
+	checkSectors(roots1)
+	checkSectors(roots2)
+
+	// removing a sector should drop its group's parity sector
+	before, err := vm.Volume(volume3.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := vm.RemoveSector(roots1[1]); err != nil {
+		t.Fatal(err)
+	} else if _, err := db.PruneSectors(); err != nil {
+		t.Fatal(err)
+	} else if after, err := vm.Volume(volume3.ID); err != nil {
+		t.Fatal(err)
+	} else if after.UsedSectors != before.UsedSectors-1 {
+		t.Fatalf("expected parity sector to be pruned, got %v used sectors", after.UsedSectors)
+	}
+}
+
+func TestParityRebuildAfterMigration(t *testing.T) {
+	const sectors = 32
+	dir := t.TempDir()
+
+	log := zaptest.NewLogger(t)
+	db, err := sqlite.OpenDatabase(filepath.Join(dir, "hostd.db"), log.Named("sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	g, err := gateway.New(":0", false, filepath.Join(dir, "gateway"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	cs, errCh := consensus.New(g, false, filepath.Join(dir, "consensus"))
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatal(err)
+		}
+	default:
+	}
+	cm, err := chain.NewManager(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cm.Close()
+
+	// disable the cache so reads always hit the disk
+	am := alerts.NewManager()
+	vm, err := storage.NewVolumeManager(db, am, cm, log.Named("volumes"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vm.Close()
+
+	result := make(chan error, 1)
+	waitResult := func() {
+		t.Helper()
+		select {
+		case err := <-result:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(30 * time.Second):
+			t.Fatal("timed out waiting for volume operation")
+		}
+	}
+	addVolume := func(name string) (storage.Volume, string) {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), name)
+		vol, err := vm.AddVolume(path, sectors, storage.VolumeBackend{}, result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitResult()
+		return vol, path
+	}
+	setReadOnly := func(readOnly bool, ids ...int) {
+		t.Helper()
+		for _, id := range ids {
+			if err := vm.SetReadOnly(id, readOnly); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	data := make(map[types.Hash256][rhpv2.SectorSize]byte)
+	writeSectors := func(n int) (roots []types.Hash256) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			var sector [rhpv2.SectorSize]byte
+			frand.Read(sector[:256])
+			root := rhpv2.SectorRoot(&sector)
+			release, err := vm.Write(root, &sector)
+			if err != nil {
+				t.Fatal(err)
+			} else if err := vm.AddTemporarySectors([]storage.TempSector{{Root: root, Expiration: 1000}}); err != nil {
+				t.Fatal(err)
+			} else if err := release(); err != nil {
+				t.Fatal(err)
+			}
+			data[root] = sector
+			roots = append(roots, root)
+		}
+		return
+	}
+	checkSectors := func(roots []types.Hash256) {
+		t.Helper()
+		for _, root := range roots {
+			sector, err := vm.Read(root)
+			if err != nil {
+				t.Fatal(err)
+			} else if *sector != data[root] {
+				t.Fatalf("sector %v data mismatch", root)
+			}
+		}
+	}
+	checkUsed := func(id int, expected uint64) {
+		t.Helper()
+		if vol, err := vm.Volume(id); err != nil {
+			t.Fatal(err)
+		} else if vol.UsedSectors != expected {
+			t.Fatalf("expected volume %v to have %v used sectors, got %v", id, expected, vol.UsedSectors)
+		}
+	}
+
+	volume1, _ := addVolume("vol1.dat")
+	roots1 := writeSectors(4)
+	setReadOnly(true, volume1.ID)
+	volume2, _ := addVolume("vol2.dat")
+	roots2 := writeSectors(4)
+	setReadOnly(true, volume2.ID)
+	volume3, _ := addVolume("vol3.dat")
+
+	if err := vm.SetParitySettings(storage.ParitySettings{Scheme: storage.ParitySchemeXOR, DataShards: 2, ParityShards: 1}); err != nil {
+		t.Fatal(err)
+	} else if n, err := vm.EncodeParity(); err != nil {
+		t.Fatal(err)
+	} else if n != 4 {
+		t.Fatalf("expected 4 parity groups, got %v", n)
+	}
+	setReadOnly(true, volume3.ID)
+
+	// migrate the first volume's sectors to a new volume. The groups are
+	// still stored in different volumes and should be kept.
+	volume4, path4 := addVolume("vol4.dat")
+	if err := vm.RemoveVolume(volume1.ID, false, result); err != nil {
+		t.Fatal(err)
+	}
+	waitResult()
+	checkUsed(volume4.ID, uint64(len(roots1)))
+	if n, err := vm.EncodeParity(); err != nil {
+		t.Fatal(err)
+	} else if n != 0 {
+		t.Fatalf("expected migrated sectors to stay protected, got %v new groups", n)
+	}
+
+	// store an unprotected sector in the new volume. It cannot be
+	// reconstructed when the volume fails.
+	lost := writeSectors(1)
+
+	// fail the new volume and rebuild it from parity
+	if err := os.Truncate(path4, 0); err != nil {
+		t.Fatal(err)
+	}
+	volume5, _ := addVolume("vol5.dat")
+	if err := vm.RebuildVolume(volume4.ID, volume5.ID, result); err != nil {
+		t.Fatal(err)
+	}
+	waitResult()
+
+	// only the rebuilt sectors should be moved
+	checkUsed(volume4.ID, 1)
+	checkUsed(volume5.ID, uint64(len(roots1)))
+	checkSectors(roots1)
+	checkSectors(roots2)
+	if _, err := vm.Read(lost[0]); err == nil {
+		t.Fatal("expected lost sector to be unavailable")
+	}
+	var found bool
+	for _, a := range am.Active() {
+		if a.Message == "Volume rebuilt with lost sectors" {
+			found = true
+			if a.Data["lost"] != 1 || a.Data["moved"] != len(roots1) {
+				t.Fatalf("unexpected rebuild result: %v", a.Data)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("expected lost sectors alert")
+	}
+
+	// moving the rebuilt sectors into the volume containing the other data
+	// sectors of their groups should drop the groups
+	setReadOnly(false, volume2.ID)
+	setReadOnly(true, volume5.ID)
+	if err := vm.RemoveVolume(volume5.ID, false, result); err != nil {
+		t.Fatal(err)
+	}
+	waitResult()
+	checkUsed(volume2.ID, uint64(len(roots1)+len(roots2)))
+	for _, root := range append(roots1, roots2...) {
+		if _, err := db.ParityGroup(root); !errors.Is(err, storage.ErrNoParity) {
+			t.Fatalf("expected parity group of %v to be dropped, got %v", root, err)
+		}
+	}
+	checkSectors(roots1)
+
+	// the parity sectors are no longer referenced
+	if _, err := db.PruneSectors(); err != nil {
+		t.Fatal(err)
+	}
+	checkUsed(volume3.ID, 0)
+}
+
+func TestTierMigration(t *testing.T) {
+	const sectors = 8
+	dir := t.TempDir()
+
+	log := zaptest.NewLogger(t)
+	db, err := sqlite.OpenDatabase(filepath.Join(dir, "hostd.db"), log.Named("sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	g, err := gateway.New(":0", false, filepath.Join(dir, "gateway"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	cs, errCh := consensus.New(g, false, filepath.Join(dir, "consensus"))
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatal(err)
+		}
+	default:
+	}
+	cm, err := chain.NewManager(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cm.Close()
+
+	// disable the cache so reads update the sectors' access times
+	am := alerts.NewManager()
+	vm, err := storage.NewVolumeManager(db, am, cm, log.Named("volumes"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vm.Close()
+
+	result := make(chan error, 1)
+	hot, err := vm.AddVolume(filepath.Join(t.TempDir(), "hot.dat"), sectors, storage.VolumeBackend{}, result)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := <-result; err != nil {
+		t.Fatal(err)
+	} else if err := vm.SetVolumeTier(hot.ID, storage.VolumeTierHot); err != nil {
+		t.Fatal(err)
+	}
+
+	// fill the hot volume
+	roots := make([]types.Hash256, sectors)
+	for i := range roots {
+		var sector [rhpv2.SectorSize]byte
+		frand.Read(sector[:256])
+		roots[i] = rhpv2.SectorRoot(&sector)
+		release, err := vm.Write(roots[i], &sector)
+		if err != nil {
+			t.Fatal(err)
+		} else if err := release(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the tier metrics should be updated as sectors are written
+	if m, err := db.Metrics(time.Now()); err != nil {
+		t.Fatal(err)
+	} else if m.Storage.Hot.PhysicalSectors != sectors {
+		t.Fatalf("expected %v hot physical sectors, got %v", sectors, m.Storage.Hot.PhysicalSectors)
+	}
+
+	cold, err := vm.AddVolume(filepath.Join(t.TempDir(), "cold.dat"), sectors*2, storage.VolumeBackend{}, result)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := <-result; err != nil {
+		t.Fatal(err)
+	} else if err := vm.SetVolumeTier(cold.ID, storage.VolumeTierCold); err != nil {
+		t.Fatal(err)
+	}
+
+	checkTiers := func(hotSectors, coldSectors uint64) {
+		t.Helper()
+		m, err := db.Metrics(time.Now())
+		if err != nil {
+			t.Fatal(err)
+		} else if m.Storage.Hot.PhysicalSectors != hotSectors {
+			t.Fatalf("expected %v hot physical sectors, got %v", hotSectors, m.Storage.Hot.PhysicalSectors)
+		} else if m.Storage.Cold.PhysicalSectors != coldSectors {
+			t.Fatalf("expected %v cold physical sectors, got %v", coldSectors, m.Storage.Cold.PhysicalSectors)
+		}
+
+		for id, expected := range map[int]uint64{hot.ID: hotSectors, cold.ID: coldSectors} {
+			if meta, err := vm.Volume(id); err != nil {
+				t.Fatal(err)
+			} else if meta.UsedSectors != expected {
+				t.Fatalf("volume %v: expected %v used sectors, got %v", id, expected, meta.UsedSectors)
+			}
+		}
+	}
+	checkTiers(sectors, 0)
+
+	// wait for the sectors to become idle, then access half of them
+	time.Sleep(2 * time.Second)
+	for _, root := range roots[sectors/2:] {
+		if _, err := vm.Read(root); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// only the idle sectors should be demoted
+	vm.SetTierSettings(storage.TierSettings{DemoteAfter: time.Second})
+	if err := vm.MigrateTiers(); err != nil {
+		t.Fatal(err)
+	}
+	checkTiers(sectors/2, sectors/2)
+	if meta, err := vm.Volume(cold.ID); err != nil {
+		t.Fatal(err)
+	} else if meta.TierMigratedSectors != sectors/2 {
+		t.Fatalf("expected %v migrated sectors, got %v", sectors/2, meta.TierMigratedSectors)
+	}
+
+	// access one of the demoted sectors and promote it
+	if _, err := vm.Read(roots[0]); err != nil {
+		t.Fatal(err)
+	}
+	vm.SetTierSettings(storage.TierSettings{PromoteWindow: time.Second})
+	if err := vm.MigrateTiers(); err != nil {
+		t.Fatal(err)
+	}
+	checkTiers(sectors/2+1, sectors/2-1)
+
+	// all sectors should still be readable
+	for _, root := range roots {
+		if _, err := vm.Read(root); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the tier metrics should be updated as sectors are removed
+	if err := vm.RemoveSector(roots[1]); err != nil {
+		t.Fatal(err)
+	}
+	checkTiers(sectors/2+1, sectors/2-2)
+
+	if m, err := db.Metrics(time.Now()); err != nil {
+		t.Fatal(err)
+	} else if m.Storage.DemotedSectors != sectors/2 {
+		t.Fatalf("expected %v demoted sectors, got %v", sectors/2, m.Storage.DemotedSectors)
+	} else if m.Storage.PromotedSectors != 1 {
+		t.Fatalf("expected 1 promoted sector, got %v", m.Storage.PromotedSectors)
+	}
+}
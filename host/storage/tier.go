@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	rhpv2 "go.sia.tech/core/rhp/v2"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// defines the storage tiers
+const (
+	// VolumeTierNone is the default tier. Volumes without a tier are ignored
+	// by the tier migrator.
+	VolumeTierNone = ""
+	// VolumeTierHot is the tier for fast volumes, such as NVMe drives.
+	// Frequently accessed sectors are moved to hot volumes.
+	VolumeTierHot = "hot"
+	// VolumeTierCold is the tier for slow volumes, such as HDDs. Idle sectors
+	// are moved to cold volumes.
+	VolumeTierCold = "cold"
+)
+
+const (
+	// tierCheckInterval is the interval between tier migrations
+	tierCheckInterval = 10 * time.Minute
+	// tierBatchSize is the maximum number of sectors moved between tiers in a
+	// single batch
+	tierBatchSize = 64 // 256 MiB
+)
+
+type (
+	// TierSettings configures the tier migrator.
+	TierSettings struct {
+		// PromoteWindow is the duration after a sector is accessed that it
+		// will be moved from a cold volume to a hot volume. A zero value
+		// disables promotion.
+		PromoteWindow time.Duration `json:"promoteWindow"`
+		// DemoteAfter is the duration a sector must be idle before it is
+		// moved from a hot volume to a cold volume. A zero value disables
+		// demotion.
+		DemoteAfter time.Duration `json:"demoteAfter"`
+		// MaxBandwidth is the maximum number of bytes per second moved
+		// between tiers. A zero value removes the limit.
+		MaxBandwidth uint64 `json:"maxBandwidth"`
+	}
+)
+
+// ErrInvalidTier is returned when a volume's tier is not recognized.
+var ErrInvalidTier = errors.New("invalid volume tier")
+
+// validTier returns true if tier is a known storage tier
+func validTier(tier string) bool {
+	switch tier {
+	case VolumeTierNone, VolumeTierHot, VolumeTierCold:
+		return true
+	}
+	return false
+}
+
+// migrateTier moves sectors from volumes in the from tier to volumes in the to
+// tier until no more sectors match the access window or the destination tier
+// is full. The number of migrated sectors is returned.
+func (vm *VolumeManager) migrateTier(ctx context.Context, from, to string, accessedAfter, accessedBefore time.Time, log *zap.Logger) (migrated int, err error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return migrated, ctx.Err()
+		default:
+		}
+
+		n, err := vm.vs.MigrateTierSectors(from, to, accessedAfter, accessedBefore, tierBatchSize, func(locations []SectorLocation) error {
+			for range locations {
+				if err := vm.tierLimit.WaitN(ctx, rhpv2.SectorSize); err != nil {
+					return err
+				}
+			}
+			n, err := vm.migrateSectors(locations, false, log)
+			if err != nil {
+				return err
+			}
+
+			vm.mu.Lock()
+			for _, loc := range locations[:n] {
+				if v, ok := vm.volumes[loc.Volume]; ok {
+					v.stats.TierMigratedSectors++
+				}
+			}
+			vm.mu.Unlock()
+			return nil
+		})
+		migrated += n
+		if errors.Is(err, ErrNotEnoughStorage) {
+			// the destination tier is full
+			return migrated, nil
+		} else if err != nil {
+			return migrated, err
+		} else if n == 0 {
+			return migrated, nil
+		}
+	}
+}
+
+// migrateTiers promotes recently accessed sectors to hot volumes and demotes
+// idle sectors to cold volumes.
+func (vm *VolumeManager) migrateTiers(ctx context.Context) error {
+	vm.mu.Lock()
+	settings := vm.tierSettings
+	vm.mu.Unlock()
+
+	log := vm.log.Named("tiers")
+	now := time.Now()
+	if settings.PromoteWindow > 0 {
+		promoted, err := vm.migrateTier(ctx, VolumeTierCold, VolumeTierHot, now.Add(-settings.PromoteWindow), now.Add(time.Second), log)
+		if err != nil {
+			return fmt.Errorf("failed to promote sectors: %w", err)
+		} else if promoted > 0 {
+			log.Info("promoted sectors", zap.Int("sectors", promoted))
+		}
+	}
+
+	if settings.DemoteAfter > 0 {
+		demoted, err := vm.migrateTier(ctx, VolumeTierHot, VolumeTierCold, time.Time{}, now.Add(-settings.DemoteAfter), log)
+		if err != nil {
+			return fmt.Errorf("failed to demote sectors: %w", err)
+		} else if demoted > 0 {
+			log.Info("demoted sectors", zap.Int("sectors", demoted))
+		}
+	}
+	return nil
+}
+
+// runTierMigrator periodically moves sectors between storage tiers based on
+// their last access time.
+func (vm *VolumeManager) runTierMigrator() {
+	t := time.NewTicker(tierCheckInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-vm.tg.Done():
+			return
+		case <-t.C:
+		}
+
+		ctx, cancel, err := vm.tg.AddContext(context.Background())
+		if err != nil {
+			return
+		}
+		if err := vm.migrateTiers(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			vm.log.Error("failed to migrate sectors between tiers", zap.Error(err))
+		}
+		cancel()
+	}
+}
+
+// MigrateTiers immediately moves sectors between storage tiers instead of
+// waiting for the next check.
+func (vm *VolumeManager) MigrateTiers() error {
+	ctx, cancel, err := vm.tg.AddContext(context.Background())
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	return vm.migrateTiers(ctx)
+}
+
+// SetVolumeTier sets the storage tier of a volume.
+func (vm *VolumeManager) SetVolumeTier(id int, tier string) error {
+	done, err := vm.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	if !validTier(tier) {
+		return fmt.Errorf("%w: %q", ErrInvalidTier, tier)
+	}
+	return vm.vs.SetVolumeTier(id, tier)
+}
+
+// SetTierSettings updates the thresholds and bandwidth limit of the tier
+// migrator.
+func (vm *VolumeManager) SetTierSettings(settings TierSettings) {
+	vm.mu.Lock()
+	vm.tierSettings = settings
+	vm.mu.Unlock()
+
+	if settings.MaxBandwidth == 0 {
+		vm.tierLimit.SetLimit(rate.Inf)
+		return
+	}
+	vm.tierLimit.SetLimit(rate.Limit(settings.MaxBandwidth))
+}
@@ -3,29 +3,16 @@ package storage
 import (
 	"errors"
 	"fmt"
-	"io"
-	"os"
 	"sync"
 
 	rhpv2 "go.sia.tech/core/rhp/v2"
 )
 
 type (
-	// volumeData wraps the methods needed to read and write sector data to a
-	// volume.
-	volumeData interface {
-		io.ReaderAt
-		io.WriterAt
-
-		Sync() error
-		Truncate(int64) error
-		Close() error
-	}
-
 	// A volume stores and retrieves sector data
 	volume struct {
-		// data is a flatfile that stores the volume's sector data
-		data volumeData
+		// data stores the volume's sector data
+		data VolumeData
 
 		mu    sync.Mutex // protects the fields below
 		stats VolumeStats
@@ -42,6 +29,14 @@ type (
 		SuccessfulWrites uint64  `json:"successfulWrites"`
 		Status           string  `json:"status"`
 		Errors           []error `json:"errors"`
+
+		// TierMigratedSectors is the number of sectors moved to the volume
+		// by the tier migrator since the host started.
+		TierMigratedSectors uint64 `json:"tierMigratedSectors"`
+		// Migration is the progress of sectors being migrated out of the
+		// volume while it is shrunk or removed. It is nil if no migration is
+		// running.
+		Migration *MigrationProgress `json:"migration,omitempty"`
 	}
 
 	// A Volume stores and retrieves sector data
@@ -52,6 +47,11 @@ type (
 		TotalSectors uint64 `json:"totalSectors"`
 		ReadOnly     bool   `json:"readOnly"`
 		Available    bool   `json:"available"`
+
+		Backend VolumeBackend `json:"backend"`
+		// Tier is the storage tier of the volume. Sectors are moved between
+		// tiers based on how recently they were accessed.
+		Tier string `json:"tier"`
 	}
 
 	// VolumeMeta contains the metadata of a volume.
@@ -71,18 +71,18 @@ func (v *volume) appendError(err error) {
 	}
 }
 
-// OpenVolume opens the volume at localPath
-func (v *volume) OpenVolume(localPath string, reload bool) error {
+// OpenVolume opens the volume at localPath using the volume's backend
+func (v *volume) OpenVolume(localPath string, backend VolumeBackend, reload bool) error {
 	v.mu.Lock()
 	defer v.mu.Unlock()
 	if v.data != nil && !reload {
 		return nil
 	}
-	f, err := os.OpenFile(localPath, os.O_RDWR, 0700)
+	data, err := openVolumeData(localPath, backend, false)
 	if err != nil {
 		return err
 	}
-	v.data = f
+	v.data = data
 	return nil
 }
 
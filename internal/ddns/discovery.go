@@ -0,0 +1,252 @@
+package ddns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"gitlab.com/NebulousLabs/encoding"
+	"go.sia.tech/siad/modules"
+)
+
+type (
+	// A Gateway is connected to peers on the Sia network.
+	Gateway interface {
+		Peers() []modules.Peer
+		RPC(addr modules.NetAddress, name string, fn modules.RPCFunc) error
+	}
+
+	// An EchoDiscoverer discovers the host's public IP address using
+	// services that respond with the caller's IP address. A quorum of the
+	// services must agree on the address so a single misbehaving service
+	// cannot redirect the host's DNS records.
+	EchoDiscoverer struct {
+		urls   []string
+		quorum int
+	}
+
+	// A PeerDiscoverer discovers the host's public IP address by asking the
+	// gateway's peers which address they see. A majority of at least
+	// minPeers responding peers must agree on the address.
+	PeerDiscoverer struct {
+		g        Gateway
+		minPeers int
+	}
+
+	// A StaticDiscoverer returns IP addresses specified by the host
+	// operator.
+	StaticDiscoverer struct {
+		ipv4 net.IP
+		ipv6 net.IP
+	}
+)
+
+// DefaultEchoURLs are the services used to discover the host's public IP
+// address if none are configured.
+var DefaultEchoURLs = []string{"https://icanhazip.com"}
+
+// ErrNoQuorum is returned when not enough services or peers agree on the
+// host's IP address.
+var ErrNoQuorum = errors.New("no quorum")
+
+// matchesNetwork returns true if ip belongs to the address family of
+// network.
+func matchesNetwork(ip net.IP, network string) bool {
+	if network == NetworkModeTCP4 {
+		return ip.To4() != nil
+	}
+	return ip.To4() == nil
+}
+
+func getIP(ctx context.Context, client *http.Client, url string) (net.IP, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get address: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received status code: %d: %w", resp.StatusCode, ErrUnexpectedResponse)
+	}
+
+	lr := io.LimitReader(resp.Body, 64)
+	buf, err := io.ReadAll(lr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ip address: %w", err)
+	}
+	str := strings.TrimSpace(string(buf))
+
+	if len(str) == 0 {
+		return nil, ErrEmptyResponse
+	}
+
+	ip := net.ParseIP(str)
+	if ip == nil {
+		return nil, ErrInvalidIP
+	}
+
+	return ip, nil
+}
+
+// DiscoverIP implements IPDiscoverer.
+func (d *EchoDiscoverer) DiscoverIP(ctx context.Context, network string) (net.IP, error) {
+	var client *http.Client
+	switch network {
+	case NetworkModeTCP4:
+		client = tcp4Client
+	case NetworkModeTCP6:
+		client = tcp6Client
+	default:
+		return nil, fmt.Errorf("unknown network %q", network)
+	}
+
+	type result struct {
+		ip  net.IP
+		err error
+	}
+	results := make(chan result, len(d.urls))
+	for _, u := range d.urls {
+		go func(u string) {
+			ip, err := getIP(ctx, client, u)
+			if err == nil && !matchesNetwork(ip, network) {
+				err = ErrInvalidIP
+			}
+			if err != nil {
+				err = fmt.Errorf("%s: %w", u, err)
+			}
+			results <- result{ip, err}
+		}(u)
+	}
+
+	counts := make(map[string]int)
+	var errs []string
+	for range d.urls {
+		res := <-results
+		if res.err != nil {
+			errs = append(errs, res.err.Error())
+			continue
+		}
+		counts[res.ip.String()]++
+		if counts[res.ip.String()] >= d.quorum {
+			return res.ip, nil
+		}
+	}
+	if len(errs) != 0 {
+		return nil, fmt.Errorf("%w: %d of %d services must agree (%s)", ErrNoQuorum, d.quorum, len(d.urls), strings.Join(errs, "; "))
+	}
+	return nil, fmt.Errorf("%w: %d of %d services must agree", ErrNoQuorum, d.quorum, len(d.urls))
+}
+
+// DiscoverIP implements IPDiscoverer.
+func (d *PeerDiscoverer) DiscoverIP(ctx context.Context, network string) (net.IP, error) {
+	if network != NetworkModeTCP4 && network != NetworkModeTCP6 {
+		return nil, fmt.Errorf("unknown network %q", network)
+	}
+
+	peers := d.g.Peers()
+	if len(peers) < d.minPeers {
+		return nil, fmt.Errorf("%w: %d peers connected, %d required", ErrNoQuorum, len(peers), d.minPeers)
+	}
+
+	// ask each peer which address it sees
+	results := make(chan net.IP, len(peers))
+	for _, peer := range peers {
+		go func(addr modules.NetAddress) {
+			var ip net.IP
+			d.g.RPC(addr, "DiscoverIP", func(conn modules.PeerConn) error {
+				if deadline, ok := ctx.Deadline(); ok {
+					conn.SetDeadline(deadline)
+				}
+				var address string
+				if err := encoding.ReadObject(conn, &address, 100); err != nil {
+					return err
+				}
+				ip = net.ParseIP(address)
+				return nil
+			})
+			results <- ip
+		}(peer.NetAddress)
+	}
+
+	counts := make(map[string]int)
+	var responses int
+	for range peers {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case ip := <-results:
+			if ip == nil || !matchesNetwork(ip, network) {
+				continue
+			}
+			counts[ip.String()]++
+			responses++
+		}
+	}
+	if responses < d.minPeers {
+		return nil, fmt.Errorf("%w: %d peers responded, %d required", ErrNoQuorum, responses, d.minPeers)
+	}
+	for addr, count := range counts {
+		if count > responses/2 {
+			return net.ParseIP(addr), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: peers disagree on the host's address", ErrNoQuorum)
+}
+
+// DiscoverIP implements IPDiscoverer.
+func (d *StaticDiscoverer) DiscoverIP(ctx context.Context, network string) (net.IP, error) {
+	switch network {
+	case NetworkModeTCP4:
+		if d.ipv4 == nil {
+			return nil, errors.New("no static ipv4 address")
+		}
+		return d.ipv4, nil
+	case NetworkModeTCP6:
+		if d.ipv6 == nil {
+			return nil, errors.New("no static ipv6 address")
+		}
+		return d.ipv6, nil
+	default:
+		return nil, fmt.Errorf("unknown network %q", network)
+	}
+}
+
+// NewEchoDiscoverer returns an IPDiscoverer that requires at least quorum of
+// the echo services to agree on the host's IP address.
+func NewEchoDiscoverer(urls []string, quorum int) (*EchoDiscoverer, error) {
+	switch {
+	case len(urls) == 0:
+		return nil, errors.New("at least one url is required")
+	case quorum < 1 || quorum > len(urls):
+		return nil, fmt.Errorf("quorum must be between 1 and %d", len(urls))
+	}
+	return &EchoDiscoverer{urls: urls, quorum: quorum}, nil
+}
+
+// NewPeerDiscoverer returns an IPDiscoverer that asks the gateway's peers for
+// the host's IP address. At least minPeers must respond.
+func NewPeerDiscoverer(g Gateway, minPeers int) (*PeerDiscoverer, error) {
+	if minPeers < 1 {
+		return nil, errors.New("at least one peer is required")
+	}
+	return &PeerDiscoverer{g: g, minPeers: minPeers}, nil
+}
+
+// NewStaticDiscoverer returns an IPDiscoverer that always returns the
+// specified addresses. Either address may be nil.
+func NewStaticDiscoverer(ipv4, ipv6 net.IP) (*StaticDiscoverer, error) {
+	if ipv4 != nil && ipv4.To4() == nil {
+		return nil, fmt.Errorf("%v is not an ipv4 address", ipv4)
+	} else if ipv6 != nil && ipv6.To4() != nil {
+		return nil, fmt.Errorf("%v is not an ipv6 address", ipv6)
+	}
+	return &StaticDiscoverer{ipv4: ipv4, ipv6: ipv6}, nil
+}
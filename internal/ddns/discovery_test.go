@@ -0,0 +1,126 @@
+package ddns_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.sia.tech/hostd/internal/ddns"
+	"go.sia.tech/siad/modules/gateway"
+)
+
+// echoServer returns a stand-in IP echo service that always responds with
+// body.
+func echoServer(t *testing.T, status int, body string) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		fmt.Fprintln(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestEchoDiscoverer(t *testing.T) {
+	honest := "192.0.2.1"
+	urls := []string{
+		echoServer(t, http.StatusOK, honest),
+		echoServer(t, http.StatusOK, honest),
+		echoServer(t, http.StatusOK, "198.51.100.7"),
+		echoServer(t, http.StatusInternalServerError, ""),
+		echoServer(t, http.StatusOK, "2001:db8::1"), // wrong address family
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	d, err := ddns.NewEchoDiscoverer(urls, 2)
+	if err != nil {
+		t.Fatal(err)
+	} else if ip, err := d.DiscoverIP(ctx, ddns.NetworkModeTCP4); err != nil {
+		t.Fatal(err)
+	} else if !ip.Equal(net.ParseIP(honest)) {
+		t.Fatalf("expected %v, got %v", honest, ip)
+	}
+
+	// only two services agree on the address
+	d, err = ddns.NewEchoDiscoverer(urls, 3)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := d.DiscoverIP(ctx, ddns.NetworkModeTCP4); !errors.Is(err, ddns.ErrNoQuorum) {
+		t.Fatalf("expected ErrNoQuorum, got %v", err)
+	}
+
+	if _, err := ddns.NewEchoDiscoverer(urls, 0); err == nil {
+		t.Fatal("expected invalid quorum error")
+	} else if _, err := ddns.NewEchoDiscoverer(urls, len(urls)+1); err == nil {
+		t.Fatal("expected invalid quorum error")
+	}
+}
+
+func TestPeerDiscoverer(t *testing.T) {
+	newGateway := func() *gateway.Gateway {
+		g, err := gateway.New("127.0.0.1:0", false, filepath.Join(t.TempDir(), "gateway"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { g.Close() })
+		return g
+	}
+
+	g := newGateway()
+	for i := 0; i < 2; i++ {
+		if err := g.Connect(newGateway().Address()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	d, err := ddns.NewPeerDiscoverer(g, 2)
+	if err != nil {
+		t.Fatal(err)
+	} else if ip, err := d.DiscoverIP(ctx, ddns.NetworkModeTCP4); err != nil {
+		t.Fatal(err)
+	} else if !ip.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Fatalf("expected 127.0.0.1, got %v", ip)
+	}
+
+	// the peers are only reachable over IPv4
+	if _, err := d.DiscoverIP(ctx, ddns.NetworkModeTCP6); !errors.Is(err, ddns.ErrNoQuorum) {
+		t.Fatalf("expected ErrNoQuorum, got %v", err)
+	}
+
+	d, err = ddns.NewPeerDiscoverer(g, 3)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := d.DiscoverIP(ctx, ddns.NetworkModeTCP4); !errors.Is(err, ddns.ErrNoQuorum) {
+		t.Fatalf("expected ErrNoQuorum, got %v", err)
+	}
+}
+
+func TestStaticDiscoverer(t *testing.T) {
+	ipv4 := net.ParseIP("192.0.2.1")
+	d, err := ddns.NewStaticDiscoverer(ipv4, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if ip, err := d.DiscoverIP(context.Background(), ddns.NetworkModeTCP4); err != nil {
+		t.Fatal(err)
+	} else if !ip.Equal(ipv4) {
+		t.Fatalf("expected %v, got %v", ipv4, ip)
+	} else if _, err := d.DiscoverIP(context.Background(), ddns.NetworkModeTCP6); err == nil {
+		t.Fatal("expected missing ipv6 address error")
+	}
+
+	if _, err := ddns.NewStaticDiscoverer(net.ParseIP("2001:db8::1"), nil); err == nil {
+		t.Fatal("expected invalid ipv4 address error")
+	} else if _, err := ddns.NewStaticDiscoverer(nil, ipv4); err == nil {
+		t.Fatal("expected invalid ipv6 address error")
+	}
+}
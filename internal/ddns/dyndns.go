@@ -4,9 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 )
@@ -22,18 +22,35 @@ type (
 		// are included, the function should return an error.
 		Update(ipv4, ipv6 net.IP) error
 	}
+
+	// A TXTProvider is a DNS provider that can manage TXT records. It is used
+	// to complete ACME DNS-01 challenges.
+	TXTProvider interface {
+		// SetTXT creates or replaces the TXT record for name.
+		SetTXT(ctx context.Context, name, value string) error
+		// DeleteTXT removes the TXT record for name with the specified
+		// value.
+		DeleteTXT(ctx context.Context, name, value string) error
+	}
+
+	// An IPDiscoverer discovers the host's public IP addresses.
+	IPDiscoverer interface {
+		// DiscoverIP returns the host's public IP address for the network,
+		// either NetworkModeTCP4 or NetworkModeTCP6.
+		DiscoverIP(ctx context.Context, network string) (net.IP, error)
+	}
 )
 
 const (
 	// NetworkModeTCP4 will get the IP address using TCP4.
-	networkModeTCP4 = "tcp4"
+	NetworkModeTCP4 = "tcp4"
 	// NetworkModeTCP6 will get the IP address using TCP6.
-	networkModeTCP6 = "tcp6"
+	NetworkModeTCP6 = "tcp6"
 )
 
 var (
-	tcp4Client = fixedHTTPClient(networkModeTCP4)
-	tcp6Client = fixedHTTPClient(networkModeTCP6)
+	tcp4Client = fixedHTTPClient(NetworkModeTCP4)
+	tcp6Client = fixedHTTPClient(NetworkModeTCP6)
 
 	// ErrEmptyResponse is returned when the ip service returns an empty
 	// response.
@@ -71,42 +88,34 @@ func fixedHTTPClient(forcedNetwork string) *http.Client {
 	}
 }
 
-func getIP(client *http.Client) (net.IP, error) {
-	resp, err := client.Get("https://icanhazip.com")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get address: %w", err)
+// RelativeName returns the name of hostname relative to zone. "@" is returned
+// if hostname is the zone apex.
+func RelativeName(hostname, zone string) (string, error) {
+	hostname = strings.ToLower(strings.TrimSuffix(hostname, "."))
+	zone = strings.ToLower(strings.TrimSuffix(zone, "."))
+	switch {
+	case hostname == zone:
+		return "@", nil
+	case strings.HasSuffix(hostname, "."+zone):
+		return strings.TrimSuffix(hostname, "."+zone), nil
+	default:
+		return "", fmt.Errorf("hostname %q is not in zone %q", hostname, zone)
 	}
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("received status code: %d: %w", resp.StatusCode, ErrUnexpectedResponse)
+// ValidateBaseURL validates the base URL of a provider's API. An empty URL
+// is valid and uses the provider's default.
+func ValidateBaseURL(baseURL string) error {
+	if len(baseURL) == 0 {
+		return nil
 	}
-
-	lr := io.LimitReader(resp.Body, 64)
-	buf, err := io.ReadAll(lr)
+	u, err := url.Parse(baseURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read ip address: %w", err)
-	}
-	str := strings.TrimSpace(string(buf))
-
-	if len(str) == 0 {
-		return nil, ErrEmptyResponse
-	}
-
-	ip := net.ParseIP(str)
-	if ip == nil {
-		return nil, ErrInvalidIP
+		return fmt.Errorf("invalid base url: %w", err)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported base url scheme %q", u.Scheme)
+	} else if len(u.Host) == 0 {
+		return errors.New("base url must include a host")
 	}
-
-	return ip, nil
-}
-
-// GetIPv4 returns the IPv4 address of the current machine.
-func GetIPv4() (net.IP, error) {
-	return getIP(tcp4Client)
-}
-
-// GetIPv6 returns the IPv6 address of the current machine.
-func GetIPv6() (net.IP, error) {
-	return getIP(tcp6Client)
+	return nil
 }
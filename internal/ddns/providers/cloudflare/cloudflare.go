@@ -133,6 +133,73 @@ func (p *Provider) Update(ipv4, ipv6 net.IP) error {
 	return nil
 }
 
+// SetTXT implements the ddns.TXTProvider interface for Cloudflare.
+func (p *Provider) SetTXT(ctx context.Context, name, value string) error {
+	client, err := cloudflare.NewWithAPIToken(p.opts.Token)
+	if err != nil {
+		return fmt.Errorf("failed to create cloudflare client: %w", err)
+	}
+
+	zoneID := cloudflare.ZoneIdentifier(p.opts.ZoneID)
+	recordID, err := getRecordID(client, zoneID, name, "TXT")
+	if err != nil && !errors.Is(err, errNotFound) {
+		return fmt.Errorf("failed to get record id: %w", err)
+	}
+
+	if len(recordID) == 0 {
+		_, err := client.CreateDNSRecord(ctx, zoneID, cloudflare.CreateDNSRecordParams{
+			Type:    "TXT",
+			Name:    name,
+			ZoneID:  p.opts.ZoneID,
+			Content: value,
+			TTL:     60,
+			Comment: "managed by hostd",
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create txt record: %w", err)
+		}
+		return nil
+	}
+
+	_, err = client.UpdateDNSRecord(ctx, zoneID, cloudflare.UpdateDNSRecordParams{
+		ID:      recordID,
+		Type:    "TXT",
+		Name:    name,
+		Content: value,
+		TTL:     60,
+		Comment: "managed by hostd",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update txt record: %w", err)
+	}
+	return nil
+}
+
+// DeleteTXT implements the ddns.TXTProvider interface for Cloudflare.
+func (p *Provider) DeleteTXT(ctx context.Context, name, value string) error {
+	client, err := cloudflare.NewWithAPIToken(p.opts.Token)
+	if err != nil {
+		return fmt.Errorf("failed to create cloudflare client: %w", err)
+	}
+
+	zoneID := cloudflare.ZoneIdentifier(p.opts.ZoneID)
+	records, _, err := client.ListDNSRecords(ctx, zoneID, cloudflare.ListDNSRecordsParams{
+		Name: name,
+		Type: "TXT",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list dns records: %w", err)
+	}
+	for _, record := range records {
+		if record.Content != value {
+			continue
+		} else if err := client.DeleteDNSRecord(ctx, zoneID, record.ID); err != nil {
+			return fmt.Errorf("failed to delete txt record: %w", err)
+		}
+	}
+	return nil
+}
+
 // ValidateOptions validates the options for the Cloudflare provider.
 func ValidateOptions(opts Options) error {
 	switch {
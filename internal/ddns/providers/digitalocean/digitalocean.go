@@ -0,0 +1,213 @@
+// Package digitalocean implements the DigitalOcean DNS provider.
+package digitalocean
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.sia.tech/hostd/internal/ddns"
+)
+
+type (
+	// Options is the set of options for the DigitalOcean provider.
+	Options struct {
+		Token string `json:"token"`
+		// Domain is the domain managed by DigitalOcean that contains the
+		// hostname.
+		Domain   string `json:"domain"`
+		Hostname string `json:"hostname"`
+		// BaseURL is the URL of the API. Defaults to DefaultBaseURL.
+		BaseURL string `json:"baseURL"`
+	}
+
+	// Provider implements the DNS provider interface for DigitalOcean.
+	Provider struct {
+		options Options
+	}
+
+	domainRecord struct {
+		ID   int    `json:"id,omitempty"`
+		Type string `json:"type"`
+		Name string `json:"name"`
+		Data string `json:"data"`
+		TTL  int    `json:"ttl"`
+	}
+)
+
+// DefaultBaseURL is the URL of the DigitalOcean API.
+const DefaultBaseURL = "https://api.digitalocean.com/v2"
+
+var (
+	c = &http.Client{
+		Timeout: time.Second * 15,
+	}
+
+	// ErrInvalidAuth is returned if the API token is incorrect.
+	ErrInvalidAuth = errors.New("invalid api token")
+)
+
+// baseURL returns the URL of the API.
+func (p *Provider) baseURL() string {
+	if len(p.options.BaseURL) == 0 {
+		return DefaultBaseURL
+	}
+	return strings.TrimSuffix(p.options.BaseURL, "/")
+}
+
+// do makes a request to the DigitalOcean API and decodes the response into
+// resp, if it is not nil.
+func (p *Provider) do(ctx context.Context, method, path string, req, resp any) error {
+	var body io.Reader
+	if req != nil {
+		buf, err := json.Marshal(req)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(buf)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, method, p.baseURL()+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	r.Header.Set("Authorization", "Bearer "+p.options.Token)
+	r.Header.Set("Content-Type", "application/json")
+	res, err := c.Do(r)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusUnauthorized:
+		return ErrInvalidAuth
+	case res.StatusCode < 200 || res.StatusCode >= 300:
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(io.LimitReader(res.Body, 1024)).Decode(&apiErr)
+		return fmt.Errorf("unexpected status code %d: %s", res.StatusCode, apiErr.Message)
+	case resp == nil:
+		return nil
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(resp); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// records returns the records of the specified type for the fully-qualified
+// name.
+func (p *Provider) records(ctx context.Context, name, recordType string) ([]domainRecord, error) {
+	var resp struct {
+		DomainRecords []domainRecord `json:"domain_records"`
+	}
+	query := url.Values{"type": []string{recordType}, "name": []string{strings.TrimSuffix(name, ".")}}
+	path := fmt.Sprintf("/domains/%s/records?%s", url.PathEscape(p.options.Domain), query.Encode())
+	if err := p.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to list records: %w", err)
+	}
+	return resp.DomainRecords, nil
+}
+
+// setRecord creates or replaces the record of the specified type for name.
+func (p *Provider) setRecord(ctx context.Context, name, recordType, data string, ttl int) error {
+	relative, err := ddns.RelativeName(name, p.options.Domain)
+	if err != nil {
+		return err
+	}
+	records, err := p.records(ctx, name, recordType)
+	if err != nil {
+		return err
+	}
+
+	record := domainRecord{Type: recordType, Name: relative, Data: data, TTL: ttl}
+	if len(records) == 0 {
+		if err := p.do(ctx, http.MethodPost, fmt.Sprintf("/domains/%s/records", url.PathEscape(p.options.Domain)), record, nil); err != nil {
+			return fmt.Errorf("failed to create %s record: %w", recordType, err)
+		}
+		return nil
+	}
+	path := fmt.Sprintf("/domains/%s/records/%d", url.PathEscape(p.options.Domain), records[0].ID)
+	if err := p.do(ctx, http.MethodPut, path, record, nil); err != nil {
+		return fmt.Errorf("failed to update %s record: %w", recordType, err)
+	}
+	return nil
+}
+
+// Update implements the ddns.Provider interface for DigitalOcean.
+func (p *Provider) Update(ipv4, ipv6 net.IP) error {
+	if ipv4 == nil && ipv6 == nil {
+		return errors.New("no ip addresses provided")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if ipv4 != nil {
+		if err := p.setRecord(ctx, p.options.Hostname, "A", ipv4.String(), 300); err != nil {
+			return err
+		}
+	}
+	if ipv6 != nil {
+		if err := p.setRecord(ctx, p.options.Hostname, "AAAA", ipv6.String(), 300); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetTXT implements the ddns.TXTProvider interface for DigitalOcean.
+func (p *Provider) SetTXT(ctx context.Context, name, value string) error {
+	return p.setRecord(ctx, name, "TXT", value, 60)
+}
+
+// DeleteTXT implements the ddns.TXTProvider interface for DigitalOcean.
+func (p *Provider) DeleteTXT(ctx context.Context, name, value string) error {
+	records, err := p.records(ctx, name, "TXT")
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if record.Data != value {
+			continue
+		}
+		path := fmt.Sprintf("/domains/%s/records/%d", url.PathEscape(p.options.Domain), record.ID)
+		if err := p.do(ctx, http.MethodDelete, path, nil, nil); err != nil {
+			return fmt.Errorf("failed to delete TXT record: %w", err)
+		}
+	}
+	return nil
+}
+
+// ValidateOptions validates the options for the DigitalOcean provider.
+func ValidateOptions(opts Options) error {
+	switch {
+	case len(opts.Token) == 0:
+		return errors.New("token is required")
+	case len(opts.Domain) == 0:
+		return errors.New("domain is required")
+	case len(opts.Hostname) == 0:
+		return errors.New("hostname is required")
+	}
+	if _, err := ddns.RelativeName(opts.Hostname, opts.Domain); err != nil {
+		return err
+	}
+	return ddns.ValidateBaseURL(opts.BaseURL)
+}
+
+// New creates a new DigitalOcean provider.
+func New(opts Options) ddns.Provider {
+	return &Provider{
+		options: opts,
+	}
+}
@@ -0,0 +1,167 @@
+package digitalocean_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.sia.tech/hostd/internal/ddns/providers/digitalocean"
+)
+
+type domainRecord struct {
+	ID   int    `json:"id,omitempty"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Data string `json:"data"`
+	TTL  int    `json:"ttl"`
+}
+
+// doAPI is a stand-in for the DigitalOcean domain records API of a single
+// domain.
+type doAPI struct {
+	token  string
+	domain string
+
+	mu      sync.Mutex
+	nextID  int
+	records map[int]domainRecord
+}
+
+func (s *doAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+s.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	prefix := "/v2/domains/" + s.domain + "/records"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+		return
+	}
+	var id int
+	if rest := strings.TrimPrefix(r.URL.Path[len(prefix):], "/"); len(rest) != 0 {
+		var err error
+		if id, err = strconv.Atoi(rest); err != nil {
+			http.Error(w, `{"message":"invalid id"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && id == 0:
+		// the name filter is fully-qualified
+		var resp struct {
+			DomainRecords []domainRecord `json:"domain_records"`
+		}
+		resp.DomainRecords = []domainRecord{}
+		for _, record := range s.records {
+			fqdn := record.Name + "." + s.domain
+			if record.Name == "@" {
+				fqdn = s.domain
+			}
+			if record.Type == r.URL.Query().Get("type") && fqdn == r.URL.Query().Get("name") {
+				resp.DomainRecords = append(resp.DomainRecords, record)
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	case r.Method == http.MethodPost && id == 0, r.Method == http.MethodPut && id != 0:
+		var record domainRecord
+		if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+			http.Error(w, `{"message":"invalid record"}`, http.StatusBadRequest)
+			return
+		}
+		if id == 0 {
+			s.nextID++
+			id = s.nextID
+		} else if _, ok := s.records[id]; !ok {
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+			return
+		}
+		record.ID = id
+		s.records[id] = record
+		json.NewEncoder(w).Encode(map[string]any{"domain_record": record})
+	case r.Method == http.MethodDelete && id != 0:
+		delete(s.records, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, `{"message":"method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *doAPI) find(name, recordType string) []domainRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var records []domainRecord
+	for _, record := range s.records {
+		if record.Name == name && record.Type == recordType {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+func TestDigitalOcean(t *testing.T) {
+	api := &doAPI{token: "foo", domain: "example.com", records: make(map[int]domainRecord)}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	opts := digitalocean.Options{
+		Token:    "foo",
+		Domain:   "example.com",
+		Hostname: "host.example.com",
+		BaseURL:  srv.URL + "/v2",
+	}
+	if err := digitalocean.ValidateOptions(opts); err != nil {
+		t.Fatal(err)
+	}
+	provider := digitalocean.New(opts).(*digitalocean.Provider)
+
+	// the first update creates the records and later updates replace them
+	for _, ip := range []string{"192.0.2.1", "192.0.2.2"} {
+		ipv4, ipv6 := net.ParseIP(ip), net.ParseIP("2001:db8::1")
+		if err := provider.Update(ipv4, ipv6); err != nil {
+			t.Fatal(err)
+		} else if a := api.find("host", "A"); len(a) != 1 || a[0].Data != ipv4.String() || a[0].TTL != 300 {
+			t.Fatalf("unexpected A records %v", a)
+		} else if aaaa := api.find("host", "AAAA"); len(aaaa) != 1 || aaaa[0].Data != ipv6.String() {
+			t.Fatalf("unexpected AAAA records %v", aaaa)
+		}
+	}
+
+	// only the TXT record with the matching value is deleted
+	ctx := context.Background()
+	name := "_acme-challenge.host.example.com."
+	if err := provider.SetTXT(ctx, name, "bar"); err != nil {
+		t.Fatal(err)
+	} else if txt := api.find("_acme-challenge.host", "TXT"); len(txt) != 1 || txt[0].Data != "bar" {
+		t.Fatalf("unexpected TXT records %v", txt)
+	} else if err := provider.DeleteTXT(ctx, name, "baz"); err != nil {
+		t.Fatal(err)
+	} else if txt := api.find("_acme-challenge.host", "TXT"); len(txt) != 1 {
+		t.Fatalf("expected TXT record to be kept, got %v", txt)
+	} else if err := provider.DeleteTXT(ctx, name, "bar"); err != nil {
+		t.Fatal(err)
+	} else if txt := api.find("_acme-challenge.host", "TXT"); len(txt) != 0 {
+		t.Fatalf("expected TXT record to be deleted, got %v", txt)
+	}
+
+	opts.Token = "bar"
+	if err := digitalocean.New(opts).Update(net.ParseIP("192.0.2.3"), nil); !errors.Is(err, digitalocean.ErrInvalidAuth) {
+		t.Fatalf("expected %v, got %v", digitalocean.ErrInvalidAuth, err)
+	}
+
+	// API errors should include the message
+	opts.Token, opts.Domain, opts.Hostname = "foo", "example.org", "host.example.org"
+	if err := digitalocean.New(opts).Update(net.ParseIP("192.0.2.3"), nil); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package duckdns
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -77,6 +78,43 @@ func (p *Provider) Update(ipv4, ipv6 net.IP) error {
 	return fmt.Errorf("failed to update host: %w", ErrUnknown)
 }
 
+// updateTXT sets or clears the TXT record of the provider's domain.
+func (p *Provider) updateTXT(ctx context.Context, v url.Values) error {
+	v.Set("domains", p.options.Hostname)
+	v.Set("token", p.options.Token)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://www.duckdns.org/update?"+v.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create update request: %w", err)
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make update request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	lr := io.LimitReader(resp.Body, 10)
+	body, err := io.ReadAll(lr)
+	if err != nil {
+		return fmt.Errorf("failed to read response status: %w", err)
+	} else if string(body) == "OK" {
+		return nil
+	}
+	return fmt.Errorf("failed to update txt record: %w", ErrUnknown)
+}
+
+// SetTXT implements the ddns.TXTProvider interface for DuckDNS. DuckDNS
+// supports a single TXT record per domain which is returned for all of its
+// subdomains, so name is ignored.
+func (p *Provider) SetTXT(ctx context.Context, name, value string) error {
+	return p.updateTXT(ctx, url.Values{"txt": []string{value}})
+}
+
+// DeleteTXT implements the ddns.TXTProvider interface for DuckDNS.
+func (p *Provider) DeleteTXT(ctx context.Context, name, value string) error {
+	return p.updateTXT(ctx, url.Values{"txt": []string{value}, "clear": []string{"true"}})
+}
+
 // ValidateOptions validates the options for the DuckDNS provider.
 func ValidateOptions(opts Options) error {
 	switch {
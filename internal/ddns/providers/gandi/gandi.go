@@ -0,0 +1,169 @@
+// Package gandi implements the Gandi LiveDNS provider.
+package gandi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.sia.tech/hostd/internal/ddns"
+)
+
+type (
+	// Options is the set of options for the Gandi provider.
+	Options struct {
+		// Token is a Gandi personal access token with permission to manage
+		// the domain's DNS records.
+		Token string `json:"token"`
+		// Domain is the domain managed by Gandi that contains the hostname.
+		Domain   string `json:"domain"`
+		Hostname string `json:"hostname"`
+		// BaseURL is the URL of the API. Defaults to DefaultBaseURL.
+		BaseURL string `json:"baseURL"`
+	}
+
+	// Provider implements the DNS provider interface for Gandi LiveDNS.
+	Provider struct {
+		options Options
+	}
+
+	rrset struct {
+		Values []string `json:"rrset_values"`
+		TTL    int      `json:"rrset_ttl"`
+	}
+)
+
+// DefaultBaseURL is the URL of the Gandi API.
+const DefaultBaseURL = "https://api.gandi.net/v5/livedns"
+
+var (
+	c = &http.Client{
+		Timeout: time.Second * 15,
+	}
+
+	// ErrInvalidAuth is returned if the token is incorrect or does not have
+	// permission to manage the domain.
+	ErrInvalidAuth = errors.New("invalid token")
+)
+
+// baseURL returns the URL of the API.
+func (p *Provider) baseURL() string {
+	if len(p.options.BaseURL) == 0 {
+		return DefaultBaseURL
+	}
+	return strings.TrimSuffix(p.options.BaseURL, "/")
+}
+
+// do makes a request to the record set for name.
+func (p *Provider) do(ctx context.Context, method, name, recordType string, req any) error {
+	relative, err := ddns.RelativeName(name, p.options.Domain)
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader
+	if req != nil {
+		buf, err := json.Marshal(req)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(buf)
+	}
+
+	u := fmt.Sprintf("%s/domains/%s/records/%s/%s", p.baseURL(), url.PathEscape(p.options.Domain), url.PathEscape(relative), recordType)
+	r, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	r.Header.Set("Authorization", "Bearer "+p.options.Token)
+	r.Header.Set("Content-Type", "application/json")
+	res, err := c.Do(r)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden:
+		return ErrInvalidAuth
+	case method == http.MethodDelete && res.StatusCode == http.StatusNotFound:
+		return nil
+	case res.StatusCode < 200 || res.StatusCode >= 300:
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(io.LimitReader(res.Body, 1024)).Decode(&apiErr)
+		return fmt.Errorf("unexpected status code %d: %s", res.StatusCode, apiErr.Message)
+	}
+	return nil
+}
+
+// Update implements the ddns.Provider interface for Gandi.
+func (p *Provider) Update(ipv4, ipv6 net.IP) error {
+	if ipv4 == nil && ipv6 == nil {
+		return errors.New("no ip addresses provided")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if ipv4 != nil {
+		if err := p.do(ctx, http.MethodPut, p.options.Hostname, "A", rrset{Values: []string{ipv4.String()}, TTL: 300}); err != nil {
+			return fmt.Errorf("failed to update ipv4 record: %w", err)
+		}
+	}
+	if ipv6 != nil {
+		if err := p.do(ctx, http.MethodPut, p.options.Hostname, "AAAA", rrset{Values: []string{ipv6.String()}, TTL: 300}); err != nil {
+			return fmt.Errorf("failed to update ipv6 record: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetTXT implements the ddns.TXTProvider interface for Gandi.
+func (p *Provider) SetTXT(ctx context.Context, name, value string) error {
+	if err := p.do(ctx, http.MethodPut, name, "TXT", rrset{Values: []string{value}, TTL: 300}); err != nil {
+		return fmt.Errorf("failed to set TXT record: %w", err)
+	}
+	return nil
+}
+
+// DeleteTXT implements the ddns.TXTProvider interface for Gandi. The entire
+// record set for name is removed.
+func (p *Provider) DeleteTXT(ctx context.Context, name, value string) error {
+	if err := p.do(ctx, http.MethodDelete, name, "TXT", nil); err != nil {
+		return fmt.Errorf("failed to delete TXT record: %w", err)
+	}
+	return nil
+}
+
+// ValidateOptions validates the options for the Gandi provider.
+func ValidateOptions(opts Options) error {
+	switch {
+	case len(opts.Token) == 0:
+		return errors.New("token is required")
+	case len(opts.Domain) == 0:
+		return errors.New("domain is required")
+	case len(opts.Hostname) == 0:
+		return errors.New("hostname is required")
+	}
+	if _, err := ddns.RelativeName(opts.Hostname, opts.Domain); err != nil {
+		return err
+	}
+	return ddns.ValidateBaseURL(opts.BaseURL)
+}
+
+// New creates a new Gandi provider.
+func New(opts Options) ddns.Provider {
+	return &Provider{
+		options: opts,
+	}
+}
@@ -0,0 +1,119 @@
+package gandi_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.sia.tech/hostd/internal/ddns/providers/gandi"
+)
+
+type rrset struct {
+	Values []string `json:"rrset_values"`
+	TTL    int      `json:"rrset_ttl"`
+}
+
+// liveDNS is a stand-in for the Gandi LiveDNS API. Record sets are keyed by
+// request path.
+type liveDNS struct {
+	token string
+
+	mu      sync.Mutex
+	records map[string]rrset
+}
+
+func (s *liveDNS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+s.token {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		var req rrset
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.records[r.URL.Path] = req
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		if _, ok := s.records[r.URL.Path]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(s.records, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *liveDNS) lookup(path string) (rrset, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rs, ok := s.records[path]
+	return rs, ok
+}
+
+func TestGandi(t *testing.T) {
+	api := &liveDNS{token: "foo", records: make(map[string]rrset)}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	opts := gandi.Options{
+		Token:    "foo",
+		Domain:   "example.com",
+		Hostname: "host.example.com",
+		BaseURL:  srv.URL + "/v5/livedns/",
+	}
+	if err := gandi.ValidateOptions(opts); err != nil {
+		t.Fatal(err)
+	}
+	provider := gandi.New(opts).(*gandi.Provider)
+
+	ipv4, ipv6 := net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")
+	if err := provider.Update(ipv4, ipv6); err != nil {
+		t.Fatal(err)
+	} else if rs, ok := api.lookup("/v5/livedns/domains/example.com/records/host/A"); !ok || len(rs.Values) != 1 || rs.Values[0] != ipv4.String() {
+		t.Fatalf("unexpected A record set %v", rs)
+	} else if rs, ok := api.lookup("/v5/livedns/domains/example.com/records/host/AAAA"); !ok || len(rs.Values) != 1 || rs.Values[0] != ipv6.String() {
+		t.Fatalf("unexpected AAAA record set %v", rs)
+	}
+
+	// the zone apex is addressed as "@"
+	const txtPath = "/v5/livedns/domains/example.com/records/@/TXT"
+	if err := provider.SetTXT(context.Background(), "example.com.", "bar"); err != nil {
+		t.Fatal(err)
+	} else if rs, ok := api.lookup(txtPath); !ok || len(rs.Values) != 1 || rs.Values[0] != "bar" {
+		t.Fatalf("unexpected TXT record set %v", rs)
+	} else if err := provider.DeleteTXT(context.Background(), "example.com.", "bar"); err != nil {
+		t.Fatal(err)
+	} else if _, ok := api.lookup(txtPath); ok {
+		t.Fatal("expected TXT record set to be deleted")
+	} else if err := provider.DeleteTXT(context.Background(), "example.com.", "bar"); err != nil {
+		t.Fatal("expected deleting a missing record set to succeed, got", err)
+	}
+
+	// names outside the domain should be rejected before making a request
+	if err := provider.SetTXT(context.Background(), "host.example.org", "bar"); err == nil {
+		t.Fatal("expected error for name outside the domain")
+	}
+
+	opts.Token = "bar"
+	if err := gandi.New(opts).Update(ipv4, nil); !errors.Is(err, gandi.ErrInvalidAuth) {
+		t.Fatalf("expected %v, got %v", gandi.ErrInvalidAuth, err)
+	}
+
+	opts.BaseURL = "ftp://example.com"
+	if err := gandi.ValidateOptions(opts); err == nil {
+		t.Fatal("expected invalid base url to be rejected")
+	}
+}
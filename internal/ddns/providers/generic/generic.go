@@ -0,0 +1,187 @@
+// Package generic implements a dynamic DNS provider for any service with an
+// HTTP update API, such as services compatible with the dyndns2 protocol.
+package generic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.sia.tech/hostd/build"
+	"go.sia.tech/hostd/internal/ddns"
+)
+
+type (
+	// Options is the set of options for the generic provider.
+	Options struct {
+		// URL is a text/template of the update URL. The template is executed
+		// with the fields of TemplateData, e.g.
+		// https://example.com/nic/update?hostname={{.Hostname}}&myip={{.IP}}
+		URL string `json:"url"`
+		// Method is the HTTP method of the update request. Defaults to GET.
+		Method string `json:"method"`
+		// Username and Password are sent using basic authentication, if set.
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Hostname string `json:"hostname"`
+	}
+
+	// TemplateData is the data the URL template is executed with. Addresses
+	// that are not being updated are empty.
+	TemplateData struct {
+		Hostname string
+		IPv4     string
+		IPv6     string
+		// IP is a comma-separated list of the addresses being updated.
+		IP string
+	}
+
+	// Provider implements the DNS provider interface for generic HTTP
+	// update APIs.
+	Provider struct {
+		options Options
+	}
+)
+
+var (
+	c = &http.Client{
+		Timeout: time.Second * 15,
+	}
+	userAgent = fmt.Sprintf("hostd/%s-%s hi@sia.tech", runtime.GOOS, build.Version())
+
+	// dyndns2Errors are the error responses of the dyndns2 protocol.
+	dyndns2Errors = map[string]bool{
+		"badauth":  true,
+		"badagent": true,
+		"notfqdn":  true,
+		"nohost":   true,
+		"numhost":  true,
+		"abuse":    true,
+		"dnserr":   true,
+		"911":      true,
+		"!donator": true,
+	}
+
+	// ErrUpdateFailed is returned if the service returns an error response.
+	ErrUpdateFailed = errors.New("update failed")
+)
+
+// parseTemplate parses the URL template.
+func parseTemplate(s string) (*template.Template, error) {
+	return template.New("url").Parse(s)
+}
+
+// Update implements the ddns.Provider interface for generic HTTP update
+// APIs.
+func (p *Provider) Update(ipv4, ipv6 net.IP) error {
+	if ipv4 == nil && ipv6 == nil {
+		return errors.New("no ip addresses provided")
+	}
+
+	data := TemplateData{Hostname: url.QueryEscape(p.options.Hostname)}
+	var ips []string
+	if ipv4 != nil {
+		data.IPv4 = ipv4.String()
+		ips = append(ips, data.IPv4)
+	}
+	if ipv6 != nil {
+		data.IPv6 = ipv6.String()
+		ips = append(ips, data.IPv6)
+	}
+	data.IP = strings.Join(ips, ",")
+
+	tmpl, err := parseTemplate(p.options.URL)
+	if err != nil {
+		return fmt.Errorf("failed to parse url template: %w", err)
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return fmt.Errorf("failed to execute url template: %w", err)
+	}
+
+	method := p.options.Method
+	if len(method) == 0 {
+		method = http.MethodGet
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, sb.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create update request: %w", err)
+	}
+	if len(p.options.Username) != 0 || len(p.options.Password) != 0 {
+		req.SetBasicAuth(p.options.Username, p.options.Password)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := c.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make update request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	status := strings.TrimSpace(string(body))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("received status code %d %q: %w", resp.StatusCode, status, ErrUpdateFailed)
+	} else if fields := strings.Fields(status); len(fields) != 0 && dyndns2Errors[strings.ToLower(fields[0])] {
+		return fmt.Errorf("received status %q: %w", status, ErrUpdateFailed)
+	}
+	return nil
+}
+
+// ValidateOptions validates the options for the generic provider.
+func ValidateOptions(opts Options) error {
+	switch {
+	case len(opts.URL) == 0:
+		return errors.New("url is required")
+	case len(opts.Hostname) == 0:
+		return errors.New("hostname is required")
+	}
+	return ValidateTemplate(opts.URL, opts.Method)
+}
+
+// ValidateTemplate validates the URL template and HTTP method.
+func ValidateTemplate(urlTemplate, method string) error {
+	switch method {
+	case "", http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return fmt.Errorf("unsupported method %q", method)
+	}
+
+	tmpl, err := parseTemplate(urlTemplate)
+	if err != nil {
+		return fmt.Errorf("invalid url template: %w", err)
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, TemplateData{Hostname: "host.example.com", IPv4: "192.0.2.1", IPv6: "2001:db8::1", IP: "192.0.2.1"}); err != nil {
+		return fmt.Errorf("invalid url template: %w", err)
+	}
+	u, err := url.Parse(sb.String())
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported url scheme %q", u.Scheme)
+	} else if len(u.Host) == 0 {
+		return errors.New("url must include a host")
+	}
+	return nil
+}
+
+// New creates a new generic provider.
+func New(opts Options) ddns.Provider {
+	return &Provider{
+		options: opts,
+	}
+}
@@ -0,0 +1,85 @@
+package generic_test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.sia.tech/hostd/internal/ddns/providers/generic"
+)
+
+func TestGenericUpdate(t *testing.T) {
+	var status int
+	var response string
+	var req *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+		w.WriteHeader(status)
+		fmt.Fprintln(w, response)
+	}))
+	defer srv.Close()
+
+	opts := generic.Options{
+		URL:      srv.URL + "/nic/update?hostname={{.Hostname}}&myip={{.IP}}&v4={{.IPv4}}&v6={{.IPv6}}",
+		Username: "foo",
+		Password: "bar",
+		Hostname: "host.example.com",
+	}
+	if err := generic.ValidateOptions(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	status, response = http.StatusOK, "good 192.0.2.1"
+	ipv4, ipv6 := net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")
+	if err := generic.New(opts).Update(ipv4, ipv6); err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Method != http.MethodGet {
+		t.Fatalf("expected method GET, got %v", req.Method)
+	} else if req.URL.Path != "/nic/update" {
+		t.Fatalf("expected path /nic/update, got %v", req.URL.Path)
+	} else if username, password, ok := req.BasicAuth(); !ok || username != "foo" || password != "bar" {
+		t.Fatalf("unexpected basic auth %q %q", username, password)
+	} else if !strings.HasPrefix(req.UserAgent(), "hostd/") {
+		t.Fatalf("unexpected user agent %q", req.UserAgent())
+	}
+	query := req.URL.Query()
+	switch {
+	case query.Get("hostname") != "host.example.com":
+		t.Fatalf("unexpected hostname %q", query.Get("hostname"))
+	case query.Get("myip") != "192.0.2.1,2001:db8::1":
+		t.Fatalf("unexpected myip %q", query.Get("myip"))
+	case query.Get("v4") != "192.0.2.1" || query.Get("v6") != "2001:db8::1":
+		t.Fatalf("unexpected addresses %q %q", query.Get("v4"), query.Get("v6"))
+	}
+
+	// addresses that are not being updated are empty
+	opts.Method = http.MethodPost
+	if err := generic.New(opts).Update(nil, ipv6); err != nil {
+		t.Fatal(err)
+	} else if req.Method != http.MethodPost {
+		t.Fatalf("expected method POST, got %v", req.Method)
+	} else if query := req.URL.Query(); query.Get("myip") != "2001:db8::1" || query.Get("v4") != "" {
+		t.Fatalf("unexpected query %v", query)
+	}
+
+	// dyndns2 error responses and error status codes should fail
+	for _, tc := range []struct {
+		status   int
+		response string
+	}{
+		{http.StatusOK, "badauth"},
+		{http.StatusOK, "nohost"},
+		{http.StatusInternalServerError, ""},
+	} {
+		status, response = tc.status, tc.response
+		if err := generic.New(opts).Update(ipv4, nil); !errors.Is(err, generic.ErrUpdateFailed) {
+			t.Fatalf("expected %v for %d %q, got %v", generic.ErrUpdateFailed, tc.status, tc.response, err)
+		}
+	}
+}
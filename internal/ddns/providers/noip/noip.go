@@ -25,7 +25,9 @@ type (
 		Password string `json:"password"`
 		Hostname string `json:"hostname"`
 	}
-	// Provider implements the DNS provider interface for No-IP.
+	// Provider implements the DNS provider interface for No-IP. No-IP does
+	// not have an API to manage TXT records, so the provider does not
+	// implement ddns.TXTProvider.
 	Provider struct {
 		options Options
 	}
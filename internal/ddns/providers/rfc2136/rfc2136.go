@@ -0,0 +1,199 @@
+// Package rfc2136 implements a dynamic DNS provider that sends RFC 2136
+// dynamic updates, optionally signed with TSIG, to an authoritative DNS server
+// such as BIND.
+package rfc2136
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+	"go.sia.tech/hostd/internal/ddns"
+)
+
+type (
+	// Options is the set of options for the RFC 2136 provider.
+	Options struct {
+		// Server is the address of the authoritative DNS server, including
+		// the port.
+		Server string `json:"server"`
+		Zone   string `json:"zone"`
+		// KeyName, KeyAlgorithm, and KeySecret are the TSIG key used to sign
+		// updates. If KeyName is empty, updates are not signed.
+		KeyName      string `json:"keyName"`
+		KeyAlgorithm string `json:"keyAlgorithm"`
+		// KeySecret is the base64 encoded TSIG secret.
+		KeySecret string `json:"keySecret"`
+		Hostname  string `json:"hostname"`
+	}
+
+	// Provider implements the DNS provider interface for RFC 2136 dynamic
+	// updates.
+	Provider struct {
+		options Options
+	}
+)
+
+const (
+	// DefaultAlgorithm is the TSIG algorithm used if none is specified.
+	DefaultAlgorithm = "hmac-sha256"
+
+	recordTTL     = 300
+	challengeTTL  = 60
+	tsigFudge     = 300
+	updateTimeout = 15 * time.Second
+)
+
+// tsigAlgorithm returns the fully-qualified name of a supported TSIG
+// algorithm. An empty algorithm returns DefaultAlgorithm.
+func tsigAlgorithm(algorithm string) (string, error) {
+	if len(algorithm) == 0 {
+		algorithm = DefaultAlgorithm
+	}
+	switch name := dns.CanonicalName(algorithm); name {
+	case dns.HmacSHA1, dns.HmacSHA224, dns.HmacSHA256, dns.HmacSHA384, dns.HmacSHA512:
+		return name, nil
+	default:
+		return "", fmt.Errorf("unsupported tsig algorithm %q", algorithm)
+	}
+}
+
+// signUpdate adds a TSIG record signed at the given time to the update if the
+// provider has a key. The TSIG secrets of the client are returned.
+func (p *Provider) signUpdate(m *dns.Msg, signed time.Time) (map[string]string, error) {
+	if len(p.options.KeyName) == 0 {
+		return nil, nil
+	}
+	algorithm, err := tsigAlgorithm(p.options.KeyAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	keyName := dns.CanonicalName(p.options.KeyName)
+	m.SetTsig(keyName, algorithm, tsigFudge, signed.Unix())
+	return map[string]string{keyName: p.options.KeySecret}, nil
+}
+
+// send signs and sends the update message to the server and checks the
+// response. The response's signature is verified by the client.
+func (p *Provider) send(ctx context.Context, m *dns.Msg) error {
+	secrets, err := p.signUpdate(m, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to sign update: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+
+	client := &dns.Client{Net: "udp", TsigSecret: secrets}
+	resp, _, err := client.ExchangeContext(ctx, m, p.options.Server)
+	if err != nil {
+		return fmt.Errorf("failed to send update: %w", err)
+	} else if resp.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("server returned %s", dns.RcodeToString[resp.Rcode])
+	} else if secrets != nil && resp.IsTsig() == nil {
+		return errors.New("server response is not signed")
+	}
+	return nil
+}
+
+// newUpdate returns an update message for the provider's zone.
+func (p *Provider) newUpdate() *dns.Msg {
+	m := new(dns.Msg)
+	m.SetUpdate(dns.Fqdn(p.options.Zone))
+	return m
+}
+
+// txtRecord returns a TXT record containing value. Values longer than 255
+// bytes are split into multiple strings.
+func txtRecord(name, value string, ttl uint32) *dns.TXT {
+	rr := &dns.TXT{
+		Hdr: dns.RR_Header{Name: dns.Fqdn(name), Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: ttl},
+	}
+	for len(value) > 255 {
+		rr.Txt = append(rr.Txt, value[:255])
+		value = value[255:]
+	}
+	rr.Txt = append(rr.Txt, value)
+	return rr
+}
+
+// addressUpdate returns an update message that replaces the provider's
+// address records.
+func (p *Provider) addressUpdate(ipv4, ipv6 net.IP) *dns.Msg {
+	name := dns.Fqdn(p.options.Hostname)
+	m := p.newUpdate()
+	if ipv4 != nil {
+		m.RemoveRRset([]dns.RR{&dns.A{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA}}})
+		m.Insert([]dns.RR{&dns.A{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: recordTTL}, A: ipv4.To4()}})
+	}
+	if ipv6 != nil {
+		m.RemoveRRset([]dns.RR{&dns.AAAA{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeAAAA}}})
+		m.Insert([]dns.RR{&dns.AAAA{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: recordTTL}, AAAA: ipv6.To16()}})
+	}
+	return m
+}
+
+// Update implements the ddns.Provider interface for RFC 2136.
+func (p *Provider) Update(ipv4, ipv6 net.IP) error {
+	if ipv4 == nil && ipv6 == nil {
+		return errors.New("no ip addresses provided")
+	}
+	return p.send(context.Background(), p.addressUpdate(ipv4, ipv6))
+}
+
+// SetTXT implements the ddns.TXTProvider interface for RFC 2136.
+func (p *Provider) SetTXT(ctx context.Context, name, value string) error {
+	m := p.newUpdate()
+	m.RemoveRRset([]dns.RR{&dns.TXT{Hdr: dns.RR_Header{Name: dns.Fqdn(name), Rrtype: dns.TypeTXT}}})
+	m.Insert([]dns.RR{txtRecord(name, value, challengeTTL)})
+	return p.send(ctx, m)
+}
+
+// DeleteTXT implements the ddns.TXTProvider interface for RFC 2136.
+func (p *Provider) DeleteTXT(ctx context.Context, name, value string) error {
+	m := p.newUpdate()
+	m.Remove([]dns.RR{txtRecord(name, value, 0)})
+	return p.send(ctx, m)
+}
+
+// ValidateOptions validates the options for the RFC 2136 provider.
+func ValidateOptions(opts Options) error {
+	switch {
+	case len(opts.Server) == 0:
+		return errors.New("server is required")
+	case len(opts.Zone) == 0:
+		return errors.New("zone is required")
+	case len(opts.Hostname) == 0:
+		return errors.New("hostname is required")
+	}
+	if _, _, err := net.SplitHostPort(opts.Server); err != nil {
+		return fmt.Errorf("invalid server address: %w", err)
+	} else if !dns.IsSubDomain(dns.Fqdn(opts.Zone), dns.Fqdn(opts.Hostname)) {
+		return fmt.Errorf("hostname %q is not in zone %q", opts.Hostname, opts.Zone)
+	} else if len(opts.KeyName) != 0 {
+		return ValidateKey(opts.KeyAlgorithm, opts.KeySecret)
+	}
+	return nil
+}
+
+// ValidateKey validates the TSIG key algorithm and secret. An empty algorithm
+// uses DefaultAlgorithm.
+func ValidateKey(algorithm, secret string) error {
+	if _, err := tsigAlgorithm(algorithm); err != nil {
+		return err
+	} else if buf, err := base64.StdEncoding.DecodeString(secret); err != nil || len(buf) == 0 {
+		return errors.New("key secret must be base64 encoded")
+	}
+	return nil
+}
+
+// New creates a new RFC 2136 provider.
+func New(opts Options) ddns.Provider {
+	return &Provider{
+		options: opts,
+	}
+}
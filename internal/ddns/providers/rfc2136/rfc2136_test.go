@@ -0,0 +1,173 @@
+package rfc2136
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"lukechampine.com/frand"
+)
+
+// testServer is a minimal authoritative DNS server that applies dynamic
+// updates signed with its key.
+type testServer struct {
+	mu      sync.Mutex
+	records map[string][]dns.RR // name/type -> records
+}
+
+func recordKey(rr dns.RR) string {
+	return dns.CanonicalName(rr.Header().Name) + "/" + dns.TypeToString[rr.Header().Rrtype]
+}
+
+func (s *testServer) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	if req.IsTsig() == nil || w.TsigStatus() != nil {
+		resp.Rcode = dns.RcodeNotAuth
+		w.WriteMsg(resp)
+		return
+	}
+
+	s.mu.Lock()
+	for _, rr := range req.Ns {
+		key := recordKey(rr)
+		switch rr.Header().Class {
+		case dns.ClassANY:
+			delete(s.records, key)
+		case dns.ClassNONE:
+			// compare the record data regardless of class
+			match := dns.Copy(rr)
+			match.Header().Class = dns.ClassINET
+			for i, existing := range s.records[key] {
+				if dns.IsDuplicate(existing, match) {
+					s.records[key] = append(s.records[key][:i], s.records[key][i+1:]...)
+					break
+				}
+			}
+		case dns.ClassINET:
+			s.records[key] = append(s.records[key], dns.Copy(rr))
+		}
+	}
+	s.mu.Unlock()
+
+	tsig := req.IsTsig()
+	resp.SetTsig(tsig.Hdr.Name, tsig.Algorithm, tsig.Fudge, time.Now().Unix())
+	w.WriteMsg(resp)
+}
+
+func (s *testServer) lookup(name string, rrType uint16) []dns.RR {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.records[dns.CanonicalName(name)+"/"+dns.TypeToString[rrType]]
+}
+
+func TestRFC2136(t *testing.T) {
+	secret := base64.StdEncoding.EncodeToString(frand.Bytes(32))
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &testServer{records: make(map[string][]dns.RR)}
+	server := &dns.Server{
+		PacketConn: conn,
+		Handler:    srv,
+		TsigSecret: map[string]string{"hostd.": secret},
+		// the default accept func rejects updates
+		MsgAcceptFunc: func(dh dns.Header) dns.MsgAcceptAction {
+			if int(dh.Bits>>11)&0xF != dns.OpcodeUpdate {
+				return dns.MsgReject
+			}
+			return dns.MsgAccept
+		},
+	}
+	go server.ActivateAndServe()
+	defer server.Shutdown()
+
+	opts := Options{
+		Server:    conn.LocalAddr().String(),
+		Zone:      "example.com",
+		KeyName:   "hostd",
+		KeySecret: secret,
+		Hostname:  "host.example.com",
+	}
+	if err := ValidateOptions(opts); err != nil {
+		t.Fatal(err)
+	}
+	provider := New(opts).(*Provider)
+
+	// updates should replace the existing records
+	for _, ip := range []string{"192.0.2.1", "192.0.2.2"} {
+		ipv4, ipv6 := net.ParseIP(ip), net.ParseIP("2001:db8::1")
+		if err := provider.Update(ipv4, ipv6); err != nil {
+			t.Fatal(err)
+		}
+
+		if a := srv.lookup("host.example.com", dns.TypeA); len(a) != 1 || !a[0].(*dns.A).A.Equal(ipv4) {
+			t.Fatalf("expected A record %v, got %v", ipv4, a)
+		} else if aaaa := srv.lookup("host.example.com", dns.TypeAAAA); len(aaaa) != 1 || !aaaa[0].(*dns.AAAA).AAAA.Equal(ipv6) {
+			t.Fatalf("expected AAAA record %v, got %v", ipv6, aaaa)
+		}
+	}
+
+	// TXT records longer than 255 bytes are split into multiple strings
+	value := strings.Repeat("a", 300)
+	name := "_acme-challenge.host.example.com"
+	if err := provider.SetTXT(context.Background(), name, value); err != nil {
+		t.Fatal(err)
+	} else if txt := srv.lookup(name, dns.TypeTXT); len(txt) != 1 || len(txt[0].(*dns.TXT).Txt) != 2 || strings.Join(txt[0].(*dns.TXT).Txt, "") != value {
+		t.Fatalf("unexpected TXT record %v", txt)
+	} else if err := provider.DeleteTXT(context.Background(), name, value); err != nil {
+		t.Fatal(err)
+	} else if txt := srv.lookup(name, dns.TypeTXT); len(txt) != 0 {
+		t.Fatalf("expected TXT record to be deleted, got %v", txt)
+	}
+
+	// updates signed with the wrong key should be rejected
+	opts.KeySecret = base64.StdEncoding.EncodeToString(frand.Bytes(32))
+	if err := New(opts).Update(net.ParseIP("192.0.2.3"), nil); err == nil || !strings.Contains(err.Error(), "NOTAUTH") {
+		t.Fatalf("expected NOTAUTH error, got %v", err)
+	} else if a := srv.lookup("host.example.com", dns.TypeA); len(a) != 1 || !a[0].(*dns.A).A.Equal(net.ParseIP("192.0.2.2")) {
+		t.Fatalf("expected A record to be unchanged, got %v", a)
+	}
+}
+
+// TestTSIGKnownAnswer checks the signature of an update against a MAC
+// computed independently from the RFC 8945 signing input.
+func TestTSIGKnownAnswer(t *testing.T) {
+	// secret is the bytes 0x00..0x1f
+	const secret = "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="
+	// HMAC-SHA256 over the update message with ID 0x1234 followed by the TSIG
+	// variables: name "hostd.", class ANY, TTL 0, algorithm "hmac-sha256.",
+	// time signed 1700000000, fudge 300, error 0, and no other data.
+	const expectedMAC = "ad27a992d25c76a7f7889df6beb32b2a0cf4bda09a09d81ede6c93a002efbf31"
+
+	provider := New(Options{
+		Zone:      "example.com",
+		KeyName:   "hostd",
+		KeySecret: secret,
+		Hostname:  "host.example.com",
+	}).(*Provider)
+
+	m := provider.addressUpdate(net.ParseIP("192.0.2.1"), nil)
+	m.Id = 0x1234
+	if _, err := provider.signUpdate(m, time.Unix(1700000000, 0)); err != nil {
+		t.Fatal(err)
+	}
+	buf, mac, err := dns.TsigGenerate(m, secret, "", false)
+	if err != nil {
+		t.Fatal(err)
+	} else if mac != expectedMAC {
+		t.Fatalf("expected MAC %v, got %v", expectedMAC, mac)
+	}
+
+	// the signed message should verify with the same key
+	if err := dns.TsigVerify(buf, secret, "", false); err != nil && err != dns.ErrTime {
+		t.Fatal(err)
+	}
+}
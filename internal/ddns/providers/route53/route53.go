@@ -1,9 +1,11 @@
 package route53
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -52,11 +54,8 @@ func (p *Provider) buildChange(ip, recordType string) *route53.Change {
 	}
 }
 
-// Update implements the ddns.Provider interface for AWS Route 53.
-func (p *Provider) Update(ipv4, ipv6 net.IP) error {
-	if ipv4 == nil && ipv6 == nil {
-		return errors.New("no ip addresses provided")
-	}
+// changeRecords applies the changes to the provider's hosted zone.
+func (p *Provider) changeRecords(ctx context.Context, changes []*route53.Change) error {
 	creds := credentials.NewStaticCredentials(p.options.ID, p.options.Secret, "")
 	sess, err := session.NewSession(&aws.Config{
 		Credentials: creds,
@@ -66,15 +65,7 @@ func (p *Provider) Update(ipv4, ipv6 net.IP) error {
 	}
 	svc := route53.New(sess)
 
-	var changes []*route53.Change
-	if ipv4 != nil {
-		changes = append(changes, p.buildChange(ipv4.String(), "A"))
-	}
-	if ipv6 != nil {
-		changes = append(changes, p.buildChange(ipv6.String(), "AAAA"))
-	}
-
-	_, err = svc.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
+	_, err = svc.ChangeResourceRecordSetsWithContext(ctx, &route53.ChangeResourceRecordSetsInput{
 		HostedZoneId: aws.String(p.options.ZoneID),
 		ChangeBatch: &route53.ChangeBatch{
 			Changes: changes,
@@ -93,6 +84,50 @@ func (p *Provider) Update(ipv4, ipv6 net.IP) error {
 	return err
 }
 
+// txtChange returns a change for the TXT record for name. Route 53 requires
+// TXT values to be quoted.
+func txtChange(action, name, value string) *route53.Change {
+	return &route53.Change{
+		Action: aws.String(action),
+		ResourceRecordSet: &route53.ResourceRecordSet{
+			Name: aws.String(name),
+			Type: aws.String("TXT"),
+			TTL:  aws.Int64(60),
+			ResourceRecords: []*route53.ResourceRecord{
+				{
+					Value: aws.String(strconv.Quote(value)),
+				},
+			},
+		},
+	}
+}
+
+// Update implements the ddns.Provider interface for AWS Route 53.
+func (p *Provider) Update(ipv4, ipv6 net.IP) error {
+	if ipv4 == nil && ipv6 == nil {
+		return errors.New("no ip addresses provided")
+	}
+
+	var changes []*route53.Change
+	if ipv4 != nil {
+		changes = append(changes, p.buildChange(ipv4.String(), "A"))
+	}
+	if ipv6 != nil {
+		changes = append(changes, p.buildChange(ipv6.String(), "AAAA"))
+	}
+	return p.changeRecords(context.Background(), changes)
+}
+
+// SetTXT implements the ddns.TXTProvider interface for AWS Route 53.
+func (p *Provider) SetTXT(ctx context.Context, name, value string) error {
+	return p.changeRecords(ctx, []*route53.Change{txtChange(route53.ChangeActionUpsert, name, value)})
+}
+
+// DeleteTXT implements the ddns.TXTProvider interface for AWS Route 53.
+func (p *Provider) DeleteTXT(ctx context.Context, name, value string) error {
+	return p.changeRecords(ctx, []*route53.Change{txtChange(route53.ChangeActionDelete, name, value)})
+}
+
 // ValidateOptions validates the options for the Route53 provider.
 func ValidateOptions(opts Options) error {
 	switch {
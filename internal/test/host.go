@@ -2,6 +2,8 @@ package test
 
 import (
 	"fmt"
+	"io"
+	stdlog "log"
 	"net"
 	"net/http"
 	"path/filepath"
@@ -110,7 +112,7 @@ func (h *Host) RHPv3WSAddr() string {
 // AddVolume adds a new volume to the host
 func (h *Host) AddVolume(path string, size uint64) error {
 	result := make(chan error, 1)
-	if _, err := h.storage.AddVolume(path, size, result); err != nil {
+	if _, err := h.storage.AddVolume(path, size, storage.VolumeBackend{}, result); err != nil {
 		return err
 	}
 	return <-result
@@ -141,6 +143,11 @@ func (h *Host) Contracts() *contracts.ContractManager {
 	return h.contracts
 }
 
+// Settings returns the host's settings manager
+func (h *Host) Settings() *settings.ConfigManager {
+	return h.settings
+}
+
 // Storage returns the host's storage manager
 func (h *Host) Storage() *storage.VolumeManager {
 	return h.storage
@@ -164,18 +171,18 @@ func NewHost(privKey types.PrivateKey, dir string, node *Node, log *zap.Logger)
 	}
 
 	am := alerts.NewManager()
-	storage, err := storage.NewVolumeManager(db, am, node.cm, log.Named("storage"), DefaultSettings.SectorCacheSize)
+	vm, err := storage.NewVolumeManager(db, am, node.cm, log.Named("storage"), DefaultSettings.SectorCacheSize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create storage manager: %w", err)
 	}
 	result := make(chan error, 1)
-	if _, err := storage.AddVolume(filepath.Join(dir, "storage.dat"), 64, result); err != nil {
+	if _, err := vm.AddVolume(filepath.Join(dir, "storage.dat"), 64, storage.VolumeBackend{}, result); err != nil {
 		return nil, fmt.Errorf("failed to add storage volume: %w", err)
 	} else if err := <-result; err != nil {
 		return nil, fmt.Errorf("failed to add storage volume: %w", err)
 	}
 
-	contracts, err := contracts.NewManager(db, am, storage, node.cm, node.tp, wallet, log.Named("contracts"))
+	contracts, err := contracts.NewManager(db, am, vm, node.cm, node.tp, wallet, log.Named("contracts"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create contract manager: %w", err)
 	}
@@ -190,7 +197,7 @@ func NewHost(privKey types.PrivateKey, dir string, node *Node, log *zap.Logger)
 		return nil, fmt.Errorf("failed to create rhp2 listener: %w", err)
 	}
 
-	settings, err := settings.NewConfigManager(dir, privKey, rhp2Listener.Addr().String(), db, node.cm, node.tp, wallet, log.Named("settings"))
+	settings, err := settings.NewConfigManager(dir, privKey, rhp2Listener.Addr().String(), db, am, node.cm, node.tp, wallet, node.g, log.Named("settings"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create settings manager: %w", err)
 	}
@@ -203,13 +210,13 @@ func NewHost(privKey types.PrivateKey, dir string, node *Node, log *zap.Logger)
 	registry := registry.NewManager(privKey, db, log.Named("registry"))
 	accounts := accounts.NewManager(db, settings)
 
-	rhpv2, err := rhpv2.NewSessionHandler(rhp2Listener, privKey, rhp3Listener.Addr().String(), node.cm, node.tp, wallet, contracts, settings, storage, stubDataMonitor{}, stubMetricReporter{}, log.Named("rhpv2"))
+	rhpv2, err := rhpv2.NewSessionHandler(rhp2Listener, privKey, rhp3Listener.Addr().String(), node.cm, node.tp, wallet, contracts, settings, vm, stubDataMonitor{}, stubMetricReporter{}, log.Named("rhpv2"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create rhpv2 session handler: %w", err)
 	}
 	go rhpv2.Serve()
 
-	rhpv3, err := rhpv3.NewSessionHandler(rhp3Listener, privKey, node.cm, node.tp, wallet, accounts, contracts, registry, storage, settings, stubDataMonitor{}, stubMetricReporter{}, log.Named("rhpv3"))
+	rhpv3, err := rhpv3.NewSessionHandler(rhp3Listener, privKey, node.cm, node.tp, wallet, accounts, contracts, registry, vm, settings, stubDataMonitor{}, stubMetricReporter{}, log.Named("rhpv3"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create rhpv3 session handler: %w", err)
 	}
@@ -224,9 +231,11 @@ func NewHost(privKey types.PrivateKey, dir string, node *Node, log *zap.Logger)
 		rhpv3WS := http.Server{
 			Handler:     rhpv3.WebSocketHandler(),
 			ReadTimeout: 30 * time.Second,
+			TLSConfig:   settings.RHP3TLSConfig(),
+			ErrorLog:    stdlog.New(io.Discard, "", 0),
 		}
 
-		if err := rhpv3WS.Serve(rhpv3WSListener); err != nil {
+		if err := rhpv3WS.ServeTLS(rhpv3WSListener, "", ""); err != nil {
 			return
 		}
 	}()
@@ -238,7 +247,7 @@ func NewHost(privKey types.PrivateKey, dir string, node *Node, log *zap.Logger)
 		log:       log,
 		wallet:    wallet,
 		settings:  settings,
-		storage:   storage,
+		storage:   vm,
 		registry:  registry,
 		accounts:  accounts,
 		contracts: contracts,
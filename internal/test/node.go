@@ -125,6 +125,11 @@ func (n *Node) GatewayAddr() string {
 	return string(n.g.Address())
 }
 
+// Gateway returns the node's gateway
+func (n *Node) Gateway() modules.Gateway {
+	return n.g
+}
+
 // ConnectPeer connects the host's gateway to a peer
 func (n *Node) ConnectPeer(addr string) error {
 	return n.g.Connect(modules.NetAddress(addr))
@@ -0,0 +1,84 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/settings"
+	"go.sia.tech/siad/modules"
+)
+
+// LastAnnouncement returns the host's last announcement. If the host has not
+// announced, an empty announcement is returned.
+func (s *Store) LastAnnouncement() (ann settings.Announcement, err error) {
+	const query = `SELECT transaction_id, net_address, block_height, confirmed_height, date_created FROM host_announcements ORDER BY block_height DESC, id DESC LIMIT 1`
+	var confirmedHeight sql.NullInt64
+	err = s.queryRow(query).Scan((*sqlHash256)(&ann.TransactionID), &ann.Address, &ann.Height, &confirmedHeight, (*sqlTime)(&ann.Timestamp))
+	if errors.Is(err, sql.ErrNoRows) {
+		return settings.Announcement{}, nil
+	} else if err != nil {
+		return settings.Announcement{}, fmt.Errorf("failed to query last announcement: %w", err)
+	}
+	ann.ConfirmedHeight = uint64(confirmedHeight.Int64)
+	return ann, nil
+}
+
+// AddAnnouncement records a broadcast announcement.
+func (s *Store) AddAnnouncement(ann settings.Announcement) error {
+	const query = `INSERT INTO host_announcements (transaction_id, net_address, block_height, date_created) VALUES ($1, $2, $3, $4)`
+	_, err := s.exec(query, sqlHash256(ann.TransactionID), ann.Address, ann.Height, sqlTime(ann.Timestamp))
+	if err != nil {
+		return fmt.Errorf("failed to add announcement: %w", err)
+	}
+	return nil
+}
+
+// LastAnnouncementChange returns the last consensus change processed by the
+// config manager. If the config manager has not processed any changes, the
+// last change processed by the contract manager is returned.
+func (s *Store) LastAnnouncementChange() (id modules.ConsensusChangeID, err error) {
+	err = s.queryRow(`SELECT COALESCE(settings_last_processed_change, contracts_last_processed_change) FROM global_settings`).Scan(nullable((*sqlHash256)(&id)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return modules.ConsensusChangeBeginning, nil
+	} else if err != nil {
+		return modules.ConsensusChangeBeginning, fmt.Errorf("failed to query last announcement change: %w", err)
+	}
+	return
+}
+
+// UpdateAnnouncements atomically unconfirms the reverted announcements, adds or
+// confirms the confirmed announcements, and sets the last processed consensus
+// change.
+func (s *Store) UpdateAnnouncements(ccID modules.ConsensusChangeID, reverted []types.TransactionID, confirmed []settings.Announcement) error {
+	return s.transaction(func(tx txn) error {
+		revertStmt, err := tx.Prepare(`UPDATE host_announcements SET confirmed_height=NULL WHERE transaction_id=$1`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare revert statement: %w", err)
+		}
+		defer revertStmt.Close()
+
+		confirmStmt, err := tx.Prepare(`INSERT INTO host_announcements (transaction_id, net_address, block_height, confirmed_height, date_created) VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (transaction_id) DO UPDATE SET confirmed_height=EXCLUDED.confirmed_height`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare confirm statement: %w", err)
+		}
+		defer confirmStmt.Close()
+
+		for _, id := range reverted {
+			if _, err := revertStmt.Exec(sqlHash256(id)); err != nil {
+				return fmt.Errorf("failed to revert announcement %v: %w", id, err)
+			}
+		}
+		for _, ann := range confirmed {
+			if _, err := confirmStmt.Exec(sqlHash256(ann.TransactionID), ann.Address, ann.Height, ann.ConfirmedHeight, sqlTime(ann.Timestamp)); err != nil {
+				return fmt.Errorf("failed to confirm announcement %v: %w", ann.TransactionID, err)
+			}
+		}
+		if _, err := tx.Exec(`UPDATE global_settings SET settings_last_processed_change=$1`, sqlHash256(ccID)); err != nil {
+			return fmt.Errorf("failed to update last processed change: %w", err)
+		}
+		return nil
+	})
+}
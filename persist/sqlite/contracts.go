@@ -53,6 +53,8 @@ func (s *Store) ReviseContract(revision contracts.SignedRevision, usage contract
 		contractID, err := reviseContract(tx, revision)
 		if err != nil {
 			return fmt.Errorf("failed to revise contract: %w", err)
+		} else if _, err := tx.Exec(`UPDATE contract_renters SET last_seen=$1 WHERE id=(SELECT renter_id FROM contracts WHERE id=$2)`, sqlTime(time.Now()), contractID); err != nil {
+			return fmt.Errorf("failed to update renter last seen: %w", err)
 		} else if err := updateContractUsage(tx, contractID, usage); err != nil {
 			return fmt.Errorf("failed to update contract usage: %w", err)
 		}
@@ -360,9 +362,69 @@ WHERE c.contract_id=$1;`
 	row := s.queryRow(query, sqlHash256(id))
 	contract, err := scanContract(row)
 	if errors.Is(err, sql.ErrNoRows) {
-		err = contracts.ErrNotFound
+		return contracts.Contract{}, contracts.ErrNotFound
+	} else if err != nil {
+		return contracts.Contract{}, err
+	}
+	contract.ProofRehearsal, err = contractProofRehearsal(&dbTxn{s}, id)
+	if err != nil {
+		return contracts.Contract{}, fmt.Errorf("failed to get proof rehearsal: %w", err)
+	}
+	contract.ProofSubmission, err = contractProofSubmission(&dbTxn{s}, id)
+	if err != nil {
+		return contracts.Contract{}, fmt.Errorf("failed to get proof submission: %w", err)
+	}
+	return contract, nil
+}
+
+// SetProofSubmission stores the most recent broadcast of the contract's
+// storage proof.
+func (s *Store) SetProofSubmission(id types.FileContractID, submission contracts.ProofSubmission) error {
+	const query = `INSERT INTO contract_proof_submissions (contract_id, transaction_id, miner_fee, attempts, block_height, error_message, date_created)
+SELECT id, $1, $2, $3, $4, $5, $6 FROM contracts WHERE contract_id=$7
+ON CONFLICT (contract_id) DO UPDATE SET transaction_id=EXCLUDED.transaction_id, miner_fee=EXCLUDED.miner_fee, attempts=EXCLUDED.attempts,
+block_height=EXCLUDED.block_height, error_message=EXCLUDED.error_message, date_created=EXCLUDED.date_created`
+
+	var errMsg *string
+	if submission.Error != "" {
+		errMsg = &submission.Error
 	}
-	return contract, err
+	res, err := s.exec(query, sqlHash256(submission.TransactionID), sqlCurrency(submission.Fee), submission.Attempts, submission.Height, errMsg, sqlTime(submission.Timestamp), sqlHash256(id))
+	if err != nil {
+		return fmt.Errorf("failed to store proof submission: %w", err)
+	} else if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	} else if n != 1 {
+		return contracts.ErrNotFound
+	}
+	return nil
+}
+
+// SetProofRehearsal stores the result of the contract's most recent storage
+// proof rehearsal.
+func (s *Store) SetProofRehearsal(id types.FileContractID, rehearsal contracts.ProofRehearsal) error {
+	const query = `INSERT INTO contract_proof_rehearsals (contract_id, revision_number, block_height, passed, failed_roots, error_message, date_created)
+SELECT id, $1, $2, $3, $4, $5, $6 FROM contracts WHERE contract_id=$7
+ON CONFLICT (contract_id) DO UPDATE SET revision_number=EXCLUDED.revision_number, block_height=EXCLUDED.block_height, passed=EXCLUDED.passed,
+failed_roots=EXCLUDED.failed_roots, error_message=EXCLUDED.error_message, date_created=EXCLUDED.date_created`
+
+	var failedRoots []byte
+	for _, root := range rehearsal.FailedRoots {
+		failedRoots = append(failedRoots, root[:]...)
+	}
+	var errMsg *string
+	if rehearsal.Error != "" {
+		errMsg = &rehearsal.Error
+	}
+	res, err := s.exec(query, sqlUint64(rehearsal.RevisionNumber), rehearsal.Height, rehearsal.Passed, failedRoots, errMsg, sqlTime(rehearsal.Timestamp), sqlHash256(id))
+	if err != nil {
+		return fmt.Errorf("failed to store proof rehearsal: %w", err)
+	} else if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	} else if n != 1 {
+		return contracts.ErrNotFound
+	}
+	return nil
 }
 
 // AddContract adds a new contract to the database.
@@ -505,6 +567,13 @@ func (s *Store) ContractAction(height uint64, contractFn func(types.FileContract
 	for _, action := range actions {
 		contractFn(action.ID, height, action.Action)
 	}
+	actions, err = rehearseContractActions(tx, height)
+	if err != nil {
+		return fmt.Errorf("failed to get rehearse actions: %w", err)
+	}
+	for _, action := range actions {
+		contractFn(action.ID, height, action.Action)
+	}
 	actions, err = resolveContractActions(tx, height)
 	if err != nil {
 		return fmt.Errorf("failed to get resolve actions: %w", err)
@@ -522,6 +591,39 @@ func (s *Store) ContractAction(height uint64, contractFn func(types.FileContract
 	return nil
 }
 
+// ContractFinancialRecords returns the financial records of the contracts with
+// the given IDs sorted by timestamp.
+func (s *Store) ContractFinancialRecords(ids []types.FileContractID) (map[types.FileContractID][]contracts.FinancialRecord, error) {
+	records := make(map[types.FileContractID][]contracts.FinancialRecord)
+	if len(ids) == 0 {
+		return records, nil
+	}
+
+	query := `SELECT c.contract_id, cfr.rpc_revenue, cfr.storage_revenue, cfr.ingress_revenue, cfr.egress_revenue, cfr.date_created
+FROM contract_financial_records cfr
+INNER JOIN contracts c ON (cfr.contract_id=c.id)
+WHERE c.contract_id IN (` + queryPlaceHolders(len(ids)) + `)
+ORDER BY cfr.date_created ASC`
+	params := make([]any, 0, len(ids))
+	for _, id := range ids {
+		params = append(params, sqlHash256(id))
+	}
+	rows, err := s.query(query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query financial records: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id types.FileContractID
+		var record contracts.FinancialRecord
+		if err := rows.Scan((*sqlHash256)(&id), (*sqlCurrency)(&record.RPCRevenue), (*sqlCurrency)(&record.StorageRevenue), (*sqlCurrency)(&record.IngressRevenue), (*sqlCurrency)(&record.EgressRevenue), (*sqlTime)(&record.Timestamp)); err != nil {
+			return nil, fmt.Errorf("failed to scan financial record: %w", err)
+		}
+		records[id] = append(records[id], record)
+	}
+	return records, rows.Err()
+}
+
 // ContractFormationSet returns the set of transactions that were created during
 // contract formation.
 func (s *Store) ContractFormationSet(id types.FileContractID) ([]types.Transaction, error) {
@@ -692,6 +794,28 @@ func revisionContractActions(tx txn, height uint64) (actions []contractAction, _
 	return
 }
 
+func rehearseContractActions(tx txn, height uint64) (actions []contractAction, _ error) {
+	// formation confirmed, resolution not confirmed, status active, just
+	// before proof window
+	const query = `SELECT contract_id FROM contracts WHERE formation_confirmed=true AND resolution_height IS NULL AND contract_status=$1 AND window_start BETWEEN $2 AND $3`
+	rows, err := tx.Query(query, contracts.ContractStatusActive, height+1, height+contracts.ProofRehearsalBuffer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query contracts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		action := contractAction{
+			Action: contracts.ActionRehearseProof,
+		}
+		if err := rows.Scan((*sqlHash256)(&action.ID)); err != nil {
+			return nil, fmt.Errorf("failed to scan contract: %w", err)
+		}
+		actions = append(actions, action)
+	}
+	return
+}
+
 func resolveContractActions(tx txn, height uint64) (actions []contractAction, _ error) {
 	// formation confirmed, resolution not confirmed, status active, in proof window
 	const query = `SELECT contract_id FROM contracts WHERE formation_confirmed=true AND resolution_height IS NULL AND window_start <= $1 AND window_end > $1 AND contract_status=$2`
@@ -755,6 +879,10 @@ raw_revision, host_sig, renter_sig, confirmed_revision_number, formation_confirm
 	if err != nil {
 		return 0, fmt.Errorf("failed to get renter id: %w", err)
 	}
+	now := sqlTime(time.Now())
+	if _, err := tx.Exec(`UPDATE contract_renters SET first_seen=COALESCE(first_seen, $1), last_seen=$1 WHERE id=$2`, now, renterID); err != nil {
+		return 0, fmt.Errorf("failed to update renter last seen: %w", err)
+	}
 	err = tx.QueryRow(query,
 		sqlHash256(revision.Revision.ParentID),
 		renterID,
@@ -899,11 +1027,57 @@ func buildOrderBy(filter contracts.ContractFilter) string {
 		return `ORDER BY c.contract_status ` + dir
 	case contracts.ContractSortNegotiationHeight:
 		return `ORDER BY c.negotiation_height ` + dir
+	case contracts.ContractSortRenterKey:
+		return `ORDER BY r.public_key ` + dir
 	default:
 		return `ORDER BY c.window_start ` + dir
 	}
 }
 
+func contractProofRehearsal(tx txn, id types.FileContractID) (*contracts.ProofRehearsal, error) {
+	const query = `SELECT cpr.revision_number, cpr.block_height, cpr.passed, cpr.failed_roots, cpr.error_message, cpr.date_created
+FROM contract_proof_rehearsals cpr
+INNER JOIN contracts c ON (cpr.contract_id=c.id)
+WHERE c.contract_id=$1`
+
+	var rehearsal contracts.ProofRehearsal
+	var failedRoots []byte
+	var errMsg sql.NullString
+	err := tx.QueryRow(query, sqlHash256(id)).Scan((*sqlUint64)(&rehearsal.RevisionNumber), &rehearsal.Height, &rehearsal.Passed, &failedRoots, &errMsg, (*sqlTime)(&rehearsal.Timestamp))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	} else if len(failedRoots)%32 != 0 {
+		return nil, fmt.Errorf("invalid failed roots length %v", len(failedRoots))
+	}
+	for i := 0; i < len(failedRoots); i += 32 {
+		var root types.Hash256
+		copy(root[:], failedRoots[i:])
+		rehearsal.FailedRoots = append(rehearsal.FailedRoots, root)
+	}
+	rehearsal.Error = errMsg.String
+	return &rehearsal, nil
+}
+
+func contractProofSubmission(tx txn, id types.FileContractID) (*contracts.ProofSubmission, error) {
+	const query = `SELECT cps.transaction_id, cps.miner_fee, cps.attempts, cps.block_height, cps.error_message, cps.date_created
+FROM contract_proof_submissions cps
+INNER JOIN contracts c ON (cps.contract_id=c.id)
+WHERE c.contract_id=$1`
+
+	var submission contracts.ProofSubmission
+	var errMsg sql.NullString
+	err := tx.QueryRow(query, sqlHash256(id)).Scan((*sqlHash256)(&submission.TransactionID), (*sqlCurrency)(&submission.Fee), &submission.Attempts, &submission.Height, &errMsg, (*sqlTime)(&submission.Timestamp))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	submission.Error = errMsg.String
+	return &submission, nil
+}
+
 func scanContract(row scanner) (c contracts.Contract, err error) {
 	var revisionBuf []byte
 	var contractID types.FileContractID
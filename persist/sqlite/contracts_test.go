@@ -1,10 +1,12 @@
 package sqlite
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"go.sia.tech/core/types"
 	"go.sia.tech/hostd/host/contracts"
@@ -62,7 +64,7 @@ func TestReviseContract(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	volumeID, err := db.AddVolume("test.dat", false)
+	volumeID, err := db.AddVolume("test.dat", false, storage.VolumeBackend{})
 	if err != nil {
 		t.Fatal(err)
 	} else if err := db.SetAvailable(volumeID, true); err != nil {
@@ -282,7 +284,7 @@ func TestContracts(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	volumeID, err := db.AddVolume("test.dat", false)
+	volumeID, err := db.AddVolume("test.dat", false, storage.VolumeBackend{})
 	if err != nil {
 		t.Fatal(err)
 	} else if err := db.SetAvailable(volumeID, true); err != nil {
@@ -312,3 +314,158 @@ func TestContracts(t *testing.T) {
 		t.Fatal("expected no contracts")
 	}
 }
+
+func TestContractFinancialRecords(t *testing.T) {
+	log := zaptest.NewLogger(t)
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "test.db"), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	renterKey := types.NewPrivateKeyFromSeed(frand.Bytes(32))
+	hostKey := types.NewPrivateKeyFromSeed(frand.Bytes(32))
+	uc := types.UnlockConditions{
+		PublicKeys: []types.UnlockKey{
+			renterKey.PublicKey().UnlockKey(),
+			hostKey.PublicKey().UnlockKey(),
+		},
+		SignaturesRequired: 2,
+	}
+
+	var ids []types.FileContractID
+	for i := 0; i < 2; i++ {
+		contract := contracts.SignedRevision{
+			Revision: types.FileContractRevision{
+				ParentID:         frand.Entropy256(),
+				UnlockConditions: uc,
+				FileContract: types.FileContract{
+					UnlockHash:  types.Hash256(uc.UnlockHash()),
+					WindowStart: 100,
+					WindowEnd:   200,
+				},
+			},
+		}
+		if err := db.AddContract(contract, nil, types.ZeroCurrency, contracts.Usage{}, 0); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, contract.Revision.ParentID)
+	}
+
+	// add two records to the first contract
+	now := time.Now().Truncate(time.Second)
+	for i := 0; i < 2; i++ {
+		_, err := db.exec(`INSERT INTO contract_financial_records (contract_id, rpc_revenue, storage_revenue, ingress_revenue, egress_revenue, date_created) VALUES ((SELECT id FROM contracts WHERE contract_id=$1), $2, $3, $4, $5, $6)`,
+			sqlHash256(ids[0]), sqlCurrency(types.Siacoins(uint32(i+1))), sqlCurrency(types.ZeroCurrency), sqlCurrency(types.ZeroCurrency), sqlCurrency(types.ZeroCurrency), sqlTime(now.Add(time.Duration(i)*time.Second)))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := db.ContractFinancialRecords(ids)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(records[ids[0]]) != 2 {
+		t.Fatalf("expected 2 records, got %v", len(records[ids[0]]))
+	} else if len(records[ids[1]]) != 0 {
+		t.Fatalf("expected 0 records, got %v", len(records[ids[1]]))
+	}
+	for i, record := range records[ids[0]] {
+		if !record.RPCRevenue.Equals(types.Siacoins(uint32(i + 1))) {
+			t.Fatalf("record %v: expected rpc revenue %v, got %v", i, types.Siacoins(uint32(i+1)), record.RPCRevenue)
+		} else if !record.Timestamp.Equal(now.Add(time.Duration(i) * time.Second)) {
+			t.Fatalf("record %v: expected timestamp %v, got %v", i, now.Add(time.Duration(i)*time.Second), record.Timestamp)
+		}
+	}
+}
+
+func TestRenterStats(t *testing.T) {
+	log := zaptest.NewLogger(t)
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "test.db"), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	hostKey := types.NewPrivateKeyFromSeed(frand.Bytes(32))
+	addContracts := func(renterKey types.PrivateKey, n int) {
+		uc := types.UnlockConditions{
+			PublicKeys: []types.UnlockKey{
+				renterKey.PublicKey().UnlockKey(),
+				hostKey.PublicKey().UnlockKey(),
+			},
+			SignaturesRequired: 2,
+		}
+		for i := 0; i < n; i++ {
+			contract := contracts.SignedRevision{
+				Revision: types.FileContractRevision{
+					ParentID:         frand.Entropy256(),
+					UnlockConditions: uc,
+					FileContract: types.FileContract{
+						UnlockHash:  types.Hash256(uc.UnlockHash()),
+						WindowStart: 100,
+						WindowEnd:   200,
+					},
+				},
+			}
+			usage := contracts.Usage{
+				RPCRevenue:       types.Siacoins(1),
+				StorageRevenue:   types.Siacoins(2),
+				RiskedCollateral: types.Siacoins(3),
+			}
+			if err := db.AddContract(contract, nil, types.Siacoins(10), usage, 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	renter1 := types.NewPrivateKeyFromSeed(frand.Bytes(32))
+	renter2 := types.NewPrivateKeyFromSeed(frand.Bytes(32))
+	addContracts(renter1, 2)
+	addContracts(renter2, 1)
+
+	// move the first renter's last seen time into the past
+	if _, err := db.exec(`UPDATE contract_renters SET last_seen=$1 WHERE public_key=$2`, sqlTime(time.Now().Add(-time.Hour)), sqlHash256(renter1.PublicKey())); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := db.RenterStats(renter1.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	} else if stats.TotalContracts != 2 || stats.ActiveContracts != 2 {
+		t.Fatalf("expected 2 total and 2 active contracts, got %v and %v", stats.TotalContracts, stats.ActiveContracts)
+	} else if !stats.Revenue.RPCRevenue.Equals(types.Siacoins(2)) || !stats.Revenue.StorageRevenue.Equals(types.Siacoins(4)) {
+		t.Fatalf("unexpected revenue %v", stats.Revenue)
+	} else if !stats.LockedCollateral.Equals(types.Siacoins(20)) {
+		t.Fatalf("expected locked collateral %v, got %v", types.Siacoins(20), stats.LockedCollateral)
+	} else if !stats.RiskedCollateral.Equals(types.Siacoins(6)) {
+		t.Fatalf("expected risked collateral %v, got %v", types.Siacoins(6), stats.RiskedCollateral)
+	} else if stats.FirstSeen.IsZero() || stats.LastSeen.IsZero() {
+		t.Fatal("expected first and last seen to be set")
+	}
+
+	if _, err := db.RenterStats(types.NewPrivateKeyFromSeed(frand.Bytes(32)).PublicKey()); !errors.Is(err, contracts.ErrRenterNotFound) {
+		t.Fatalf("expected ErrRenterNotFound, got %v", err)
+	}
+
+	renters, count, err := db.Renters(100, 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if count != 2 || len(renters) != 2 {
+		t.Fatalf("expected 2 renters, got %v (%v)", len(renters), count)
+	} else if renters[0].RenterKey != renter2.PublicKey() || renters[0].TotalContracts != 1 {
+		t.Fatalf("expected most recently seen renter first, got %v", renters[0].RenterKey)
+	}
+
+	// check that contracts can be sorted by renter key
+	contractList, _, err := db.Contracts(contracts.ContractFilter{SortField: contracts.ContractSortRenterKey, Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(contractList); i++ {
+		prev, cur := contractList[i-1].RenterKey(), contractList[i].RenterKey()
+		if bytes.Compare(prev[:], cur[:]) > 0 {
+			t.Fatal("expected contracts to be sorted by renter key")
+		}
+	}
+}
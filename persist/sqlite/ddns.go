@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"go.sia.tech/hostd/host/settings"
+)
+
+func nullIP(ip net.IP) sql.NullString {
+	if ip == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: ip.String(), Valid: true}
+}
+
+func scanDDNSAttempt(s scanner) (attempt settings.DDNSAttempt, err error) {
+	var ipv4, ipv6, errMsg sql.NullString
+	err = s.Scan(&attempt.Provider, &attempt.Hostname, &ipv4, &ipv6, &attempt.Success, &attempt.Updated, &errMsg, (*sqlTime)(&attempt.Timestamp))
+	if err != nil {
+		return
+	}
+	if ipv4.Valid {
+		attempt.IPv4 = net.ParseIP(ipv4.String)
+	}
+	if ipv6.Valid {
+		attempt.IPv6 = net.ParseIP(ipv6.String)
+	}
+	attempt.Error = errMsg.String
+	return
+}
+
+// AddDDNSAttempt records a dynamic DNS update attempt.
+func (s *Store) AddDDNSAttempt(attempt settings.DDNSAttempt) error {
+	var errMsg sql.NullString
+	if len(attempt.Error) != 0 {
+		errMsg = sql.NullString{String: attempt.Error, Valid: true}
+	}
+	const query = `INSERT INTO ddns_attempts (provider, hostname, ipv4, ipv6, success, updated, error_message, date_created) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	if _, err := s.exec(query, attempt.Provider, attempt.Hostname, nullIP(attempt.IPv4), nullIP(attempt.IPv6), attempt.Success, attempt.Updated, errMsg, sqlTime(attempt.Timestamp)); err != nil {
+		return fmt.Errorf("failed to add attempt: %w", err)
+	}
+	return nil
+}
+
+// PruneDDNSAttempts removes the dynamic DNS update attempts recorded before
+// the given time. The last successful attempt is always kept.
+func (s *Store) PruneDDNSAttempts(before time.Time) error {
+	const query = `DELETE FROM ddns_attempts WHERE date_created < $1
+AND id <> (SELECT COALESCE(MAX(id), 0) FROM ddns_attempts WHERE success)`
+	if _, err := s.exec(query, sqlTime(before)); err != nil {
+		return fmt.Errorf("failed to prune attempts: %w", err)
+	}
+	return nil
+}
+
+// DDNSAttempts returns the recorded dynamic DNS update attempts, newest first.
+func (s *Store) DDNSAttempts(limit, offset int) (attempts []settings.DDNSAttempt, err error) {
+	const query = `SELECT provider, hostname, ipv4, ipv6, success, updated, error_message, date_created FROM ddns_attempts ORDER BY id DESC LIMIT $1 OFFSET $2`
+	rows, err := s.query(query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attempts: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		attempt, err := scanDDNSAttempt(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attempt: %w", err)
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, rows.Err()
+}
+
+// LastDDNSAttempt returns the last successful or failed dynamic DNS update
+// attempt. If there is no such attempt, an empty attempt is returned.
+func (s *Store) LastDDNSAttempt(success bool) (settings.DDNSAttempt, error) {
+	const query = `SELECT provider, hostname, ipv4, ipv6, success, updated, error_message, date_created FROM ddns_attempts WHERE success=$1 ORDER BY id DESC LIMIT 1`
+	attempt, err := scanDDNSAttempt(s.queryRow(query, success))
+	if errors.Is(err, sql.ErrNoRows) {
+		return settings.DDNSAttempt{}, nil
+	} else if err != nil {
+		return settings.DDNSAttempt{}, fmt.Errorf("failed to query last attempt: %w", err)
+	}
+	return attempt, nil
+}
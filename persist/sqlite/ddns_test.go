@@ -0,0 +1,123 @@
+package sqlite
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.sia.tech/hostd/host/settings"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestDDNSAttempts(t *testing.T) {
+	log := zaptest.NewLogger(t)
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "hostdb.db"), log.Named("sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if last, err := db.LastDDNSAttempt(true); err != nil {
+		t.Fatal(err)
+	} else if !last.Timestamp.IsZero() {
+		t.Fatalf("expected no attempt, got %v", last)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	success := settings.DDNSAttempt{
+		Provider:  settings.DNSProviderCloudflare,
+		Hostname:  "host.example.com",
+		IPv4:      net.ParseIP("192.0.2.1"),
+		Success:   true,
+		Updated:   true,
+		Timestamp: now.Add(-48 * time.Hour),
+	}
+	if err := db.AddDDNSAttempt(success); err != nil {
+		t.Fatal(err)
+	}
+
+	// add old failures that should be pruned and recent failures that
+	// should be kept
+	failure := settings.DDNSAttempt{
+		Provider:  settings.DNSProviderCloudflare,
+		Hostname:  "host.example.com",
+		IPv4:      net.ParseIP("192.0.2.2"),
+		IPv6:      net.ParseIP("2001:db8::1"),
+		Error:     "failed to update dns",
+		Timestamp: now.Add(-47 * time.Hour),
+	}
+	for i := 0; i < 10; i++ {
+		if err := db.AddDDNSAttempt(failure); err != nil {
+			t.Fatal(err)
+		}
+	}
+	failure.Timestamp = now
+	for i := 0; i < 5; i++ {
+		if err := db.AddDDNSAttempt(failure); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertAttempt := func(expected, actual settings.DDNSAttempt) {
+		t.Helper()
+		switch {
+		case expected.Provider != actual.Provider || expected.Hostname != actual.Hostname:
+			t.Fatalf("expected %v, got %v", expected, actual)
+		case !expected.IPv4.Equal(actual.IPv4) || !expected.IPv6.Equal(actual.IPv6):
+			t.Fatalf("expected ips %v %v, got %v %v", expected.IPv4, expected.IPv6, actual.IPv4, actual.IPv6)
+		case expected.Success != actual.Success || expected.Updated != actual.Updated || expected.Error != actual.Error:
+			t.Fatalf("expected result %v %v %q, got %v %v %q", expected.Success, expected.Updated, expected.Error, actual.Success, actual.Updated, actual.Error)
+		case !expected.Timestamp.Equal(actual.Timestamp):
+			t.Fatalf("expected timestamp %v, got %v", expected.Timestamp, actual.Timestamp)
+		}
+	}
+
+	assertCount := func(n int) {
+		t.Helper()
+		attempts, err := db.DDNSAttempts(100, 0)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(attempts) != n {
+			t.Fatalf("expected %v attempts, got %v", n, len(attempts))
+		}
+	}
+
+	assertCount(16)
+	if err := db.PruneDDNSAttempts(now.Add(-24 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	// the old failures should be removed, but the last successful attempt
+	// should be kept
+	assertCount(6)
+
+	if last, err := db.LastDDNSAttempt(true); err != nil {
+		t.Fatal(err)
+	} else {
+		assertAttempt(success, last)
+	}
+	if last, err := db.LastDDNSAttempt(false); err != nil {
+		t.Fatal(err)
+	} else {
+		assertAttempt(failure, last)
+	}
+
+	// record a successful check that did not update the provider
+	check := success
+	check.Updated = false
+	check.Timestamp = now
+	if err := db.AddDDNSAttempt(check); err != nil {
+		t.Fatal(err)
+	} else if err := db.PruneDDNSAttempts(now.Add(-24 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	// the old successful attempt is no longer the last one
+	assertCount(6)
+
+	attempts, err := db.DDNSAttempts(100, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertAttempt(check, attempts[0])
+	assertAttempt(failure, attempts[len(attempts)-1])
+}
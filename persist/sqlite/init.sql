@@ -39,7 +39,10 @@ CREATE TABLE storage_volumes (
 	used_sectors INTEGER NOT NULL,
 	total_sectors INTEGER NOT NULL,
 	read_only BOOLEAN NOT NULL,
-	available BOOLEAN NOT NULL DEFAULT false
+	available BOOLEAN NOT NULL DEFAULT false,
+	backend_type TEXT NOT NULL DEFAULT 'file',
+	backend_opts BLOB,
+	tier TEXT NOT NULL DEFAULT ''
 );
 CREATE INDEX storage_volumes_read_only_available ON storage_volumes(read_only, available);
 
@@ -62,9 +65,67 @@ CREATE TABLE locked_volume_sectors ( -- should be cleared at startup. currently
 );
 CREATE INDEX locked_volume_sectors_sector_id ON locked_volume_sectors(volume_sector_id);
 
+CREATE TABLE quarantined_volume_sectors ( -- locations that failed an integrity check. Quarantined locations are never reused.
+	volume_sector_id INTEGER PRIMARY KEY REFERENCES volume_sectors(id) ON DELETE CASCADE,
+	sector_root BLOB NOT NULL, -- root of the sector that was stored when the location was quarantined
+	reason TEXT NOT NULL,
+	date_created INTEGER NOT NULL
+);
+
+CREATE TABLE volume_scrubs (
+	volume_id INTEGER PRIMARY KEY REFERENCES storage_volumes(id) ON DELETE CASCADE,
+	scrub_cursor INTEGER NOT NULL, -- volume index of the next sector to check
+	checked_sectors INTEGER NOT NULL,
+	corrupt_sectors INTEGER NOT NULL,
+	missing_sectors INTEGER NOT NULL,
+	last_started INTEGER NOT NULL,
+	last_completed INTEGER NOT NULL
+);
+
+CREATE TABLE volume_operations ( -- add, resize and remove operations that are resumed at startup
+	id INTEGER PRIMARY KEY,
+	volume_id INTEGER UNIQUE NOT NULL REFERENCES storage_volumes(id) ON DELETE CASCADE,
+	operation_type TEXT NOT NULL,
+	target_sectors INTEGER NOT NULL,
+	progress_cursor INTEGER NOT NULL,
+	force BOOLEAN NOT NULL,
+	restore_read_only BOOLEAN NOT NULL, -- read-only status to restore when the operation completes
+	date_created INTEGER NOT NULL
+);
+
+CREATE TABLE parity_groups (
+	id INTEGER PRIMARY KEY,
+	scheme TEXT NOT NULL,
+	data_shards INTEGER NOT NULL,
+	parity_shards INTEGER NOT NULL,
+	date_created INTEGER NOT NULL
+);
+
+CREATE TABLE parity_group_sectors (
+	group_id INTEGER NOT NULL REFERENCES parity_groups(id) ON DELETE CASCADE,
+	shard_index INTEGER NOT NULL,
+	sector_id INTEGER NOT NULL REFERENCES stored_sectors(id),
+	parity BOOLEAN NOT NULL,
+	PRIMARY KEY (group_id, shard_index)
+);
+CREATE INDEX parity_group_sectors_sector_id ON parity_group_sectors(sector_id);
+
 CREATE TABLE contract_renters (
 	id INTEGER PRIMARY KEY,
-	public_key BLOB UNIQUE NOT NULL
+	public_key BLOB UNIQUE NOT NULL,
+	first_seen INTEGER, -- null until the renter forms a contract
+	last_seen INTEGER
+);
+CREATE INDEX contract_renters_last_seen ON contract_renters(last_seen);
+
+CREATE TABLE contract_renter_policies (
+	renter_id INTEGER PRIMARY KEY REFERENCES contract_renters(id),
+	blocked BOOLEAN NOT NULL,
+	max_contracts INTEGER NOT NULL,
+	max_storage INTEGER NOT NULL,
+	price_multiplier REAL NOT NULL,
+	note TEXT NOT NULL,
+	last_updated INTEGER NOT NULL
 );
 
 CREATE TABLE contracts (
@@ -115,6 +176,26 @@ CREATE TABLE contract_sector_roots (
 CREATE INDEX contract_sector_roots_sector_id ON contract_sector_roots(sector_id);
 CREATE INDEX contract_sector_roots_contract_id_root_index ON contract_sector_roots(contract_id, root_index);
 
+CREATE TABLE contract_proof_rehearsals (
+	contract_id INTEGER PRIMARY KEY REFERENCES contracts(id) ON DELETE CASCADE,
+	revision_number BLOB NOT NULL, -- stored as BLOB to support uint64_max on clearing revisions
+	block_height INTEGER NOT NULL,
+	passed BOOLEAN NOT NULL,
+	failed_roots BLOB, -- concatenated roots of the sectors that could not be proven
+	error_message TEXT,
+	date_created INTEGER NOT NULL
+);
+
+CREATE TABLE contract_proof_submissions (
+	contract_id INTEGER PRIMARY KEY REFERENCES contracts(id) ON DELETE CASCADE,
+	transaction_id BLOB NOT NULL,
+	miner_fee BLOB NOT NULL,
+	attempts INTEGER NOT NULL,
+	block_height INTEGER NOT NULL,
+	error_message TEXT,
+	date_created INTEGER NOT NULL
+);
+
 CREATE TABLE temp_storage_sector_roots (
 	id INTEGER PRIMARY KEY,
 	sector_id INTEGER NOT NULL REFERENCES stored_sectors(id),
@@ -206,8 +287,84 @@ CREATE TABLE host_settings (
 	ddns_update_v6 BOOLEAN NOT NULL,
 	ddns_opts BLOB,
 	registry_limit INTEGER NOT NULL,
-	sector_cache_size INTEGER NOT NULL DEFAULT 0
+	sector_cache_size INTEGER NOT NULL DEFAULT 0,
+	tier_promote_window INTEGER NOT NULL DEFAULT 0,
+	tier_demote_after INTEGER NOT NULL DEFAULT 0,
+	tier_migration_limit INTEGER NOT NULL DEFAULT 0,
+	parity_scheme TEXT NOT NULL DEFAULT '',
+	parity_data_shards INTEGER NOT NULL DEFAULT 0,
+	parity_shards INTEGER NOT NULL DEFAULT 0,
+	proof_resubmit_interval INTEGER NOT NULL DEFAULT 3,
+	proof_fee_increase INTEGER NOT NULL DEFAULT 50,
+	proof_max_fee BLOB NOT NULL DEFAULT X'00000000000000000000000000000000',
+	contract_webhooks BLOB, -- JSON encoded list of webhook URLs
+	pricing_engine BLOB, -- JSON encoded pricing engine settings
+	price_alert_threshold INTEGER NOT NULL DEFAULT 25,
+	announce_interval INTEGER NOT NULL DEFAULT 12960,
+	announce_confirmation_blocks INTEGER NOT NULL DEFAULT 18,
+	acme BLOB, -- JSON encoded ACME settings
+	ddns_alert_failures INTEGER NOT NULL DEFAULT 10,
+	ddns_history_retention INTEGER NOT NULL DEFAULT 604800000000000,
+	ddns_discovery BLOB, -- JSON encoded IP discovery settings
+	scrub_rate INTEGER NOT NULL DEFAULT 33554432
+);
+
+CREATE TABLE host_announcements (
+	id INTEGER PRIMARY KEY,
+	transaction_id BLOB UNIQUE NOT NULL,
+	net_address TEXT NOT NULL,
+	block_height INTEGER NOT NULL, -- height of the chain when the announcement was broadcast
+	confirmed_height INTEGER, -- height of the block containing the announcement
+	date_created INTEGER NOT NULL
+);
+CREATE INDEX host_announcements_block_height ON host_announcements(block_height);
+
+CREATE TABLE ddns_attempts (
+	id INTEGER PRIMARY KEY,
+	provider TEXT NOT NULL,
+	hostname TEXT NOT NULL,
+	ipv4 TEXT,
+	ipv6 TEXT,
+	success BOOLEAN NOT NULL,
+	updated BOOLEAN NOT NULL,
+	error_message TEXT,
+	date_created INTEGER NOT NULL
+);
+CREATE INDEX ddns_attempts_success ON ddns_attempts(success);
+CREATE INDEX ddns_attempts_date_created ON ddns_attempts(date_created);
+
+CREATE TABLE host_settings_profiles (
+	name TEXT PRIMARY KEY,
+	schedule TEXT NOT NULL,
+	duration INTEGER NOT NULL,
+	priority INTEGER NOT NULL,
+	overrides BLOB NOT NULL -- JSON encoded settings overrides
+);
+
+CREATE TABLE host_settings_history (
+	id INTEGER PRIMARY KEY,
+	settings_revision INTEGER UNIQUE NOT NULL,
+	source TEXT NOT NULL, -- what created the revision
+	settings BLOB NOT NULL, -- JSON encoded settings
+	changes BLOB NOT NULL, -- JSON encoded list of changed fields
+	date_created INTEGER NOT NULL
+);
+
+CREATE TABLE host_price_changes (
+	id INTEGER PRIMARY KEY,
+	contract_price BLOB NOT NULL,
+	base_rpc_price BLOB NOT NULL,
+	sector_access_price BLOB NOT NULL,
+	storage_price BLOB NOT NULL,
+	egress_price BLOB NOT NULL,
+	ingress_price BLOB NOT NULL,
+	collateral_multiplier REAL NOT NULL,
+	utilization REAL NOT NULL,
+	exchange_rate REAL NOT NULL,
+	contract_demand INTEGER NOT NULL,
+	date_created INTEGER NOT NULL
 );
+CREATE INDEX host_price_changes_date_created ON host_price_changes(date_created);
 
 CREATE TABLE log_lines (
 	id INTEGER PRIMARY KEY,
@@ -232,7 +389,8 @@ CREATE TABLE global_settings (
 	wallet_last_processed_change BLOB, -- last processed consensus change for the wallet
 	contracts_last_processed_change BLOB, -- last processed consensus change for the contract manager
 	wallet_height INTEGER, -- height of the wallet as of the last processed change
-	contracts_height INTEGER -- height of the contract manager as of the last processed change
+	contracts_height INTEGER, -- height of the contract manager as of the last processed change
+	settings_last_processed_change BLOB -- last processed consensus change for the config manager
 );
 
-INSERT INTO global_settings (id, db_version) VALUES (0, 7); -- version must be updated when the schema changes
\ No newline at end of file
+INSERT INTO global_settings (id, db_version) VALUES (0, 30); -- version must be updated when the schema changes
\ No newline at end of file
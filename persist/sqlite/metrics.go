@@ -29,6 +29,14 @@ const (
 	metricSectorCacheHit  = "sectorCacheHit"
 	metricSectorCacheMiss = "sectorCacheMiss"
 
+	// tiers
+	metricHotTotalSectors     = "hotTotalSectors"
+	metricHotPhysicalSectors  = "hotPhysicalSectors"
+	metricColdTotalSectors    = "coldTotalSectors"
+	metricColdPhysicalSectors = "coldPhysicalSectors"
+	metricPromotedSectors     = "promotedSectors"
+	metricDemotedSectors      = "demotedSectors"
+
 	// registry
 	metricMaxRegistryEntries = "maxRegistryEntries"
 	metricRegistryEntries    = "registryEntries"
@@ -198,6 +206,40 @@ func (s *Store) Metrics(timestamp time.Time) (m metrics.Metrics, err error) {
 	return
 }
 
+// AddPriceChange records an adjustment of the host's prices by the pricing
+// engine.
+func (s *Store) AddPriceChange(change metrics.PriceChange) error {
+	const query = `INSERT INTO host_price_changes (contract_price, base_rpc_price, sector_access_price, storage_price, egress_price, ingress_price, collateral_multiplier, utilization, exchange_rate, contract_demand, date_created) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	p := change.Pricing
+	_, err := s.exec(query, sqlCurrency(p.ContractPrice), sqlCurrency(p.BaseRPCPrice), sqlCurrency(p.SectorAccessPrice), sqlCurrency(p.StoragePrice), sqlCurrency(p.EgressPrice), sqlCurrency(p.IngressPrice), p.CollateralMultiplier, change.Utilization, change.ExchangeRate, change.Demand, sqlTime(change.Timestamp))
+	if err != nil {
+		return fmt.Errorf("failed to insert price change: %w", err)
+	}
+	return nil
+}
+
+// PriceHistory returns the price changes between start and end ordered by
+// timestamp.
+func (s *Store) PriceHistory(start, end time.Time) (changes []metrics.PriceChange, err error) {
+	const query = `SELECT contract_price, base_rpc_price, sector_access_price, storage_price, egress_price, ingress_price, collateral_multiplier, utilization, exchange_rate, contract_demand, date_created
+FROM host_price_changes WHERE date_created BETWEEN $1 AND $2 ORDER BY date_created ASC, id ASC`
+	rows, err := s.query(query, sqlTime(start), sqlTime(end))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var change metrics.PriceChange
+		p := &change.Pricing
+		if err := rows.Scan((*sqlCurrency)(&p.ContractPrice), (*sqlCurrency)(&p.BaseRPCPrice), (*sqlCurrency)(&p.SectorAccessPrice), (*sqlCurrency)(&p.StoragePrice), (*sqlCurrency)(&p.EgressPrice), (*sqlCurrency)(&p.IngressPrice), &p.CollateralMultiplier, &change.Utilization, &change.ExchangeRate, &change.Demand, (*sqlTime)(&change.Timestamp)); err != nil {
+			return nil, fmt.Errorf("failed to scan price change: %w", err)
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
 // IncrementRHP2DataUsage increments the RHP2 ingress and egress metrics.
 func (s *Store) IncrementRHP2DataUsage(ingress, egress uint64) error {
 	return s.transaction(func(tx txn) error {
@@ -340,6 +382,18 @@ func mustParseMetricValue(stat string, buf []byte, m *metrics.Metrics) {
 		m.Storage.Writes = mustScanUint64(buf)
 	case metricSectorCacheHit:
 		m.Storage.SectorCacheHits = mustScanUint64(buf)
+	case metricHotTotalSectors:
+		m.Storage.Hot.TotalSectors = mustScanUint64(buf)
+	case metricHotPhysicalSectors:
+		m.Storage.Hot.PhysicalSectors = mustScanUint64(buf)
+	case metricColdTotalSectors:
+		m.Storage.Cold.TotalSectors = mustScanUint64(buf)
+	case metricColdPhysicalSectors:
+		m.Storage.Cold.PhysicalSectors = mustScanUint64(buf)
+	case metricPromotedSectors:
+		m.Storage.PromotedSectors = mustScanUint64(buf)
+	case metricDemotedSectors:
+		m.Storage.DemotedSectors = mustScanUint64(buf)
 	case metricSectorCacheMiss:
 		m.Storage.SectorCacheMisses = mustScanUint64(buf)
 	// registry
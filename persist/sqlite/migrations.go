@@ -5,6 +5,323 @@ import (
 	"time"
 )
 
+// migrateVersion30 adds the error_message column to the
+// contract_proof_submissions table
+func migrateVersion30(tx txn) error {
+	_, err := tx.Exec(`ALTER TABLE contract_proof_submissions ADD COLUMN error_message TEXT;`)
+	return err
+}
+
+// migrateVersion29 adds the scrub_rate column to the host_settings table
+func migrateVersion29(tx txn) error {
+	_, err := tx.Exec(`ALTER TABLE host_settings ADD COLUMN scrub_rate INTEGER NOT NULL DEFAULT 33554432;`)
+	return err
+}
+
+// migrateVersion28 adds the ddns_discovery column to the host_settings table
+func migrateVersion28(tx txn) error {
+	_, err := tx.Exec(`ALTER TABLE host_settings ADD COLUMN ddns_discovery BLOB;`)
+	return err
+}
+
+// migrateVersion27 adds the dynamic DNS alert threshold and history retention
+// to the host_settings table and the updated column to the ddns_attempts table
+func migrateVersion27(tx txn) error {
+	const query = `ALTER TABLE host_settings ADD COLUMN ddns_alert_failures INTEGER NOT NULL DEFAULT 10;
+ALTER TABLE host_settings ADD COLUMN ddns_history_retention INTEGER NOT NULL DEFAULT 604800000000000;
+ALTER TABLE ddns_attempts ADD COLUMN updated BOOLEAN NOT NULL DEFAULT false;
+UPDATE ddns_attempts SET updated=success;
+CREATE INDEX ddns_attempts_date_created ON ddns_attempts(date_created);`
+	_, err := tx.Exec(query)
+	return err
+}
+
+// migrateVersion26 adds the source column to the host_settings_history table
+func migrateVersion26(tx txn) error {
+	_, err := tx.Exec(`ALTER TABLE host_settings_history ADD COLUMN source TEXT NOT NULL DEFAULT '';`)
+	return err
+}
+
+// migrateVersion25 adds the ddns_attempts table
+func migrateVersion25(tx txn) error {
+	const query = `CREATE TABLE ddns_attempts (
+	id INTEGER PRIMARY KEY,
+	provider TEXT NOT NULL,
+	hostname TEXT NOT NULL,
+	ipv4 TEXT,
+	ipv6 TEXT,
+	success BOOLEAN NOT NULL,
+	error_message TEXT,
+	date_created INTEGER NOT NULL
+);
+CREATE INDEX ddns_attempts_success ON ddns_attempts(success);`
+	_, err := tx.Exec(query)
+	return err
+}
+
+// migrateVersion24 adds the acme column to the host_settings table
+func migrateVersion24(tx txn) error {
+	_, err := tx.Exec(`ALTER TABLE host_settings ADD COLUMN acme BLOB;`)
+	return err
+}
+
+// migrateVersion23 adds the columns to track the confirmation of host
+// announcements
+func migrateVersion23(tx txn) error {
+	const query = `ALTER TABLE host_settings ADD COLUMN announce_confirmation_blocks INTEGER NOT NULL DEFAULT 18;
+ALTER TABLE host_announcements ADD COLUMN confirmed_height INTEGER;
+ALTER TABLE global_settings ADD COLUMN settings_last_processed_change BLOB;
+CREATE INDEX host_announcements_block_height ON host_announcements(block_height);`
+	_, err := tx.Exec(query)
+	return err
+}
+
+// migrateVersion22 adds the announce_interval column to the host_settings
+// table and the host_announcements table
+func migrateVersion22(tx txn) error {
+	const query = `ALTER TABLE host_settings ADD COLUMN announce_interval INTEGER NOT NULL DEFAULT 12960;
+CREATE TABLE host_announcements (
+	id INTEGER PRIMARY KEY,
+	transaction_id BLOB UNIQUE NOT NULL,
+	net_address TEXT NOT NULL,
+	block_height INTEGER NOT NULL, -- height of the chain when the announcement was broadcast
+	date_created INTEGER NOT NULL
+);`
+	_, err := tx.Exec(query)
+	return err
+}
+
+// migrateVersion21 adds the host_settings_profiles table
+func migrateVersion21(tx txn) error {
+	const query = `CREATE TABLE host_settings_profiles (
+	name TEXT PRIMARY KEY,
+	schedule TEXT NOT NULL,
+	duration INTEGER NOT NULL,
+	priority INTEGER NOT NULL,
+	overrides BLOB NOT NULL -- JSON encoded settings overrides
+);`
+	_, err := tx.Exec(query)
+	return err
+}
+
+// migrateVersion20 adds the price_alert_threshold column to the host_settings
+// table and the host_settings_history table. Settings changes before the
+// migration are not recorded.
+func migrateVersion20(tx txn) error {
+	const query = `ALTER TABLE host_settings ADD COLUMN price_alert_threshold INTEGER NOT NULL DEFAULT 25;
+CREATE TABLE host_settings_history (
+	id INTEGER PRIMARY KEY,
+	settings_revision INTEGER UNIQUE NOT NULL,
+	settings BLOB NOT NULL, -- JSON encoded settings
+	changes BLOB NOT NULL, -- JSON encoded list of changed fields
+	date_created INTEGER NOT NULL
+);`
+	_, err := tx.Exec(query)
+	return err
+}
+
+// migrateVersion19 adds the pricing_engine column to the host_settings table
+// and the host_price_changes table
+func migrateVersion19(tx txn) error {
+	const query = `ALTER TABLE host_settings ADD COLUMN pricing_engine BLOB;
+CREATE TABLE host_price_changes (
+	id INTEGER PRIMARY KEY,
+	contract_price BLOB NOT NULL,
+	base_rpc_price BLOB NOT NULL,
+	sector_access_price BLOB NOT NULL,
+	storage_price BLOB NOT NULL,
+	egress_price BLOB NOT NULL,
+	ingress_price BLOB NOT NULL,
+	collateral_multiplier REAL NOT NULL,
+	utilization REAL NOT NULL,
+	exchange_rate REAL NOT NULL,
+	contract_demand INTEGER NOT NULL,
+	date_created INTEGER NOT NULL
+);
+CREATE INDEX host_price_changes_date_created ON host_price_changes(date_created);`
+	_, err := tx.Exec(query)
+	return err
+}
+
+// migrateVersion18 adds the first_seen and last_seen columns to the
+// contract_renters table. Existing renters are not backfilled since contracts
+// do not have a creation timestamp.
+func migrateVersion18(tx txn) error {
+	if _, err := tx.Exec(`ALTER TABLE contract_renters ADD COLUMN first_seen INTEGER;`); err != nil {
+		return fmt.Errorf("failed to add first_seen column: %w", err)
+	} else if _, err := tx.Exec(`ALTER TABLE contract_renters ADD COLUMN last_seen INTEGER;`); err != nil {
+		return fmt.Errorf("failed to add last_seen column: %w", err)
+	} else if _, err := tx.Exec(`CREATE INDEX contract_renters_last_seen ON contract_renters(last_seen);`); err != nil {
+		return fmt.Errorf("failed to create last_seen index: %w", err)
+	}
+	return nil
+}
+
+// migrateVersion17 adds the contract_renter_policies table
+func migrateVersion17(tx txn) error {
+	const query = `CREATE TABLE contract_renter_policies (
+	renter_id INTEGER PRIMARY KEY REFERENCES contract_renters(id),
+	blocked BOOLEAN NOT NULL,
+	max_contracts INTEGER NOT NULL,
+	max_storage INTEGER NOT NULL,
+	price_multiplier REAL NOT NULL,
+	note TEXT NOT NULL,
+	last_updated INTEGER NOT NULL
+);`
+	_, err := tx.Exec(query)
+	return err
+}
+
+// migrateVersion16 adds the contract_webhooks column to the host_settings
+// table
+func migrateVersion16(tx txn) error {
+	_, err := tx.Exec(`ALTER TABLE host_settings ADD COLUMN contract_webhooks BLOB;`)
+	return err
+}
+
+// migrateVersion15 adds the contract_proof_submissions table and the proof fee
+// settings to the host_settings table
+func migrateVersion15(tx txn) error {
+	const query = `CREATE TABLE contract_proof_submissions (
+	contract_id INTEGER PRIMARY KEY REFERENCES contracts(id) ON DELETE CASCADE,
+	transaction_id BLOB NOT NULL,
+	miner_fee BLOB NOT NULL,
+	attempts INTEGER NOT NULL,
+	block_height INTEGER NOT NULL,
+	date_created INTEGER NOT NULL
+);`
+	if _, err := tx.Exec(query); err != nil {
+		return fmt.Errorf("failed to create contract_proof_submissions table: %w", err)
+	} else if _, err := tx.Exec(`ALTER TABLE host_settings ADD COLUMN proof_resubmit_interval INTEGER NOT NULL DEFAULT 3;`); err != nil {
+		return fmt.Errorf("failed to add proof_resubmit_interval column: %w", err)
+	} else if _, err := tx.Exec(`ALTER TABLE host_settings ADD COLUMN proof_fee_increase INTEGER NOT NULL DEFAULT 50;`); err != nil {
+		return fmt.Errorf("failed to add proof_fee_increase column: %w", err)
+	} else if _, err := tx.Exec(`ALTER TABLE host_settings ADD COLUMN proof_max_fee BLOB NOT NULL DEFAULT X'00000000000000000000000000000000';`); err != nil {
+		return fmt.Errorf("failed to add proof_max_fee column: %w", err)
+	}
+	return nil
+}
+
+// migrateVersion14 adds the contract_proof_rehearsals table
+func migrateVersion14(tx txn) error {
+	const query = `CREATE TABLE contract_proof_rehearsals (
+	contract_id INTEGER PRIMARY KEY REFERENCES contracts(id) ON DELETE CASCADE,
+	revision_number BLOB NOT NULL,
+	block_height INTEGER NOT NULL,
+	passed BOOLEAN NOT NULL,
+	failed_roots BLOB,
+	error_message TEXT,
+	date_created INTEGER NOT NULL
+);`
+	_, err := tx.Exec(query)
+	return err
+}
+
+// migrateVersion13 adds the parity_groups and parity_group_sectors tables and
+// the parity settings to the host_settings table
+func migrateVersion13(tx txn) error {
+	const groupsQuery = `CREATE TABLE parity_groups (
+	id INTEGER PRIMARY KEY,
+	scheme TEXT NOT NULL,
+	data_shards INTEGER NOT NULL,
+	parity_shards INTEGER NOT NULL,
+	date_created INTEGER NOT NULL
+);`
+	const sectorsQuery = `CREATE TABLE parity_group_sectors (
+	group_id INTEGER NOT NULL REFERENCES parity_groups(id) ON DELETE CASCADE,
+	shard_index INTEGER NOT NULL,
+	sector_id INTEGER NOT NULL REFERENCES stored_sectors(id),
+	parity BOOLEAN NOT NULL,
+	PRIMARY KEY (group_id, shard_index)
+);`
+	if _, err := tx.Exec(groupsQuery); err != nil {
+		return fmt.Errorf("failed to create parity_groups table: %w", err)
+	} else if _, err := tx.Exec(sectorsQuery); err != nil {
+		return fmt.Errorf("failed to create parity_group_sectors table: %w", err)
+	} else if _, err := tx.Exec(`CREATE INDEX parity_group_sectors_sector_id ON parity_group_sectors(sector_id);`); err != nil {
+		return fmt.Errorf("failed to create parity_group_sectors index: %w", err)
+	} else if _, err := tx.Exec(`ALTER TABLE host_settings ADD COLUMN parity_scheme TEXT NOT NULL DEFAULT '';`); err != nil {
+		return fmt.Errorf("failed to add parity_scheme column: %w", err)
+	} else if _, err := tx.Exec(`ALTER TABLE host_settings ADD COLUMN parity_data_shards INTEGER NOT NULL DEFAULT 0;`); err != nil {
+		return fmt.Errorf("failed to add parity_data_shards column: %w", err)
+	} else if _, err := tx.Exec(`ALTER TABLE host_settings ADD COLUMN parity_shards INTEGER NOT NULL DEFAULT 0;`); err != nil {
+		return fmt.Errorf("failed to add parity_shards column: %w", err)
+	}
+	return nil
+}
+
+// migrateVersion12 adds the volume_operations table to resume volume
+// operations interrupted by a restart
+func migrateVersion12(tx txn) error {
+	const query = `CREATE TABLE volume_operations (
+	id INTEGER PRIMARY KEY,
+	volume_id INTEGER UNIQUE NOT NULL REFERENCES storage_volumes(id) ON DELETE CASCADE,
+	operation_type TEXT NOT NULL,
+	target_sectors INTEGER NOT NULL,
+	progress_cursor INTEGER NOT NULL,
+	force BOOLEAN NOT NULL,
+	restore_read_only BOOLEAN NOT NULL,
+	date_created INTEGER NOT NULL
+);`
+	_, err := tx.Exec(query)
+	return err
+}
+
+// migrateVersion11 adds the tier column to the storage_volumes table and the
+// tiering settings to the host_settings table
+func migrateVersion11(tx txn) error {
+	if _, err := tx.Exec(`ALTER TABLE storage_volumes ADD COLUMN tier TEXT NOT NULL DEFAULT '';`); err != nil {
+		return fmt.Errorf("failed to add tier column: %w", err)
+	} else if _, err := tx.Exec(`ALTER TABLE host_settings ADD COLUMN tier_promote_window INTEGER NOT NULL DEFAULT 0;`); err != nil {
+		return fmt.Errorf("failed to add tier_promote_window column: %w", err)
+	} else if _, err := tx.Exec(`ALTER TABLE host_settings ADD COLUMN tier_demote_after INTEGER NOT NULL DEFAULT 0;`); err != nil {
+		return fmt.Errorf("failed to add tier_demote_after column: %w", err)
+	} else if _, err := tx.Exec(`ALTER TABLE host_settings ADD COLUMN tier_migration_limit INTEGER NOT NULL DEFAULT 0;`); err != nil {
+		return fmt.Errorf("failed to add tier_migration_limit column: %w", err)
+	}
+	return nil
+}
+
+// migrateVersion10 adds the backend_type and backend_opts columns to the
+// storage_volumes table. Existing volumes are stored in flat files.
+func migrateVersion10(tx txn) error {
+	if _, err := tx.Exec(`ALTER TABLE storage_volumes ADD COLUMN backend_type TEXT NOT NULL DEFAULT 'file';`); err != nil {
+		return fmt.Errorf("failed to add backend_type column: %w", err)
+	} else if _, err := tx.Exec(`ALTER TABLE storage_volumes ADD COLUMN backend_opts BLOB;`); err != nil {
+		return fmt.Errorf("failed to add backend_opts column: %w", err)
+	}
+	return nil
+}
+
+// migrateVersion9 adds the quarantined_volume_sectors table to prevent corrupt
+// sector locations from being reused
+func migrateVersion9(tx txn) error {
+	const query = `CREATE TABLE quarantined_volume_sectors (
+	volume_sector_id INTEGER PRIMARY KEY REFERENCES volume_sectors(id) ON DELETE CASCADE,
+	sector_root BLOB NOT NULL,
+	reason TEXT NOT NULL,
+	date_created INTEGER NOT NULL
+);`
+	_, err := tx.Exec(query)
+	return err
+}
+
+// migrateVersion8 adds the volume_scrubs table to track the progress of volume
+// integrity scrubs
+func migrateVersion8(tx txn) error {
+	const query = `CREATE TABLE volume_scrubs (
+	volume_id INTEGER PRIMARY KEY REFERENCES storage_volumes(id) ON DELETE CASCADE,
+	scrub_cursor INTEGER NOT NULL,
+	checked_sectors INTEGER NOT NULL,
+	corrupt_sectors INTEGER NOT NULL,
+	missing_sectors INTEGER NOT NULL,
+	last_started INTEGER NOT NULL,
+	last_completed INTEGER NOT NULL
+);`
+	_, err := tx.Exec(query)
+	return err
+}
+
 // migrateVersion7 adds the sector_cache_size column to the host_settings table
 func migrateVersion7(tx txn) error {
 	_, err := tx.Exec(`ALTER TABLE host_settings ADD COLUMN sector_cache_size INTEGER NOT NULL DEFAULT 0;`)
@@ -160,4 +477,27 @@ var migrations = []func(tx txn) error{
 	migrateVersion5,
 	migrateVersion6,
 	migrateVersion7,
+	migrateVersion8,
+	migrateVersion9,
+	migrateVersion10,
+	migrateVersion11,
+	migrateVersion12,
+	migrateVersion13,
+	migrateVersion14,
+	migrateVersion15,
+	migrateVersion16,
+	migrateVersion17,
+	migrateVersion18,
+	migrateVersion19,
+	migrateVersion20,
+	migrateVersion21,
+	migrateVersion22,
+	migrateVersion23,
+	migrateVersion24,
+	migrateVersion25,
+	migrateVersion26,
+	migrateVersion27,
+	migrateVersion28,
+	migrateVersion29,
+	migrateVersion30,
 }
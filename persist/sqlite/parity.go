@@ -0,0 +1,215 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/storage"
+)
+
+// ParityCandidates returns at most limit sector locations per available volume
+// that are not yet protected by a parity group.
+func (s *Store) ParityCandidates(limit int) (locations []storage.SectorLocation, err error) {
+	const query = `SELECT id, volume_id, volume_index, sector_root FROM (
+	SELECT vs.id, vs.volume_id, vs.volume_index, ss.sector_root, ROW_NUMBER() OVER (PARTITION BY vs.volume_id ORDER BY vs.volume_index ASC) AS row_num
+	FROM volume_sectors vs
+	INNER JOIN stored_sectors ss ON (vs.sector_id=ss.id)
+	INNER JOIN storage_volumes v ON (vs.volume_id=v.id)
+	WHERE v.available=true
+	AND vs.sector_id NOT IN (SELECT sector_id FROM parity_group_sectors)
+	AND vs.id NOT IN (SELECT volume_sector_id FROM locked_volume_sectors)
+	AND vs.id NOT IN (SELECT volume_sector_id FROM quarantined_volume_sectors)
+) WHERE row_num <= $1`
+	rows, err := s.query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sectors: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var loc storage.SectorLocation
+		if err := rows.Scan(&loc.ID, &loc.Volume, &loc.Index, (*sqlHash256)(&loc.Root)); err != nil {
+			return nil, fmt.Errorf("failed to scan sector location: %w", err)
+		}
+		locations = append(locations, loc)
+	}
+	return locations, rows.Err()
+}
+
+// StoreParityGroup adds a parity group protecting the group's data sectors.
+// Each parity sector is stored in a different writable volume that does not
+// contain any of the group's data sectors. fn is called with a location for
+// each parity sector, the parity data must be written to disk within fn. The
+// group is committed after fn returns.
+func (s *Store) StoreParityGroup(group storage.ParityGroup, excludeVolumes []int, fn func(locations []storage.SectorLocation) error) error {
+	if len(group.Roots) != group.DataShards+group.ParityShards {
+		panic("parity group roots do not match shard count") // developer error
+	}
+	parityRoots := group.Roots[group.DataShards:]
+
+	var locations []storage.SectorLocation
+	var locks []int64
+	err := s.transaction(func(tx txn) error {
+		exclude := append([]int(nil), excludeVolumes...)
+		for _, root := range parityRoots {
+			loc, err := parityLocation(tx, exclude)
+			if err != nil {
+				return fmt.Errorf("failed to get parity location: %w", err)
+			}
+			loc.Root = root
+			locations = append(locations, loc)
+			exclude = append(exclude, loc.Volume)
+		}
+
+		var err error
+		locks, err = lockLocationBatch(tx, locations...)
+		if err != nil {
+			return fmt.Errorf("failed to lock parity locations: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	defer unlockLocationBatch(&dbTxn{s}, locks...)
+
+	if err := fn(locations); err != nil {
+		return fmt.Errorf("failed to store parity: %w", err)
+	}
+
+	return s.transaction(func(tx txn) error {
+		var groupID int64
+		err := tx.QueryRow(`INSERT INTO parity_groups (scheme, data_shards, parity_shards, date_created) VALUES ($1, $2, $3, $4) RETURNING id`, group.Scheme, group.DataShards, group.ParityShards, sqlTime(time.Now())).Scan(&groupID)
+		if err != nil {
+			return fmt.Errorf("failed to add parity group: %w", err)
+		}
+
+		memberStmt, err := tx.Prepare(`INSERT INTO parity_group_sectors (group_id, shard_index, sector_id, parity) VALUES ($1, $2, $3, $4)`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare member statement: %w", err)
+		}
+		defer memberStmt.Close()
+
+		for i, root := range group.Roots[:group.DataShards] {
+			var sectorID int64
+			err := tx.QueryRow(`SELECT ss.id FROM stored_sectors ss INNER JOIN volume_sectors vs ON (vs.sector_id=ss.id) WHERE ss.sector_root=$1`, sqlHash256(root)).Scan(&sectorID)
+			if errors.Is(err, sql.ErrNoRows) {
+				// the sector was removed while the parity was calculated
+				return fmt.Errorf("data sector %v: %w", root, storage.ErrSectorNotFound)
+			} else if err != nil {
+				return fmt.Errorf("failed to get data sector id: %w", err)
+			} else if _, err := memberStmt.Exec(groupID, i, sectorID, false); err != nil {
+				return fmt.Errorf("failed to add data sector to group: %w", err)
+			}
+		}
+
+		for i, loc := range locations {
+			var sectorID int64
+			var stored bool
+			err := tx.QueryRow(`SELECT ss.id, EXISTS(SELECT 1 FROM volume_sectors vs WHERE vs.sector_id=ss.id) FROM stored_sectors ss WHERE ss.sector_root=$1`, sqlHash256(loc.Root)).Scan(&sectorID, &stored)
+			if errors.Is(err, sql.ErrNoRows) {
+				sectorID, err = sectorDBID(tx, loc.Root)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to get parity sector id: %w", err)
+			}
+
+			// a sector with the same data is already stored. The existing
+			// copy is used instead.
+			if !stored {
+				if _, err := tx.Exec(`UPDATE volume_sectors SET sector_id=$1 WHERE id=$2`, sectorID, loc.ID); err != nil {
+					return fmt.Errorf("failed to commit parity location: %w", err)
+				} else if _, err := tx.Exec(`UPDATE storage_volumes SET used_sectors=used_sectors+1 WHERE id=$1`, loc.Volume); err != nil {
+					return fmt.Errorf("failed to update volume usage: %w", err)
+				} else if err := incrementNumericStat(tx, metricPhysicalSectors, 1, time.Now()); err != nil {
+					return fmt.Errorf("failed to update metric: %w", err)
+				}
+			}
+			if _, err := memberStmt.Exec(groupID, group.DataShards+i, sectorID, true); err != nil {
+				return fmt.Errorf("failed to add parity sector to group: %w", err)
+			}
+		}
+		return updateTierMetrics(tx)
+	})
+}
+
+// ParityGroup returns the parity group protecting a sector. If the sector is
+// not protected, storage.ErrNoParity is returned.
+func (s *Store) ParityGroup(root types.Hash256) (group storage.ParityGroup, err error) {
+	const groupQuery = `SELECT pg.id, pg.scheme, pg.data_shards, pg.parity_shards FROM parity_groups pg
+INNER JOIN parity_group_sectors pgs ON (pgs.group_id=pg.id)
+INNER JOIN stored_sectors ss ON (pgs.sector_id=ss.id)
+WHERE ss.sector_root=$1 LIMIT 1`
+	err = s.queryRow(groupQuery, sqlHash256(root)).Scan(&group.ID, &group.Scheme, &group.DataShards, &group.ParityShards)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ParityGroup{}, storage.ErrNoParity
+	} else if err != nil {
+		return storage.ParityGroup{}, fmt.Errorf("failed to get parity group: %w", err)
+	}
+
+	const membersQuery = `SELECT ss.sector_root FROM parity_group_sectors pgs
+INNER JOIN stored_sectors ss ON (pgs.sector_id=ss.id)
+WHERE pgs.group_id=$1 ORDER BY pgs.shard_index ASC`
+	rows, err := s.query(membersQuery, group.ID)
+	if err != nil {
+		return storage.ParityGroup{}, fmt.Errorf("failed to query parity group sectors: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var root types.Hash256
+		if err := rows.Scan((*sqlHash256)(&root)); err != nil {
+			return storage.ParityGroup{}, fmt.Errorf("failed to scan sector root: %w", err)
+		}
+		group.Roots = append(group.Roots, root)
+	}
+	if err := rows.Err(); err != nil {
+		return storage.ParityGroup{}, err
+	} else if len(group.Roots) != group.DataShards+group.ParityShards {
+		return storage.ParityGroup{}, fmt.Errorf("parity group %v is incomplete", group.ID)
+	}
+	return group, nil
+}
+
+// dropParityGroups removes the parity groups containing a sector. The group's
+// parity sectors are no longer referenced and will be pruned.
+func dropParityGroups(tx txn, sectorID int64) error {
+	_, err := tx.Exec(`DELETE FROM parity_groups WHERE id IN (SELECT group_id FROM parity_group_sectors WHERE sector_id=$1)`, sectorID)
+	return err
+}
+
+// parityLocation returns an empty location in a writable volume that is not in
+// the exclude list.
+func parityLocation(tx txn, exclude []int) (loc storage.SectorLocation, err error) {
+	query := `SELECT vs.id, vs.volume_id, vs.volume_index
+FROM volume_sectors vs
+INNER JOIN storage_volumes v ON (vs.volume_id=v.id)
+WHERE vs.sector_id IS NULL AND v.read_only=false AND v.available=true
+AND vs.id NOT IN (SELECT volume_sector_id FROM locked_volume_sectors)
+AND vs.id NOT IN (SELECT volume_sector_id FROM quarantined_volume_sectors)`
+	if len(exclude) > 0 {
+		query += ` AND vs.volume_id NOT IN (` + queryPlaceHolders(len(exclude)) + `)`
+	}
+	query += ` ORDER BY vs.volume_index ASC LIMIT 1`
+	err = tx.QueryRow(query, queryArgs(exclude)...).Scan(&loc.ID, &loc.Volume, &loc.Index)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = storage.ErrNotEnoughStorage
+	}
+	return
+}
+
+// updateParityGroups removes the parity groups of a sector that was moved to a
+// volume containing another member of the same group. A group only survives
+// the loss of a volume if each member is stored in a different volume. The
+// group's data sectors are protected again by the next encoding pass.
+func updateParityGroups(tx txn, sectorID int64, volumeID int64) error {
+	const query = `DELETE FROM parity_groups WHERE id IN (
+	SELECT pgs.group_id FROM parity_group_sectors pgs
+	INNER JOIN parity_group_sectors other ON (other.group_id=pgs.group_id AND other.sector_id<>pgs.sector_id)
+	INNER JOIN volume_sectors vs ON (vs.sector_id=other.sector_id)
+	WHERE pgs.sector_id=$1 AND vs.volume_id=$2
+)`
+	_, err := tx.Exec(query, sectorID, volumeID)
+	return err
+}
@@ -0,0 +1,213 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/contracts"
+)
+
+// RenterPolicy returns the policy of a renter. If the renter does not have a
+// policy, contracts.ErrNotFound is returned.
+func (s *Store) RenterPolicy(renterKey types.PublicKey) (contracts.RenterPolicy, error) {
+	const query = `SELECT r.public_key, p.blocked, p.max_contracts, p.max_storage, p.price_multiplier, p.note, p.last_updated
+FROM contract_renter_policies p
+INNER JOIN contract_renters r ON (p.renter_id=r.id)
+WHERE r.public_key=$1`
+	policy, err := scanRenterPolicy(s.queryRow(query, sqlHash256(renterKey)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return contracts.RenterPolicy{}, contracts.ErrNotFound
+	} else if err != nil {
+		return contracts.RenterPolicy{}, fmt.Errorf("failed to get renter policy: %w", err)
+	}
+	return policy, nil
+}
+
+// RenterPolicies returns all renter policies.
+func (s *Store) RenterPolicies() (policies []contracts.RenterPolicy, err error) {
+	const query = `SELECT r.public_key, p.blocked, p.max_contracts, p.max_storage, p.price_multiplier, p.note, p.last_updated
+FROM contract_renter_policies p
+INNER JOIN contract_renters r ON (p.renter_id=r.id)
+ORDER BY p.last_updated DESC`
+	rows, err := s.query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query renter policies: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		policy, err := scanRenterPolicy(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan renter policy: %w", err)
+		}
+		policies = append(policies, policy)
+	}
+	return policies, rows.Err()
+}
+
+// SetRenterPolicy adds or updates the policy of a renter.
+func (s *Store) SetRenterPolicy(policy contracts.RenterPolicy) error {
+	const query = `INSERT INTO contract_renter_policies (renter_id, blocked, max_contracts, max_storage, price_multiplier, note, last_updated) VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (renter_id) DO UPDATE SET blocked=EXCLUDED.blocked, max_contracts=EXCLUDED.max_contracts, max_storage=EXCLUDED.max_storage,
+price_multiplier=EXCLUDED.price_multiplier, note=EXCLUDED.note, last_updated=EXCLUDED.last_updated`
+	return s.transaction(func(tx txn) error {
+		renterID, err := renterDBID(tx, policy.RenterKey)
+		if err != nil {
+			return fmt.Errorf("failed to get renter id: %w", err)
+		}
+		_, err = tx.Exec(query, renterID, policy.Blocked, policy.MaxContracts, policy.MaxStorage, policy.PriceMultiplier, policy.Note, sqlTime(policy.LastUpdated))
+		if err != nil {
+			return fmt.Errorf("failed to set renter policy: %w", err)
+		}
+		return nil
+	})
+}
+
+// RemoveRenterPolicy removes the policy of a renter.
+func (s *Store) RemoveRenterPolicy(renterKey types.PublicKey) error {
+	_, err := s.exec(`DELETE FROM contract_renter_policies WHERE renter_id IN (SELECT id FROM contract_renters WHERE public_key=$1)`, sqlHash256(renterKey))
+	return err
+}
+
+// RenterUsage returns the number of active contracts and sectors stored by a
+// renter. Contracts that have been renewed are not counted.
+func (s *Store) RenterUsage(renterKey types.PublicKey) (usage contracts.RenterUsage, err error) {
+	const contractsQuery = `SELECT COUNT(*) FROM contracts c
+INNER JOIN contract_renters r ON (c.renter_id=r.id)
+WHERE r.public_key=$1 AND c.renewed_to IS NULL AND c.contract_status IN ($2, $3)`
+	const sectorsQuery = `SELECT COUNT(*) FROM contract_sector_roots csr
+INNER JOIN contracts c ON (csr.contract_id=c.id)
+INNER JOIN contract_renters r ON (c.renter_id=r.id)
+WHERE r.public_key=$1 AND c.renewed_to IS NULL AND c.contract_status IN ($2, $3)`
+
+	err = s.transaction(func(tx txn) error {
+		if err := tx.QueryRow(contractsQuery, sqlHash256(renterKey), contracts.ContractStatusPending, contracts.ContractStatusActive).Scan(&usage.Contracts); err != nil {
+			return fmt.Errorf("failed to count contracts: %w", err)
+		} else if err := tx.QueryRow(sectorsQuery, sqlHash256(renterKey), contracts.ContractStatusPending, contracts.ContractStatusActive).Scan(&usage.Sectors); err != nil {
+			return fmt.Errorf("failed to count sectors: %w", err)
+		}
+		return nil
+	})
+	return
+}
+
+func scanRenterPolicy(s scanner) (policy contracts.RenterPolicy, err error) {
+	err = s.Scan((*sqlHash256)(&policy.RenterKey), &policy.Blocked, &policy.MaxContracts, &policy.MaxStorage, &policy.PriceMultiplier, &policy.Note, (*sqlTime)(&policy.LastUpdated))
+	return
+}
+
+// Renters returns aggregate statistics for renters ordered by the time they
+// were last seen and the total number of renters.
+func (s *Store) Renters(limit, offset int) (renters []contracts.RenterStats, count int, err error) {
+	const query = `SELECT id, public_key, first_seen, last_seen FROM contract_renters ORDER BY last_seen DESC, id ASC LIMIT $1 OFFSET $2`
+	err = s.transaction(func(tx txn) error {
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM contract_renters`).Scan(&count); err != nil {
+			return fmt.Errorf("failed to count renters: %w", err)
+		}
+
+		rows, err := tx.Query(query, limit, offset)
+		if err != nil {
+			return fmt.Errorf("failed to query renters: %w", err)
+		}
+		defer rows.Close()
+
+		var ids []int64
+		for rows.Next() {
+			var id int64
+			var stats contracts.RenterStats
+			if err := rows.Scan(&id, (*sqlHash256)(&stats.RenterKey), nullable((*sqlTime)(&stats.FirstSeen)), nullable((*sqlTime)(&stats.LastSeen))); err != nil {
+				return fmt.Errorf("failed to scan renter: %w", err)
+			}
+			ids = append(ids, id)
+			renters = append(renters, stats)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		// close the rows before aggregating to avoid nested queries
+		rows.Close()
+
+		for i := range renters {
+			if err := aggregateRenterStats(tx, ids[i], &renters[i]); err != nil {
+				return fmt.Errorf("failed to get stats for renter %v: %w", renters[i].RenterKey, err)
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// RenterStats returns aggregate statistics for a renter.
+func (s *Store) RenterStats(renterKey types.PublicKey) (stats contracts.RenterStats, err error) {
+	err = s.transaction(func(tx txn) error {
+		var id int64
+		err := tx.QueryRow(`SELECT id, public_key, first_seen, last_seen FROM contract_renters WHERE public_key=$1`, sqlHash256(renterKey)).Scan(&id, (*sqlHash256)(&stats.RenterKey), nullable((*sqlTime)(&stats.FirstSeen)), nullable((*sqlTime)(&stats.LastSeen)))
+		if errors.Is(err, sql.ErrNoRows) {
+			return contracts.ErrRenterNotFound
+		} else if err != nil {
+			return fmt.Errorf("failed to get renter: %w", err)
+		}
+		return aggregateRenterStats(tx, id, &stats)
+	})
+	return
+}
+
+// aggregateRenterStats sums the contracts, sectors, and account balances of a
+// renter. Currencies are stored as blobs, so they are summed in Go.
+func aggregateRenterStats(tx txn, renterID int64, stats *contracts.RenterStats) error {
+	const contractsQuery = `SELECT contract_status, renewed_to IS NULL, locked_collateral, risked_collateral, rpc_revenue, storage_revenue, ingress_revenue, egress_revenue, account_funding
+FROM contracts WHERE renter_id=$1`
+	rows, err := tx.Query(contractsQuery, renterID)
+	if err != nil {
+		return fmt.Errorf("failed to query contracts: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status contracts.ContractStatus
+		var notRenewed bool
+		var locked, risked types.Currency
+		var usage contracts.Usage
+		if err := rows.Scan(&status, &notRenewed, (*sqlCurrency)(&locked), (*sqlCurrency)(&risked), (*sqlCurrency)(&usage.RPCRevenue), (*sqlCurrency)(&usage.StorageRevenue), (*sqlCurrency)(&usage.IngressRevenue), (*sqlCurrency)(&usage.EgressRevenue), (*sqlCurrency)(&usage.AccountFunding)); err != nil {
+			return fmt.Errorf("failed to scan contract: %w", err)
+		}
+
+		stats.TotalContracts++
+		if status != contracts.ContractStatusRejected {
+			stats.Revenue = stats.Revenue.Add(usage)
+		}
+		if notRenewed && (status == contracts.ContractStatusPending || status == contracts.ContractStatusActive) {
+			stats.ActiveContracts++
+			stats.LockedCollateral = stats.LockedCollateral.Add(locked)
+			stats.RiskedCollateral = stats.RiskedCollateral.Add(risked)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	const sectorsQuery = `SELECT COUNT(*) FROM contract_sector_roots csr
+INNER JOIN contracts c ON (csr.contract_id=c.id)
+WHERE c.renter_id=$1 AND c.renewed_to IS NULL AND c.contract_status IN ($2, $3)`
+	if err := tx.QueryRow(sectorsQuery, renterID, contracts.ContractStatusPending, contracts.ContractStatusActive).Scan(&stats.Sectors); err != nil {
+		return fmt.Errorf("failed to count sectors: %w", err)
+	}
+
+	const accountsQuery = `SELECT balance FROM accounts WHERE id IN (SELECT caf.account_id FROM contract_account_funding caf
+INNER JOIN contracts c ON (caf.contract_id=c.id)
+WHERE c.renter_id=$1)`
+	balances, err := tx.Query(accountsQuery, renterID)
+	if err != nil {
+		return fmt.Errorf("failed to query account balances: %w", err)
+	}
+	defer balances.Close()
+	for balances.Next() {
+		var balance types.Currency
+		if err := balances.Scan((*sqlCurrency)(&balance)); err != nil {
+			return fmt.Errorf("failed to scan account balance: %w", err)
+		}
+		stats.AccountBalance = stats.AccountBalance.Add(balance)
+	}
+	return balances.Err()
+}
@@ -30,20 +30,34 @@ func (s *Store) unlockLocationFn(id int64) func() error {
 // location in the volume.
 func (s *Store) RemoveSector(root types.Hash256) (err error) {
 	return s.transaction(func(tx txn) error {
+		var sectorID int64
+		err = tx.QueryRow(`SELECT id FROM stored_sectors WHERE sector_root=$1;`, sqlHash256(root)).Scan(&sectorID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrSectorNotFound
+		} else if err != nil {
+			return fmt.Errorf("failed to get sector id: %w", err)
+		}
+
 		var volumeID int64
-		err = tx.QueryRow(`UPDATE volume_sectors SET sector_id=null WHERE sector_id IN (SELECT id FROM stored_sectors WHERE sector_root=$1) RETURNING volume_id;`, sqlHash256(root)).Scan(&volumeID)
+		err = tx.QueryRow(`UPDATE volume_sectors SET sector_id=null WHERE sector_id=$1 RETURNING volume_id;`, sectorID).Scan(&volumeID)
 		if errors.Is(err, sql.ErrNoRows) {
 			return storage.ErrSectorNotFound
 		} else if err != nil {
 			return fmt.Errorf("failed to remove sector: %w", err)
 		}
 
+		// the sector's data is zeroed, the parity of its groups is no
+		// longer valid
+		if err := dropParityGroups(tx, sectorID); err != nil {
+			return fmt.Errorf("failed to remove parity groups: %w", err)
+		}
+
 		// decrement volume usage
 		_, err = tx.Exec(`UPDATE storage_volumes SET used_sectors=used_sectors-1 WHERE id=$1;`, volumeID)
 		if err != nil {
 			return fmt.Errorf("failed to update volume: %w", err)
 		}
-		return nil
+		return updateTierMetrics(tx)
 	})
 }
 
@@ -179,6 +193,8 @@ func (s *Store) PruneSectors() (int, error) {
 				var volumeID int64
 				if err := updateVolumeStmt.QueryRow(sector.ID).Scan(&volumeID); err != nil {
 					return fmt.Errorf("failed to get volume id for sector: %w", err)
+				} else if err := dropParityGroups(tx, sector.ID); err != nil {
+					return fmt.Errorf("failed to remove parity groups: %w", err)
 				} else if _, err := deleteStmt.Exec(sector.ID); err != nil {
 					return fmt.Errorf("failed to delete sector: %w", err)
 				} else if _, err := metaUpdateStmt.Exec(volumeID); err != nil {
@@ -195,6 +211,8 @@ func (s *Store) PruneSectors() (int, error) {
 
 			if err := incrementNumericStat(tx, metricPhysicalSectors, -len(sectors), time.Now()); err != nil {
 				return fmt.Errorf("failed to update metric: %w", err)
+			} else if err := updateTierMetrics(tx); err != nil {
+				return fmt.Errorf("failed to update tier metrics: %w", err)
 			}
 			count += len(sectors)
 			return nil
@@ -257,6 +275,8 @@ UNION
 SELECT vs.sector_id FROM locked_volume_sectors ls INNER JOIN volume_sectors vs ON (ls.volume_sector_id=vs.id)
 UNION
 SELECT sector_id FROM temp_storage_sector_roots
+UNION
+SELECT sector_id FROM parity_group_sectors WHERE parity=true
 ) LIMIT $1;`, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to select sectors: %w", err)
@@ -15,14 +15,21 @@ import (
 
 // Settings returns the current host settings.
 func (s *Store) Settings() (config settings.Settings, err error) {
-	var dyndnsBuf []byte
+	return getSettings(&dbTxn{s})
+}
+
+func getSettings(tx txn) (config settings.Settings, err error) {
+	var dyndnsBuf, discoveryBuf, webhooksBuf, pricingBuf, acmeBuf []byte
 	const query = `SELECT settings_revision, accepting_contracts, net_address, 
 	contract_price, base_rpc_price, sector_access_price, collateral_multiplier, 
 	max_collateral, storage_price, egress_price, ingress_price, 
 	max_account_balance, max_account_age, price_table_validity, max_contract_duration, window_size, 
-	ingress_limit, egress_limit, registry_limit, ddns_provider, ddns_update_v4, ddns_update_v6, ddns_opts, sector_cache_size
+	ingress_limit, egress_limit, registry_limit, ddns_provider, ddns_update_v4, ddns_update_v6, ddns_opts, sector_cache_size,
+	tier_promote_window, tier_demote_after, tier_migration_limit, parity_scheme, parity_data_shards, parity_shards,
+	proof_resubmit_interval, proof_fee_increase, proof_max_fee, contract_webhooks, pricing_engine, price_alert_threshold, announce_interval, announce_confirmation_blocks, acme,
+	ddns_alert_failures, ddns_history_retention, ddns_discovery, scrub_rate
 FROM host_settings;`
-	err = s.queryRow(query).Scan(&config.Revision, &config.AcceptingContracts,
+	err = tx.QueryRow(query).Scan(&config.Revision, &config.AcceptingContracts,
 		&config.NetAddress, (*sqlCurrency)(&config.ContractPrice),
 		(*sqlCurrency)(&config.BaseRPCPrice), (*sqlCurrency)(&config.SectorAccessPrice),
 		&config.CollateralMultiplier, (*sqlCurrency)(&config.MaxCollateral),
@@ -30,9 +37,16 @@ FROM host_settings;`
 		(*sqlCurrency)(&config.IngressPrice), (*sqlCurrency)(&config.MaxAccountBalance),
 		&config.AccountExpiry, &config.PriceTableValidity, &config.MaxContractDuration, &config.WindowSize,
 		&config.IngressLimit, &config.EgressLimit, &config.MaxRegistryEntries,
-		&config.DDNS.Provider, &config.DDNS.IPv4, &config.DDNS.IPv6, &dyndnsBuf, &config.SectorCacheSize)
+		&config.DDNS.Provider, &config.DDNS.IPv4, &config.DDNS.IPv6, &dyndnsBuf, &config.SectorCacheSize,
+		&config.TierPromoteWindow, &config.TierDemoteAfter, &config.TierMigrationLimit,
+		&config.ParityScheme, &config.ParityDataShards, &config.ParityShards,
+		&config.ProofResubmitInterval, &config.ProofFeeIncrease, (*sqlCurrency)(&config.ProofMaxFee), &webhooksBuf, &pricingBuf,
+		&config.PriceAlertThreshold, &config.AnnounceInterval, &config.AnnounceConfirmationBlocks, &acmeBuf,
+		&config.DDNS.AlertFailures, &config.DDNS.HistoryRetention, &discoveryBuf, &config.ScrubRate)
 	if errors.Is(err, sql.ErrNoRows) {
 		return settings.Settings{}, settings.ErrNoSettings
+	} else if err != nil {
+		return settings.Settings{}, fmt.Errorf("failed to query settings: %w", err)
 	}
 	if dyndnsBuf != nil {
 		err = json.Unmarshal(dyndnsBuf, &config.DDNS.Options)
@@ -40,77 +54,283 @@ FROM host_settings;`
 			return settings.Settings{}, fmt.Errorf("failed to unmarshal ddns options: %w", err)
 		}
 	}
+	if discoveryBuf != nil {
+		if err := json.Unmarshal(discoveryBuf, &config.DDNS.Discovery); err != nil {
+			return settings.Settings{}, fmt.Errorf("failed to unmarshal ip discovery settings: %w", err)
+		}
+	}
+	if webhooksBuf != nil {
+		if err := json.Unmarshal(webhooksBuf, &config.ContractWebhooks); err != nil {
+			return settings.Settings{}, fmt.Errorf("failed to unmarshal contract webhooks: %w", err)
+		}
+	}
+	if pricingBuf != nil {
+		if err := json.Unmarshal(pricingBuf, &config.PricingEngine); err != nil {
+			return settings.Settings{}, fmt.Errorf("failed to unmarshal pricing engine settings: %w", err)
+		}
+	}
+	if acmeBuf != nil {
+		if err := json.Unmarshal(acmeBuf, &config.ACME); err != nil {
+			return settings.Settings{}, fmt.Errorf("failed to unmarshal acme settings: %w", err)
+		}
+	}
 	return
 }
 
-// UpdateSettings updates the host's stored settings.
-func (s *Store) UpdateSettings(settings settings.Settings) error {
+// UpdateSettings updates the host's stored settings and records them as a
+// revision from source.
+func (s *Store) UpdateSettings(config settings.Settings, source string) error {
 	const query = `INSERT INTO host_settings (id, settings_revision, 
 		accepting_contracts, net_address, contract_price, base_rpc_price, 
 		sector_access_price, collateral_multiplier, max_collateral, storage_price, 
 		egress_price, ingress_price, max_account_balance, 
 		max_account_age, price_table_validity, max_contract_duration, window_size, ingress_limit, 
-		egress_limit, registry_limit, ddns_provider, ddns_update_v4, ddns_update_v6, ddns_opts, sector_cache_size) 
-		VALUES (0, 0, $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23) 
+		egress_limit, registry_limit, ddns_provider, ddns_update_v4, ddns_update_v6, ddns_opts, sector_cache_size,
+		tier_promote_window, tier_demote_after, tier_migration_limit, parity_scheme, parity_data_shards, parity_shards,
+		proof_resubmit_interval, proof_fee_increase, proof_max_fee, contract_webhooks, pricing_engine,
+		price_alert_threshold, announce_interval, announce_confirmation_blocks, acme,
+		ddns_alert_failures, ddns_history_retention, ddns_discovery, scrub_rate) 
+		VALUES (0, 0, $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, $34, $35, $36, $37, $38, $39, $40, $41, $42) 
 ON CONFLICT (id) DO UPDATE SET (settings_revision, 
 	accepting_contracts, net_address, contract_price, base_rpc_price, 
 	sector_access_price, collateral_multiplier, max_collateral, storage_price, 
 	egress_price, ingress_price, max_account_balance, 
 	max_account_age, price_table_validity, max_contract_duration, window_size, ingress_limit, 
-	egress_limit, registry_limit, ddns_provider, ddns_update_v4, ddns_update_v6, ddns_opts, sector_cache_size) = (
+	egress_limit, registry_limit, ddns_provider, ddns_update_v4, ddns_update_v6, ddns_opts, sector_cache_size,
+	tier_promote_window, tier_demote_after, tier_migration_limit, parity_scheme, parity_data_shards, parity_shards,
+	proof_resubmit_interval, proof_fee_increase, proof_max_fee, contract_webhooks, pricing_engine,
+	price_alert_threshold, announce_interval, announce_confirmation_blocks, acme,
+	ddns_alert_failures, ddns_history_retention, ddns_discovery, scrub_rate) = (
 	settings_revision + 1, EXCLUDED.accepting_contracts, EXCLUDED.net_address,
 	EXCLUDED.contract_price, EXCLUDED.base_rpc_price, EXCLUDED.sector_access_price,
 	EXCLUDED.collateral_multiplier, EXCLUDED.max_collateral, EXCLUDED.storage_price,
 	EXCLUDED.egress_price, EXCLUDED.ingress_price, EXCLUDED.max_account_balance,
 	EXCLUDED.max_account_age, EXCLUDED.price_table_validity, EXCLUDED.max_contract_duration, EXCLUDED.window_size, 
 	EXCLUDED.ingress_limit, EXCLUDED.egress_limit, EXCLUDED.registry_limit, EXCLUDED.ddns_provider, 
-	EXCLUDED.ddns_update_v4, EXCLUDED.ddns_update_v6, EXCLUDED.ddns_opts, EXCLUDED.sector_cache_size);`
+	EXCLUDED.ddns_update_v4, EXCLUDED.ddns_update_v6, EXCLUDED.ddns_opts, EXCLUDED.sector_cache_size,
+	EXCLUDED.tier_promote_window, EXCLUDED.tier_demote_after, EXCLUDED.tier_migration_limit,
+	EXCLUDED.parity_scheme, EXCLUDED.parity_data_shards, EXCLUDED.parity_shards,
+	EXCLUDED.proof_resubmit_interval, EXCLUDED.proof_fee_increase, EXCLUDED.proof_max_fee, EXCLUDED.contract_webhooks,
+	EXCLUDED.pricing_engine, EXCLUDED.price_alert_threshold, EXCLUDED.announce_interval,
+	EXCLUDED.announce_confirmation_blocks, EXCLUDED.acme,
+	EXCLUDED.ddns_alert_failures, EXCLUDED.ddns_history_retention, EXCLUDED.ddns_discovery,
+	EXCLUDED.scrub_rate);`
 	var dnsOptsBuf []byte
-	if len(settings.DDNS.Provider) > 0 {
+	if len(config.DDNS.Provider) > 0 {
 		var err error
-		dnsOptsBuf, err = json.Marshal(settings.DDNS.Options)
+		dnsOptsBuf, err = json.Marshal(config.DDNS.Options)
 		if err != nil {
 			return fmt.Errorf("failed to marshal ddns options: %w", err)
 		}
 	}
+	discoveryBuf, err := json.Marshal(config.DDNS.Discovery)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ip discovery settings: %w", err)
+	}
+	var webhooksBuf []byte
+	if len(config.ContractWebhooks) > 0 {
+		var err error
+		webhooksBuf, err = json.Marshal(config.ContractWebhooks)
+		if err != nil {
+			return fmt.Errorf("failed to marshal contract webhooks: %w", err)
+		}
+	}
+	pricingBuf, err := json.Marshal(config.PricingEngine)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pricing engine settings: %w", err)
+	}
+	acmeBuf, err := json.Marshal(config.ACME)
+	if err != nil {
+		return fmt.Errorf("failed to marshal acme settings: %w", err)
+	}
 
 	return s.transaction(func(tx txn) error {
-		_, err := tx.Exec(query, settings.AcceptingContracts,
-			settings.NetAddress, sqlCurrency(settings.ContractPrice),
-			sqlCurrency(settings.BaseRPCPrice), sqlCurrency(settings.SectorAccessPrice),
-			settings.CollateralMultiplier, sqlCurrency(settings.MaxCollateral),
-			sqlCurrency(settings.StoragePrice), sqlCurrency(settings.EgressPrice),
-			sqlCurrency(settings.IngressPrice), sqlCurrency(settings.MaxAccountBalance),
-			settings.AccountExpiry, settings.PriceTableValidity, settings.MaxContractDuration, settings.WindowSize,
-			settings.IngressLimit, settings.EgressLimit, settings.MaxRegistryEntries,
-			settings.DDNS.Provider, settings.DDNS.IPv4, settings.DDNS.IPv6, dnsOptsBuf, settings.SectorCacheSize)
+		prev, err := getSettings(tx)
+		if err != nil && !errors.Is(err, settings.ErrNoSettings) {
+			return fmt.Errorf("failed to get current settings: %w", err)
+		}
+
+		_, err = tx.Exec(query, config.AcceptingContracts,
+			config.NetAddress, sqlCurrency(config.ContractPrice),
+			sqlCurrency(config.BaseRPCPrice), sqlCurrency(config.SectorAccessPrice),
+			config.CollateralMultiplier, sqlCurrency(config.MaxCollateral),
+			sqlCurrency(config.StoragePrice), sqlCurrency(config.EgressPrice),
+			sqlCurrency(config.IngressPrice), sqlCurrency(config.MaxAccountBalance),
+			config.AccountExpiry, config.PriceTableValidity, config.MaxContractDuration, config.WindowSize,
+			config.IngressLimit, config.EgressLimit, config.MaxRegistryEntries,
+			config.DDNS.Provider, config.DDNS.IPv4, config.DDNS.IPv6, dnsOptsBuf, config.SectorCacheSize,
+			config.TierPromoteWindow, config.TierDemoteAfter, config.TierMigrationLimit,
+			config.ParityScheme, config.ParityDataShards, config.ParityShards,
+			config.ProofResubmitInterval, config.ProofFeeIncrease, sqlCurrency(config.ProofMaxFee), webhooksBuf, pricingBuf,
+			config.PriceAlertThreshold, config.AnnounceInterval, config.AnnounceConfirmationBlocks, acmeBuf,
+			config.DDNS.AlertFailures, config.DDNS.HistoryRetention, discoveryBuf, config.ScrubRate)
 		if err != nil {
 			return fmt.Errorf("failed to update settings: %w", err)
+		} else if err := addSettingsRevision(tx, prev, config, source); err != nil {
+			return fmt.Errorf("failed to record settings revision: %w", err)
 		}
 
 		// update the currency stats
 		timestamp := time.Now()
-		if err := setCurrencyStat(tx, metricContractPrice, settings.ContractPrice, timestamp); err != nil {
+		if err := setCurrencyStat(tx, metricContractPrice, config.ContractPrice, timestamp); err != nil {
 			return fmt.Errorf("failed to update contract price stat: %w", err)
-		} else if err := setCurrencyStat(tx, metricBaseRPCPrice, settings.BaseRPCPrice, timestamp); err != nil {
+		} else if err := setCurrencyStat(tx, metricBaseRPCPrice, config.BaseRPCPrice, timestamp); err != nil {
 			return fmt.Errorf("failed to update base RPC price stat: %w", err)
-		} else if err := setCurrencyStat(tx, metricSectorAccessPrice, settings.SectorAccessPrice, timestamp); err != nil {
+		} else if err := setCurrencyStat(tx, metricSectorAccessPrice, config.SectorAccessPrice, timestamp); err != nil {
 			return fmt.Errorf("failed to update sector access price stat: %w", err)
-		} else if err := setCurrencyStat(tx, metricStoragePrice, settings.StoragePrice, timestamp); err != nil {
+		} else if err := setCurrencyStat(tx, metricStoragePrice, config.StoragePrice, timestamp); err != nil {
 			return fmt.Errorf("failed to update storage price stat: %w", err)
-		} else if err := setCurrencyStat(tx, metricEgressPrice, settings.EgressPrice, timestamp); err != nil {
+		} else if err := setCurrencyStat(tx, metricEgressPrice, config.EgressPrice, timestamp); err != nil {
 			return fmt.Errorf("failed to update egress price stat: %w", err)
-		} else if err := setCurrencyStat(tx, metricIngressPrice, settings.IngressPrice, timestamp); err != nil {
+		} else if err := setCurrencyStat(tx, metricIngressPrice, config.IngressPrice, timestamp); err != nil {
 			return fmt.Errorf("failed to update ingress price stat: %w", err)
-		} else if err := setNumericStat(tx, metricMaxRegistryEntries, settings.MaxRegistryEntries, timestamp); err != nil {
+		} else if err := setNumericStat(tx, metricMaxRegistryEntries, config.MaxRegistryEntries, timestamp); err != nil {
 			return fmt.Errorf("failed to update max registry entries stat: %w", err)
-		} else if err := setFloat64Stat(tx, metricCollateralMultiplier, settings.CollateralMultiplier, timestamp); err != nil {
+		} else if err := setFloat64Stat(tx, metricCollateralMultiplier, config.CollateralMultiplier, timestamp); err != nil {
 			return fmt.Errorf("failed to update collateral stat: %w", err)
 		}
 		return nil
 	})
 }
 
+// SettingsHistory returns the recorded revisions of the host's settings,
+// newest first.
+func (s *Store) SettingsHistory(limit, offset int) (revisions []settings.SettingsRevision, err error) {
+	const query = `SELECT settings_revision, source, settings, changes, date_created FROM host_settings_history ORDER BY settings_revision DESC LIMIT $1 OFFSET $2`
+	rows, err := s.query(query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query settings history: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		rev, err := scanSettingsRevision(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan settings revision: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
+
+// SettingsRevision returns a recorded revision of the host's settings.
+func (s *Store) SettingsRevision(revision uint64) (settings.SettingsRevision, error) {
+	const query = `SELECT settings_revision, source, settings, changes, date_created FROM host_settings_history WHERE settings_revision=$1`
+	rev, err := scanSettingsRevision(s.queryRow(query, revision))
+	if errors.Is(err, sql.ErrNoRows) {
+		return settings.SettingsRevision{}, settings.ErrRevisionNotFound
+	} else if err != nil {
+		return settings.SettingsRevision{}, fmt.Errorf("failed to get settings revision: %w", err)
+	}
+	return rev, nil
+}
+
+// addSettingsRevision records the current settings revision, its source, and
+// the changes from the previous settings.
+func addSettingsRevision(tx txn, prev, config settings.Settings, source string) error {
+	var revision uint64
+	if err := tx.QueryRow(`SELECT settings_revision FROM host_settings`).Scan(&revision); err != nil {
+		return fmt.Errorf("failed to get settings revision: %w", err)
+	}
+	config.Revision = revision
+
+	changes, err := settings.Diff(prev, config)
+	if err != nil {
+		return fmt.Errorf("failed to diff settings: %w", err)
+	}
+	settingsBuf, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+	changesBuf, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal changes: %w", err)
+	}
+	_, err = tx.Exec(`INSERT INTO host_settings_history (settings_revision, source, settings, changes, date_created) VALUES ($1, $2, $3, $4, $5)`, revision, source, settingsBuf, changesBuf, sqlTime(time.Now()))
+	return err
+}
+
+func scanSettingsRevision(s scanner) (rev settings.SettingsRevision, err error) {
+	var settingsBuf, changesBuf []byte
+	if err := s.Scan(&rev.Revision, &rev.Source, &settingsBuf, &changesBuf, (*sqlTime)(&rev.Timestamp)); err != nil {
+		return settings.SettingsRevision{}, err
+	} else if err := json.Unmarshal(settingsBuf, &rev.Settings); err != nil {
+		return settings.SettingsRevision{}, fmt.Errorf("failed to unmarshal settings: %w", err)
+	} else if err := json.Unmarshal(changesBuf, &rev.Changes); err != nil {
+		return settings.SettingsRevision{}, fmt.Errorf("failed to unmarshal changes: %w", err)
+	}
+	return rev, nil
+}
+
+// profileOverrides are the settings overridden by a settings profile. They
+// are stored as JSON so profiles can override more settings without a
+// migration.
+type profileOverrides struct {
+	AcceptingContracts *bool           `json:"acceptingContracts,omitempty"`
+	IngressLimit       *uint64         `json:"ingressLimit,omitempty"`
+	EgressLimit        *uint64         `json:"egressLimit,omitempty"`
+	IngressPrice       *types.Currency `json:"ingressPrice,omitempty"`
+	EgressPrice        *types.Currency `json:"egressPrice,omitempty"`
+}
+
+// SettingsProfiles returns the host's settings profiles.
+func (s *Store) SettingsProfiles() (profiles []settings.SettingsProfile, err error) {
+	rows, err := s.query(`SELECT name, schedule, duration, priority, overrides FROM host_settings_profiles ORDER BY name ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query settings profiles: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p settings.SettingsProfile
+		var buf []byte
+		if err := rows.Scan(&p.Name, &p.Schedule, &p.Duration, &p.Priority, &buf); err != nil {
+			return nil, fmt.Errorf("failed to scan settings profile: %w", err)
+		}
+		var overrides profileOverrides
+		if err := json.Unmarshal(buf, &overrides); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal overrides of profile %q: %w", p.Name, err)
+		}
+		p.AcceptingContracts = overrides.AcceptingContracts
+		p.IngressLimit = overrides.IngressLimit
+		p.EgressLimit = overrides.EgressLimit
+		p.IngressPrice = overrides.IngressPrice
+		p.EgressPrice = overrides.EgressPrice
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+// UpdateSettingsProfile adds or replaces a settings profile.
+func (s *Store) UpdateSettingsProfile(p settings.SettingsProfile) error {
+	overrides := profileOverrides{
+		AcceptingContracts: p.AcceptingContracts,
+		IngressLimit:       p.IngressLimit,
+		EgressLimit:        p.EgressLimit,
+		IngressPrice:       p.IngressPrice,
+		EgressPrice:        p.EgressPrice,
+	}
+	buf, err := json.Marshal(overrides)
+	if err != nil {
+		return fmt.Errorf("failed to marshal overrides: %w", err)
+	}
+	const query = `INSERT INTO host_settings_profiles (name, schedule, duration, priority, overrides) VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (name) DO UPDATE SET schedule=EXCLUDED.schedule, duration=EXCLUDED.duration, priority=EXCLUDED.priority, overrides=EXCLUDED.overrides`
+	_, err = s.exec(query, p.Name, p.Schedule, p.Duration, p.Priority, buf)
+	return err
+}
+
+// DeleteSettingsProfile removes a settings profile.
+func (s *Store) DeleteSettingsProfile(name string) error {
+	res, err := s.exec(`DELETE FROM host_settings_profiles WHERE name=$1`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete settings profile: %w", err)
+	} else if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	} else if n == 0 {
+		return settings.ErrProfileNotFound
+	}
+	return nil
+}
+
 // HostKey returns the host's private key.
 func (s *Store) HostKey() (pk types.PrivateKey) {
 	err := s.queryRow(`SELECT host_key FROM global_settings WHERE id=0;`).Scan(&pk)
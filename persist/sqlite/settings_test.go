@@ -2,6 +2,7 @@ package sqlite
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"math"
 	"path/filepath"
@@ -31,9 +32,21 @@ func randomSettings() settings.Settings {
 		IngressLimit:         uint64(frand.Intn(math.MaxInt)),
 		EgressLimit:          uint64(frand.Intn(math.MaxInt)),
 		MaxRegistryEntries:   uint64(frand.Intn(math.MaxInt)),
+		ScrubRate:            uint64(frand.Intn(math.MaxInt)),
 		AccountExpiry:        time.Duration(frand.Intn(math.MaxInt)),
 		PriceTableValidity:   time.Duration(frand.Intn(math.MaxInt)),
 		MaxAccountBalance:    types.NewCurrency(frand.Uint64n(math.MaxUint64), frand.Uint64n(math.MaxUint64)),
+		DDNS: settings.DNSSettings{
+			Provider: settings.DNSProviderDuckDNS,
+			IPv4:     true,
+			Options:  json.RawMessage(`{"token":"` + hex.EncodeToString(frand.Bytes(16)) + `"}`),
+			Discovery: settings.IPDiscoverySettings{
+				Mode:       settings.IPDiscoveryStatic,
+				StaticIPv4: "192.0.2.1",
+			},
+			AlertFailures:    frand.Intn(100),
+			HistoryRetention: time.Duration(frand.Intn(math.MaxInt)),
+		},
 	}
 }
 
@@ -53,7 +66,7 @@ func TestSettings(t *testing.T) {
 
 	// set initial settings
 	initial := randomSettings()
-	if err := db.UpdateSettings(initial); err != nil {
+	if err := db.UpdateSettings(initial, settings.SourceOperator); err != nil {
 		t.Fatal(err)
 	}
 
@@ -67,7 +80,7 @@ func TestSettings(t *testing.T) {
 
 	// change the settings
 	updated := randomSettings()
-	if err := db.UpdateSettings(updated); err != nil {
+	if err := db.UpdateSettings(updated, settings.SourceOperator); err != nil {
 		t.Fatal(err)
 	}
 
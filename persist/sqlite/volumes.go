@@ -27,7 +27,7 @@ func (s *Store) StorageUsage() (usedSectors, totalSectors uint64, err error) {
 
 // Volumes returns a list of all volumes.
 func (s *Store) Volumes() ([]storage.Volume, error) {
-	const query = `SELECT v.id, v.disk_path, v.read_only, v.available, v.total_sectors, v.used_sectors
+	const query = `SELECT v.id, v.disk_path, v.read_only, v.available, v.total_sectors, v.used_sectors, v.backend_type, v.backend_opts, v.tier
 FROM storage_volumes v
 ORDER BY v.id ASC`
 	rows, err := s.query(query)
@@ -49,7 +49,7 @@ ORDER BY v.id ASC`
 
 // Volume returns a volume by its ID.
 func (s *Store) Volume(id int) (storage.Volume, error) {
-	const query = `SELECT v.id, v.disk_path, v.read_only, v.available, v.total_sectors, v.used_sectors
+	const query = `SELECT v.id, v.disk_path, v.read_only, v.available, v.total_sectors, v.used_sectors, v.backend_type, v.backend_opts, v.tier
 FROM storage_volumes v
 WHERE v.id=$1`
 	row := s.queryRow(query, id)
@@ -69,15 +69,32 @@ WHERE v.id=$1`
 // rolled back. If no space is available, ErrNotEnoughStorage is
 // returned. The location is locked until release is called.
 //
+// If the existing location of the sector has been quarantined, fn is called
+// with a new empty location and exists is false. The sector's metadata is
+// moved to the new location after fn returns.
+//
 // The sector should be referenced by either a contract or temp store
 // before release is called to prevent Prune() from removing it.
 func (s *Store) StoreSector(root types.Hash256, fn func(loc storage.SectorLocation, exists bool) error) (func() error, error) {
 	var lockID int64
 	var location storage.SectorLocation
+	var quarantined *storage.SectorLocation
 	var exists bool
 	err := s.transaction(func(tx txn) error {
 		var err error
 		location, err = sectorLocation(tx, root)
+		if err == nil {
+			// if the existing location has been quarantined, the sector
+			// needs to be stored in a new location
+			isQuarantined, qErr := locationQuarantined(tx, location.ID)
+			if qErr != nil {
+				return fmt.Errorf("failed to check quarantine status: %w", qErr)
+			} else if isQuarantined {
+				old := location
+				quarantined = &old
+				err = storage.ErrSectorNotFound
+			}
+		}
 		exists = err == nil
 		if errors.Is(err, storage.ErrSectorNotFound) {
 			location, err = emptyLocation(tx)
@@ -113,6 +130,18 @@ func (s *Store) StoreSector(root types.Hash256, fn func(loc storage.SectorLocati
 		if err != nil {
 			return fmt.Errorf("failed to get sector id: %w", err)
 		}
+
+		// clear the quarantined location before the sector is moved, the
+		// location will stay quarantined.
+		if quarantined != nil {
+			var volumeID int64
+			if err := tx.QueryRow(`UPDATE volume_sectors SET sector_id=null WHERE id=$1 AND sector_id=$2 RETURNING volume_id`, quarantined.ID, sectorID).Scan(&volumeID); err != nil {
+				return fmt.Errorf("failed to clear quarantined location: %w", err)
+			} else if _, err := tx.Exec(`UPDATE storage_volumes SET used_sectors=used_sectors-1 WHERE id=$1`, volumeID); err != nil {
+				return fmt.Errorf("failed to update volume usage: %w", err)
+			}
+		}
+
 		var updatedID int64
 		err = tx.QueryRow(`UPDATE volume_sectors SET sector_id=$1 WHERE id=$2 RETURNING id`, sectorID, location.ID).Scan(&updatedID)
 		if err != nil {
@@ -125,10 +154,16 @@ func (s *Store) StoreSector(root types.Hash256, fn func(loc storage.SectorLocati
 		_, err = tx.Exec(`UPDATE storage_volumes SET used_sectors=used_sectors+1 WHERE id=$1`, location.Volume)
 		if err != nil {
 			return fmt.Errorf("failed to update volume usage: %w", err)
+		} else if quarantined != nil {
+			// the sector was moved, the number of physical sectors did not
+			// change
+			if err := updateParityGroups(tx, sectorID, int64(location.Volume)); err != nil {
+				return fmt.Errorf("failed to update parity groups: %w", err)
+			}
 		} else if err := incrementNumericStat(tx, metricPhysicalSectors, 1, time.Now()); err != nil {
 			return fmt.Errorf("failed to update metric: %w", err)
 		}
-		return nil
+		return updateTierMetrics(tx)
 	})
 	if err != nil {
 		unlockLocation(&dbTxn{s}, lockID)
@@ -137,125 +172,423 @@ func (s *Store) StoreSector(root types.Hash256, fn func(loc storage.SectorLocati
 	return s.unlockLocationFn(lockID), nil
 }
 
+// QuarantineLocation marks a sector location as corrupt. The location will not
+// be used to store new sectors. The sector's metadata will continue to
+// reference the location until a good copy of the sector is stored with
+// StoreSector.
+func (s *Store) QuarantineLocation(loc storage.SectorLocation, reason string) error {
+	const query = `INSERT INTO quarantined_volume_sectors (volume_sector_id, sector_root, reason, date_created) VALUES ($1, $2, $3, $4)
+ON CONFLICT (volume_sector_id) DO UPDATE SET sector_root=EXCLUDED.sector_root, reason=EXCLUDED.reason`
+	_, err := s.exec(query, loc.ID, sqlHash256(loc.Root), reason, sqlTime(time.Now()))
+	return err
+}
+
+// SectorContracts returns the IDs of all contracts that reference a sector.
+func (s *Store) SectorContracts(root types.Hash256) (ids []types.FileContractID, err error) {
+	const query = `SELECT DISTINCT c.contract_id FROM contracts c
+INNER JOIN contract_sector_roots csr ON (csr.contract_id=c.id)
+INNER JOIN stored_sectors ss ON (ss.id=csr.sector_id)
+WHERE ss.sector_root=$1`
+	rows, err := s.query(query, sqlHash256(root))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query contracts: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id types.FileContractID
+		if err := rows.Scan((*sqlHash256)(&id)); err != nil {
+			return nil, fmt.Errorf("failed to scan contract id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// sectorMigration is a batch of sectors migrated by MigrateSectors. The old
+// and new locations are locked until the batch is committed.
+type sectorMigration struct {
+	oldLocations []storage.SectorLocation
+	newLocations []storage.SectorLocation
+	locks        []int64
+	err          chan error
+}
+
+// selectMigration selects and locks a batch of sectors stored in the volume at
+// or after cursor and a new location for each sector. Locations in the volume
+// before startIndex may be used as new locations.
+func (s *Store) selectMigration(volumeID int, startIndex, cursor uint64, batchSize int64) (m sectorMigration, err error) {
+	err = s.transaction(func(tx txn) (err error) {
+		m.oldLocations, err = sectorsForMigration(tx, volumeID, cursor, batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to get sectors for migration: %w", err)
+		} else if len(m.oldLocations) == 0 {
+			return nil // no more sectors to migrate
+		}
+
+		// get new locations for each sector
+		m.newLocations, err = locationsForMigration(tx, volumeID, startIndex, len(m.oldLocations))
+		if err != nil {
+			return fmt.Errorf("failed to get new locations: %w", err)
+		} else if len(m.newLocations) == 0 {
+			// if no new locations were returned, there's no more space
+			return storage.ErrNotEnoughStorage
+		} else if len(m.newLocations) < len(m.oldLocations) {
+			// only enough space to partially migrate the batch. truncate
+			// the old locations to avoid unnecessary locks
+			m.oldLocations = m.oldLocations[:len(m.newLocations)]
+		}
+
+		// add the sector root to the new locations
+		for i := range m.newLocations {
+			m.newLocations[i].Root = m.oldLocations[i].Root
+		}
+
+		// lock the old and new locations
+		m.locks, err = lockLocationBatch(tx, append(m.oldLocations, m.newLocations...)...)
+		if err != nil {
+			return fmt.Errorf("failed to lock sectors: %w", err)
+		}
+		return nil
+	})
+	return
+}
+
+// commitMigration waits for the batch's data to be migrated, then updates the
+// sector locations and unlocks the batch.
+func (s *Store) commitMigration(m sectorMigration) error {
+	defer unlockLocationBatch(&dbTxn{s}, m.locks...)
+	if err := <-m.err; err != nil {
+		return fmt.Errorf("failed to migrate data: %w", err)
+	}
+	// update the sector locations in a separate transaction
+	return s.transaction(func(tx txn) error {
+		return moveSectorLocations(tx, m.oldLocations, m.newLocations)
+	})
+}
+
 // MigrateSectors migrates each occupied sector of a volume starting at
 // startIndex. The sector data should be copied to the new location and synced
-// to disk during migrateFn. Sectors are migrated in batches of 256.
+// to disk during migrateFn. Sectors are migrated in batches of 256. The next
+// batch is passed to migrateFn while the previous batch is still being
+// migrated, so migrateFn must be safe for concurrent use.
 func (s *Store) MigrateSectors(volumeID int, startIndex uint64, migrateFn func(locations []storage.SectorLocation) error) error {
 	const batchSize = 256 // migrate 1GiB at a time
+
+	// the sectors of the pending batch are still stored in the volume.
+	// cursor skips past them so the next batch can be selected before the
+	// pending batch is committed. Sectors stored behind the cursor during
+	// the migration are picked up by a final pass from startIndex.
+	cursor := startIndex
+	var pending *sectorMigration
 	for {
-		// get a batch of sectors to migrate
-		var oldLocations, newLocations []storage.SectorLocation
-		var locks []int64
-		err := s.transaction(func(tx txn) (err error) {
-			oldLocations, err = sectorsForMigration(tx, volumeID, startIndex, batchSize)
+		next, err := s.selectMigration(volumeID, startIndex, cursor, batchSize)
+		if err != nil || len(next.oldLocations) == 0 {
+			if pending != nil {
+				if err := s.commitMigration(*pending); err != nil {
+					return err
+				}
+				pending = nil
+			}
 			if err != nil {
-				return fmt.Errorf("failed to get sectors for migration: %w", err)
-			} else if len(oldLocations) == 0 {
+				return fmt.Errorf("failed to move sectors: %w", err)
+			} else if cursor == startIndex {
 				return nil // no more sectors to migrate
 			}
+			cursor = startIndex
+			continue
+		}
+		cursor = next.oldLocations[len(next.oldLocations)-1].Index + 1
 
-			// get new locations for each sector
-			newLocations, err = locationsForMigration(tx, volumeID, startIndex, len(oldLocations))
-			if err != nil {
-				return fmt.Errorf("failed to get new locations: %w", err)
-			} else if len(newLocations) == 0 {
-				// if no new locations were returned, there's no more space
-				return storage.ErrNotEnoughStorage
-			} else if len(newLocations) < len(oldLocations) {
-				// only enough space to partially migrate the batch. truncate
-				// the old locations to avoid unnecessary locks
-				oldLocations = oldLocations[:len(newLocations)]
-			}
+		// call migrateFn with the new locations, data should be copied to the
+		// new locations and synced to disk
+		next.err = make(chan error, 1)
+		go func(locations []storage.SectorLocation, errCh chan<- error) {
+			err := errors.New("migration stopped")
+			defer func() { errCh <- err }()
+			err = migrateFn(locations)
+		}(next.newLocations, next.err)
 
-			// add the sector root to the new locations
-			for i := range newLocations {
-				newLocations[i].Root = oldLocations[i].Root
+		if pending != nil {
+			if err := s.commitMigration(*pending); err != nil {
+				<-next.err
+				unlockLocationBatch(&dbTxn{s}, next.locks...)
+				return err
 			}
+		}
+		pending = &next
+		time.Sleep(time.Millisecond) // allow other transactions to run
+	}
+}
 
-			// lock the old and new locations
-			locks, err = lockLocationBatch(tx, append(oldLocations, newLocations...)...)
-			if err != nil {
-				return fmt.Errorf("failed to lock sectors: %w", err)
-			}
-			return nil
-		})
+// migrateBatch moves a single batch of sectors. selectFn is called to select
+// the old and new locations of the sectors, which are locked until the batch
+// is complete. The sector data should be copied to the new locations and
+// synced to disk during migrateFn, which returns the new locations that were
+// written. Only the written locations are committed. If commitFn is not nil,
+// it is called with the written locations in the same transaction that moves
+// the sector metadata. The number of migrated sectors is returned.
+func (s *Store) migrateBatch(selectFn func(tx txn) (oldLocations, newLocations []storage.SectorLocation, err error), migrateFn func([]storage.SectorLocation) ([]storage.SectorLocation, error), commitFn func(tx txn, oldLocations, newLocations []storage.SectorLocation) error) (int, error) {
+	var oldLocations, newLocations []storage.SectorLocation
+	var locks []int64
+	err := s.transaction(func(tx txn) (err error) {
+		oldLocations, newLocations, err = selectFn(tx)
 		if err != nil {
-			return fmt.Errorf("failed to move sectors: %w", err)
+			return err
 		} else if len(oldLocations) == 0 {
-			return nil // no more sectors to migrate
+			return nil // no sectors to migrate
+		} else if len(newLocations) == 0 {
+			return storage.ErrNotEnoughStorage
+		} else if len(newLocations) < len(oldLocations) {
+			// only enough space to partially migrate the batch. truncate
+			// the old locations to avoid unnecessary locks
+			oldLocations = oldLocations[:len(newLocations)]
 		}
-		defer unlockLocationBatch(&dbTxn{s}, locks...)
+		newLocations = newLocations[:len(oldLocations)]
 
-		// call migrateFn with the new locations, data should be copied to the
-		// new locations and synced to disk
-		if err := migrateFn(newLocations); err != nil {
-			return fmt.Errorf("failed to migrate data: %w", err)
+		// add the sector root to the new locations
+		for i := range newLocations {
+			newLocations[i].Root = oldLocations[i].Root
 		}
 
-		// update the sector locations in a separate transaction
-		err = s.transaction(func(tx txn) error {
-			selectStmt, err := tx.Prepare(`SELECT id FROM stored_sectors WHERE sector_root=$1`)
-			if err != nil {
-				return fmt.Errorf("failed to prepare sector select statement: %w", err)
-			}
-			defer selectStmt.Close()
+		locks, err = lockLocationBatch(tx, append(oldLocations, newLocations...)...)
+		if err != nil {
+			return fmt.Errorf("failed to lock sectors: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to move sectors: %w", err)
+	} else if len(oldLocations) == 0 {
+		return 0, nil
+	}
+	defer unlockLocationBatch(&dbTxn{s}, locks...)
 
-			clearStmt, err := tx.Prepare(`UPDATE volume_sectors SET sector_id=null WHERE id=$1 RETURNING volume_id`)
-			if err != nil {
-				return fmt.Errorf("failed to prepare sector clear statement: %w", err)
-			}
-			defer clearStmt.Close()
+	written, err := migrateFn(newLocations)
+	if err != nil {
+		return 0, fmt.Errorf("failed to migrate data: %w", err)
+	}
 
-			updateSectorStmt, err := tx.Prepare(`UPDATE volume_sectors SET sector_id=$1 WHERE id=$2 RETURNING volume_id;`)
-			if err != nil {
-				return fmt.Errorf("failed to prepare sector update statement: %w", err)
-			}
-			defer updateSectorStmt.Close()
+	// only move the metadata of sectors that were written to their new
+	// locations
+	isWritten := make(map[int64]bool, len(written))
+	for _, loc := range written {
+		isWritten[loc.ID] = true
+	}
+	var committedOld, committedNew []storage.SectorLocation
+	for i := range newLocations {
+		if isWritten[newLocations[i].ID] {
+			committedOld = append(committedOld, oldLocations[i])
+			committedNew = append(committedNew, newLocations[i])
+		}
+	}
+	oldLocations, newLocations = committedOld, committedNew
+	if len(newLocations) == 0 {
+		return 0, nil
+	}
 
-			updateMetaStmt, err := tx.Prepare(`UPDATE storage_volumes SET used_sectors=used_sectors+$1 WHERE id=$2`)
-			if err != nil {
-				return fmt.Errorf("failed to prepare sector metadata update statement: %w", err)
+	err = s.transaction(func(tx txn) error {
+		if err := moveSectorLocations(tx, oldLocations, newLocations); err != nil {
+			return err
+		} else if commitFn != nil {
+			return commitFn(tx, oldLocations, newLocations)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(newLocations), nil
+}
+
+// MigrateTierSectors moves at most limit sectors stored in volumes of the from
+// tier to empty locations in volumes of the to tier. Only sectors last accessed
+// within [accessedAfter, accessedBefore) are moved. The sector data should be
+// copied to the new locations and synced to disk during migrateFn. The
+// sectors' last access time is not changed by the migration. The number of
+// migrated sectors is returned.
+func (s *Store) MigrateTierSectors(from, to string, accessedAfter, accessedBefore time.Time, limit int, migrateFn func(locations []storage.SectorLocation) error) (int, error) {
+	lastAccess := make(map[types.Hash256]time.Time)
+	selectFn := func(tx txn) (oldLocations, newLocations []storage.SectorLocation, err error) {
+		var accessed []time.Time
+		oldLocations, accessed, err = tierSectorsForMigration(tx, from, accessedAfter, accessedBefore, limit)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get sectors for migration: %w", err)
+		} else if len(oldLocations) == 0 {
+			return nil, nil, nil
+		}
+		for i, loc := range oldLocations {
+			lastAccess[loc.Root] = accessed[i]
+		}
+		newLocations, err = tierLocationsForMigration(tx, to, len(oldLocations))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get new locations: %w", err)
+		}
+		return oldLocations, newLocations, nil
+	}
+	commitFn := func(tx txn, oldLocations, newLocations []storage.SectorLocation) error {
+		// reading the sectors during migration updates their access time.
+		// Restore it so the sectors are not immediately moved back.
+		stmt, err := tx.Prepare(`UPDATE stored_sectors SET last_access_timestamp=$1 WHERE sector_root=$2`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare access time statement: %w", err)
+		}
+		defer stmt.Close()
+		for _, loc := range oldLocations {
+			if _, err := stmt.Exec(sqlTime(lastAccess[loc.Root]), sqlHash256(loc.Root)); err != nil {
+				return fmt.Errorf("failed to restore sector access time: %w", err)
 			}
-			defer updateMetaStmt.Close()
+		}
 
-			for i, newLoc := range newLocations {
-				oldLoc := oldLocations[i]
+		stat := metricDemotedSectors
+		if to == storage.VolumeTierHot {
+			stat = metricPromotedSectors
+		}
+		if err := incrementNumericStat(tx, stat, len(newLocations), time.Now()); err != nil {
+			return fmt.Errorf("failed to update %v stat: %w", stat, err)
+		}
+		return updateTierMetrics(tx)
+	}
+	return s.migrateBatch(selectFn, func(locations []storage.SectorLocation) ([]storage.SectorLocation, error) {
+		if err := migrateFn(locations); err != nil {
+			return nil, err
+		}
+		return locations, nil
+	}, commitFn)
+}
 
-				var sectorDBID int64
-				if err = selectStmt.QueryRow(sqlHash256(oldLoc.Root)).Scan(&sectorDBID); err != nil {
-					return fmt.Errorf("failed to select sector: %w", err)
-				}
-				var oldVolumeID int64
-				if err = clearStmt.QueryRow(oldLoc.ID).Scan(&oldVolumeID); err != nil {
-					return fmt.Errorf("failed to clear sector location: %w", err)
-				} else if _, err = updateMetaStmt.Exec(-1, oldVolumeID); err != nil {
-					return fmt.Errorf("failed to update sector metadata: %w", err)
-				}
-				var newVolumeID int64
-				if err = updateSectorStmt.QueryRow(sectorDBID, newLoc.ID).Scan(&newVolumeID); err != nil {
-					return fmt.Errorf("failed to update sector location: %w", err)
-				} else if _, err = updateMetaStmt.Exec(1, newVolumeID); err != nil {
-					return fmt.Errorf("failed to update sector metadata: %w", err)
-				}
+// MoveSectors moves at most limit sectors from the end of one volume to empty
+// locations in another writable volume. The sector data should be copied to
+// the new locations and synced to disk during migrateFn, which returns the new
+// locations that were written. Sectors that were not written are not moved.
+// The number of moved sectors is returned.
+func (s *Store) MoveSectors(fromVolumeID, toVolumeID int, limit int, migrateFn func(locations []storage.SectorLocation) ([]storage.SectorLocation, error)) (int, error) {
+	selectFn := func(tx txn) (oldLocations, newLocations []storage.SectorLocation, err error) {
+		const sectorsQuery = `SELECT vs.id, vs.volume_id, vs.volume_index, ss.sector_root
+FROM volume_sectors vs
+INNER JOIN stored_sectors ss ON (vs.sector_id=ss.id)
+WHERE vs.volume_id=$1
+AND vs.id NOT IN (SELECT volume_sector_id FROM locked_volume_sectors)
+AND vs.id NOT IN (SELECT volume_sector_id FROM quarantined_volume_sectors)
+ORDER BY vs.volume_index DESC
+LIMIT $2`
+		rows, err := tx.Query(sectorsQuery, fromVolumeID, limit)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to query sectors: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var loc storage.SectorLocation
+			if err := rows.Scan(&loc.ID, &loc.Volume, &loc.Index, (*sqlHash256)(&loc.Root)); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan sector: %w", err)
 			}
-			return nil
-		})
+			oldLocations = append(oldLocations, loc)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, nil, fmt.Errorf("failed to query sectors: %w", err)
+		} else if len(oldLocations) == 0 {
+			return nil, nil, nil
+		}
+
+		const locationsQuery = `SELECT vs.id, vs.volume_id, vs.volume_index
+FROM volume_sectors vs
+INNER JOIN storage_volumes v ON (vs.volume_id=v.id)
+WHERE vs.volume_id=$1 AND vs.sector_id IS NULL AND v.available=true AND v.read_only=false
+AND vs.id NOT IN (SELECT volume_sector_id FROM locked_volume_sectors)
+AND vs.id NOT IN (SELECT volume_sector_id FROM quarantined_volume_sectors)
+ORDER BY vs.volume_index ASC
+LIMIT $2`
+		rows, err = tx.Query(locationsQuery, toVolumeID, len(oldLocations))
 		if err != nil {
-			return err
+			return nil, nil, fmt.Errorf("failed to query locations: %w", err)
 		}
-		time.Sleep(time.Millisecond) // allow other transactions to run
+		defer rows.Close()
+		for rows.Next() {
+			var loc storage.SectorLocation
+			if err := rows.Scan(&loc.ID, &loc.Volume, &loc.Index); err != nil {
+				return nil, nil, fmt.Errorf("failed to scan volume sector: %w", err)
+			}
+			newLocations = append(newLocations, loc)
+		}
+		return oldLocations, newLocations, rows.Err()
 	}
+	return s.migrateBatch(selectFn, migrateFn, nil)
+}
+
+// ScrubSectors returns at most limit occupied sector locations of a volume
+// starting at startIndex ordered by volume index. The locations are locked
+// until release is called.
+func (s *Store) ScrubSectors(volumeID int, startIndex uint64, limit int) (locations []storage.SectorLocation, release func() error, err error) {
+	var locks []int64
+	err = s.transaction(func(tx txn) error {
+		locations, err = sectorsForMigration(tx, volumeID, startIndex, int64(limit))
+		if err != nil {
+			return fmt.Errorf("failed to get volume sectors: %w", err)
+		}
+		locks, err = lockLocationBatch(tx, locations...)
+		if err != nil {
+			return fmt.Errorf("failed to lock sectors: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return locations, func() error { return unlockLocationBatch(&dbTxn{s}, locks...) }, nil
+}
+
+// VolumeScrub returns the scrub status of a volume. If the volume has never
+// been scrubbed, an empty status is returned.
+func (s *Store) VolumeScrub(volumeID int) (status storage.ScrubStatus, err error) {
+	const query = `SELECT scrub_cursor, checked_sectors, corrupt_sectors, missing_sectors, last_started, last_completed
+FROM volume_scrubs WHERE volume_id=$1`
+	status.VolumeID = volumeID
+	err = s.queryRow(query, volumeID).Scan(&status.Cursor, &status.Checked, &status.Corrupt, &status.Missing, (*sqlTime)(&status.LastStarted), (*sqlTime)(&status.LastCompleted))
+	if errors.Is(err, sql.ErrNoRows) {
+		return status, nil
+	}
+	return
+}
+
+// SetVolumeScrub updates the persisted scrub status of a volume.
+func (s *Store) SetVolumeScrub(status storage.ScrubStatus) error {
+	const query = `INSERT INTO volume_scrubs (volume_id, scrub_cursor, checked_sectors, corrupt_sectors, missing_sectors, last_started, last_completed) VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (volume_id) DO UPDATE SET scrub_cursor=EXCLUDED.scrub_cursor, checked_sectors=EXCLUDED.checked_sectors, corrupt_sectors=EXCLUDED.corrupt_sectors,
+missing_sectors=EXCLUDED.missing_sectors, last_started=EXCLUDED.last_started, last_completed=EXCLUDED.last_completed`
+	_, err := s.exec(query, status.VolumeID, status.Cursor, status.Checked, status.Corrupt, status.Missing, sqlTime(status.LastStarted), sqlTime(status.LastCompleted))
+	return err
 }
 
 // AddVolume initializes a new storage volume and adds it to the volume
 // store. GrowVolume must be called afterwards to initialize the volume
 // to its desired size.
-func (s *Store) AddVolume(localPath string, readOnly bool) (volumeID int, err error) {
-	const query = `INSERT INTO storage_volumes (disk_path, read_only, used_sectors, total_sectors) VALUES (?, ?, 0, 0) RETURNING id;`
-	err = s.queryRow(query, localPath, readOnly).Scan(&volumeID)
+func (s *Store) AddVolume(localPath string, readOnly bool, backend storage.VolumeBackend) (volumeID int, err error) {
+	if backend.Type == "" {
+		backend.Type = storage.VolumeBackendFile
+	}
+	var opts []byte
+	if len(backend.Options) > 0 {
+		opts = backend.Options
+	}
+	const query = `INSERT INTO storage_volumes (disk_path, read_only, used_sectors, total_sectors, backend_type, backend_opts) VALUES (?, ?, 0, 0, ?, ?) RETURNING id;`
+	err = s.queryRow(query, localPath, readOnly, backend.Type, opts).Scan(&volumeID)
 	return
 }
 
+// SetVolumeTier sets the storage tier of a volume.
+func (s *Store) SetVolumeTier(volumeID int, tier string) error {
+	return s.transaction(func(tx txn) error {
+		res, err := tx.Exec(`UPDATE storage_volumes SET tier=$1 WHERE id=$2`, tier, volumeID)
+		if err != nil {
+			return fmt.Errorf("failed to update volume tier: %w", err)
+		} else if n, err := res.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to check rows affected: %w", err)
+		} else if n != 1 {
+			return storage.ErrVolumeNotFound
+		}
+		return updateTierMetrics(tx)
+	})
+}
+
 // RemoveVolume removes a storage volume from the volume store. If there
 // are used sectors in the volume, ErrVolumeNotEmpty is returned. If force is
 // true, the volume is removed regardless of whether it is empty.
@@ -314,7 +647,7 @@ func (s *Store) RemoveVolume(id int, force bool) error {
 			} else if err := incrementNumericStat(tx, metricTotalSectors, -len(locIDs), time.Now()); err != nil {
 				return fmt.Errorf("failed to update total sector metric: %w", err)
 			}
-			return nil
+			return updateTierMetrics(tx)
 		})
 		if err != nil {
 			return fmt.Errorf("failed to remove volume: %w", err)
@@ -360,7 +693,7 @@ func (s *Store) GrowVolume(id int, maxSectors uint64) error {
 		} else if err := incrementNumericStat(tx, metricTotalSectors, int(maxSectors-nextIndex), time.Now()); err != nil {
 			return fmt.Errorf("failed to update total sectors metric: %w", err)
 		}
-		return nil
+		return updateTierMetrics(tx)
 	})
 }
 
@@ -400,7 +733,7 @@ func (s *Store) ShrinkVolume(id int, maxSectors uint64) error {
 		} else if err := incrementNumericStat(tx, metricTotalSectors, -int(totalSectors-maxSectors), time.Now()); err != nil {
 			return fmt.Errorf("failed to update total sectors metric: %w", err)
 		}
-		return nil
+		return updateTierMetrics(tx)
 	})
 }
 
@@ -446,6 +779,150 @@ func sectorsForMigration(tx txn, volumeID int, startIndex uint64, batchSize int6
 	return sectors, nil
 }
 
+// moveSectorLocations moves the sectors stored in oldLocations to the
+// corresponding newLocations and updates the volumes' used sectors.
+func moveSectorLocations(tx txn, oldLocations, newLocations []storage.SectorLocation) error {
+	selectStmt, err := tx.Prepare(`SELECT id FROM stored_sectors WHERE sector_root=$1`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare sector select statement: %w", err)
+	}
+	defer selectStmt.Close()
+
+	clearStmt, err := tx.Prepare(`UPDATE volume_sectors SET sector_id=null WHERE id=$1 RETURNING volume_id`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare sector clear statement: %w", err)
+	}
+	defer clearStmt.Close()
+
+	updateSectorStmt, err := tx.Prepare(`UPDATE volume_sectors SET sector_id=$1 WHERE id=$2 RETURNING volume_id;`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare sector update statement: %w", err)
+	}
+	defer updateSectorStmt.Close()
+
+	updateMetaStmt, err := tx.Prepare(`UPDATE storage_volumes SET used_sectors=used_sectors+$1 WHERE id=$2`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare sector metadata update statement: %w", err)
+	}
+	defer updateMetaStmt.Close()
+
+	for i, newLoc := range newLocations {
+		oldLoc := oldLocations[i]
+
+		var sectorDBID int64
+		if err = selectStmt.QueryRow(sqlHash256(oldLoc.Root)).Scan(&sectorDBID); err != nil {
+			return fmt.Errorf("failed to select sector: %w", err)
+		}
+		var oldVolumeID int64
+		if err = clearStmt.QueryRow(oldLoc.ID).Scan(&oldVolumeID); err != nil {
+			return fmt.Errorf("failed to clear sector location: %w", err)
+		} else if _, err = updateMetaStmt.Exec(-1, oldVolumeID); err != nil {
+			return fmt.Errorf("failed to update sector metadata: %w", err)
+		}
+		var newVolumeID int64
+		if err = updateSectorStmt.QueryRow(sectorDBID, newLoc.ID).Scan(&newVolumeID); err != nil {
+			return fmt.Errorf("failed to update sector location: %w", err)
+		} else if _, err = updateMetaStmt.Exec(1, newVolumeID); err != nil {
+			return fmt.Errorf("failed to update sector metadata: %w", err)
+		} else if err = updateParityGroups(tx, sectorDBID, newVolumeID); err != nil {
+			return fmt.Errorf("failed to update parity groups: %w", err)
+		}
+	}
+	return nil
+}
+
+// updateTierMetrics sets the total and physical sector metrics of each storage
+// tier.
+func updateTierMetrics(tx txn) error {
+	const query = `SELECT tier, COALESCE(SUM(total_sectors), 0), COALESCE(SUM(used_sectors), 0) FROM storage_volumes WHERE tier IN ($1, $2) GROUP BY tier`
+	rows, err := tx.Query(query, storage.VolumeTierHot, storage.VolumeTierCold)
+	if err != nil {
+		return fmt.Errorf("failed to query tier usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := make(map[string][2]uint64)
+	for rows.Next() {
+		var tier string
+		var total, used uint64
+		if err := rows.Scan(&tier, &total, &used); err != nil {
+			return fmt.Errorf("failed to scan tier usage: %w", err)
+		}
+		usage[tier] = [2]uint64{total, used}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query tier usage: %w", err)
+	}
+	rows.Close()
+
+	timestamp := time.Now()
+	if err := setNumericStat(tx, metricHotTotalSectors, usage[storage.VolumeTierHot][0], timestamp); err != nil {
+		return fmt.Errorf("failed to set hot total sectors: %w", err)
+	} else if err := setNumericStat(tx, metricHotPhysicalSectors, usage[storage.VolumeTierHot][1], timestamp); err != nil {
+		return fmt.Errorf("failed to set hot physical sectors: %w", err)
+	} else if err := setNumericStat(tx, metricColdTotalSectors, usage[storage.VolumeTierCold][0], timestamp); err != nil {
+		return fmt.Errorf("failed to set cold total sectors: %w", err)
+	} else if err := setNumericStat(tx, metricColdPhysicalSectors, usage[storage.VolumeTierCold][1], timestamp); err != nil {
+		return fmt.Errorf("failed to set cold physical sectors: %w", err)
+	}
+	return nil
+}
+
+// tierSectorsForMigration returns at most limit occupied locations in available
+// volumes of the given tier with sectors last accessed within
+// [accessedAfter, accessedBefore). The sectors' last access times are also
+// returned.
+func tierSectorsForMigration(tx txn, tier string, accessedAfter, accessedBefore time.Time, limit int) (locations []storage.SectorLocation, lastAccess []time.Time, _ error) {
+	const query = `SELECT vs.id, vs.volume_id, vs.volume_index, ss.sector_root, ss.last_access_timestamp
+FROM volume_sectors vs
+INNER JOIN stored_sectors ss ON (vs.sector_id=ss.id)
+INNER JOIN storage_volumes v ON (vs.volume_id=v.id)
+WHERE v.tier=$1 AND v.available=true AND ss.last_access_timestamp >= $2 AND ss.last_access_timestamp < $3
+AND vs.id NOT IN (SELECT volume_sector_id FROM locked_volume_sectors)
+AND vs.id NOT IN (SELECT volume_sector_id FROM quarantined_volume_sectors)
+LIMIT $4`
+	rows, err := tx.Query(query, tier, sqlTime(accessedAfter), sqlTime(accessedBefore), limit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query sectors: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var loc storage.SectorLocation
+		var accessed time.Time
+		if err := rows.Scan(&loc.ID, &loc.Volume, &loc.Index, (*sqlHash256)(&loc.Root), (*sqlTime)(&accessed)); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan sector: %w", err)
+		}
+		locations = append(locations, loc)
+		lastAccess = append(lastAccess, accessed)
+	}
+	return locations, lastAccess, rows.Err()
+}
+
+// tierLocationsForMigration returns at most limit empty locations in
+// writable volumes of the given tier.
+func tierLocationsForMigration(tx txn, tier string, limit int) (locations []storage.SectorLocation, _ error) {
+	const query = `SELECT vs.id, vs.volume_id, vs.volume_index
+FROM volume_sectors vs
+INNER JOIN storage_volumes v ON (vs.volume_id=v.id)
+WHERE vs.sector_id IS NULL AND v.tier=$1 AND v.available=true AND v.read_only=false
+AND vs.id NOT IN (SELECT volume_sector_id FROM locked_volume_sectors)
+AND vs.id NOT IN (SELECT volume_sector_id FROM quarantined_volume_sectors)
+LIMIT $2`
+	rows, err := tx.Query(query, tier, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query locations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var loc storage.SectorLocation
+		if err := rows.Scan(&loc.ID, &loc.Volume, &loc.Index); err != nil {
+			return nil, fmt.Errorf("failed to scan volume sector: %w", err)
+		}
+		locations = append(locations, loc)
+	}
+	return locations, rows.Err()
+}
+
 func sectorDBID(tx txn, root types.Hash256) (id int64, err error) {
 	err = tx.QueryRow(`INSERT INTO stored_sectors (sector_root, last_access_timestamp) VALUES ($1, $2) ON CONFLICT (sector_root) DO UPDATE SET last_access_timestamp=EXCLUDED.last_access_timestamp RETURNING id`, sqlHash256(root), sqlTime(time.Now())).Scan(&id)
 	return
@@ -480,7 +957,7 @@ func emptyLocation(tx txn) (loc storage.SectorLocation, err error) {
 	const query = `SELECT vs.id, vs.volume_id, vs.volume_index
 FROM volume_sectors vs
 INNER JOIN storage_volumes v ON +(vs.volume_id = v.id)
-WHERE +vs.sector_id IS NULL AND vs.id NOT IN (SELECT volume_sector_id FROM locked_volume_sectors) AND v.read_only=false AND v.available = true
+WHERE +vs.sector_id IS NULL AND vs.id NOT IN (SELECT volume_sector_id FROM locked_volume_sectors) AND vs.id NOT IN (SELECT volume_sector_id FROM quarantined_volume_sectors) AND v.read_only=false AND v.available = true
 ORDER BY vs.volume_index ASC -- order by distributes data evenly across volumes rather than filling the first volume.
 LIMIT 1;`
 	err = tx.QueryRow(query).Scan(&loc.ID, &loc.Volume, &loc.Index)
@@ -490,6 +967,12 @@ LIMIT 1;`
 	return
 }
 
+// locationQuarantined returns true if the location has been quarantined
+func locationQuarantined(tx txn, locationID int64) (quarantined bool, err error) {
+	err = tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM quarantined_volume_sectors WHERE volume_sector_id=$1)`, locationID).Scan(&quarantined)
+	return
+}
+
 func volumeSectorsForDeletion(tx txn, volumeID, batchSize int) (locs []volumeSectorRef, err error) {
 	const query = `SELECT id, sector_id IS NULL AS empty FROM volume_sectors WHERE volume_id=$1 LIMIT $2`
 	rows, err := tx.Query(query, volumeID, batchSize)
@@ -517,6 +1000,7 @@ func locationsForMigration(tx txn, volumeID int, startIndex uint64, batchSize in
 	FROM volume_sectors vs
 	INNER JOIN storage_volumes v ON (vs.volume_id=v.id)
 	WHERE vs.sector_id IS NULL AND vs.id NOT IN (SELECT volume_sector_id FROM locked_volume_sectors) 
+	AND vs.id NOT IN (SELECT volume_sector_id FROM quarantined_volume_sectors)
 	AND v.available=true AND ((v.read_only=false AND vs.volume_id <> $1) OR (vs.volume_id=$1 AND vs.volume_index<$2))
 	LIMIT $3;`
 
@@ -538,6 +1022,48 @@ func locationsForMigration(tx txn, volumeID int, startIndex uint64, batchSize in
 }
 
 func scanVolume(s scanner) (volume storage.Volume, err error) {
-	err = s.Scan(&volume.ID, &volume.LocalPath, &volume.ReadOnly, &volume.Available, &volume.TotalSectors, &volume.UsedSectors)
+	var opts []byte
+	err = s.Scan(&volume.ID, &volume.LocalPath, &volume.ReadOnly, &volume.Available, &volume.TotalSectors, &volume.UsedSectors, &volume.Backend.Type, &opts, &volume.Tier)
+	if len(opts) > 0 {
+		volume.Backend.Options = opts
+	}
 	return
 }
+
+// AddVolumeOperation persists a volume operation so it can be resumed after a
+// restart. The ID of the operation is returned.
+func (s *Store) AddVolumeOperation(op storage.VolumeOperation) (id int64, err error) {
+	const query = `INSERT INTO volume_operations (volume_id, operation_type, target_sectors, progress_cursor, force, restore_read_only, date_created) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+	err = s.queryRow(query, op.VolumeID, op.Type, op.TargetSectors, op.Cursor, op.Force, op.RestoreReadOnly, sqlTime(op.Started)).Scan(&id)
+	return
+}
+
+// UpdateVolumeOperation updates the progress cursor of a volume operation.
+func (s *Store) UpdateVolumeOperation(id int64, cursor uint64) error {
+	_, err := s.exec(`UPDATE volume_operations SET progress_cursor=$1 WHERE id=$2`, cursor, id)
+	return err
+}
+
+// RemoveVolumeOperation removes a completed or cancelled volume operation.
+func (s *Store) RemoveVolumeOperation(id int64) error {
+	_, err := s.exec(`DELETE FROM volume_operations WHERE id=$1`, id)
+	return err
+}
+
+// VolumeOperations returns all unfinished volume operations.
+func (s *Store) VolumeOperations() (ops []storage.VolumeOperation, err error) {
+	const query = `SELECT id, volume_id, operation_type, target_sectors, progress_cursor, force, restore_read_only, date_created FROM volume_operations ORDER BY id ASC`
+	rows, err := s.query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query volume operations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var op storage.VolumeOperation
+		if err := rows.Scan(&op.ID, &op.VolumeID, &op.Type, &op.TargetSectors, &op.Cursor, &op.Force, &op.RestoreReadOnly, (*sqlTime)(&op.Started)); err != nil {
+			return nil, fmt.Errorf("failed to scan volume operation: %w", err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, rows.Err()
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -17,7 +18,7 @@ import (
 
 // addVolume is a helper to add a new volume to the database
 func addVolume(db *Store, name string, size uint64) (storage.Volume, error) {
-	volumeID, err := db.AddVolume(name, false)
+	volumeID, err := db.AddVolume(name, false, storage.VolumeBackend{})
 	if err != nil {
 		return storage.Volume{}, fmt.Errorf("failed to add volume: %w", err)
 	} else if err := db.GrowVolume(volumeID, size); err != nil {
@@ -71,7 +72,7 @@ func TestAddSector(t *testing.T) {
 	}
 	defer db.Close()
 
-	volumeID, err := db.AddVolume("test", false)
+	volumeID, err := db.AddVolume("test", false, storage.VolumeBackend{})
 	if err != nil {
 		t.Fatal(err)
 	} else if err := db.SetAvailable(volumeID, true); err != nil {
@@ -180,7 +181,7 @@ func TestVolumeAdd(t *testing.T) {
 	var volumes []storage.Volume
 	for i := 1; i <= 5; i++ {
 		localPath := fmt.Sprintf("test %v", i)
-		volumeID, err := db.AddVolume(localPath, false)
+		volumeID, err := db.AddVolume(localPath, false, storage.VolumeBackend{})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -442,6 +443,93 @@ func TestRemoveVolume(t *testing.T) {
 	}
 }
 
+func TestMigrateSectorsOverlap(t *testing.T) {
+	const sectors = 600 // 3 batches
+	log := zaptest.NewLogger(t)
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "test.db"), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	volume1, err := addVolume(db, "test", sectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := make([]types.Hash256, sectors)
+	for i := range roots {
+		roots[i] = frand.Entropy256()
+		release, err := db.StoreSector(roots[i], func(storage.SectorLocation, bool) error { return nil })
+		if err != nil {
+			t.Fatal(err)
+		} else if err := release(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	volume2, err := addVolume(db, "test2", sectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first batch does not complete until the second batch has started
+	secondBatch := make(chan struct{})
+	var mu sync.Mutex
+	var batches, migrated int
+	err = db.MigrateSectors(volume1.ID, 0, func(locations []storage.SectorLocation) error {
+		mu.Lock()
+		batches++
+		n := batches
+		migrated += len(locations)
+		mu.Unlock()
+
+		for _, loc := range locations {
+			if loc.Volume != volume2.ID {
+				return fmt.Errorf("expected volume ID %v, got %v", volume2.ID, loc.Volume)
+			}
+		}
+
+		switch n {
+		case 1:
+			select {
+			case <-secondBatch:
+			case <-time.After(10 * time.Second):
+				return errors.New("second batch was not started before the first batch completed")
+			}
+		case 2:
+			close(secondBatch)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if batches != 3 {
+		t.Fatalf("expected 3 batches, got %v", batches)
+	} else if migrated != sectors {
+		t.Fatalf("expected %v migrated sectors, got %v", sectors, migrated)
+	}
+
+	if v1, err := db.Volume(volume1.ID); err != nil {
+		t.Fatal(err)
+	} else if v1.UsedSectors != 0 {
+		t.Fatalf("expected volume 1 to be empty, got %v sectors", v1.UsedSectors)
+	} else if v2, err := db.Volume(volume2.ID); err != nil {
+		t.Fatal(err)
+	} else if v2.UsedSectors != sectors {
+		t.Fatalf("expected volume 2 to have %v sectors, got %v", sectors, v2.UsedSectors)
+	}
+
+	for _, root := range roots {
+		if loc, release, err := db.SectorLocation(root); err != nil {
+			t.Fatal(err)
+		} else if loc.Volume != volume2.ID {
+			t.Fatalf("expected sector %v in volume %v, got %v", root, volume2.ID, loc.Volume)
+		} else if err := release(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
 func TestMigrateSectors(t *testing.T) {
 	const initialSectors = 64
 	log := zaptest.NewLogger(t)
@@ -492,11 +580,11 @@ func TestMigrateSectors(t *testing.T) {
 	err = db.MigrateSectors(volume.ID, initialSectors/2, func(locations []storage.SectorLocation) error {
 		for _, loc := range locations {
 			if loc.Volume != volume.ID {
-				t.Fatalf("expected volume ID %v, got %v", volume.ID, loc.Volume)
+				return fmt.Errorf("expected volume ID %v, got %v", volume.ID, loc.Volume)
 			} else if loc.Index != uint64(i) {
-				t.Fatalf("expected sector index %v, got %v", i, loc.Index)
+				return fmt.Errorf("expected sector index %v, got %v", i, loc.Index)
 			} else if loc.Root != roots[i] {
-				t.Fatalf("expected sector root %v, got %v", roots[i], loc.Root)
+				return fmt.Errorf("expected sector root %v, got %v", roots[i], loc.Root)
 			}
 			i++
 		}
@@ -531,7 +619,7 @@ func TestMigrateSectors(t *testing.T) {
 	// migrate the remaining sectors from the first volume; should partially complete
 	err = db.MigrateSectors(volume.ID, 0, func(locations []storage.SectorLocation) error {
 		if len(locations) > initialSectors/4 {
-			t.Fatalf("expected only %v migrations, got %v", initialSectors/4, len(locations))
+			return fmt.Errorf("expected only %v migrations, got %v", initialSectors/4, len(locations))
 		}
 		return nil
 	})
@@ -560,6 +648,132 @@ func TestMigrateSectors(t *testing.T) {
 	}
 }
 
+func TestMigrateTierSectors(t *testing.T) {
+	const sectors = 10
+	log := zaptest.NewLogger(t)
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "test.db"), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	hot, err := addVolume(db, "hot", sectors)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := db.SetVolumeTier(hot.ID, storage.VolumeTierHot); err != nil {
+		t.Fatal(err)
+	}
+
+	// fill the hot volume before adding the cold volume
+	roots := make([]types.Hash256, sectors)
+	for i := range roots {
+		roots[i] = frand.Entropy256()
+		release, err := db.StoreSector(roots[i], func(loc storage.SectorLocation, exists bool) error { return nil })
+		if err != nil {
+			t.Fatal(err)
+		} else if err := release(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if m, err := db.Metrics(time.Now()); err != nil {
+		t.Fatal(err)
+	} else if m.Storage.Hot.PhysicalSectors != sectors {
+		t.Fatalf("expected %v hot physical sectors, got %v", sectors, m.Storage.Hot.PhysicalSectors)
+	}
+
+	cold, err := addVolume(db, "cold", sectors)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := db.SetVolumeTier(cold.ID, storage.VolumeTierCold); err != nil {
+		t.Fatal(err)
+	}
+
+	// mark half of the sectors as idle
+	idle := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	for _, root := range roots[:sectors/2] {
+		if _, err := db.exec(`UPDATE stored_sectors SET last_access_timestamp=$1 WHERE sector_root=$2`, sqlTime(idle), sqlHash256(root)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// demote the idle sectors
+	n, err := db.MigrateTierSectors(storage.VolumeTierHot, storage.VolumeTierCold, time.Time{}, time.Now().Add(-24*time.Hour), 64, func(locations []storage.SectorLocation) error {
+		for _, loc := range locations {
+			if loc.Volume != cold.ID {
+				t.Fatalf("expected sector to be migrated to volume %v, got %v", cold.ID, loc.Volume)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if n != sectors/2 {
+		t.Fatalf("expected %v sectors to be demoted, got %v", sectors/2, n)
+	}
+
+	if v, err := db.Volume(hot.ID); err != nil {
+		t.Fatal(err)
+	} else if v.UsedSectors != sectors/2 {
+		t.Fatalf("expected %v used sectors in hot volume, got %v", sectors/2, v.UsedSectors)
+	} else if v.Tier != storage.VolumeTierHot {
+		t.Fatalf("expected tier %q, got %q", storage.VolumeTierHot, v.Tier)
+	}
+	if v, err := db.Volume(cold.ID); err != nil {
+		t.Fatal(err)
+	} else if v.UsedSectors != sectors/2 {
+		t.Fatalf("expected %v used sectors in cold volume, got %v", sectors/2, v.UsedSectors)
+	}
+
+	// the access time of the migrated sectors should not change
+	for _, root := range roots[:sectors/2] {
+		var accessed time.Time
+		if err := db.queryRow(`SELECT last_access_timestamp FROM stored_sectors WHERE sector_root=$1`, sqlHash256(root)).Scan((*sqlTime)(&accessed)); err != nil {
+			t.Fatal(err)
+		} else if !accessed.Equal(idle) {
+			t.Fatalf("expected access time %v, got %v", idle, accessed)
+		}
+	}
+
+	// no sectors should be demoted a second time
+	n, err = db.MigrateTierSectors(storage.VolumeTierHot, storage.VolumeTierCold, time.Time{}, time.Now().Add(-24*time.Hour), 64, func([]storage.SectorLocation) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	} else if n != 0 {
+		t.Fatalf("expected 0 sectors to be demoted, got %v", n)
+	}
+
+	// access one of the demoted sectors and promote it
+	if _, release, err := db.SectorLocation(roots[0]); err != nil {
+		t.Fatal(err)
+	} else if err := release(); err != nil {
+		t.Fatal(err)
+	}
+	n, err = db.MigrateTierSectors(storage.VolumeTierCold, storage.VolumeTierHot, time.Now().Add(-time.Hour), time.Now().Add(time.Second), 64, func([]storage.SectorLocation) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatalf("expected 1 sector to be promoted, got %v", n)
+	}
+
+	m, err := db.Metrics(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	switch {
+	case m.Storage.DemotedSectors != sectors/2:
+		t.Fatalf("expected %v demoted sectors, got %v", sectors/2, m.Storage.DemotedSectors)
+	case m.Storage.PromotedSectors != 1:
+		t.Fatalf("expected 1 promoted sector, got %v", m.Storage.PromotedSectors)
+	case m.Storage.Hot.TotalSectors != sectors:
+		t.Fatalf("expected %v hot sectors, got %v", sectors, m.Storage.Hot.TotalSectors)
+	case m.Storage.Hot.PhysicalSectors != sectors/2+1:
+		t.Fatalf("expected %v hot physical sectors, got %v", sectors/2+1, m.Storage.Hot.PhysicalSectors)
+	case m.Storage.Cold.PhysicalSectors != sectors/2-1:
+		t.Fatalf("expected %v cold physical sectors, got %v", sectors/2-1, m.Storage.Cold.PhysicalSectors)
+	}
+}
+
 func TestPrune(t *testing.T) {
 	const sectors = 256
 
@@ -782,7 +996,7 @@ func BenchmarkVolumeGrow(b *testing.B) {
 	}
 	defer db.Close()
 
-	volumeID, err := db.AddVolume("test", false)
+	volumeID, err := db.AddVolume("test", false, storage.VolumeBackend{})
 	if err != nil {
 		b.Fatal(err)
 	}
@@ -805,7 +1019,7 @@ func BenchmarkVolumeShrink(b *testing.B) {
 	}
 	defer db.Close()
 
-	volumeID, err := db.AddVolume("test", false)
+	volumeID, err := db.AddVolume("test", false, storage.VolumeBackend{})
 	if err != nil {
 		b.Fatal(err)
 	}
@@ -47,6 +47,12 @@ type (
 
 		// SectorRoots returns the sector roots of the contract with the given ID.
 		SectorRoots(id types.FileContractID, limit, offset uint64) ([]types.Hash256, error)
+
+		// RenterPolicy returns the policy of a renter.
+		RenterPolicy(renterKey types.PublicKey) (contracts.RenterPolicy, error)
+		// CheckRenterPolicy returns the renter's policy or an error if the
+		// renter is not allowed to add contracts.
+		CheckRenterPolicy(renterKey types.PublicKey, additionalContracts uint64) (contracts.RenterPolicy, error)
 	}
 
 	// A StorageManager manages the storage of sectors on disk.
@@ -203,7 +209,43 @@ func (sh *SessionHandler) Close() error {
 
 // Settings returns the host's current settings
 func (sh *SessionHandler) Settings() (rhpv2.HostSettings, error) {
-	settings := sh.settings.Settings()
+	return sh.hostSettings(sh.settings.Settings())
+}
+
+// RenterSettings returns the host's current settings with the renter's
+// policy applied to the prices.
+func (sh *SessionHandler) RenterSettings(renterKey types.PublicKey) (rhpv2.HostSettings, error) {
+	policy, err := sh.contracts.RenterPolicy(renterKey)
+	if err != nil {
+		return rhpv2.HostSettings{}, fmt.Errorf("failed to get renter policy: %w", err)
+	}
+	return sh.hostSettings(policy.ApplyPricing(sh.settings.Settings()))
+}
+
+// sessionSettings returns the settings for the renter of the session's locked
+// contract. If no contract is locked, the host's current settings are
+// returned.
+func (sh *SessionHandler) sessionSettings(s *session) (rhpv2.HostSettings, error) {
+	if s.contract.Revision.ParentID == (types.FileContractID{}) {
+		return sh.Settings()
+	}
+	return sh.RenterSettings(s.contract.RenterKey())
+}
+
+// negotiatedSettings returns the settings sent to the renter in the session so
+// that contracts are validated against the prices the renter was offered.
+// Settings are requested before the renter is known, so settings sent on an
+// unlocked session do not include the renter's policy. If no settings were
+// sent, or they were sent for another renter's contract, the renter's current
+// settings are returned.
+func (sh *SessionHandler) negotiatedSettings(s *session, renterKey types.PublicKey) (rhpv2.HostSettings, error) {
+	if s.settings != nil && (s.contract.Revision.ParentID == (types.FileContractID{}) || s.contract.RenterKey() == renterKey) {
+		return *s.settings, nil
+	}
+	return sh.RenterSettings(renterKey)
+}
+
+func (sh *SessionHandler) hostSettings(settings settings.Settings) (rhpv2.HostSettings, error) {
 	usedSectors, totalSectors, err := sh.storage.Usage()
 	if err != nil {
 		return rhpv2.HostSettings{}, fmt.Errorf("failed to get storage usage: %w", err)
@@ -36,7 +36,7 @@ var (
 )
 
 func (sh *SessionHandler) rpcSettings(s *session, log *zap.Logger) error {
-	settings, err := sh.Settings()
+	settings, err := sh.sessionSettings(s)
 	if err != nil {
 		s.t.WriteResponseErr(ErrHostInternalError)
 		return fmt.Errorf("failed to get host settings: %w", err)
@@ -46,6 +46,7 @@ func (sh *SessionHandler) rpcSettings(s *session, log *zap.Logger) error {
 		s.t.WriteResponseErr(ErrHostInternalError)
 		return fmt.Errorf("failed to marshal settings: %v", err)
 	}
+	s.settings = &settings
 	return s.writeResponse(&rhpv2.RPCSettingsResponse{
 		Settings: js,
 	}, 30*time.Second)
@@ -85,6 +86,7 @@ func (sh *SessionHandler) rpcLock(s *session, log *zap.Logger) error {
 
 	// set the contract
 	s.contract = contract
+	s.settings = nil
 	lockResp := &rhpv2.RPCLockResponse{
 		Acquired:     true,
 		NewChallenge: newChallenge,
@@ -107,6 +109,7 @@ func (sh *SessionHandler) rpcUnlock(s *session, log *zap.Logger) error {
 	}
 	sh.contracts.Unlock(s.contract.Revision.ParentID)
 	s.contract = contracts.SignedRevision{}
+	s.settings = nil
 	return nil
 }
 
@@ -134,9 +137,15 @@ func (sh *SessionHandler) rpcFormContract(s *session, log *zap.Logger) error {
 		return err
 	}
 	renterPub := *(*types.PublicKey)(req.RenterKey.Key)
+	// check that the renter is allowed to form another contract
+	if _, err := sh.contracts.CheckRenterPolicy(renterPub, 1); err != nil {
+		err = fmt.Errorf("contract rejected: %w", err)
+		s.t.WriteResponseErr(err)
+		return err
+	}
 	// get the host's public key, current block height, and settings
 	hostPub := sh.privateKey.PublicKey()
-	settings, err := sh.Settings()
+	settings, err := sh.negotiatedSettings(s, renterPub)
 	if err != nil {
 		s.t.WriteResponseErr(ErrHostInternalError)
 		return fmt.Errorf("failed to get host settings: %w", err)
@@ -243,11 +252,7 @@ func (sh *SessionHandler) rpcFormContract(s *session, log *zap.Logger) error {
 // existing contract
 func (sh *SessionHandler) rpcRenewAndClearContract(s *session, log *zap.Logger) error {
 	state := sh.cm.TipState()
-	settings, err := sh.Settings()
-	if err != nil {
-		s.t.WriteResponseErr(ErrHostInternalError)
-		return fmt.Errorf("failed to get host settings: %w", err)
-	} else if !settings.AcceptingContracts {
+	if !sh.settings.Settings().AcceptingContracts {
 		s.t.WriteResponseErr(ErrNotAcceptingContracts)
 		return ErrNotAcceptingContracts
 	}
@@ -261,6 +266,18 @@ func (sh *SessionHandler) rpcRenewAndClearContract(s *session, log *zap.Logger)
 		return err
 	}
 
+	// check that the renter is allowed to renew the contract
+	if _, err := sh.contracts.CheckRenterPolicy(s.contract.RenterKey(), 0); err != nil {
+		err = fmt.Errorf("contract rejected: %w", err)
+		s.t.WriteResponseErr(err)
+		return err
+	}
+	settings, err := sh.negotiatedSettings(s, s.contract.RenterKey())
+	if err != nil {
+		s.t.WriteResponseErr(ErrHostInternalError)
+		return fmt.Errorf("failed to get host settings: %w", err)
+	}
+
 	var req rhpv2.RPCRenewAndClearContractRequest
 	if err := s.readRequest(&req, 10*minMessageSize, time.Minute); err != nil {
 		return fmt.Errorf("failed to read renew request: %w", err)
@@ -434,7 +451,7 @@ func (sh *SessionHandler) rpcSectorRoots(s *session, log *zap.Logger) error {
 		return fmt.Errorf("failed to read sector roots request: %w", err)
 	}
 
-	settings, err := sh.Settings()
+	settings, err := sh.sessionSettings(s)
 	if err != nil {
 		s.t.WriteResponseErr(ErrHostInternalError)
 		return fmt.Errorf("failed to get host settings: %w", err)
@@ -519,7 +536,7 @@ func (sh *SessionHandler) rpcWrite(s *session, log *zap.Logger) error {
 		s.t.WriteResponseErr(err)
 		return err
 	}
-	settings, err := sh.Settings()
+	settings, err := sh.sessionSettings(s)
 	if err != nil {
 		s.t.WriteResponseErr(ErrHostInternalError)
 		return fmt.Errorf("failed to get settings: %w", err)
@@ -689,7 +706,10 @@ func (sh *SessionHandler) rpcWrite(s *session, log *zap.Logger) error {
 	costs.Collateral = risked
 
 	// commit the contract modifications
-	if err := contractUpdater.Commit(signedRevision, costs.ToUsage()); err != nil {
+	if err := contractUpdater.Commit(signedRevision, costs.ToUsage()); errors.Is(err, contracts.ErrRenterStorageLimit) || errors.Is(err, contracts.ErrRenterBlocked) {
+		s.t.WriteResponseErr(err)
+		return fmt.Errorf("failed to commit contract modifications: %w", err)
+	} else if err != nil {
 		s.t.WriteResponseErr(ErrHostInternalError)
 		return fmt.Errorf("failed to commit contract modifications: %w", err)
 	}
@@ -714,7 +734,7 @@ func (sh *SessionHandler) rpcRead(s *session, log *zap.Logger) error {
 	}
 
 	// get the host's current settings
-	settings, err := sh.Settings()
+	settings, err := sh.sessionSettings(s)
 	if err != nil {
 		s.t.WriteResponseErr(ErrHostInternalError)
 		return fmt.Errorf("failed to get host settings: %w", err)
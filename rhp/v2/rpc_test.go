@@ -6,11 +6,13 @@ import (
 	"io"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	rhpv2 "go.sia.tech/core/rhp/v2"
 	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/contracts"
 	"go.sia.tech/hostd/internal/test"
 	"go.sia.tech/renterd/wallet"
 	"go.uber.org/zap/zaptest"
@@ -122,6 +124,187 @@ func TestUploadDownload(t *testing.T) {
 	}
 }
 
+func TestRenterPolicy(t *testing.T) {
+	log := zaptest.NewLogger(t)
+	renter, host, err := test.NewTestingPair(t.TempDir(), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer renter.Close()
+	defer host.Close()
+
+	policy := contracts.RenterPolicy{
+		RenterKey:    renter.PublicKey(),
+		MaxContracts: 1,
+		MaxStorage:   rhpv2.SectorSize,
+	}
+	if err := host.Contracts().SetRenterPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+
+	contract, err := renter.FormContract(context.Background(), host.RHPv2Addr(), host.PublicKey(), types.Siacoins(10), types.Siacoins(20), 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the renter has reached its contract limit
+	_, err = renter.FormContract(context.Background(), host.RHPv2Addr(), host.PublicKey(), types.Siacoins(10), types.Siacoins(20), 200)
+	if err == nil || !strings.Contains(err.Error(), contracts.ErrRenterContractLimit.Error()) {
+		t.Fatalf("expected contract limit error, got %v", err)
+	}
+
+	session, err := renter.NewRHP2Session(context.Background(), host.RHPv2Addr(), host.PublicKey(), contract.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	appendSector := func() error {
+		var sector [rhpv2.SectorSize]byte
+		frand.Read(sector[:256])
+		remainingDuration := uint64(session.Revision().Revision.WindowEnd) - renter.TipState().Index.Height
+		price, collateral := rhpv2.RPCAppendCost(session.Settings(), remainingDuration)
+		_, err := session.Append(context.Background(), &sector, price, collateral)
+		return err
+	}
+
+	// the first sector is within the storage limit
+	if err := appendSector(); err != nil {
+		t.Fatal(err)
+	}
+	// the second sector exceeds the storage limit
+	if err := appendSector(); err == nil || !strings.Contains(err.Error(), contracts.ErrRenterStorageLimit.Error()) {
+		t.Fatalf("expected storage limit error, got %v", err)
+	}
+
+	// apply a price multiplier, the renter's settings should be adjusted
+	policy.PriceMultiplier = 2
+	if err := host.Contracts().SetRenterPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	hostSettings, err := host.RHPv2Settings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	session2, err := renter.NewRHP2Session(context.Background(), host.RHPv2Addr(), host.PublicKey(), contract.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session2.Close()
+	renterSettings := session2.Settings()
+	if !renterSettings.StoragePrice.Equals(hostSettings.StoragePrice.Mul64(2)) {
+		t.Fatalf("expected storage price %v, got %v", hostSettings.StoragePrice.Mul64(2), renterSettings.StoragePrice)
+	} else if !renterSettings.UploadBandwidthPrice.Equals(hostSettings.UploadBandwidthPrice.Mul64(2)) {
+		t.Fatalf("expected upload price %v, got %v", hostSettings.UploadBandwidthPrice.Mul64(2), renterSettings.UploadBandwidthPrice)
+	} else if !renterSettings.Collateral.Equals(hostSettings.Collateral) {
+		t.Fatalf("expected collateral %v, got %v", hostSettings.Collateral, renterSettings.Collateral)
+	}
+
+	// block the renter
+	policy = contracts.RenterPolicy{RenterKey: renter.PublicKey(), Blocked: true}
+	if err := host.Contracts().SetRenterPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	_, err = renter.FormContract(context.Background(), host.RHPv2Addr(), host.PublicKey(), types.Siacoins(10), types.Siacoins(20), 200)
+	if err == nil || !strings.Contains(err.Error(), contracts.ErrRenterBlocked.Error()) {
+		t.Fatalf("expected blocked error, got %v", err)
+	}
+
+	// remove the policy
+	if err := host.Contracts().RemoveRenterPolicy(renter.PublicKey()); err != nil {
+		t.Fatal(err)
+	} else if _, err = renter.FormContract(context.Background(), host.RHPv2Addr(), host.PublicKey(), types.Siacoins(10), types.Siacoins(20), 200); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRenterPolicyPricing(t *testing.T) {
+	log := zaptest.NewLogger(t)
+	renter, host, err := test.NewTestingPair(t.TempDir(), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer renter.Close()
+	defer host.Close()
+
+	policy := contracts.RenterPolicy{
+		RenterKey:       renter.PublicKey(),
+		PriceMultiplier: 2,
+	}
+	if err := host.Contracts().SetRenterPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+
+	hostSettings, err := host.RHPv2Settings()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// settings are requested before the renter is known, so the contract is
+	// formed using the settings the renter received
+	state := renter.TipState()
+	origin, err := renter.FormContract(context.Background(), host.RHPv2Addr(), host.PublicKey(), types.Siacoins(10), types.Siacoins(20), state.Index.Height+200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	formed, err := host.Contracts().Contract(origin.ID())
+	if err != nil {
+		t.Fatal(err)
+	} else if !formed.Usage.RPCRevenue.Equals(hostSettings.ContractPrice) {
+		t.Fatalf("expected %d RPC revenue, got %d", hostSettings.ContractPrice, formed.Usage.RPCRevenue)
+	}
+
+	// the renter is known once the contract is locked
+	session, err := renter.NewRHP2Session(context.Background(), host.RHPv2Addr(), host.PublicKey(), origin.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	settings := session.Settings()
+	if !settings.ContractPrice.Equals(hostSettings.ContractPrice.Mul64(2)) {
+		t.Fatalf("expected contract price %v, got %v", hostSettings.ContractPrice.Mul64(2), settings.ContractPrice)
+	}
+
+	// upload a sector using the renter's prices
+	var sector [rhpv2.SectorSize]byte
+	frand.Read(sector[:256])
+	remainingDuration := uint64(session.Revision().Revision.WindowEnd) - renter.TipState().Index.Height
+	price, collateral := rhpv2.RPCAppendCost(settings, remainingDuration)
+	if _, err := session.Append(context.Background(), &sector, price, collateral); err != nil {
+		t.Fatal(err)
+	}
+
+	// renew the contract using the renter's prices
+	renewHeight := origin.Revision.WindowEnd + 10
+	current := session.Revision().Revision
+	additionalCollateral := rhpv2.ContractRenewalCollateral(current.FileContract, 1<<22, settings, renter.TipState().Index.Height, renewHeight)
+	renewed, basePrice := rhpv2.PrepareContractRenewal(current, renter.WalletAddress(), types.Siacoins(10), additionalCollateral, host.PublicKey(), settings, renewHeight)
+	renewalTxn := types.Transaction{
+		FileContracts: []types.FileContract{renewed},
+	}
+	cost := rhpv2.ContractRenewalCost(renter.TipState(), renewed, settings.ContractPrice, types.ZeroCurrency, basePrice)
+	toSign, discard, err := renter.Wallet().FundTransaction(&renewalTxn, cost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer discard()
+	if err := renter.Wallet().SignTransaction(host.TipState(), &renewalTxn, toSign, wallet.ExplicitCoveredFields(renewalTxn)); err != nil {
+		t.Fatal(err)
+	}
+	renewal, _, err := session.RenewContract(context.Background(), []types.Transaction{renewalTxn}, settings.BaseRPCPrice)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contract, err := host.Contracts().Contract(renewal.ID())
+	if err != nil {
+		t.Fatal(err)
+	} else if !contract.Usage.RPCRevenue.Equals(settings.ContractPrice) {
+		t.Fatalf("expected %d RPC revenue, got %d", settings.ContractPrice, contract.Usage.RPCRevenue)
+	}
+}
+
 func TestRenew(t *testing.T) {
 	log := zaptest.NewLogger(t)
 	renter, host, err := test.NewTestingPair(t.TempDir(), log)
@@ -20,6 +20,10 @@ type session struct {
 	t    *rhpv2.Transport
 
 	contract contracts.SignedRevision
+	// settings are the settings last sent to the renter. They are cleared
+	// when a contract is locked or unlocked, since the prices depend on the
+	// renter.
+	settings *rhpv2.HostSettings
 
 	uid     UniqueID
 	spent   types.Currency
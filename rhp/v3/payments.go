@@ -14,7 +14,7 @@ import (
 )
 
 // processContractPayment initializes an RPC budget using funds from a contract.
-func (sh *SessionHandler) processContractPayment(s *rhpv3.Stream, height uint64) (rhpv3.Account, types.Currency, error) {
+func (sh *SessionHandler) processContractPayment(sess *session, s *rhpv3.Stream, pt renterPriceTable) (rhpv3.Account, types.Currency, error) {
 	var req rhpv3.PayByContractRequest
 	if err := s.ReadRequest(&req, maxRequestSize); err != nil {
 		return rhpv3.ZeroAccount, types.ZeroCurrency, fmt.Errorf("failed to read contract payment request: %w", err)
@@ -57,6 +57,9 @@ func (sh *SessionHandler) processContractPayment(s *rhpv3.Stream, height uint64)
 	sigHash := rhp.HashRevision(revision)
 	if !contract.RenterKey().VerifyHash(sigHash, req.Signature) {
 		return rhpv3.ZeroAccount, types.ZeroCurrency, ErrInvalidRenterSignature
+	} else if err := sh.checkPriceTable(sess, pt, contract.RenterKey()); err != nil {
+		s.WriteResponseErr(err)
+		return rhpv3.ZeroAccount, types.ZeroCurrency, err
 	}
 
 	settings := sh.settings.Settings()
@@ -74,6 +77,7 @@ func (sh *SessionHandler) processContractPayment(s *rhpv3.Stream, height uint64)
 		}
 		return rhpv3.ZeroAccount, types.ZeroCurrency, fmt.Errorf("failed to credit refund account: %w", err)
 	}
+	sh.setAccountRenter(req.RefundAccount, contract.RenterKey())
 
 	// update the host signature and the contract
 	hostSig := sh.privateKey.SignHash(sigHash)
@@ -108,12 +112,13 @@ func (sh *SessionHandler) processContractPayment(s *rhpv3.Stream, height uint64)
 
 // processAccountPayment initializes an RPC budget using an ephemeral
 // account.
-func (sh *SessionHandler) processAccountPayment(s *rhpv3.Stream, height uint64) (rhpv3.Account, types.Currency, error) {
+func (sh *SessionHandler) processAccountPayment(sess *session, s *rhpv3.Stream, pt renterPriceTable) (rhpv3.Account, types.Currency, error) {
 	var req rhpv3.PayByEphemeralAccountRequest
 	if err := s.ReadRequest(&req, maxRequestSize); err != nil {
 		return rhpv3.ZeroAccount, types.ZeroCurrency, fmt.Errorf("failed to read ephemeral account payment request: %w", err)
 	}
 
+	height := pt.HostBlockHeight
 	switch {
 	case req.Expiry < height:
 		return rhpv3.ZeroAccount, types.ZeroCurrency, errors.New("withdrawal request expired")
@@ -126,12 +131,18 @@ func (sh *SessionHandler) processAccountPayment(s *rhpv3.Stream, height uint64)
 	case !types.PublicKey(req.Account).VerifyHash(req.SigHash(), req.Signature):
 		return rhpv3.ZeroAccount, types.ZeroCurrency, ErrInvalidRenterSignature
 	}
+
+	if renterKey, ok := sh.accountRenter(req.Account); ok {
+		if err := sh.checkPriceTable(sess, pt, renterKey); err != nil {
+			return rhpv3.ZeroAccount, types.ZeroCurrency, err
+		}
+	}
 	return req.Account, req.Amount, nil
 }
 
 // processPayment initializes an RPC budget using funds from a contract or an
 // ephemeral account.
-func (sh *SessionHandler) processPayment(s *rhpv3.Stream, pt *rhpv3.HostPriceTable) (*accounts.Budget, error) {
+func (sh *SessionHandler) processPayment(sess *session, s *rhpv3.Stream, pt renterPriceTable) (*accounts.Budget, error) {
 	var paymentType types.Specifier
 	if err := s.ReadRequest(&paymentType, 16); err != nil {
 		return nil, fmt.Errorf("failed to read payment type: %w", err)
@@ -139,15 +150,14 @@ func (sh *SessionHandler) processPayment(s *rhpv3.Stream, pt *rhpv3.HostPriceTab
 	var account rhpv3.Account
 	var amount types.Currency
 	var err error
-	currentHeight := pt.HostBlockHeight
 	switch paymentType {
 	case rhpv3.PaymentTypeContract:
-		account, amount, err = sh.processContractPayment(s, currentHeight)
+		account, amount, err = sh.processContractPayment(sess, s, pt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to process contract payment: %w", err)
 		}
 	case rhpv3.PaymentTypeEphemeralAccount:
-		account, amount, err = sh.processAccountPayment(s, currentHeight)
+		account, amount, err = sh.processAccountPayment(sess, s, pt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to process account payment: %w", err)
 		}
@@ -162,7 +172,7 @@ func (sh *SessionHandler) processPayment(s *rhpv3.Stream, pt *rhpv3.HostPriceTab
 // processFundAccountPayment processes a contract payment to fund an account for
 // RPCFundAccount returning the fund amount and the current balance of the
 // account. Accounts can only be funded by a contract.
-func (sh *SessionHandler) processFundAccountPayment(pt rhpv3.HostPriceTable, s *rhpv3.Stream, accountID rhpv3.Account) (fundAmount, balance types.Currency, _ error) {
+func (sh *SessionHandler) processFundAccountPayment(sess *session, pt renterPriceTable, s *rhpv3.Stream, accountID rhpv3.Account) (fundAmount, balance types.Currency, _ error) {
 	var paymentType types.Specifier
 	if err := s.ReadRequest(&paymentType, 16); err != nil {
 		return types.ZeroCurrency, types.ZeroCurrency, fmt.Errorf("failed to read payment type: %w", err)
@@ -211,6 +221,9 @@ func (sh *SessionHandler) processFundAccountPayment(pt rhpv3.HostPriceTable, s *
 	sigHash := rhp.HashRevision(revision)
 	if !contract.RenterKey().VerifyHash(sigHash, req.Signature) {
 		return types.ZeroCurrency, types.ZeroCurrency, ErrInvalidRenterSignature
+	} else if err := sh.checkPriceTable(sess, pt, contract.RenterKey()); err != nil {
+		s.WriteResponseErr(err)
+		return types.ZeroCurrency, types.ZeroCurrency, err
 	}
 
 	settings := sh.settings.Settings()
@@ -231,6 +244,7 @@ func (sh *SessionHandler) processFundAccountPayment(pt rhpv3.HostPriceTable, s *
 		}
 		return types.ZeroCurrency, types.ZeroCurrency, fmt.Errorf("failed to credit refund account: %w", err)
 	}
+	sh.setAccountRenter(accountID, contract.RenterKey())
 
 	// update the host signature and the contract
 	hostSig := sh.privateKey.SignHash(sigHash)
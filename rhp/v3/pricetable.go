@@ -9,10 +9,21 @@ import (
 
 	rhpv3 "go.sia.tech/core/rhp/v3"
 	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/contracts"
 	"lukechampine.com/frand"
 )
 
 type (
+	// A renterPriceTable is a price table and the price multiplier of the
+	// renter it was built for.
+	renterPriceTable struct {
+		rhpv3.HostPriceTable
+		// multiplier is the renter's price multiplier applied to the price
+		// table. It is one if the renter was not known when the price table
+		// was built.
+		multiplier float64
+	}
+
 	// expiringPriceTable pairs a price table UID with an expiration timestamp.
 	expiringPriceTable struct {
 		uid    rhpv3.SettingsID
@@ -33,7 +44,7 @@ type (
 		expirationTimer *time.Timer
 		// priceTables is a map of valid price tables. The key is the UID of the
 		// price table. Keys are removed by the loop in pruneExpired.
-		priceTables map[rhpv3.SettingsID]rhpv3.HostPriceTable
+		priceTables map[rhpv3.SettingsID]renterPriceTable
 	}
 )
 
@@ -41,6 +52,11 @@ var (
 	// ErrNoPriceTable is returned if a price table is requested but the UID
 	// does not exist or has expired.
 	ErrNoPriceTable = errors.New("no price table found")
+	// ErrPriceTableRenter is returned if a renter pays using a price table
+	// with lower prices than the renter's policy requires. The renter has
+	// been identified and should request a new price table using the same
+	// transport.
+	ErrPriceTableRenter = errors.New("price table was not built for renter, request a new price table")
 )
 
 // expirePriceTables removes expired price tables from the list of valid price
@@ -71,18 +87,18 @@ func (pm *priceTableManager) pruneExpired() {
 
 // Get returns the price table with the given UID if it exists and
 // has not expired.
-func (pm *priceTableManager) Get(id [16]byte) (rhpv3.HostPriceTable, error) {
+func (pm *priceTableManager) Get(id [16]byte) (renterPriceTable, error) {
 	pm.mu.RLock()
 	pt, ok := pm.priceTables[id]
 	pm.mu.RUnlock()
 	if !ok {
-		return rhpv3.HostPriceTable{}, ErrNoPriceTable
+		return renterPriceTable{}, ErrNoPriceTable
 	}
 	return pt, nil
 }
 
 // Register adds a price table to the list of valid price tables.
-func (pm *priceTableManager) Register(pt rhpv3.HostPriceTable) {
+func (pm *priceTableManager) Register(pt renterPriceTable) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
@@ -171,13 +187,66 @@ func (sh *SessionHandler) PriceTable() (rhpv3.HostPriceTable, error) {
 	}, nil
 }
 
+// normalizeMultiplier returns the price multiplier of a policy. Zero means
+// the host's prices are used.
+func normalizeMultiplier(policy contracts.RenterPolicy) float64 {
+	if policy.PriceMultiplier == 0 {
+		return 1
+	}
+	return policy.PriceMultiplier
+}
+
+// buildPriceTable returns a price table for the renter identified on the
+// session. Price tables are sent before the renter pays for them, so the
+// renter's policy can only be applied if an earlier RPC on the same transport
+// identified the renter. Collateral is not adjusted.
+func (sh *SessionHandler) buildPriceTable(sess *session) (renterPriceTable, error) {
+	pt, err := sh.PriceTable()
+	if err != nil {
+		return renterPriceTable{}, err
+	}
+	renterKey, ok := sess.Renter()
+	if !ok {
+		return renterPriceTable{HostPriceTable: pt, multiplier: 1}, nil
+	}
+	policy, err := sh.contracts.RenterPolicy(renterKey)
+	if err != nil {
+		return renterPriceTable{}, fmt.Errorf("failed to get renter policy: %w", err)
+	}
+	pt.InitBaseCost = policy.ApplyPrice(pt.InitBaseCost)
+	pt.ReadBaseCost = policy.ApplyPrice(pt.ReadBaseCost)
+	pt.WriteBaseCost = policy.ApplyPrice(pt.WriteBaseCost)
+	pt.WriteStoreCost = policy.ApplyPrice(pt.WriteStoreCost)
+	pt.DownloadBandwidthCost = policy.ApplyPrice(pt.DownloadBandwidthCost)
+	pt.UploadBandwidthCost = policy.ApplyPrice(pt.UploadBandwidthCost)
+	pt.LatestRevisionCost = policy.ApplyPrice(pt.LatestRevisionCost)
+	pt.ContractPrice = policy.ApplyPrice(pt.ContractPrice)
+	pt.RenewContractCost = policy.ApplyPrice(pt.RenewContractCost)
+	return renterPriceTable{HostPriceTable: pt, multiplier: normalizeMultiplier(policy)}, nil
+}
+
+// checkPriceTable identifies the renter paying for an RPC on the session and
+// returns ErrPriceTableRenter if the price table has lower prices than the
+// renter's policy requires. Price tables with higher prices are accepted so
+// that renters with a discount are not rejected before they are identified.
+func (sh *SessionHandler) checkPriceTable(sess *session, pt renterPriceTable, renterKey types.PublicKey) error {
+	sess.Identify(renterKey)
+	policy, err := sh.contracts.RenterPolicy(renterKey)
+	if err != nil {
+		return fmt.Errorf("failed to get renter policy: %w", err)
+	} else if normalizeMultiplier(policy) > pt.multiplier {
+		return ErrPriceTableRenter
+	}
+	return nil
+}
+
 // readPriceTable reads the price table ID from the stream and returns an error
 // if the price table is invalid or expired.
-func (sh *SessionHandler) readPriceTable(s *rhpv3.Stream) (rhpv3.HostPriceTable, error) {
+func (sh *SessionHandler) readPriceTable(s *rhpv3.Stream) (renterPriceTable, error) {
 	// read the price table ID from the stream
 	var uid rhpv3.SettingsID
 	if err := s.ReadRequest(&uid, 16); err != nil {
-		return rhpv3.HostPriceTable{}, fmt.Errorf("failed to read price table ID: %w", err)
+		return renterPriceTable{}, fmt.Errorf("failed to read price table ID: %w", err)
 	}
 	return sh.priceTables.Get(uid)
 }
@@ -187,7 +256,7 @@ func (sh *SessionHandler) readPriceTable(s *rhpv3.Stream) (rhpv3.HostPriceTable,
 func newPriceTableManager() *priceTableManager {
 	pm := &priceTableManager{
 		expirationList: list.New(),
-		priceTables:    make(map[rhpv3.SettingsID]rhpv3.HostPriceTable),
+		priceTables:    make(map[rhpv3.SettingsID]renterPriceTable),
 	}
 	return pm
 }
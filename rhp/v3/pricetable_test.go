@@ -12,9 +12,11 @@ import (
 func TestPriceTableManager(t *testing.T) {
 	pm := newPriceTableManager()
 	t.Run("serial", func(t *testing.T) {
-		pt := rhpv3.HostPriceTable{
-			UID:      frand.Entropy128(),
-			Validity: 100 * time.Millisecond,
+		pt := renterPriceTable{
+			HostPriceTable: rhpv3.HostPriceTable{
+				UID:      frand.Entropy128(),
+				Validity: 100 * time.Millisecond,
+			},
 		}
 		if _, err := pm.Get(pt.UID); err == nil {
 			t.Error("expected error")
@@ -51,9 +53,11 @@ func TestPriceTableManager(t *testing.T) {
 		wg.Add(len(tables))
 		for _, id := range tables {
 			go func(id [16]byte) {
-				pm.Register(rhpv3.HostPriceTable{
-					UID:      id,
-					Validity: 250 * time.Millisecond,
+				pm.Register(renterPriceTable{
+					HostPriceTable: rhpv3.HostPriceTable{
+						UID:      id,
+						Validity: 250 * time.Millisecond,
+					},
 				})
 				wg.Done()
 			}(id)
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sync"
 	"time"
 
 	"go.sia.tech/core/consensus"
@@ -52,6 +53,12 @@ type (
 
 		// SectorRoots returns the sector roots of the contract with the given ID.
 		SectorRoots(id types.FileContractID, limit, offset uint64) ([]types.Hash256, error)
+
+		// RenterPolicy returns the policy of a renter.
+		RenterPolicy(renterKey types.PublicKey) (contracts.RenterPolicy, error)
+		// CheckRenterPolicy returns the renter's policy or an error if the
+		// renter is not allowed to add contracts.
+		CheckRenterPolicy(renterKey types.PublicKey, additionalContracts uint64) (contracts.RenterPolicy, error)
 	}
 
 	// A StorageManager manages the storage of sectors on disk.
@@ -118,6 +125,16 @@ type (
 		Add(financials.Record) error
 	}
 
+	// A session tracks the renter identified on a transport. RHP3 price
+	// tables are sent before the renter pays for them, so the renter's
+	// policy is applied to price tables requested after an earlier RPC on
+	// the same transport identified the renter.
+	session struct {
+		mu         sync.Mutex
+		renterKey  types.PublicKey
+		identified bool
+	}
+
 	// A SessionHandler handles the host side of the renter-host protocol and
 	// manages renter sessions
 	SessionHandler struct {
@@ -140,6 +157,12 @@ type (
 		wallet   Wallet
 
 		priceTables *priceTableManager
+
+		mu sync.Mutex // protects the fields below
+		// accountRenters maps ephemeral accounts to the renter whose contract
+		// last funded the account. It is used to identify renters paying
+		// with an account.
+		accountRenters map[rhpv3.Account]types.PublicKey
 	}
 )
 
@@ -175,8 +198,38 @@ var (
 	ErrUpdateProofSize = errors.New("update section is not a multiple of the segment size")
 )
 
+// Renter returns the renter identified on the session, if any.
+func (sess *session) Renter() (types.PublicKey, bool) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.renterKey, sess.identified
+}
+
+// Identify sets the renter identified on the session.
+func (sess *session) Identify(renterKey types.PublicKey) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.renterKey, sess.identified = renterKey, true
+}
+
+// setAccountRenter records the renter whose contract funded an account.
+func (sh *SessionHandler) setAccountRenter(account rhpv3.Account, renterKey types.PublicKey) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.accountRenters[account] = renterKey
+}
+
+// accountRenter returns the renter whose contract funded an account. Accounts
+// funded before the host started are not known.
+func (sh *SessionHandler) accountRenter(account rhpv3.Account) (types.PublicKey, bool) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	renterKey, ok := sh.accountRenters[account]
+	return renterKey, ok
+}
+
 // handleHostStream handles streams routed to the "host" subscriber
-func (sh *SessionHandler) handleHostStream(remoteAddr string, s *rhpv3.Stream) {
+func (sh *SessionHandler) handleHostStream(remoteAddr string, sess *session, s *rhpv3.Stream) {
 	defer s.Close() // close the stream when the RPC has completed
 
 	done, err := sh.tg.Add() // add the RPC to the threadgroup
@@ -192,7 +245,7 @@ func (sh *SessionHandler) handleHostStream(remoteAddr string, s *rhpv3.Stream) {
 		return
 	}
 
-	rpcs := map[types.Specifier]func(*rhpv3.Stream, *zap.Logger) error{
+	rpcs := map[types.Specifier]func(*session, *rhpv3.Stream, *zap.Logger) error{
 		rhpv3.RPCAccountBalanceID:   sh.handleRPCAccountBalance,
 		rhpv3.RPCUpdatePriceTableID: sh.handleRPCPriceTable,
 		rhpv3.RPCExecuteProgramID:   sh.handleRPCExecute,
@@ -209,7 +262,7 @@ func (sh *SessionHandler) handleHostStream(remoteAddr string, s *rhpv3.Stream) {
 	log := sh.log.Named(rpcID.String()).With(zap.String("peerAddr", remoteAddr))
 	start := time.Now()
 	s.SetDeadline(time.Now().Add(time.Minute)) // set the initial deadline, may be overwritten by the handler
-	if err = rpcFn(s, log); err != nil {
+	if err = rpcFn(sess, s, log); err != nil {
 		log.Warn("RPC failed", zap.Error(err), zap.Duration("elapsed", time.Since(start)))
 		return
 	}
@@ -247,6 +300,7 @@ func (sh *SessionHandler) Serve() error {
 			}
 			defer t.Close()
 
+			sess := new(session)
 			for {
 				stream, err := t.AcceptStream()
 				if err != nil {
@@ -255,7 +309,7 @@ func (sh *SessionHandler) Serve() error {
 					}
 					return
 				}
-				go sh.handleHostStream(conn.RemoteAddr().String(), stream)
+				go sh.handleHostStream(conn.RemoteAddr().String(), sess, stream)
 			}
 		}()
 	}
@@ -288,6 +342,8 @@ func NewSessionHandler(l net.Listener, hostKey types.PrivateKey, chain ChainMana
 		log:       log,
 
 		priceTables: newPriceTableManager(),
+
+		accountRenters: make(map[rhpv3.Account]types.PublicKey),
 	}
 	return sh, nil
 }
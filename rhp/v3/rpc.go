@@ -41,14 +41,16 @@ var (
 	ErrNotAcceptingContracts = errors.New("host is not accepting contracts")
 )
 
-// handleRPCPriceTable sends the host's price table to the renter.
-func (sh *SessionHandler) handleRPCPriceTable(s *rhpv3.Stream, log *zap.Logger) error {
-	pt, err := sh.PriceTable()
+// handleRPCPriceTable sends the host's price table to the renter. If the
+// renter has been identified on the transport, the renter's policy is applied
+// to the price table.
+func (sh *SessionHandler) handleRPCPriceTable(sess *session, s *rhpv3.Stream, log *zap.Logger) error {
+	pt, err := sh.buildPriceTable(sess)
 	if err != nil {
 		s.WriteResponseErr(ErrHostInternalError)
 		return fmt.Errorf("failed to get price table: %w", err)
 	}
-	buf, err := json.Marshal(pt)
+	buf, err := json.Marshal(pt.HostPriceTable)
 	if err != nil {
 		s.WriteResponseErr(ErrHostInternalError)
 		return fmt.Errorf("failed to marshal price table: %w", err)
@@ -63,7 +65,7 @@ func (sh *SessionHandler) handleRPCPriceTable(s *rhpv3.Stream, log *zap.Logger)
 
 	// process the payment, catch connection closed errors since the renter
 	// likely did not intend to pay
-	budget, err := sh.processPayment(s, &pt)
+	budget, err := sh.processPayment(sess, s, pt)
 	if isNonPaymentErr(err) {
 		return nil
 	} else if err != nil {
@@ -91,7 +93,7 @@ func (sh *SessionHandler) handleRPCPriceTable(s *rhpv3.Stream, log *zap.Logger)
 	return nil
 }
 
-func (sh *SessionHandler) handleRPCFundAccount(s *rhpv3.Stream, log *zap.Logger) error {
+func (sh *SessionHandler) handleRPCFundAccount(sess *session, s *rhpv3.Stream, log *zap.Logger) error {
 	s.SetDeadline(time.Now().Add(time.Minute))
 	// read the price table ID from the stream
 	pt, err := sh.readPriceTable(s)
@@ -108,7 +110,7 @@ func (sh *SessionHandler) handleRPCFundAccount(s *rhpv3.Stream, log *zap.Logger)
 	}
 
 	// process the payment for funding the account
-	fundAmount, balance, err := sh.processFundAccountPayment(pt, s, fundReq.Account)
+	fundAmount, balance, err := sh.processFundAccountPayment(sess, pt, s, fundReq.Account)
 	if err != nil {
 		err = fmt.Errorf("failed to process payment: %w", err)
 		s.WriteResponseErr(err)
@@ -135,7 +137,7 @@ func (sh *SessionHandler) handleRPCFundAccount(s *rhpv3.Stream, log *zap.Logger)
 	return nil
 }
 
-func (sh *SessionHandler) handleRPCAccountBalance(s *rhpv3.Stream, log *zap.Logger) error {
+func (sh *SessionHandler) handleRPCAccountBalance(sess *session, s *rhpv3.Stream, log *zap.Logger) error {
 	s.SetDeadline(time.Now().Add(time.Minute))
 	// get the price table to use for payment
 	pt, err := sh.readPriceTable(s)
@@ -146,7 +148,7 @@ func (sh *SessionHandler) handleRPCAccountBalance(s *rhpv3.Stream, log *zap.Logg
 	}
 
 	// read the payment from the stream
-	budget, err := sh.processPayment(s, &pt)
+	budget, err := sh.processPayment(sess, s, pt)
 	if err != nil {
 		err = fmt.Errorf("failed to process payment: %w", err)
 		s.WriteResponseErr(err)
@@ -185,7 +187,7 @@ func (sh *SessionHandler) handleRPCAccountBalance(s *rhpv3.Stream, log *zap.Logg
 	return nil
 }
 
-func (sh *SessionHandler) handleRPCLatestRevision(s *rhpv3.Stream, log *zap.Logger) error {
+func (sh *SessionHandler) handleRPCLatestRevision(sess *session, s *rhpv3.Stream, log *zap.Logger) error {
 	s.SetDeadline(time.Now().Add(time.Minute))
 	var req rhpv3.RPCLatestRevisionRequest
 	if err := s.ReadRequest(&req, maxRequestSize); err != nil {
@@ -215,7 +217,7 @@ func (sh *SessionHandler) handleRPCLatestRevision(s *rhpv3.Stream, log *zap.Logg
 		return err
 	}
 
-	budget, err := sh.processPayment(s, &pt)
+	budget, err := sh.processPayment(sess, s, pt)
 	if isNonPaymentErr(err) {
 		return nil
 	} else if err != nil {
@@ -235,7 +237,7 @@ func (sh *SessionHandler) handleRPCLatestRevision(s *rhpv3.Stream, log *zap.Logg
 	return nil
 }
 
-func (sh *SessionHandler) handleRPCRenew(s *rhpv3.Stream, log *zap.Logger) error {
+func (sh *SessionHandler) handleRPCRenew(sess *session, s *rhpv3.Stream, log *zap.Logger) error {
 	s.SetDeadline(time.Now().Add(2 * time.Minute))
 	if !sh.settings.Settings().AcceptingContracts {
 		s.WriteResponseErr(ErrNotAcceptingContracts)
@@ -244,12 +246,12 @@ func (sh *SessionHandler) handleRPCRenew(s *rhpv3.Stream, log *zap.Logger) error
 	pt, err := sh.readPriceTable(s)
 	if errors.Is(err, ErrNoPriceTable) {
 		// no price table, send the renter a default one
-		pt, err = sh.PriceTable()
+		pt, err = sh.buildPriceTable(sess)
 		if err != nil {
 			s.WriteResponseErr(ErrHostInternalError)
 			return fmt.Errorf("failed to get price table: %w", err)
 		}
-		buf, err := json.Marshal(pt)
+		buf, err := json.Marshal(pt.HostPriceTable)
 		if err != nil {
 			s.WriteResponseErr(ErrHostInternalError)
 			return fmt.Errorf("failed to marshal price table: %w", err)
@@ -284,6 +286,17 @@ func (sh *SessionHandler) handleRPCRenew(s *rhpv3.Stream, log *zap.Logger) error
 	clearingRevision := renewalTxn.FileContractRevisions[0]
 	renewal := renewalTxn.FileContracts[0]
 
+	// check that the renter is allowed to renew and that the price table
+	// was built for the renter
+	if _, err := sh.contracts.CheckRenterPolicy(renterKey, 0); err != nil {
+		err = fmt.Errorf("contract rejected: %w", err)
+		s.WriteResponseErr(err)
+		return err
+	} else if err := sh.checkPriceTable(sess, pt, renterKey); err != nil {
+		s.WriteResponseErr(err)
+		return err
+	}
+
 	// lock the existing contract
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -327,7 +340,7 @@ func (sh *SessionHandler) handleRPCRenew(s *rhpv3.Stream, log *zap.Logger) error
 		baseCollateral = pt.CollateralCost.Mul64(renewal.Filesize).Mul64(extension)
 	}
 
-	riskedCollateral, lockedCollateral, err := validateContractRenewal(existing.Revision, renewal, hostUnlockKey, req.RenterKey, sh.wallet.Address(), baseRevenue, baseCollateral, pt)
+	riskedCollateral, lockedCollateral, err := validateContractRenewal(existing.Revision, renewal, hostUnlockKey, req.RenterKey, sh.wallet.Address(), baseRevenue, baseCollateral, pt.HostPriceTable)
 	if err != nil {
 		err := fmt.Errorf("failed to validate renewal: %w", err)
 		s.WriteResponseErr(err)
@@ -438,7 +451,7 @@ func (sh *SessionHandler) handleRPCRenew(s *rhpv3.Stream, log *zap.Logger) error
 }
 
 // handleRPCExecute handles an RPCExecuteProgram request.
-func (sh *SessionHandler) handleRPCExecute(s *rhpv3.Stream, log *zap.Logger) error {
+func (sh *SessionHandler) handleRPCExecute(sess *session, s *rhpv3.Stream, log *zap.Logger) error {
 	s.SetDeadline(time.Now().Add(5 * time.Minute))
 	// read the price table
 	pt, err := sh.readPriceTable(s)
@@ -449,7 +462,7 @@ func (sh *SessionHandler) handleRPCExecute(s *rhpv3.Stream, log *zap.Logger) err
 	}
 
 	// create the program budget
-	budget, err := sh.processPayment(s, &pt)
+	budget, err := sh.processPayment(sess, s, pt)
 	if err != nil {
 		err = fmt.Errorf("failed to process payment: %w", err)
 		s.WriteResponseErr(err)
@@ -515,7 +528,7 @@ func (sh *SessionHandler) handleRPCExecute(s *rhpv3.Stream, log *zap.Logger) err
 	log.Debug("executing program", zap.Int("instructions", len(instructions)), zap.String("budget", budget.Remaining().ExactString()), zap.Bool("requiresFinalization", requiresFinalization))
 	// create the program executor
 	// note: the budget is committed by the executor, no need to commit it in the handler.
-	executor, err := sh.newExecutor(instructions, executeReq.ProgramData, pt, budget, revision, requiresFinalization, log)
+	executor, err := sh.newExecutor(instructions, executeReq.ProgramData, pt.HostPriceTable, budget, revision, requiresFinalization, log)
 	if err != nil {
 		s.WriteResponseErr(ErrHostInternalError)
 		return fmt.Errorf("failed to create program executor: %w", err)
@@ -5,15 +5,18 @@ import (
 	"context"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	rhpv2 "go.sia.tech/core/rhp/v2"
 	rhpv3 "go.sia.tech/core/rhp/v3"
 	"go.sia.tech/core/types"
+	"go.sia.tech/hostd/host/contracts"
 	"go.sia.tech/hostd/host/settings"
 	"go.sia.tech/hostd/internal/test"
 	proto3 "go.sia.tech/hostd/internal/test/rhp/v3"
+	rhp3 "go.sia.tech/hostd/rhp/v3"
 	"go.uber.org/zap/zaptest"
 	"lukechampine.com/frand"
 )
@@ -483,6 +486,112 @@ func TestRenew(t *testing.T) {
 	})
 }
 
+func TestRenterPolicyPricing(t *testing.T) {
+	log := zaptest.NewLogger(t)
+	renter, host, err := test.NewTestingPair(t.TempDir(), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer renter.Close()
+	defer host.Close()
+
+	policy := contracts.RenterPolicy{
+		RenterKey:       renter.PublicKey(),
+		PriceMultiplier: 2,
+	}
+	if err := host.Contracts().SetRenterPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+
+	hostPT, err := host.RHPv3PriceTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := renter.TipState()
+	origin, err := renter.FormContract(context.Background(), host.RHPv2Addr(), host.PublicKey(), types.Siacoins(50), types.Siacoins(100), state.Index.Height+200)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := renter.NewRHP3Session(context.Background(), host.RHPv3Addr(), host.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	// the renter has not been identified on the transport, so the price table
+	// has the host's prices and the payment should be rejected
+	account := rhpv3.Account(renter.PublicKey())
+	payment := proto3.ContractPayment(&origin, renter.PrivateKey(), account)
+	if _, err := session.RegisterPriceTable(payment); err == nil || !strings.Contains(err.Error(), rhp3.ErrPriceTableRenter.Error()) {
+		t.Fatalf("expected price table renter error, got %v", err)
+	}
+
+	// the payment identified the renter, the next price table should have the
+	// renter's prices
+	pt, err := session.RegisterPriceTable(payment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	switch {
+	case !pt.WriteStoreCost.Equals(hostPT.WriteStoreCost.Mul64(2)):
+		t.Fatalf("expected write store cost %v, got %v", hostPT.WriteStoreCost.Mul64(2), pt.WriteStoreCost)
+	case !pt.UploadBandwidthCost.Equals(hostPT.UploadBandwidthCost.Mul64(2)):
+		t.Fatalf("expected upload cost %v, got %v", hostPT.UploadBandwidthCost.Mul64(2), pt.UploadBandwidthCost)
+	case !pt.ContractPrice.Equals(hostPT.ContractPrice.Mul64(2)):
+		t.Fatalf("expected contract price %v, got %v", hostPT.ContractPrice.Mul64(2), pt.ContractPrice)
+	case !pt.RenewContractCost.Equals(hostPT.RenewContractCost.Mul64(2)):
+		t.Fatalf("expected renew contract cost %v, got %v", hostPT.RenewContractCost.Mul64(2), pt.RenewContractCost)
+	case !pt.CollateralCost.Equals(hostPT.CollateralCost):
+		t.Fatalf("expected collateral cost %v, got %v", hostPT.CollateralCost, pt.CollateralCost)
+	}
+
+	// fund an account and pay for a price table with it
+	if _, err := session.FundAccount(account, payment, types.Siacoins(1)); err != nil {
+		t.Fatal(err)
+	}
+	payment = proto3.AccountPayment(account, renter.PrivateKey())
+	if pt, err = session.RegisterPriceTable(payment); err != nil {
+		t.Fatal(err)
+	}
+
+	// upload a sector using the renter's prices
+	var sector [rhpv2.SectorSize]byte
+	frand.Read(sector[:256])
+	cost, _ := pt.BaseCost().Add(pt.AppendSectorCost(origin.Revision.WindowEnd - renter.TipState().Index.Height)).Total()
+	if _, err := session.AppendSector(&sector, &origin, renter.PrivateKey(), payment, cost); err != nil {
+		t.Fatal(err)
+	}
+	// paying the host's prices is not enough
+	hostCost, _ := hostPT.BaseCost().Add(hostPT.AppendSectorCost(origin.Revision.WindowEnd - renter.TipState().Index.Height)).Total()
+	if _, err := session.AppendSector(&sector, &origin, renter.PrivateKey(), payment, hostCost); err == nil {
+		t.Fatal("expected upload to fail")
+	}
+
+	// renew the contract using the renter's price table
+	settings, err := renter.Settings(context.Background(), host.RHPv2Addr(), host.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	renewal, _, err := session.RenewContract(&origin, settings.Address, renter.PrivateKey(), types.Siacoins(10), types.Siacoins(20), origin.Revision.WindowEnd+10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contract, err := host.Contracts().Contract(renewal.ID())
+	if err != nil {
+		t.Fatal(err)
+	} else if !contract.Usage.RPCRevenue.Equals(pt.ContractPrice) {
+		t.Fatalf("expected %d RPC revenue, got %d", pt.ContractPrice, contract.Usage.RPCRevenue)
+	}
+	extension := renewal.Revision.WindowEnd - origin.Revision.WindowEnd
+	baseStorageRevenue := pt.RenewContractCost.Add(pt.WriteStoreCost.Mul64(origin.Revision.Filesize).Mul64(extension))
+	if !contract.Usage.StorageRevenue.Equals(baseStorageRevenue) {
+		t.Fatalf("expected %d storage revenue, got %d", baseStorageRevenue, contract.Usage.StorageRevenue)
+	}
+}
+
 func BenchmarkAppendSector(b *testing.B) {
 	log := zaptest.NewLogger(b)
 	renter, host, err := test.NewTestingPair(b.TempDir(), log)
@@ -2,7 +2,9 @@ package rhp_test
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"net/http"
 	"testing"
 
 	rhpv3 "go.sia.tech/core/rhp/v3"
@@ -20,7 +22,12 @@ func TestWebSockets(t *testing.T) {
 	defer renter.Close()
 	defer host.Close()
 
-	c, _, err := websocket.Dial(context.Background(), "ws://"+host.RHPv3WSAddr()+"/ws", nil)
+	// the test host uses a self-signed certificate
+	c, _, err := websocket.Dial(context.Background(), "wss://"+host.RHPv3WSAddr()+"/ws", &websocket.DialOptions{
+		HTTPClient: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		},
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
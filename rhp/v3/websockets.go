@@ -31,13 +31,14 @@ func (sh *SessionHandler) handleWebSockets(w http.ResponseWriter, r *http.Reques
 	}
 	defer t.Close()
 
+	sess := new(session)
 	for {
 		stream, err := t.AcceptStream()
 		if err != nil {
 			log.Debug("failed to accept stream", zap.Error(err))
 			return
 		}
-		go sh.handleHostStream(conn.RemoteAddr().String(), stream)
+		go sh.handleHostStream(conn.RemoteAddr().String(), sess, stream)
 	}
 }
 